@@ -51,6 +51,24 @@ func main() {
 	revenueRepo := repositories.NewRevenueRepository(sqlDB)
 	reminderRepo := repositories.NewReminderRepository(db)
 	saleChargeRepo := repositories.NewSaleChargeRepository(sqlDB)
+	systemSettingsRepo := repositories.NewSystemSettingsRepository(sqlDB)
+	webhookRepo := repositories.NewWebhookRepository(sqlDB)
+	servicingRepo := repositories.NewServicingRepository(sqlDB)
+	maintenanceRepo := repositories.NewMaintenanceRepository(sqlDB)
+	ledgerRepo := repositories.NewLedgerRepository(sqlDB)
+	reportRepo := repositories.NewReportRepository(sqlDB)
+	dashboardRepo := repositories.NewDashboardRepository(sqlDB)
+	paymentMethodRepo := repositories.NewPaymentMethodRepository(sqlDB)
+	receiptRepo := repositories.NewReceiptRepository(sqlDB)
+	paymentGatewayRepo := repositories.NewPaymentGatewayRepository(sqlDB)
+	saleBalanceRepo := repositories.NewSaleBalanceRepository(sqlDB)
+	budgetRepo := repositories.NewBudgetRepository(sqlDB)
+	cashShiftRepo := repositories.NewCashShiftRepository(sqlDB)
+	branchRepo := repositories.NewBranchRepository(sqlDB)
+	companyRepo := repositories.NewCompanyRepository(sqlDB)
+	taxRepo := repositories.NewTaxRepository(sqlDB)
+
+	r2Storage := repositories.NewR2Storage(cfg)
 
 	// Initialize services
 	returnService := services.NewReturnService(returnRepo)
@@ -60,23 +78,50 @@ func main() {
 	videoService := services.NewVideoService(videoRepo)
 	futurBookingService := services.NewFuturBookingService(futurBookingRepo)
 	paymentVerificationService := services.NewPaymentVerificationService(paymentVerificationRepo)
-	paymentService := services.NewPaymentService(paymentRepo)
+	paymentService := services.NewPaymentService(paymentRepo, r2Storage)
 	saleDetailService := services.NewSaleDetailService(saleDetailRepo)
 	dataService := services.NewDataAggregateService(dataRepo, dataRepo)
 	disableDateService := services.NewDisableDateService(disableDateRepo)
-	statementService := services.NewStatementService(statementRepo)
-	expenseService := services.NewExpenseService(expenseRepo)
+	systemSettingsService := services.NewSystemSettingsService(systemSettingsRepo)
+	statementService := services.NewStatementService(statementRepo, systemSettingsService)
+	expenseService := services.NewExpenseService(expenseRepo, r2Storage, cfg.ExpenseApprovalThreshold)
 	revenueService := services.NewRevenueService(revenueRepo)
 	reminderService := services.NewReminderService(reminderRepo)
 	saleChargeService := services.NewSaleChargeService(saleChargeRepo)
-	systemSettingsService := services.NewSystemSettingsService(systemSettingsRepo)
+	webhookService := services.NewWebhookService(webhookRepo)
+	servicingService := services.NewServicingService(servicingRepo)
+	maintenanceService := services.NewMaintenanceService(maintenanceRepo)
+	ledgerService := services.NewLedgerService(ledgerRepo)
+	reportService := services.NewReportService(reportRepo)
+	dashboardService := services.NewDashboardService(dashboardRepo, systemSettingsService)
+	paymentMethodService := services.NewPaymentMethodService(paymentMethodRepo, receiptRepo)
+	var paymentGateways []services.PaymentGateway
+	if cfg.MockGatewaySecret != "" {
+		paymentGateways = append(paymentGateways, services.NewMockGateway(cfg.MockGatewaySecret, cfg.MockGatewayMethod, cfg.PublicBaseURL))
+	}
+	saleBalanceService := services.NewSaleBalanceService(saleBalanceRepo)
+	paymentGatewayService := services.NewPaymentGatewayService(paymentGatewayRepo, cfg.PublicBaseURL, paymentGateways...)
+	budgetService := services.NewBudgetService(budgetRepo, expenseRepo, cfg.ExpenseApprovalThreshold)
+	cashShiftService := services.NewCashShiftService(cashShiftRepo)
+	branchService := services.NewBranchService(branchRepo)
+	companyService := services.NewCompanyService(companyRepo)
+	taxService := services.NewTaxService(taxRepo)
+
+	// Deliver queued webhook events in the background
+	webhookService.StartDispatcher()
+
+	// Flip vehicle status as maintenance blocks start and end
+	maintenanceService.StartScheduler()
+
+	// Book recurring expenses as they come due and alert on overspent budgets
+	budgetService.StartScheduler()
 
 	// Initialize handlers
 	returnHandler := handlers.NewReturnHandler(returnService, cfg.JWTSecret)
 	authHandler := handlers.NewAuthHandler(authService, systemSettingsService)
 	vehicleHandler := handlers.NewVehicleHandler(vehicleService)
 	saleHandler := handlers.NewSaleHandler(saleService, cfg.JWTSecret)
-	videoHandler := handlers.NewVideoHandler(videoService, "https://pub-8da91f66939f4cdc9e4206024a0e68e9.r2.dev")
+	videoHandler := handlers.NewVideoHandler(videoService, cfg.R2PublicURL)
 	futurBookingHandler := handlers.NewFuturBookingHandler(futurBookingService)
 	paymentVerificationHandler := handlers.NewPaymentVerification(paymentVerificationService, cfg.JWTSecret)
 	paymentHandler := handlers.NewPaymentHandler(paymentService, cfg.JWTSecret)
@@ -89,6 +134,20 @@ func main() {
 	reminderHandler := handlers.NewReminderHandler(reminderService, userRepo)
 	saleChargeHandler := handlers.NewSaleChargeHandler(saleChargeService, cfg.JWTSecret)
 	systemSettingsHandler := handlers.NewSystemSettingsHandler(systemSettingsService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	servicingHandler := handlers.NewServicingHandler(servicingService)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	reportHandler := handlers.NewReportHandler(reportService)
+	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
+	paymentMethodHandler := handlers.NewPaymentMethodHandler(paymentMethodService)
+	paymentGatewayHandler := handlers.NewPaymentGatewayHandler(paymentGatewayService)
+	saleBalanceHandler := handlers.NewSaleBalanceHandler(saleBalanceService)
+	budgetHandler := handlers.NewBudgetHandler(budgetService)
+	cashShiftHandler := handlers.NewCashShiftHandler(cashShiftService)
+	branchHandler := handlers.NewBranchHandler(branchService)
+	companyHandler := handlers.NewCompanyHandler(companyService)
+	taxHandler := handlers.NewTaxHandler(taxService)
 
 	// Initialize Gin router
 	router := gin.Default()
@@ -100,7 +159,7 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Platform-Key"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
@@ -120,6 +179,26 @@ func main() {
 		v1.POST("/register", authHandler.Register)
 		v1.POST("/login", authHandler.Login)
 		v1.GET("/system-settings", systemSettingsHandler.GetSystemSettings)
+		v1.POST("/invites/accept", companyHandler.AcceptInvite)
+
+		// Platform operator routes, trusted by the platform key rather than a company user's token
+		if cfg.PlatformAdminKey != "" {
+			platform := v1.Group("/platform")
+			platform.Use(middleware.PlatformKey(cfg.PlatformAdminKey))
+			{
+				platform.POST("/companies", companyHandler.OnboardCompany)
+				platform.GET("/settings", systemSettingsHandler.GetPlatformSettings)
+				platform.PUT("/settings/:key", systemSettingsHandler.UpdatePlatformSetting)
+			}
+		}
+
+		// Payment gateway callbacks, trusted by the provider's signature rather than a token
+		v1.GET("/payment-gateway/:provider/callback", paymentGatewayHandler.HandleCallback)
+		v1.POST("/payment-gateway/:provider/webhook", paymentGatewayHandler.HandleWebhook)
+		// The mock checkout is unauthenticated; LoadConfig only allows the secret with a localhost PUBLIC_BASE_URL
+		if cfg.MockGatewaySecret != "" {
+			v1.GET("/payment-gateway/mock/checkout/:reference", paymentGatewayHandler.MockCheckout)
+		}
 
 		// Protected routes, JWT authentication required
 		protected := v1.Group("")
@@ -138,6 +217,7 @@ func main() {
 
 			// Vehicle routes
 			protected.GET("/vehicle", vehicleHandler.ListVehicles)
+			protected.PUT("/vehicle/:vehicle_id/branch", reminderHandler.CheckAdminPermission(), branchHandler.AssignVehicle)
 
 			// Return routes
 			protected.POST("/sales/:id/return", returnHandler.CreateReturn)
@@ -146,6 +226,7 @@ func main() {
 			protected.POST("/sales", saleHandler.CreateSale)
 			protected.GET("/sales/:id", saleHandler.GetSaleByID)
 			protected.GET("/sales", saleHandler.GetSales)
+			protected.GET("/sales/:id/balance", saleBalanceHandler.GetSaleBalance)
 
 			// Sale charges routes (must come before /sales/:saleID to avoid route conflicts)
 			protected.GET("/sales/charges/test", saleChargeHandler.TestEndpoint)
@@ -164,10 +245,33 @@ func main() {
 			protected.PUT("/payment/:payment_id/sales/:sale_id/verify", paymentVerificationHandler.VerifyPayment) // Updated path
 			protected.GET("/payment/:payment_id", paymentVerificationHandler.GetPaymentDetails)                   // New GET endpoint
 			protected.POST("/payment/:payment_id/cancel", paymentVerificationHandler.CancelPayment)               // New Cancel endpoint
+			protected.POST("/payment/:payment_id/refund", paymentVerificationHandler.RefundPayment)
+			paymentQueue := protected.Group("/payment/verification")
+			paymentQueue.Use(reminderHandler.CheckAdminPermission())
+			{
+				paymentQueue.GET("/queue", paymentVerificationHandler.GetVerificationQueue)
+				paymentQueue.POST("/bulk", paymentVerificationHandler.BulkVerify)
+			}
 
 			// Payment routes
 			protected.PUT("/payment/:payment_id", paymentHandler.UpdatePayment)
 			protected.POST("/sales/:id/payment", paymentHandler.InsertPayment)
+			protected.GET("/payment/:payment_id/receipt", paymentMethodHandler.GetPaymentReceipt)
+			protected.POST("/payment/:payment_id/attachments", paymentHandler.UploadAttachment)
+
+			// Payment gateway routes
+			protected.POST("/sales/:id/payment/gateway", paymentGatewayHandler.InitiatePayment)
+			protected.GET("/payment-gateway/providers", paymentGatewayHandler.GetProviders)
+			protected.GET("/payment-gateway/payments/:reference", paymentGatewayHandler.GetGatewayPayment)
+
+			// Payment method routes
+			protected.GET("/payment-methods", paymentMethodHandler.GetPaymentMethods)
+			adminPaymentMethods := protected.Group("/payment-methods")
+			adminPaymentMethods.Use(reminderHandler.CheckAdminPermission())
+			{
+				adminPaymentMethods.POST("", paymentMethodHandler.SavePaymentMethod)
+				adminPaymentMethods.PUT("/:code", paymentMethodHandler.SavePaymentMethod)
+			}
 
 			// FuturBooking routes
 			protected.GET("/futur-bookings", futurBookingHandler.GetFuturBookingsByMonth)
@@ -178,15 +282,29 @@ func main() {
 
 			// DataAggregate route
 			protected.GET("/aggregate", dataHandler.GetAggregatedData)
+			protected.GET("/dashboard", dashboardHandler.GetDashboard)
 			protected.GET("/disabled-dates", disableDateHandler.GetDisabledDates)
 
 			// Statement routes
 			protected.GET("/statements", statementHandler.GetOutstandingStatements)
+			protected.GET("/statements/customer", statementHandler.GetCustomerStatement)
+			protected.GET("/statements/customer/pdf", statementHandler.GetCustomerStatementPDF)
+			protected.GET("/statements/:sale_id/account", statementHandler.GetSaleStatement)
+			protected.GET("/statements/:sale_id/pdf", statementHandler.GetSaleStatementPDF)
+
+			// Receivable ageing (accounting permission required)
+			ageing := protected.Group("/statements/ageing")
+			ageing.Use(authHandler.CheckAccountingPermission())
+			{
+				ageing.GET("", statementHandler.GetAgeingReport)
+				ageing.GET("/sales", statementHandler.GetAgeingSales)
+			}
 
 			// Expense routes
 			expenses := protected.Group("/expenses")
 			{
 				// Read operations accessible to all authenticated users
+				expenses.GET("/categories", expenseHandler.GetCategories)
 				expenses.GET("/:id", expenseHandler.GetExpenseByID)
 				expenses.GET("", expenseHandler.GetAllExpenses)
 
@@ -197,6 +315,76 @@ func main() {
 					expensesWithPermission.POST("", expenseHandler.CreateExpense)
 					expensesWithPermission.PUT("/:id", expenseHandler.UpdateExpense)
 					expensesWithPermission.DELETE("/:id", expenseHandler.DeleteExpense)
+					expensesWithPermission.POST("/:id/receipts", expenseHandler.UploadReceipt)
+					expensesWithPermission.POST("/categories", expenseHandler.CreateCategory)
+					expensesWithPermission.DELETE("/categories/:category_id", expenseHandler.DeleteCategory)
+
+					// Recurring expense templates
+					expensesWithPermission.GET("/recurring", budgetHandler.GetRecurringExpenses)
+					expensesWithPermission.POST("/recurring", budgetHandler.CreateRecurringExpense)
+					expensesWithPermission.POST("/recurring/run", budgetHandler.RunRecurringExpenses)
+					expensesWithPermission.PUT("/recurring/:recurring_id", budgetHandler.UpdateRecurringExpense)
+					expensesWithPermission.DELETE("/recurring/:recurring_id", budgetHandler.DeleteRecurringExpense)
+
+					// Monthly budgets per category
+					expensesWithPermission.GET("/budgets", budgetHandler.GetBudgets)
+					expensesWithPermission.POST("/budgets", budgetHandler.SetBudget)
+					expensesWithPermission.GET("/budgets/report", budgetHandler.GetBudgetReport)
+					expensesWithPermission.GET("/budgets/alerts", budgetHandler.GetBudgetAlerts)
+					expensesWithPermission.DELETE("/budgets/:budget_id", budgetHandler.DeleteBudget)
+				}
+
+				// Approving expenses above the threshold requires admin permission
+				adminExpenses := expenses.Group("")
+				adminExpenses.Use(reminderHandler.CheckAdminPermission())
+				{
+					adminExpenses.POST("/:id/approve", expenseHandler.ApproveExpense)
+					adminExpenses.POST("/:id/reject", expenseHandler.RejectExpense)
+				}
+			}
+
+			// General ledger routes (accounting permission required)
+			ledger := protected.Group("/ledger")
+			ledger.Use(authHandler.CheckAccountingPermission())
+			{
+				ledger.GET("/accounts", ledgerHandler.GetAccounts)
+				ledger.POST("/accounts", ledgerHandler.CreateAccount)
+				ledger.GET("/accounts/:code/ledger", ledgerHandler.GetAccountLedger)
+				ledger.GET("/entries", ledgerHandler.GetEntries)
+				ledger.GET("/entries/:id", ledgerHandler.GetEntry)
+				ledger.POST("/entries", ledgerHandler.CreateManualEntry)
+				ledger.GET("/trial-balance", ledgerHandler.GetTrialBalance)
+				ledger.GET("/periods", ledgerHandler.GetPeriods)
+				ledger.POST("/periods/close", ledgerHandler.ClosePeriod)
+			}
+
+			// Financial report routes (accounting permission required)
+			reports := protected.Group("/reports")
+			reports.Use(authHandler.CheckAccountingPermission())
+			{
+				reports.GET("/profit-loss", reportHandler.GetProfitLoss)
+				reports.GET("/cash-flow", reportHandler.GetCashFlow)
+				reports.GET("/vehicles", reportHandler.GetFleetAnalytics)
+				reports.GET("/vehicles/:vehicle_id", reportHandler.GetVehicleAnalytics)
+			}
+
+			// VAT rates, tax invoices and the monthly sales-tax report (accounting permission required)
+			tax := protected.Group("/tax")
+			tax.Use(authHandler.CheckAccountingPermission())
+			{
+				tax.GET("/rates", taxHandler.GetRates)
+				tax.GET("/invoices", taxHandler.GetInvoices)
+				tax.POST("/invoices", taxHandler.IssueInvoice)
+				tax.GET("/invoices/:invoice_id", taxHandler.GetInvoice)
+				tax.POST("/invoices/:invoice_id/void", taxHandler.VoidInvoice)
+				tax.GET("/report", taxHandler.GetSalesTaxReport)
+
+				// Changing rates requires admin permission as well
+				adminTax := tax.Group("")
+				adminTax.Use(reminderHandler.CheckAdminPermission())
+				{
+					adminTax.PUT("/rates", taxHandler.SetRate)
+					adminTax.DELETE("/rates/:charge_type", taxHandler.DeleteRate)
 				}
 			}
 
@@ -204,6 +392,14 @@ func main() {
 			protected.GET("/revenue", revenueHandler.GetRevenue)
 			protected.GET("/revenue/monthly", revenueHandler.GetMonthlyRevenue)
 			protected.GET("/revenue/mobile-visualization", revenueHandler.GetMobileRevenueVisualization)
+			protected.GET("/revenue/recognition/:sale_id", revenueHandler.GetRecognitionSchedule)
+
+			adminRevenue := protected.Group("/revenue/recognition")
+			adminRevenue.Use(reminderHandler.CheckAdminPermission())
+			{
+				adminRevenue.POST("/recompute", revenueHandler.RecomputeAllRecognition)
+				adminRevenue.POST("/:sale_id/recompute", revenueHandler.RecomputeSaleRecognition)
+			}
 
 			// Reminder routes
 			reminders := protected.Group("/reminders")
@@ -212,6 +408,11 @@ func main() {
 				reminders.GET("/vehicles/:vehicle_id/reminders", reminderHandler.GetRemindersByVehicle)
 				reminders.GET("/vehicles/:vehicle_id/reminders/filter", reminderHandler.GetRemindersByVehicleAndType)
 				reminders.GET("/due", reminderHandler.GetDueReminders)
+				reminders.GET("/alerts", reminderHandler.GetUpcomingAlerts)
+				reminders.GET("", reminderHandler.ListReminders)
+				reminders.GET("/types", reminderHandler.GetReminderTypes)
+				reminders.GET("/:reminder_id", reminderHandler.GetReminder)
+				reminders.GET("/:reminder_id/history", reminderHandler.GetReminderHistory)
 
 				// Write operations require admin permission
 				adminReminders := reminders.Group("")
@@ -219,21 +420,125 @@ func main() {
 				{
 					adminReminders.POST("", reminderHandler.CreateReminder)
 					adminReminders.POST("/:reminder_id/acknowledge", reminderHandler.AcknowledgeReminder)
+					adminReminders.POST("/:reminder_id/snooze", reminderHandler.SnoozeReminder)
+					adminReminders.PUT("/:reminder_id", reminderHandler.UpdateReminder)
+					adminReminders.DELETE("/:reminder_id", reminderHandler.DeleteReminder)
+					adminReminders.POST("/types", reminderHandler.CreateReminderType)
+					adminReminders.DELETE("/types/:type_id", reminderHandler.DeleteReminderType)
 				}
 			}
 
-			// System settings routes (admin only)
+			// Servicing routes
+			servicing := protected.Group("/servicing")
+			{
+				servicing.GET("", servicingHandler.GetServicingStatuses)
+				servicing.GET("/intervals", servicingHandler.GetIntervals)
+				servicing.GET("/vehicles/:vehicle_id", servicingHandler.GetVehicleServicing)
+
+				// Write operations require admin permission
+				adminServicing := servicing.Group("")
+				adminServicing.Use(reminderHandler.CheckAdminPermission())
+				{
+					adminServicing.PUT("/intervals/:vehicle_type_id", servicingHandler.UpdateInterval)
+					adminServicing.POST("/vehicles/:vehicle_id/start", servicingHandler.StartServicing)
+					adminServicing.POST("/vehicles/:vehicle_id/complete", servicingHandler.MarkServiced)
+				}
+			}
+
+			// Maintenance block routes
+			maintenance := protected.Group("/maintenance-blocks")
+			{
+				maintenance.GET("", maintenanceHandler.GetBlocks)
+
+				// Write operations require admin permission
+				adminMaintenance := maintenance.Group("")
+				adminMaintenance.Use(reminderHandler.CheckAdminPermission())
+				{
+					adminMaintenance.POST("", maintenanceHandler.CreateBlock)
+					adminMaintenance.PUT("/:id", maintenanceHandler.UpdateBlock)
+					adminMaintenance.POST("/:id/cancel", maintenanceHandler.CancelBlock)
+				}
+			}
+
+			// Cash drawer shifts: staff open and close their own, managers sign them off
+			cashShifts := protected.Group("/cash-shifts")
+			{
+				cashShifts.POST("/open", cashShiftHandler.OpenShift)
+				cashShifts.GET("/current", cashShiftHandler.GetCurrentShift)
+				cashShifts.POST("/current/close", cashShiftHandler.CloseShift)
+
+				adminCashShifts := cashShifts.Group("")
+				adminCashShifts.Use(reminderHandler.CheckAdminPermission())
+				{
+					adminCashShifts.GET("", cashShiftHandler.GetShifts)
+					adminCashShifts.GET("/report", cashShiftHandler.GetEndOfDayReport)
+					adminCashShifts.GET("/:shift_id", cashShiftHandler.GetShift)
+					adminCashShifts.POST("/:shift_id/approve", cashShiftHandler.ApproveShift)
+				}
+			}
+
+			// Branch routes: staff can look branches and one-way fees up, admins manage them
+			branches := protected.Group("/branches")
+			{
+				branches.GET("", branchHandler.GetBranches)
+				branches.GET("/transfer-fees", branchHandler.GetTransferFees)
+				branches.GET("/:branch_id", branchHandler.GetBranch)
+
+				adminBranches := branches.Group("")
+				adminBranches.Use(reminderHandler.CheckAdminPermission())
+				{
+					adminBranches.POST("", branchHandler.CreateBranch)
+					adminBranches.PUT("/transfer-fees", branchHandler.SetTransferFee)
+					adminBranches.DELETE("/transfer-fees/:fee_id", branchHandler.DeleteTransferFee)
+					adminBranches.PUT("/:branch_id", branchHandler.UpdateBranch)
+				}
+			}
+
+			// System settings routes (admin only); kept for clients from before /settings
 			systemSettings := protected.Group("/system-settings")
 			systemSettings.Use(reminderHandler.CheckAdminPermission())
 			{
-				systemSettings.PUT("/:key", systemSettingsHandler.UpdateSystemSetting)
+				systemSettings.PUT("/:key", systemSettingsHandler.UpdateSetting)
 			}
 
-			// User management routes (admin only)
+			// Company settings: everyone can read them, admins change them
+			settings := protected.Group("/settings")
+			{
+				settings.GET("", systemSettingsHandler.GetSettings)
+				settings.GET("/:key", systemSettingsHandler.GetSetting)
+
+				adminSettings := settings.Group("")
+				adminSettings.Use(reminderHandler.CheckAdminPermission())
+				{
+					adminSettings.PUT("/:key", systemSettingsHandler.UpdateSetting)
+					adminSettings.DELETE("/:key", systemSettingsHandler.ResetSetting)
+				}
+			}
+
+			// User management routes (admin only), scoped to the admin's company
 			users := protected.Group("/users")
 			users.Use(reminderHandler.CheckAdminPermission())
 			{
-				users.POST("/:user_id/lockout", authHandler.LockoutUser)
+				users.GET("", companyHandler.GetUsers)
+				users.GET("/invites", companyHandler.GetPendingInvites)
+				users.POST("/invites", companyHandler.InviteUser)
+				users.GET("/:user_id", companyHandler.GetUser)
+				users.PUT("/:user_id/roles", companyHandler.SetUserRoles)
+				users.POST("/:user_id/lockout", companyHandler.LockUser)
+				users.POST("/:user_id/unlock", companyHandler.UnlockUser)
+				users.PUT("/:user_id/branch", branchHandler.AssignUser)
+			}
+
+			// Webhook routes (admin only)
+			webhooks := protected.Group("/webhooks")
+			webhooks.Use(reminderHandler.CheckAdminPermission())
+			{
+				webhooks.GET("", webhookHandler.GetEndpoints)
+				webhooks.POST("", webhookHandler.CreateEndpoint)
+				webhooks.PUT("/:id", webhookHandler.UpdateEndpoint)
+				webhooks.DELETE("/:id", webhookHandler.DeleteEndpoint)
+				webhooks.GET("/deliveries/dead", webhookHandler.GetDeadDeliveries)
+				webhooks.POST("/deliveries/:id/retry", webhookHandler.RetryDelivery)
 			}
 		}
 	}
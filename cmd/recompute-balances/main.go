@@ -0,0 +1,46 @@
+// Command recompute-balances re-derives the payment status of every sale from its charges and
+// payments, repairing sales whose status drifted before it was computed in one place.
+//
+//	go run ./cmd/recompute-balances [-company 3]
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"renting/internal/config"
+	"renting/internal/repositories"
+	"renting/internal/services"
+)
+
+func main() {
+	companyID := flag.Int("company", 0, "only recompute sales of this company (0 for all)")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := sql.Open("postgres", cfg.DBConnStr)
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+
+	service := services.NewSaleBalanceService(repositories.NewSaleBalanceRepository(db))
+	checked, changed, failed, err := service.RecomputeAll(*companyID)
+	if err != nil {
+		log.Fatalf("Failed to recompute sale balances: %v", err)
+	}
+
+	log.Printf("Checked %d sales, %d changed, %d failed", checked, len(changed), len(failed))
+	if len(failed) > 0 {
+		log.Printf("Failed sales: %v", failed)
+		os.Exit(1)
+	}
+}
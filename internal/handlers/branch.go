@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BranchHandler struct {
+	service *services.BranchService
+}
+
+func NewBranchHandler(service *services.BranchService) *BranchHandler {
+	return &BranchHandler{service: service}
+}
+
+func branchError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrBranchNotFound):
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, repositories.ErrInvalidBranch):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+	}
+}
+
+// branchIDFromQuery reads the optional branch_id filter, answering 400 when it is not a number
+func branchIDFromQuery(c *gin.Context) (*int, bool) {
+	value := c.Query("branch_id")
+	if value == "" {
+		return nil, true
+	}
+	branchID, err := strconv.Atoi(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid branch_id", nil))
+		return nil, false
+	}
+	return &branchID, true
+}
+
+// GetBranches handles GET /api/v1/branches?include_inactive=true
+func (h *BranchHandler) GetBranches(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	branches, err := h.service.GetBranches(companyID, c.Query("include_inactive") == "true")
+	if err != nil {
+		branchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Branches retrieved successfully", branches))
+}
+
+// GetBranch handles GET /api/v1/branches/:branch_id
+func (h *BranchHandler) GetBranch(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	branchID, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid branch ID", nil))
+		return
+	}
+
+	branch, err := h.service.GetBranch(companyID, branchID)
+	if err != nil {
+		branchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Branch retrieved successfully", branch))
+}
+
+// CreateBranch handles POST /api/v1/branches
+func (h *BranchHandler) CreateBranch(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.BranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	branch, err := h.service.CreateBranch(companyID, req)
+	if err != nil {
+		branchError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Branch created successfully", branch))
+}
+
+// UpdateBranch handles PUT /api/v1/branches/:branch_id
+func (h *BranchHandler) UpdateBranch(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	branchID, err := strconv.Atoi(c.Param("branch_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid branch ID", nil))
+		return
+	}
+
+	var req models.BranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	branch, err := h.service.UpdateBranch(companyID, branchID, req)
+	if err != nil {
+		branchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Branch updated successfully", branch))
+}
+
+// GetTransferFees handles GET /api/v1/branches/transfer-fees
+func (h *BranchHandler) GetTransferFees(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	fees, err := h.service.GetTransferFees(companyID)
+	if err != nil {
+		branchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Transfer fees retrieved successfully", fees))
+}
+
+// SetTransferFee handles PUT /api/v1/branches/transfer-fees
+func (h *BranchHandler) SetTransferFee(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.BranchTransferFeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	fee, err := h.service.SetTransferFee(companyID, req)
+	if err != nil {
+		branchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Transfer fee saved successfully", fee))
+}
+
+// DeleteTransferFee handles DELETE /api/v1/branches/transfer-fees/:fee_id
+func (h *BranchHandler) DeleteTransferFee(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	feeID, err := strconv.Atoi(c.Param("fee_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid transfer fee ID", nil))
+		return
+	}
+
+	if err := h.service.DeleteTransferFee(companyID, feeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Transfer fee not found", nil))
+			return
+		}
+		branchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Transfer fee deleted successfully", nil))
+}
+
+// AssignUser handles PUT /api/v1/users/:user_id/branch
+func (h *BranchHandler) AssignUser(c *gin.Context) {
+	h.assign(c, "user_id", "user", h.service.AssignUser)
+}
+
+// AssignVehicle handles PUT /api/v1/vehicle/:vehicle_id/branch
+func (h *BranchHandler) AssignVehicle(c *gin.Context) {
+	h.assign(c, "vehicle_id", "vehicle", h.service.AssignVehicle)
+}
+
+func (h *BranchHandler) assign(c *gin.Context, param, subject string, assign func(companyID, id int, req models.AssignBranchRequest) error) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid "+subject+" ID", nil))
+		return
+	}
+
+	var req models.AssignBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	if err := assign(companyID, id, req); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, subject+" not found", nil))
+			return
+		}
+		branchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Branch assigned successfully", req))
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetHandler struct {
+	service *services.BudgetService
+}
+
+func NewBudgetHandler(service *services.BudgetService) *BudgetHandler {
+	return &BudgetHandler{service: service}
+}
+
+// GetRecurringExpenses handles GET /api/v1/expenses/recurring
+func (h *BudgetHandler) GetRecurringExpenses(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	templates, err := h.service.GetRecurringExpenses(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Recurring expenses retrieved successfully", templates))
+}
+
+// CreateRecurringExpense handles POST /api/v1/expenses/recurring
+func (h *BudgetHandler) CreateRecurringExpense(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.RecurringExpenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	template, err := h.service.CreateRecurringExpense(companyID, userID.(int), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Recurring expense created successfully", template))
+}
+
+// UpdateRecurringExpense handles PUT /api/v1/expenses/recurring/:recurring_id
+func (h *BudgetHandler) UpdateRecurringExpense(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	recurringID, err := strconv.Atoi(c.Param("recurring_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid recurring expense ID", nil))
+		return
+	}
+
+	var req models.RecurringExpenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	template, err := h.service.UpdateRecurringExpense(companyID, recurringID, req)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Recurring expense not found", nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Recurring expense updated successfully", template))
+}
+
+// DeleteRecurringExpense handles DELETE /api/v1/expenses/recurring/:recurring_id
+func (h *BudgetHandler) DeleteRecurringExpense(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	recurringID, err := strconv.Atoi(c.Param("recurring_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid recurring expense ID", nil))
+		return
+	}
+
+	if err := h.service.DeleteRecurringExpense(companyID, recurringID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Recurring expense not found", nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Recurring expense deleted successfully", nil))
+}
+
+// RunRecurringExpenses handles POST /api/v1/expenses/recurring/run, booking due occurrences now
+// instead of waiting for the scheduler. Only the caller's company is run.
+func (h *BudgetHandler) RunRecurringExpenses(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	booked, err := h.service.RunRecurringExpenses(&companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	h.service.CheckBudgets(&companyID)
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Recurring expenses run successfully", gin.H{"booked": booked}))
+}
+
+// GetBudgets handles GET /api/v1/expenses/budgets?month=YYYY-MM
+func (h *BudgetHandler) GetBudgets(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	budgets, err := h.service.GetBudgets(companyID, c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Budgets retrieved successfully", budgets))
+}
+
+// SetBudget handles POST /api/v1/expenses/budgets
+func (h *BudgetHandler) SetBudget(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.ExpenseBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	budget, err := h.service.SetBudget(companyID, userID.(int), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Budget saved successfully", budget))
+}
+
+// DeleteBudget handles DELETE /api/v1/expenses/budgets/:budget_id
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	budgetID, err := strconv.Atoi(c.Param("budget_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid budget ID", nil))
+		return
+	}
+
+	if err := h.service.DeleteBudget(companyID, budgetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Budget not found", nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Budget deleted successfully", nil))
+}
+
+// GetBudgetReport handles GET /api/v1/expenses/budgets/report?month=YYYY-MM
+func (h *BudgetHandler) GetBudgetReport(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetBudgetReport(companyID, c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Budget report generated successfully", report))
+}
+
+// GetBudgetAlerts handles GET /api/v1/expenses/budgets/alerts?month=YYYY-MM
+func (h *BudgetHandler) GetBudgetAlerts(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	alerts, err := h.service.GetBudgetAlerts(companyID, c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Budget alerts retrieved successfully", alerts))
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CashShiftHandler struct {
+	service *services.CashShiftService
+}
+
+func NewCashShiftHandler(service *services.CashShiftService) *CashShiftHandler {
+	return &CashShiftHandler{service: service}
+}
+
+func cashShiftError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrCashShiftNotFound), errors.Is(err, repositories.ErrNoOpenCashShift):
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, repositories.ErrCashShiftAlreadyOpen), errors.Is(err, repositories.ErrCashShiftNotClosed):
+		c.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, err.Error(), nil))
+	case errors.Is(err, services.ErrInvalidCashShift):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+	}
+}
+
+// OpenShift handles POST /api/v1/cash-shifts/open
+func (h *CashShiftHandler) OpenShift(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.OpenCashShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	shift, err := h.service.OpenShift(companyID, userID.(int), req)
+	if err != nil {
+		cashShiftError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Cash shift opened successfully", shift))
+}
+
+// GetCurrentShift handles GET /api/v1/cash-shifts/current
+func (h *CashShiftHandler) GetCurrentShift(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	shift, err := h.service.GetCurrentShift(userID.(int))
+	if err != nil {
+		cashShiftError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Cash shift retrieved successfully", shift))
+}
+
+// CloseShift handles POST /api/v1/cash-shifts/current/close
+func (h *CashShiftHandler) CloseShift(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.CloseCashShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	shift, err := h.service.CloseShift(userID.(int), req)
+	if err != nil {
+		cashShiftError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Cash shift closed successfully", shift))
+}
+
+// GetShifts handles GET /api/v1/cash-shifts?user_id=&status=&date=YYYY-MM-DD
+func (h *CashShiftHandler) GetShifts(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var filter models.CashShiftFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	shifts, err := h.service.GetShifts(companyID, filter)
+	if err != nil {
+		cashShiftError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Cash shifts retrieved successfully", shifts))
+}
+
+// GetShift handles GET /api/v1/cash-shifts/:shift_id
+func (h *CashShiftHandler) GetShift(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	shiftID, err := strconv.Atoi(c.Param("shift_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid shift ID", nil))
+		return
+	}
+
+	shift, err := h.service.GetShift(companyID, shiftID)
+	if err != nil {
+		cashShiftError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Cash shift retrieved successfully", shift))
+}
+
+// ApproveShift handles POST /api/v1/cash-shifts/:shift_id/approve
+func (h *CashShiftHandler) ApproveShift(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	shiftID, err := strconv.Atoi(c.Param("shift_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid shift ID", nil))
+		return
+	}
+
+	var req models.ApproveCashShiftRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	shift, err := h.service.ApproveShift(companyID, shiftID, userID.(int), req)
+	if err != nil {
+		cashShiftError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Cash shift signed off successfully", shift))
+}
+
+// GetEndOfDayReport handles GET /api/v1/cash-shifts/report?date=YYYY-MM-DD&branch_id=
+func (h *CashShiftHandler) GetEndOfDayReport(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	branchID, ok := branchIDFromQuery(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetEndOfDayReport(companyID, c.Query("date"), branchID)
+	if err != nil {
+		cashShiftError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "End of day report retrieved successfully", report))
+}
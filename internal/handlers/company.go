@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CompanyHandler struct {
+	service *services.CompanyService
+}
+
+func NewCompanyHandler(service *services.CompanyService) *CompanyHandler {
+	return &CompanyHandler{service: service}
+}
+
+func companyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, repositories.ErrInviteNotFound):
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, repositories.ErrCompanyCodeTaken), errors.Is(err, repositories.ErrUserExists),
+		errors.Is(err, repositories.ErrLastAdmin):
+		c.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, err.Error(), nil))
+	case errors.Is(err, services.ErrInvalidCompany), errors.Is(err, services.ErrInvalidUser),
+		errors.Is(err, repositories.ErrInvalidBranch):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+	}
+}
+
+// OnboardCompany handles POST /api/v1/platform/companies
+func (h *CompanyHandler) OnboardCompany(c *gin.Context) {
+	var req models.OnboardCompanyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	onboarded, err := h.service.OnboardCompany(req)
+	if err != nil {
+		companyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Company onboarded successfully", onboarded))
+}
+
+// GetUsers handles GET /api/v1/users?status=&role=&branch_id=&search=
+func (h *CompanyHandler) GetUsers(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var filter models.CompanyUserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	users, err := h.service.GetUsers(companyID, filter)
+	if err != nil {
+		companyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Users retrieved successfully", users))
+}
+
+// GetUser handles GET /api/v1/users/:user_id
+func (h *CompanyHandler) GetUser(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid user ID", nil))
+		return
+	}
+
+	user, err := h.service.GetUser(companyID, userID)
+	if err != nil {
+		companyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "User retrieved successfully", user))
+}
+
+// SetUserRoles handles PUT /api/v1/users/:user_id/roles
+func (h *CompanyHandler) SetUserRoles(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid user ID", nil))
+		return
+	}
+
+	var req models.UpdateUserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	user, err := h.service.SetUserRoles(companyID, userID, req)
+	if err != nil {
+		companyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "User roles updated successfully", user))
+}
+
+// LockUser handles POST /api/v1/users/:user_id/lockout
+func (h *CompanyHandler) LockUser(c *gin.Context) {
+	h.setLocked(c, true, "User locked out successfully")
+}
+
+// UnlockUser handles POST /api/v1/users/:user_id/unlock
+func (h *CompanyHandler) UnlockUser(c *gin.Context) {
+	h.setLocked(c, false, "User unlocked successfully")
+}
+
+func (h *CompanyHandler) setLocked(c *gin.Context, locked bool, message string) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid user ID", nil))
+		return
+	}
+
+	var user *models.CompanyUser
+	if locked {
+		user, err = h.service.LockUser(companyID, userID)
+	} else {
+		user, err = h.service.UnlockUser(companyID, userID)
+	}
+	if err != nil {
+		companyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, message, user))
+}
+
+// InviteUser handles POST /api/v1/users/invites
+func (h *CompanyHandler) InviteUser(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+
+	var req models.InviteUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	invite, err := h.service.InviteUser(companyID, userID.(int), req)
+	if err != nil {
+		companyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "User invited successfully", invite))
+}
+
+// GetPendingInvites handles GET /api/v1/users/invites
+func (h *CompanyHandler) GetPendingInvites(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	invites, err := h.service.GetPendingInvites(companyID)
+	if err != nil {
+		companyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Invites retrieved successfully", invites))
+}
+
+// AcceptInvite handles POST /api/v1/invites/accept
+func (h *CompanyHandler) AcceptInvite(c *gin.Context) {
+	var req models.AcceptInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	user, err := h.service.AcceptInvite(req)
+	if err != nil {
+		companyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Invite accepted successfully", user))
+}
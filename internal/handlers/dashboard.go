@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"renting/internal/models"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DashboardHandler struct {
+	service *services.DashboardService
+}
+
+func NewDashboardHandler(service *services.DashboardService) *DashboardHandler {
+	return &DashboardHandler{service: service}
+}
+
+// GetDashboard handles GET /api/v1/dashboard?period=day|week|month|year&date=YYYY-MM-DD
+func (h *DashboardHandler) GetDashboard(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.DashboardRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	dashboard, err := h.service.GetDashboard(companyID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDashboardPeriod) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Dashboard retrieved successfully", dashboard))
+}
@@ -26,13 +26,18 @@ func (h *DataAggregateHandler) GetAggregatedData(c *gin.Context) {
 	var aggregatedData services.AggregatedData
 	var err error
 
+	branchID, ok := branchIDFromQuery(c)
+	if !ok {
+		return
+	}
+
 	if date != "" {
 		parsedTime, parseErr := time.Parse("2006-01-02", date)
 		if parseErr != nil {
 			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid date format", nil))
 			return
 		}
-		aggregatedData, err = h.DataAggregateService.GetAggregatedData(parsedTime, "date")
+		aggregatedData, err = h.DataAggregateService.GetAggregatedData(parsedTime, "date", branchID)
 	} else if year != "" {
 		yearInt, parseErr := strconv.Atoi(year)
 		if parseErr != nil {
@@ -40,14 +45,14 @@ func (h *DataAggregateHandler) GetAggregatedData(c *gin.Context) {
 			return
 		}
 		parsedTime := time.Date(yearInt, 1, 1, 0, 0, 0, 0, time.UTC)
-		aggregatedData, err = h.DataAggregateService.GetAggregatedData(parsedTime, "year")
+		aggregatedData, err = h.DataAggregateService.GetAggregatedData(parsedTime, "year", branchID)
 	} else if month != "" {
 		parsedTime, parseErr := time.Parse("2006-01", month)
 		if parseErr != nil {
 			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid month format", nil))
 			return
 		}
-		aggregatedData, err = h.DataAggregateService.GetAggregatedData(parsedTime, "month")
+		aggregatedData, err = h.DataAggregateService.GetAggregatedData(parsedTime, "month", branchID)
 	} else {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Please provide a date, year, or month", nil))
 		return
@@ -63,7 +68,12 @@ func (h *DataAggregateHandler) GetAggregatedData(c *gin.Context) {
 
 // GetTotalAvailableCars handles retrieving the total count of available cars
 func (h *DataAggregateHandler) GetTotalAvailableCars(c *gin.Context) {
-	count, err := h.DataAggregateService.GetTotalAvailableCars()
+	branchID, ok := branchIDFromQuery(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.DataAggregateService.GetTotalAvailableCars(branchID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  http.StatusInternalServerError,
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -79,13 +80,24 @@ func CheckAccountingPermission() gin.HandlerFunc {
 }
 
 func (h *ExpenseHandler) CreateExpense(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
 	var expense models.Expense
 	if err := c.ShouldBindJSON(&expense); err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
 		return
 	}
+	if expense.RecordedBy == nil {
+		if userID, exists := c.Get("userID"); exists {
+			recordedBy := userID.(int)
+			expense.RecordedBy = &recordedBy
+		}
+	}
 
-	if err := h.service.CreateExpense(&expense); err != nil {
+	if err := h.service.CreateExpense(companyID, &expense); err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
 		return
 	}
@@ -93,6 +105,11 @@ func (h *ExpenseHandler) CreateExpense(c *gin.Context) {
 }
 
 func (h *ExpenseHandler) UpdateExpense(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid expense ID", nil))
@@ -105,14 +122,24 @@ func (h *ExpenseHandler) UpdateExpense(c *gin.Context) {
 		return
 	}
 
+	if expense.RecordedBy == nil {
+		if userID, exists := c.Get("userID"); exists {
+			recordedBy := userID.(int)
+			expense.RecordedBy = &recordedBy
+		}
+	}
+
 	expense.ExpenseID = id
-	if err := h.service.UpdateExpense(id, &expense); err != nil {
+	if err := h.service.UpdateExpense(companyID, id, &expense); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Expense not found", nil))
+			return
+		}
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
 		return
 	}
 	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Expense updated successfully", expense))
 }
-
 func (h *ExpenseHandler) DeleteExpense(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -157,3 +184,155 @@ func (h *ExpenseHandler) GetAllExpenses(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Expenses retrieved successfully", expenses))
 }
+
+// ApproveExpense handles POST /api/v1/expenses/:id/approve
+func (h *ExpenseHandler) ApproveExpense(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid expense ID", nil))
+		return
+	}
+
+	expense, err := h.service.ApproveExpense(id, userID.(int))
+	if err != nil {
+		if errors.Is(err, repositories.ErrExpenseNotPending) {
+			c.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Expense approved successfully", expense))
+}
+
+// RejectExpense handles POST /api/v1/expenses/:id/reject
+func (h *ExpenseHandler) RejectExpense(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid expense ID", nil))
+		return
+	}
+
+	var req models.ExpenseRejectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	expense, err := h.service.RejectExpense(id, userID.(int), req.Reason)
+	if err != nil {
+		if errors.Is(err, repositories.ErrExpenseNotPending) {
+			c.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Expense rejected successfully", expense))
+}
+
+// UploadReceipt handles POST /api/v1/expenses/:id/receipts with a multipart "file" field
+func (h *ExpenseHandler) UploadReceipt(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid expense ID", nil))
+		return
+	}
+
+	maxSize := int64(10 << 20) // 10MB
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Failed to read file. Maximum size is 10MB", err.Error()))
+		return
+	}
+
+	receipt, err := h.service.UploadReceipt(id, userID.(int), file)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Expense not found", nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Receipt uploaded successfully", receipt))
+}
+
+// GetCategories handles listing the built-in and company-defined expense categories
+func (h *ExpenseHandler) GetCategories(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	categories, err := h.service.GetCategories(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Expense categories retrieved successfully", categories))
+}
+
+// CreateCategory handles adding a company-defined expense category
+func (h *ExpenseHandler) CreateCategory(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.ExpenseCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	category, err := h.service.CreateCategory(companyID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Expense category created successfully", category))
+}
+
+// DeleteCategory handles removing a company-defined expense category
+func (h *ExpenseHandler) DeleteCategory(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	categoryID, err := strconv.Atoi(c.Param("category_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid expense category ID", nil))
+		return
+	}
+
+	if err := h.service.DeleteCategory(companyID, categoryID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Expense category not found", nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Expense category deleted successfully", nil))
+}
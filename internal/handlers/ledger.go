@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerHandler struct {
+	service *services.LedgerService
+}
+
+func NewLedgerHandler(service *services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{service: service}
+}
+
+// parseOptionalDate reads a YYYY-MM-DD query parameter, returning nil when it is absent
+func parseOptionalDate(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.New("invalid " + name + ", expected YYYY-MM-DD")
+	}
+	return &date, nil
+}
+
+func (h *LedgerHandler) GetAccounts(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.service.GetAccounts(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Accounts retrieved successfully", accounts))
+}
+
+func (h *LedgerHandler) CreateAccount(c *gin.Context) {
+	var req models.AccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	account, err := h.service.CreateAccount(companyID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Account created successfully", account))
+}
+
+func (h *LedgerHandler) GetAccountLedger(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	startDate, err := parseOptionalDate(c, "start_date")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	endDate, err := parseOptionalDate(c, "end_date")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+
+	ledger, err := h.service.GetAccountLedger(companyID, c.Param("code"), startDate, endDate)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "account not found" {
+			status = http.StatusNotFound
+		} else if err.Error() == "end_date must not be before start_date" {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Account ledger retrieved successfully", ledger))
+}
+
+func (h *LedgerHandler) GetEntries(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var filter models.JournalEntryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	entries, err := h.service.GetEntries(companyID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Journal entries retrieved successfully", entries))
+}
+
+func (h *LedgerHandler) GetEntry(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	entryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid entry ID", nil))
+		return
+	}
+
+	entry, err := h.service.GetEntry(companyID, entryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Journal entry retrieved successfully", entry))
+}
+
+func (h *LedgerHandler) CreateManualEntry(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.JournalEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	entry, err := h.service.CreateManualEntry(companyID, userID.(int), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repositories.ErrUnbalancedEntry) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Journal entry posted successfully", entry))
+}
+
+func (h *LedgerHandler) GetTrialBalance(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	asOf, err := parseOptionalDate(c, "as_of")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	date := time.Now()
+	if asOf != nil {
+		date = *asOf
+	}
+
+	trialBalance, err := h.service.GetTrialBalance(companyID, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Trial balance retrieved successfully", trialBalance))
+}
+
+func (h *LedgerHandler) GetPeriods(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	periods, err := h.service.GetPeriods(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Accounting periods retrieved successfully", periods))
+}
+
+func (h *LedgerHandler) ClosePeriod(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.ClosePeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	period, err := h.service.ClosePeriod(companyID, userID.(int), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Accounting period closed successfully", period))
+}
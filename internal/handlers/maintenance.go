@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MaintenanceHandler struct {
+	service *services.MaintenanceService
+}
+
+func NewMaintenanceHandler(service *services.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{service: service}
+}
+
+func (h *MaintenanceHandler) GetBlocks(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var filter models.MaintenanceBlockFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	blocks, err := h.service.GetBlocks(companyID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Maintenance blocks retrieved successfully", blocks))
+}
+
+func (h *MaintenanceHandler) CreateBlock(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.MaintenanceBlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	block, err := h.service.CreateBlock(companyID, userID.(int), req)
+	if err != nil {
+		if errors.Is(err, repositories.ErrVehicleNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Maintenance block created successfully", block))
+}
+
+func (h *MaintenanceHandler) UpdateBlock(c *gin.Context) {
+	blockID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid block ID", nil))
+		return
+	}
+
+	var req models.MaintenanceBlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	block, err := h.service.UpdateBlock(companyID, blockID, req)
+	if err != nil {
+		if errors.Is(err, repositories.ErrMaintenanceBlockNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Maintenance block updated successfully", block))
+}
+
+func (h *MaintenanceHandler) CancelBlock(c *gin.Context) {
+	blockID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid block ID", nil))
+		return
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelBlock(companyID, blockID); err != nil {
+		if errors.Is(err, repositories.ErrMaintenanceBlockNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Maintenance block cancelled successfully", nil))
+}
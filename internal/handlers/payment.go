@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
+	"mime/multipart"
 	"net/http"
+	"renting/internal/repositories"
 	"renting/internal/services"
 	"renting/internal/utils"
 	"strconv"
@@ -10,6 +13,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// maxPaymentUploadSize caps a payment request carrying evidence files
+const maxPaymentUploadSize = int64(25 << 20) // 25MB
+
 type PaymentHandler struct {
 	PaymentService *services.PaymentService
 	jwtSecret      string // Added jwtSecret field
@@ -68,6 +74,19 @@ func (h *PaymentHandler) GetPaymentsWithSales(c *gin.Context) {
 		filter.SaleType = &saleType
 	}
 
+	// mine=true lists the caller's own payments, e.g. to see why one was rejected
+	if c.Query("mine") == "true" {
+		if userID, exists := c.Get("userID"); exists {
+			id := userID.(int)
+			filter.SubmittedBy = &id
+		}
+	} else if submittedBy := c.Query("submitted_by"); submittedBy != "" {
+		id, err := strconv.Atoi(submittedBy)
+		if err == nil {
+			filter.SubmittedBy = &id
+		}
+	}
+
 	// Parse pagination parameters
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
@@ -120,6 +139,10 @@ func (h *PaymentHandler) UpdatePayment(c *gin.Context) {
 	// Call the service to update payment
 	err = h.PaymentService.UpdatePayment(paymentID, userID, req.PaymentType, req.AmountPaid)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidPaymentMethod) || errors.Is(err, repositories.ErrAttachmentRequired) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
 		switch err.Error() {
 		case "cannot update a completed payment":
 			c.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, err.Error(), nil))
@@ -144,28 +167,43 @@ func (h *PaymentHandler) InsertPayment(c *gin.Context) {
 		return
 	}
 
-	// Extract user_id using utils.ExtractUserIDFromToken
-	// userID, err := utils.ExtractUserIDFromToken(c, h.jwtSecret)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "Unauthorized", err.Error()))
+	// The staff member taking the payment is recorded so rejections can be shown to them
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "Unauthorized", nil))
 		return
 	}
 
-	// Parse request body
+	// Parse request body: JSON, or multipart form fields with evidence files under "files"
 	type InsertPaymentRequest struct {
-		PaymentType string  `json:"payment_type" binding:"required"`
-		AmountPaid  float64 `json:"amount_paid" binding:"required,gt=0"`
-		Remark      string  `json:"remark"`
+		PaymentType string  `json:"payment_type" form:"payment_type" binding:"required"`
+		AmountPaid  float64 `json:"amount_paid" form:"amount_paid" binding:"required,gt=0"`
+		Remark      string  `json:"remark" form:"remark"`
 	}
 	var req InsertPaymentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	var files []*multipart.FileHeader
+	if c.ContentType() == "multipart/form-data" {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPaymentUploadSize)
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+			return
+		}
+		if form, err := c.MultipartForm(); err == nil {
+			files = append(form.File["files"], form.File["file"]...)
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
 		return
 	}
 
 	// Call the service to insert payment
-	paymentID, err := h.PaymentService.InsertPayment(saleID, req.PaymentType, req.AmountPaid, req.Remark)
+	paymentID, err := h.PaymentService.InsertPayment(saleID, userID.(int), req.PaymentType, req.AmountPaid, req.Remark, files)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidPaymentMethod) || errors.Is(err, repositories.ErrAttachmentRequired) ||
+			errors.Is(err, services.ErrInvalidPaymentAttachment) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to create payment", err.Error()))
 		return
 	}
@@ -174,3 +212,45 @@ func (h *PaymentHandler) InsertPayment(c *gin.Context) {
 	responseData := map[string]int{"payment_id": paymentID}
 	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Payment created successfully", responseData))
 }
+
+// UploadAttachment handles POST /api/v1/payment/:payment_id/attachments with a multipart "file"
+// field, adding evidence to a payment that is still pending
+func (h *PaymentHandler) UploadAttachment(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	paymentID, err := strconv.Atoi(c.Param("payment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid payment ID", nil))
+		return
+	}
+
+	maxSize := int64(10 << 20) // 10MB
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Failed to read file. Maximum size is 10MB", err.Error()))
+		return
+	}
+
+	attachment, err := h.PaymentService.AddAttachment(companyID, paymentID, userID.(int), file)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrPaymentNotFound):
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+		case errors.Is(err, repositories.ErrPaymentNotPending), errors.Is(err, services.ErrInvalidPaymentAttachment):
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		default:
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Attachment uploaded successfully", attachment))
+}
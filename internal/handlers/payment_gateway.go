@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentGatewayHandler struct {
+	service *services.PaymentGatewayService
+}
+
+func NewPaymentGatewayHandler(service *services.PaymentGatewayService) *PaymentGatewayHandler {
+	return &PaymentGatewayHandler{service: service}
+}
+
+func gatewayErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUnknownGateway), errors.Is(err, repositories.ErrGatewayPaymentNotFound),
+		errors.Is(err, repositories.ErrSaleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidGatewaySignature):
+		return http.StatusUnauthorized
+	case errors.Is(err, repositories.ErrGatewayAmountMismatch):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidGatewayRequest), errors.Is(err, repositories.ErrInvalidPaymentMethod):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetProviders handles GET /api/v1/payment-gateway/providers
+func (h *PaymentGatewayHandler) GetProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Payment gateways retrieved successfully", h.service.Providers()))
+}
+
+// InitiatePayment handles POST /api/v1/sales/:id/payment/gateway
+func (h *PaymentGatewayHandler) InitiatePayment(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	saleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid sale ID", nil))
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User not found in token", nil))
+		return
+	}
+
+	var req models.GatewayInitiateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	checkout, err := h.service.Initiate(companyID, saleID, userID.(int), req)
+	if err != nil {
+		status := gatewayErrorStatus(err)
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Gateway payment initiated successfully", checkout))
+}
+
+// GetGatewayPayment handles GET /api/v1/payment-gateway/payments/:reference
+func (h *PaymentGatewayHandler) GetGatewayPayment(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	payment, err := h.service.GetPayment(companyID, c.Param("reference"))
+	if err != nil {
+		status := gatewayErrorStatus(err)
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Gateway payment retrieved successfully", payment))
+}
+
+// HandleCallback handles the customer's redirect back from the provider,
+// GET /api/v1/payment-gateway/:provider/callback. The customer is sent on to the return URL given
+// when the payment was initiated, if any.
+func (h *PaymentGatewayHandler) HandleCallback(c *gin.Context) {
+	payment, err := h.service.HandleCallback(c.Param("provider"), c.Request.URL.Query(), nil)
+	if err != nil {
+		log.Printf("Payment gateway callback from %s rejected: %v", c.Param("provider"), err)
+		status := gatewayErrorStatus(err)
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+
+	if payment.ReturnURL != nil {
+		if target, err := url.Parse(*payment.ReturnURL); err == nil {
+			query := target.Query()
+			query.Set("reference", payment.Reference)
+			query.Set("status", payment.Status)
+			target.RawQuery = query.Encode()
+			c.Redirect(http.StatusFound, target.String())
+			return
+		}
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Gateway payment "+payment.Status, payment))
+}
+
+// HandleWebhook handles server-to-server notifications, POST /api/v1/payment-gateway/:provider/webhook
+func (h *PaymentGatewayHandler) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 64<<10))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	payment, err := h.service.HandleCallback(c.Param("provider"), c.Request.URL.Query(), body)
+	if err != nil {
+		log.Printf("Payment gateway webhook from %s rejected: %v", c.Param("provider"), err)
+		status := gatewayErrorStatus(err)
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Gateway payment "+payment.Status, payment))
+}
+
+// MockCheckout stands in for the provider's payment page,
+// GET /api/v1/payment-gateway/mock/checkout/:reference?status=complete|cancel. It redirects the
+// customer back to the callback exactly as a real provider would.
+func (h *PaymentGatewayHandler) MockCheckout(c *gin.Context) {
+	completed := c.DefaultQuery("status", "complete") != "cancel"
+
+	callbackURL, err := h.service.SimulateMockPayment(c.Param("reference"), completed)
+	if err != nil {
+		status := gatewayErrorStatus(err)
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.Redirect(http.StatusFound, callbackURL)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PaymentMethodHandler struct {
+	service *services.PaymentMethodService
+}
+
+func NewPaymentMethodHandler(service *services.PaymentMethodService) *PaymentMethodHandler {
+	return &PaymentMethodHandler{service: service}
+}
+
+// GetPaymentMethods handles GET /api/v1/payment-methods?include_inactive=true
+func (h *PaymentMethodHandler) GetPaymentMethods(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	methods, err := h.service.GetMethods(companyID, c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Payment methods retrieved successfully", methods))
+}
+
+// SavePaymentMethod handles POST /api/v1/payment-methods and PUT /api/v1/payment-methods/:code
+func (h *PaymentMethodHandler) SavePaymentMethod(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.PaymentMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+	if code := c.Param("code"); code != "" {
+		req.Code = code
+	}
+
+	method, err := h.service.SaveMethod(companyID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Payment method saved successfully", method))
+}
+
+// GetPaymentReceipt handles GET /api/v1/payment/:payment_id/receipt?format=pdf|text|json&width=42
+func (h *PaymentMethodHandler) GetPaymentReceipt(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	paymentID, err := strconv.Atoi(c.Param("payment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid payment ID", nil))
+		return
+	}
+
+	receipt, err := h.service.GetReceipt(companyID, paymentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Receipt not found; payments get a receipt once verified", nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+
+	switch c.DefaultQuery("format", "pdf") {
+	case "pdf":
+		var buf bytes.Buffer
+		if err := services.RenderReceiptPDF(&buf, receipt); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="receipt_%s.pdf"`, receipt.ReceiptNumber))
+		c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+	case "text":
+		width, err := strconv.Atoi(c.DefaultQuery("width", strconv.Itoa(services.ReceiptWidth80mm)))
+		if err != nil || width > 80 {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid width", nil))
+			return
+		}
+		c.String(http.StatusOK, services.RenderReceiptText(receipt, width))
+	case "json":
+		c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Receipt retrieved successfully", receipt))
+	default:
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "format must be pdf, text or json", nil))
+	}
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"renting/internal/models"
+	"renting/internal/repositories"
 	"renting/internal/services"
 	"renting/internal/utils"
 	"strconv"
@@ -21,6 +23,7 @@ func NewPaymentVerification(paymentService *services.PaymentVerificationService,
 
 type VerifyPaymentRequest struct {
 	Status string `json:"status"`
+	Reason string `json:"reason"` // shown to the staff member when the payment is rejected
 }
 
 type CancelPaymentRequest struct {
@@ -55,7 +58,7 @@ func (h *PaymentVerification) VerifyPayment(c *gin.Context) {
 	}
 
 	// Call the service layer to verify the payment
-	err = h.paymentService.VerifyPayment(paymentID, req.Status, userID, saleID)
+	err = h.paymentService.VerifyPayment(paymentID, req.Status, userID, saleID, req.Reason)
 	if err != nil {
 		switch {
 		case errors.Is(err, errors.New("only admin users can verify payments")):
@@ -129,3 +132,113 @@ func (h *PaymentVerification) CancelPayment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Payment canceled successfully", nil))
 }
+
+// RefundPayment handles refunding a completed payment (POST)
+func (h *PaymentVerification) RefundPayment(c *gin.Context) {
+	paymentID, err := strconv.Atoi(c.Param("payment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid payment ID", nil))
+		return
+	}
+
+	userID, err := utils.ExtractUserIDFromToken(c, h.jwtSecret)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "Unauthorized", err.Error()))
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.paymentService.RefundPayment(companyID, paymentID, userID, req.Reason); err != nil {
+		if errors.Is(err, repositories.ErrPaymentNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Payment refunded successfully", nil))
+}
+
+// GetVerificationQueue handles GET /api/v1/payment/verification/queue?sale_type=&submitted_by=&limit=&offset=
+func (h *PaymentVerification) GetVerificationQueue(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	filter := models.VerificationQueueFilter{SaleType: c.Query("sale_type")}
+	if submittedBy := c.Query("submitted_by"); submittedBy != "" {
+		id, err := strconv.Atoi(submittedBy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid submitted_by", nil))
+			return
+		}
+		filter.SubmittedBy = &id
+	}
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	queue, err := h.paymentService.GetVerificationQueue(companyID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Verification queue retrieved successfully", queue))
+}
+
+// BulkVerify handles POST /api/v1/payment/verification/bulk. It answers 200 with a result per
+// payment even when some of them could not be settled.
+func (h *PaymentVerification) BulkVerify(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User not found in token", nil))
+		return
+	}
+
+	var req models.BulkVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	results, err := h.paymentService.BulkVerify(companyID, userID.(int), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBulkVerification) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Payments processed", gin.H{
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	}))
+}
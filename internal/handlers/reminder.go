@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"renting/internal/models"
 	"renting/internal/repositories"
@@ -10,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ReminderHandler struct {
@@ -68,7 +70,12 @@ func (h *ReminderHandler) CreateReminder(c *gin.Context) {
 	}
 	reminder.UserID = userID.(int)
 
-	if err := h.reminderService.CreateReminder(c.Request.Context(), &reminder); err != nil {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.reminderService.CreateReminder(c.Request.Context(), companyID, &reminder); err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
 	}
@@ -91,12 +98,78 @@ func (h *ReminderHandler) AcknowledgeReminder(c *gin.Context) {
 		return
 	}
 
-	if err := h.reminderService.AcknowledgeReminder(c.Request.Context(), reminderID, userID.(int)); err != nil {
+	// The completion record is optional; an empty body acknowledges against the due date
+	var req models.AcknowledgeReminderRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	ack, err := h.reminderService.AcknowledgeReminder(c.Request.Context(), companyID, reminderID, userID.(int), req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Reminder not found", nil))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder acknowledged successfully", nil))
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder acknowledged successfully", ack))
+}
+
+// SnoozeReminder handles muting a reminder's alerts until a given time
+func (h *ReminderHandler) SnoozeReminder(c *gin.Context) {
+	reminderID, err := strconv.Atoi(c.Param("reminder_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid reminder ID", nil))
+		return
+	}
+
+	var req models.SnoozeReminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.reminderService.SnoozeReminder(c.Request.Context(), companyID, reminderID, req.SnoozedUntil); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Reminder not found", nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder snoozed successfully", req))
+}
+
+// GetUpcomingAlerts handles fetching reminders that have reached a lead-time alert
+func (h *ReminderHandler) GetUpcomingAlerts(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	alerts, err := h.reminderService.GetUpcomingAlerts(c.Request.Context(), companyID, c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder alerts retrieved successfully", alerts))
 }
 
 // GetRemindersByVehicle handles fetching all reminders for a vehicle
@@ -107,7 +180,12 @@ func (h *ReminderHandler) GetRemindersByVehicle(c *gin.Context) {
 		return
 	}
 
-	reminders, err := h.reminderService.GetRemindersByVehicleIDWithVehicleDetails(c.Request.Context(), vehicleID)
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	reminders, err := h.reminderService.GetRemindersByVehicleIDWithVehicleDetails(c.Request.Context(), companyID, vehicleID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
@@ -118,6 +196,11 @@ func (h *ReminderHandler) GetRemindersByVehicle(c *gin.Context) {
 
 // GetDueReminders handles fetching all reminders that are due
 func (h *ReminderHandler) GetDueReminders(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
 	// Get pagination parameters
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
@@ -125,7 +208,7 @@ func (h *ReminderHandler) GetDueReminders(c *gin.Context) {
 	// Get reminder type filter if provided
 	reminderType := c.Query("type")
 
-	reminders, err := h.reminderService.GetDueRemindersWithVehicleDetails(c.Request.Context(), limit, offset, reminderType)
+	reminders, err := h.reminderService.GetDueRemindersWithVehicleDetails(c.Request.Context(), companyID, limit, offset, reminderType)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
@@ -142,8 +225,17 @@ func (h *ReminderHandler) GetReminderHistory(c *gin.Context) {
 		return
 	}
 
-	history, err := h.reminderService.GetReminderHistory(c.Request.Context(), reminderID)
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.reminderService.GetReminderHistory(c.Request.Context(), companyID, reminderID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Reminder not found", nil))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
 	}
@@ -151,40 +243,100 @@ func (h *ReminderHandler) GetReminderHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder history retrieved successfully", history))
 }
 
-// UpdateReminder handles updating an existing reminder
-func (h *ReminderHandler) UpdateReminder(c *gin.Context) {
+// GetReminder handles fetching a single reminder
+func (h *ReminderHandler) GetReminder(c *gin.Context) {
 	reminderID, err := strconv.Atoi(c.Param("reminder_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid reminder ID", nil))
 		return
 	}
 
-	var reminder models.Reminder
-	if err := c.ShouldBindJSON(&reminder); err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", nil))
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
 		return
 	}
 
-	// Ensure the ID in the path matches the ID in the body
-	reminder.ID = reminderID
+	reminder, err := h.reminderService.GetReminder(c.Request.Context(), companyID, reminderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Reminder not found", nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
 
-	// Get user ID from context (set by JWT middleware)
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder retrieved successfully", reminder))
+}
+
+// ListReminders handles fetching reminders across the fleet filtered by type and due window
+func (h *ReminderHandler) ListReminders(c *gin.Context) {
+	var filter models.ReminderFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
 		return
 	}
-	reminder.UserID = userID.(int)
 
-	if err := h.reminderService.UpdateReminder(c.Request.Context(), &reminder); err != nil {
+	// Accept a comma separated list in either "type" or "types"
+	typesStr := c.Query("types")
+	if typesStr == "" {
+		typesStr = c.Query("type")
+	}
+	if typesStr != "" {
+		for _, t := range strings.Split(typesStr, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	reminders, err := h.reminderService.GetReminders(c.Request.Context(), companyID, filter)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
 	}
 
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminders retrieved successfully", reminders))
+}
+
+// UpdateReminder handles updating an existing reminder
+func (h *ReminderHandler) UpdateReminder(c *gin.Context) {
+	reminderID, err := strconv.Atoi(c.Param("reminder_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid reminder ID", nil))
+		return
+	}
+
+	var req models.UpdateReminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	reminder, err := h.reminderService.UpdateReminder(c.Request.Context(), companyID, reminderID, req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Reminder not found", nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+
 	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder updated successfully", reminder))
 }
 
-// DeleteReminder handles soft deleting a reminder
+// DeleteReminder handles deleting a reminder
 func (h *ReminderHandler) DeleteReminder(c *gin.Context) {
 	reminderID, err := strconv.Atoi(c.Param("reminder_id"))
 	if err != nil {
@@ -192,7 +344,16 @@ func (h *ReminderHandler) DeleteReminder(c *gin.Context) {
 		return
 	}
 
-	if err := h.reminderService.DeleteReminder(c.Request.Context(), reminderID); err != nil {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.reminderService.DeleteReminder(c.Request.Context(), companyID, reminderID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Reminder not found", nil))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
 	}
@@ -200,6 +361,69 @@ func (h *ReminderHandler) DeleteReminder(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder deleted successfully", nil))
 }
 
+// GetReminderTypes handles listing the built-in and company-defined reminder types
+func (h *ReminderHandler) GetReminderTypes(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	types, err := h.reminderService.GetReminderTypes(c.Request.Context(), companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder types retrieved successfully", types))
+}
+
+// CreateReminderType handles adding a company-defined reminder type
+func (h *ReminderHandler) CreateReminderType(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReminderTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	reminderType, err := h.reminderService.CreateReminderType(c.Request.Context(), companyID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Reminder type created successfully", reminderType))
+}
+
+// DeleteReminderType handles removing a company-defined reminder type
+func (h *ReminderHandler) DeleteReminderType(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	typeID, err := strconv.Atoi(c.Param("type_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid reminder type ID", nil))
+		return
+	}
+
+	if err := h.reminderService.DeleteReminderType(c.Request.Context(), companyID, typeID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Reminder type not found", nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Reminder type deleted successfully", nil))
+}
+
 // GetRemindersByVehicleAndType handles fetching all reminders for a vehicle filtered by multiple types
 func (h *ReminderHandler) GetRemindersByVehicleAndType(c *gin.Context) {
 	vehicleID, err := strconv.Atoi(c.Param("vehicle_id"))
@@ -219,7 +443,12 @@ func (h *ReminderHandler) GetRemindersByVehicleAndType(c *gin.Context) {
 		}
 	}
 
-	reminders, err := h.reminderService.GetRemindersByVehicleIDWithVehicleDetails(c.Request.Context(), vehicleID)
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	reminders, err := h.reminderService.GetRemindersByVehicleIDWithVehicleDetails(c.Request.Context(), companyID, vehicleID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
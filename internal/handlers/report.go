@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	service *services.ReportService
+}
+
+func NewReportHandler(service *services.ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// GetProfitLoss handles GET /api/v1/reports/profit-loss
+func (h *ReportHandler) GetProfitLoss(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	report, err := h.service.GetProfitLoss(companyID, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidReportRange) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Profit and loss report generated successfully", report))
+}
+
+// GetCashFlow handles GET /api/v1/reports/cash-flow
+func (h *ReportHandler) GetCashFlow(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	report, err := h.service.GetCashFlow(companyID, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidReportRange) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Cash flow report generated successfully", report))
+}
+
+// GetFleetAnalytics handles GET /api/v1/reports/vehicles
+func (h *ReportHandler) GetFleetAnalytics(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	analytics, err := h.service.GetFleetAnalytics(companyID, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidReportRange) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Fleet analytics generated successfully", analytics))
+}
+
+// GetVehicleAnalytics handles GET /api/v1/reports/vehicles/:vehicle_id
+func (h *ReportHandler) GetVehicleAnalytics(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	vehicleID, err := strconv.Atoi(c.Param("vehicle_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid vehicle ID", nil))
+		return
+	}
+
+	var req models.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	analytics, err := h.service.GetVehicleAnalytics(companyID, vehicleID, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrInvalidReportRange):
+			status = http.StatusBadRequest
+		case errors.Is(err, services.ErrVehicleNotInRange):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, utils.ErrorResponse(status, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Vehicle analytics generated successfully", analytics))
+}
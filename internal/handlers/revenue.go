@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"renting/internal/models"
 	"renting/internal/services"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,14 +22,23 @@ func NewRevenueHandler(service *services.RevenueService) *RevenueHandler {
 
 // GetRevenue handles GET /api/v1/revenue
 func (h *RevenueHandler) GetRevenue(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
 	var req models.RevenueRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	resp, err := h.service.GetRevenue(req)
+	resp, err := h.service.GetRevenue(companyID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidRevenueRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -107,3 +119,55 @@ func (h *RevenueHandler) GetMobileRevenueVisualization(c *gin.Context) {
 
 	c.JSON(http.StatusOK, visualizationData)
 }
+
+// GetRecognitionSchedule handles GET /api/v1/revenue/recognition/:sale_id
+func (h *RevenueHandler) GetRecognitionSchedule(c *gin.Context) {
+	saleID, err := strconv.Atoi(c.Param("sale_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sale_id"})
+		return
+	}
+
+	schedule, err := h.service.GetRecognitionSchedule(saleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// RecomputeSaleRecognition handles POST /api/v1/revenue/recognition/:sale_id/recompute
+func (h *RevenueHandler) RecomputeSaleRecognition(c *gin.Context) {
+	saleID, err := strconv.Atoi(c.Param("sale_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sale_id"})
+		return
+	}
+
+	schedule, err := h.service.RecomputeSaleRecognition(saleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "sale not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// RecomputeAllRecognition handles POST /api/v1/revenue/recognition/recompute
+func (h *RevenueHandler) RecomputeAllRecognition(c *gin.Context) {
+	recomputed, failed, err := h.service.RecomputeAllRecognition()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recomputed": recomputed,
+		"failed":     failed,
+	})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"renting/internal/repositories"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SaleBalanceHandler struct {
+	service *services.SaleBalanceService
+}
+
+func NewSaleBalanceHandler(service *services.SaleBalanceService) *SaleBalanceHandler {
+	return &SaleBalanceHandler{service: service}
+}
+
+// GetSaleBalance handles GET /api/v1/sales/:id/balance
+func (h *SaleBalanceHandler) GetSaleBalance(c *gin.Context) {
+	saleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid sale ID", nil))
+		return
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	balance, err := h.service.GetSaleBalance(companyID, saleID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrSaleNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Sale balance retrieved successfully", balance))
+}
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"renting/internal/models"
+	"renting/internal/repositories"
 	"renting/internal/services"
 	"renting/internal/utils"
 	"strconv"
@@ -79,6 +81,10 @@ func (h *SaleChargeHandler) UpdateSalesCharge(c *gin.Context) {
 
 	if err := h.saleService.UpdateSalesCharge(chargeID, req); err != nil {
 		fmt.Printf("Handler: Error updating sales charge: %v\n", err)
+		if errors.Is(err, repositories.ErrSalesChargeNotFound) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "Sales charge not found", err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to update sales charge", err.Error()))
 		return
 	}
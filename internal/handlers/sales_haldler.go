@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"renting/internal/models"
+	"renting/internal/repositories"
 	"renting/internal/services"
 	"renting/internal/utils"
 	"strconv"
@@ -60,6 +62,9 @@ func (h *SaleHandler) CreateSale(c *gin.Context) {
 		Payments            []models.Payment      `json:"payments"`
 		SalesImages         []models.SalesImage   `json:"sales_images"`
 		SalesVideos         []models.SalesVideo   `json:"sales_videos"`
+		PickupBranchID      *int                  `json:"pickup_branch_id"`
+		DropoffBranchID     *int                  `json:"dropoff_branch_id"`
+		CustomerPAN         *string               `json:"customer_pan"`
 	}
 
 	if err := c.ShouldBindJSON(&saleRequest); err != nil {
@@ -150,11 +155,18 @@ func (h *SaleHandler) CreateSale(c *gin.Context) {
 		Payments:            saleRequest.Payments,
 		SalesImages:         saleRequest.SalesImages,
 		SalesVideos:         saleRequest.SalesVideos,
+		PickupBranchID:      saleRequest.PickupBranchID,
+		DropoffBranchID:     saleRequest.DropoffBranchID,
+		CustomerPAN:         saleRequest.CustomerPAN,
 	}
 
 	// Create sale
 	response, err := h.saleService.CreateSale(sale)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidBranch) || errors.Is(err, repositories.ErrOneWayNotOffered) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Failed to create sale", err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, "Failed to create sale", err.Error()))
 		return
 	}
@@ -220,6 +232,13 @@ func (h *SaleHandler) GetSales(c *gin.Context) {
 	if vehicleID := c.Query("vehicle_id"); vehicleID != "" {
 		filters["vehicle_id"] = vehicleID
 	}
+	if branchID := c.Query("branch_id"); branchID != "" {
+		if _, err := strconv.Atoi(branchID); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid branch_id", nil))
+			return
+		}
+		filters["branch_id"] = branchID
+	}
 
 	sort := c.Query("sort")
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "0"))
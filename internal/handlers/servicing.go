@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ServicingHandler struct {
+	service *services.ServicingService
+}
+
+func NewServicingHandler(service *services.ServicingService) *ServicingHandler {
+	return &ServicingHandler{service: service}
+}
+
+// GetIntervals lists the company's servicing interval for every vehicle type
+func (h *ServicingHandler) GetIntervals(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	intervals, err := h.service.GetIntervals(companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Servicing intervals retrieved successfully", intervals))
+}
+
+// UpdateInterval sets the company's km and day interval for a vehicle type
+func (h *ServicingHandler) UpdateInterval(c *gin.Context) {
+	vehicleTypeID, err := strconv.Atoi(c.Param("vehicle_type_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid vehicle type ID", nil))
+		return
+	}
+
+	var req models.ServicingIntervalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	interval, err := h.service.UpdateInterval(companyID, vehicleTypeID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Servicing interval updated successfully", interval))
+}
+
+// GetServicingStatuses lists the fleet's servicing state; ?due=true returns only vehicles due for service
+func (h *ServicingHandler) GetServicingStatuses(c *gin.Context) {
+	dueOnly := c.Query("due") == "true"
+
+	statuses, err := h.service.GetServicingStatuses(dueOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Servicing status retrieved successfully", statuses))
+}
+
+// GetVehicleServicing returns a vehicle's servicing state and history
+func (h *ServicingHandler) GetVehicleServicing(c *gin.Context) {
+	vehicleID, err := strconv.Atoi(c.Param("vehicle_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid vehicle ID", nil))
+		return
+	}
+
+	detail, err := h.service.GetVehicleServicing(vehicleID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Vehicle servicing retrieved successfully", detail))
+}
+
+// StartServicing marks a vehicle as under maintenance
+func (h *ServicingHandler) StartServicing(c *gin.Context) {
+	vehicleID, err := strconv.Atoi(c.Param("vehicle_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid vehicle ID", nil))
+		return
+	}
+
+	if err := h.service.StartServicing(vehicleID); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Vehicle moved to maintenance", nil))
+}
+
+// MarkServiced records a completed service for a vehicle
+func (h *ServicingHandler) MarkServiced(c *gin.Context) {
+	vehicleID, err := strconv.Atoi(c.Param("vehicle_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid vehicle ID", nil))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.MarkServicedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	record, err := h.service.MarkServiced(vehicleID, userID.(int), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Servicing recorded successfully", record))
+}
@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"renting/internal/models"
 	"renting/internal/services"
 	"renting/internal/utils"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -47,6 +53,11 @@ func (h *StatementHandler) GetOutstandingStatements(c *gin.Context) {
 	if vehicleName := c.Query("vehicle_name"); vehicleName != "" {
 		filters["vehicle_name"] = vehicleName
 	}
+	if branchID, ok := branchIDFromQuery(c); !ok {
+		return
+	} else if branchID != nil {
+		filters["branch_id"] = strconv.Itoa(*branchID)
+	}
 
 	// Parse pagination parameters
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
@@ -75,3 +86,161 @@ func (h *StatementHandler) GetOutstandingStatements(c *gin.Context) {
 		statements,
 	))
 }
+
+// ageingAsOf reads the optional as_of date of the ageing endpoints, defaulting to today
+func ageingAsOf(c *gin.Context) (time.Time, bool) {
+	asOf := time.Now()
+	if value := c.Query("as_of"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid as_of format, use YYYY-MM-DD", nil))
+			return asOf, false
+		}
+		asOf = parsed
+	}
+	return asOf, true
+}
+
+// GetAgeingReport handles GET /api/v1/statements/ageing?group_by=customer|staff&as_of=YYYY-MM-DD
+func (h *StatementHandler) GetAgeingReport(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	asOf, ok := ageingAsOf(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.svc.GetAgeingReport(c.Request.Context(), companyID, asOf, c.Query("group_by"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAgeingRequest) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Ageing report generated successfully", report))
+}
+
+// GetAgeingSales handles GET /api/v1/statements/ageing/sales?bucket=&key=&user_id=&as_of=
+func (h *StatementHandler) GetAgeingSales(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	asOf, ok := ageingAsOf(c)
+	if !ok {
+		return
+	}
+
+	var filter models.AgeingFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	sales, err := h.svc.GetAgeingSales(c.Request.Context(), companyID, asOf, filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAgeingRequest) {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Ageing sales retrieved successfully", sales))
+}
+
+// statementError maps account statement errors to responses
+func statementError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "No sales found for this statement", nil))
+	case errors.Is(err, services.ErrCustomerRequired):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+	}
+}
+
+func (h *StatementHandler) saleStatement(c *gin.Context) (*models.AccountStatement, bool) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return nil, false
+	}
+	saleID, err := strconv.Atoi(c.Param("sale_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid sale ID", nil))
+		return nil, false
+	}
+
+	st, err := h.svc.GetSaleStatement(c.Request.Context(), companyID, saleID)
+	if err != nil {
+		statementError(c, err)
+		return nil, false
+	}
+	return st, true
+}
+
+func (h *StatementHandler) customerStatement(c *gin.Context) (*models.AccountStatement, bool) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return nil, false
+	}
+
+	st, err := h.svc.GetCustomerStatement(c.Request.Context(), companyID, c.Query("phone"), c.Query("name"))
+	if err != nil {
+		statementError(c, err)
+		return nil, false
+	}
+	return st, true
+}
+
+// writeStatementPDF renders a statement and sends it as a download
+func writeStatementPDF(c *gin.Context, st *models.AccountStatement, filename string) {
+	var buf bytes.Buffer
+	if err := services.RenderStatementPDF(&buf, st, services.DefaultStatementTemplate); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// GetSaleStatement handles GET /api/v1/statements/:sale_id/account
+func (h *StatementHandler) GetSaleStatement(c *gin.Context) {
+	st, ok := h.saleStatement(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Statement retrieved successfully", st))
+}
+
+// GetSaleStatementPDF handles GET /api/v1/statements/:sale_id/pdf
+func (h *StatementHandler) GetSaleStatementPDF(c *gin.Context) {
+	st, ok := h.saleStatement(c)
+	if !ok {
+		return
+	}
+	writeStatementPDF(c, st, fmt.Sprintf("statement_sale_%d.pdf", *st.SaleID))
+}
+
+// GetCustomerStatement handles GET /api/v1/statements/customer?phone=&name=
+func (h *StatementHandler) GetCustomerStatement(c *gin.Context) {
+	st, ok := h.customerStatement(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Statement retrieved successfully", st))
+}
+
+// GetCustomerStatementPDF handles GET /api/v1/statements/customer/pdf?phone=&name=
+func (h *StatementHandler) GetCustomerStatementPDF(c *gin.Context) {
+	st, ok := h.customerStatement(c)
+	if !ok {
+		return
+	}
+	writeStatementPDF(c, st, fmt.Sprintf("statement_customer_%s.pdf", st.GeneratedAt.Format("20060102")))
+}
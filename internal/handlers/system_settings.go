@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
+	"renting/internal/models"
 	"renting/internal/services"
 	"renting/internal/utils"
 
@@ -16,8 +19,22 @@ func NewSystemSettingsHandler(service *services.SystemSettingsService) *SystemSe
 	return &SystemSettingsHandler{service: service}
 }
 
+func settingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownSetting):
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, services.ErrPlatformOnly):
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(http.StatusForbidden, err.Error(), nil))
+	case errors.Is(err, services.ErrInvalidSetting):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+	}
+}
+
+// GetSystemSettings handles GET /api/v1/system-settings?company_code=
 func (h *SystemSettingsHandler) GetSystemSettings(c *gin.Context) {
-	settings, err := h.service.GetSystemSettings()
+	settings, err := h.service.GetSystemSettings(c.Query("company_code"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
 		return
@@ -25,25 +42,103 @@ func (h *SystemSettingsHandler) GetSystemSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "System settings retrieved successfully", settings))
 }
 
-func (h *SystemSettingsHandler) UpdateSystemSetting(c *gin.Context) {
-	key := c.Param("key")
-	if key != "enable_registration" && key != "enable_login" {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid setting key", nil))
+// GetSettings handles GET /api/v1/settings
+func (h *SystemSettingsHandler) GetSettings(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.service.GetSettings(companyID)
+	if err != nil {
+		settingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Settings retrieved successfully", settings))
+}
+
+// GetSetting handles GET /api/v1/settings/:key
+func (h *SystemSettingsHandler) GetSetting(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
 		return
 	}
 
-	var request struct {
-		Value bool `json:"value"`
+	setting, err := h.service.GetSetting(companyID, c.Param("key"))
+	if err != nil {
+		settingError(c, err)
+		return
 	}
-	if err := c.ShouldBindJSON(&request); err != nil {
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Setting retrieved successfully", setting))
+}
+
+// UpdateSetting handles PUT /api/v1/settings/:key, and the older PUT /api/v1/system-settings/:key
+func (h *SystemSettingsHandler) UpdateSetting(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+
+	var req models.UpdateSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
 		return
 	}
 
-	if err := h.service.UpdateSystemSetting(key, request.Value); err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+	setting, err := h.service.SetCompanySetting(companyID, userID.(int), c.Param("key"), req.Value)
+	if err != nil {
+		settingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Setting updated successfully", setting))
+}
+
+// ResetSetting handles DELETE /api/v1/settings/:key
+func (h *SystemSettingsHandler) ResetSetting(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	setting, err := h.service.ResetCompanySetting(companyID, c.Param("key"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "The company has no value of its own for this setting", nil))
+			return
+		}
+		settingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Setting reset to the platform default", setting))
+}
+
+// GetPlatformSettings handles GET /api/v1/platform/settings
+func (h *SystemSettingsHandler) GetPlatformSettings(c *gin.Context) {
+	settings, err := h.service.GetSettings(0)
+	if err != nil {
+		settingError(c, err)
 		return
 	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Platform settings retrieved successfully", settings))
+}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "System setting updated successfully", nil))
+// UpdatePlatformSetting handles PUT /api/v1/platform/settings/:key
+func (h *SystemSettingsHandler) UpdatePlatformSetting(c *gin.Context) {
+	var req models.UpdateSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	setting, err := h.service.SetPlatformDefault(c.Param("key"), req.Value)
+	if err != nil {
+		settingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Platform setting updated successfully", setting))
 }
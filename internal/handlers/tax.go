@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"renting/internal/services"
+	"renting/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaxHandler struct {
+	service *services.TaxService
+}
+
+func NewTaxHandler(service *services.TaxService) *TaxHandler {
+	return &TaxHandler{service: service}
+}
+
+func taxError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrTaxInvoiceNotFound), errors.Is(err, repositories.ErrSaleNotFound):
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, err.Error(), nil))
+	case errors.Is(err, repositories.ErrTaxInvoiceExists):
+		c.JSON(http.StatusConflict, utils.ErrorResponse(http.StatusConflict, err.Error(), nil))
+	case errors.Is(err, services.ErrInvalidTaxRequest), errors.Is(err, repositories.ErrInvalidTaxInvoice):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(http.StatusInternalServerError, err.Error(), nil))
+	}
+}
+
+// GetRates handles GET /api/v1/tax/rates
+func (h *TaxHandler) GetRates(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	rates, err := h.service.GetRates(companyID)
+	if err != nil {
+		taxError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Tax rates retrieved successfully", rates))
+}
+
+// SetRate handles PUT /api/v1/tax/rates
+func (h *TaxHandler) SetRate(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChargeTaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	rate, err := h.service.SetRate(companyID, req)
+	if err != nil {
+		taxError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Tax rate saved successfully", rate))
+}
+
+// DeleteRate handles DELETE /api/v1/tax/rates/:charge_type
+func (h *TaxHandler) DeleteRate(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRate(companyID, c.Param("charge_type")); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(http.StatusNotFound, "The charge type has no rate of its own", nil))
+			return
+		}
+		taxError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Tax rate removed, the default rate applies", nil))
+}
+
+// IssueInvoice handles POST /api/v1/tax/invoices
+func (h *TaxHandler) IssueInvoice(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	var req models.IssueTaxInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	invoice, err := h.service.IssueInvoice(companyID, userID.(int), req)
+	if err != nil {
+		taxError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, utils.SuccessResponse(http.StatusCreated, "Tax invoice issued successfully", invoice))
+}
+
+// GetInvoices handles GET /api/v1/tax/invoices?month=YYYY-MM&status=&sale_id=
+func (h *TaxHandler) GetInvoices(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var filter models.TaxInvoiceFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid query parameters", err.Error()))
+		return
+	}
+
+	invoices, err := h.service.GetInvoices(companyID, filter)
+	if err != nil {
+		taxError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Tax invoices retrieved successfully", invoices))
+}
+
+// GetInvoice handles GET /api/v1/tax/invoices/:invoice_id
+func (h *TaxHandler) GetInvoice(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	invoiceID, err := strconv.Atoi(c.Param("invoice_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid invoice ID", nil))
+		return
+	}
+
+	invoice, err := h.service.GetInvoice(companyID, invoiceID)
+	if err != nil {
+		taxError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Tax invoice retrieved successfully", invoice))
+}
+
+// VoidInvoice handles POST /api/v1/tax/invoices/:invoice_id/void
+func (h *TaxHandler) VoidInvoice(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(http.StatusUnauthorized, "User ID not found in context", nil))
+		return
+	}
+
+	invoiceID, err := strconv.Atoi(c.Param("invoice_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid invoice ID", nil))
+		return
+	}
+
+	var req models.VoidTaxInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(http.StatusBadRequest, "Invalid request body", err.Error()))
+		return
+	}
+
+	invoice, err := h.service.VoidInvoice(companyID, invoiceID, userID.(int), req)
+	if err != nil {
+		taxError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Tax invoice voided successfully", invoice))
+}
+
+// GetSalesTaxReport handles GET /api/v1/tax/report?month=YYYY-MM
+func (h *TaxHandler) GetSalesTaxReport(c *gin.Context) {
+	companyID, ok := companyIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetSalesTaxReport(companyID, c.Query("month"))
+	if err != nil {
+		taxError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(http.StatusOK, "Sales tax report retrieved successfully", report))
+}
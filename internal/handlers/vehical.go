@@ -61,6 +61,13 @@ func (h *VehicleHandler) ListVehicles(c *gin.Context) {
 		VehicleRegistrationNumber: queryParams.Get("vehicle_registration_number"),
 		IsAvailable:               queryParams.Get("is_available"),
 		Status:                    queryParams.Get("status"),
+		BranchID:                  queryParams.Get("branch_id"),
+	}
+	if filters.BranchID != "" {
+		if _, err := strconv.Atoi(filters.BranchID); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(http.StatusBadRequest, "Invalid branch_id value", nil))
+			return
+		}
 	}
 
 	// Parse pagination parameters
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlatformKey middleware lets through requests that carry the platform operator's key in the
+// X-Platform-Key header
+func PlatformKey(platformKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-Platform-Key")
+		if platformKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(platformKey)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid platform key"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Account statement line types
+const (
+	StatementLineRental  = "rental"
+	StatementLineCharge  = "charge"
+	StatementLineDeposit = "deposit"
+	StatementLinePayment = "payment"
+	StatementLineRefund  = "refund"
+)
+
+// StatementCompany is the letterhead printed at the top of a statement
+type StatementCompany struct {
+	CompanyID   int    `json:"company_id"`
+	Name        string `json:"name"`
+	CompanyCode string `json:"company_code"`
+	Currency    string `json:"currency"`
+}
+
+// AccountSale is a sale included in a customer statement
+type AccountSale struct {
+	SaleID        int    `json:"sale_id"`
+	CustomerName  string `json:"customer_name"`
+	CustomerPhone string `json:"customer_phone"`
+	Status        string `json:"status"`
+}
+
+// AccountStatementLine is one movement on a customer's account. Debits are what the customer
+// owes (rentals, charges, refunds paid back), credits what they paid (deposits, payments).
+type AccountStatementLine struct {
+	Date        time.Time `json:"date"`
+	SaleID      int       `json:"sale_id"`
+	Type        string    `json:"type"`
+	Description string    `json:"description"`
+	Debit       float64   `json:"debit"`
+	Credit      float64   `json:"credit"`
+	Balance     float64   `json:"balance"` // running balance after this line
+}
+
+// AccountStatement is the statement of one sale or of every sale of a customer
+type AccountStatement struct {
+	Company        StatementCompany       `json:"company"`
+	CustomerName   string                 `json:"customer_name"`
+	CustomerPhone  string                 `json:"customer_phone"`
+	SaleID         *int                   `json:"sale_id,omitempty"` // set for a single-sale statement
+	Sales          []AccountSale          `json:"sales"`
+	GeneratedAt    time.Time              `json:"generated_at"`
+	Lines          []AccountStatementLine `json:"lines"`
+	TotalDebits    float64                `json:"total_debits"`
+	TotalCredits   float64                `json:"total_credits"`
+	ClosingBalance float64                `json:"closing_balance"` // positive when the customer owes money
+}
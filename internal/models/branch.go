@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// ChargeTypeTransferFee is the sale charge added when a vehicle is dropped off at another branch
+const ChargeTypeTransferFee = "transfer_fee"
+
+// Branch is a pickup location of a company
+type Branch struct {
+	BranchID  int       `json:"branch_id"`
+	CompanyID int       `json:"company_id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Address   *string   `json:"address,omitempty"`
+	Phone     *string   `json:"phone,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type BranchRequest struct {
+	Code     string  `json:"code" binding:"required"`
+	Name     string  `json:"name" binding:"required"`
+	Address  *string `json:"address"`
+	Phone    *string `json:"phone"`
+	IsActive *bool   `json:"is_active"`
+}
+
+// BranchTransferFee is what a one-way rental from one branch to another costs. A one-way rental
+// is only offered between branches that have one, even if the fee is 0.
+type BranchTransferFee struct {
+	FeeID          int       `json:"fee_id"`
+	FromBranchID   int       `json:"from_branch_id"`
+	FromBranchName string    `json:"from_branch_name"`
+	ToBranchID     int       `json:"to_branch_id"`
+	ToBranchName   string    `json:"to_branch_name"`
+	Fee            float64   `json:"fee"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type BranchTransferFeeRequest struct {
+	FromBranchID int     `json:"from_branch_id" binding:"required"`
+	ToBranchID   int     `json:"to_branch_id" binding:"required"`
+	Fee          float64 `json:"fee" binding:"gte=0"`
+}
+
+// AssignBranchRequest sets the home branch of a user or vehicle; a null branch clears it
+type AssignBranchRequest struct {
+	BranchID *int `json:"branch_id"`
+}
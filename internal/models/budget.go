@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// Recurring expense frequencies
+const (
+	RecurrenceWeekly    = "weekly"
+	RecurrenceMonthly   = "monthly"
+	RecurrenceQuarterly = "quarterly"
+	RecurrenceYearly    = "yearly"
+)
+
+// RecurringExpense is a template that books an expense such as rent or salaries on a schedule
+type RecurringExpense struct {
+	RecurringID int        `json:"recurring_id"`
+	CompanyID   int        `json:"company_id"`
+	ExpenseType string     `json:"expense_type"`
+	Amount      float64    `json:"amount"`
+	Description *string    `json:"description,omitempty"`
+	VehicleID   *int       `json:"vehicle_id,omitempty"`
+	Frequency   string     `json:"frequency"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+	NextRunDate time.Time  `json:"next_run_date"`
+	RunCount    int        `json:"run_count"` // occurrences generated so far
+	IsActive    bool       `json:"is_active"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	CreatedBy   int        `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type RecurringExpenseRequest struct {
+	ExpenseType string     `json:"expense_type" binding:"required"`
+	Amount      float64    `json:"amount" binding:"required"`
+	Description *string    `json:"description"`
+	VehicleID   *int       `json:"vehicle_id"`
+	Frequency   string     `json:"frequency" binding:"required"`
+	StartDate   time.Time  `json:"start_date" binding:"required"`
+	EndDate     *time.Time `json:"end_date"`
+	IsActive    *bool      `json:"is_active"`
+}
+
+// ExpenseBudget caps spending in one expense category for one month
+type ExpenseBudget struct {
+	BudgetID    int       `json:"budget_id"`
+	CompanyID   int       `json:"company_id"`
+	ExpenseType string    `json:"expense_type"`
+	Month       time.Time `json:"month"` // first day of the month
+	Amount      float64   `json:"amount"`
+	CreatedBy   *int      `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ExpenseBudgetRequest sets the budget of a category for a month given as YYYY-MM
+type ExpenseBudgetRequest struct {
+	ExpenseType string  `json:"expense_type" binding:"required"`
+	Month       string  `json:"month" binding:"required"`
+	Amount      float64 `json:"amount"`
+}
+
+// BudgetReportRow compares a category's budget with what was actually spent
+type BudgetReportRow struct {
+	ExpenseType string  `json:"expense_type"`
+	Budget      float64 `json:"budget"`
+	Actual      float64 `json:"actual"`
+	Remaining   float64 `json:"remaining"`
+	UsedPercent float64 `json:"used_percent"`
+	OverBudget  bool    `json:"over_budget"`
+	Budgeted    bool    `json:"budgeted"` // false for spending in a category without a budget
+}
+
+type BudgetReport struct {
+	Month       time.Time         `json:"month"`
+	TotalBudget float64           `json:"total_budget"`
+	TotalActual float64           `json:"total_actual"`
+	Categories  []BudgetReportRow `json:"categories"`
+}
+
+// BudgetAlert records that spending in a category went over its monthly budget
+type BudgetAlert struct {
+	AlertID     int       `json:"alert_id"`
+	BudgetID    int       `json:"budget_id"`
+	ExpenseType string    `json:"expense_type"`
+	Month       time.Time `json:"month"`
+	Budget      float64   `json:"budget"`
+	Actual      float64   `json:"actual"`
+	CreatedAt   time.Time `json:"created_at"`
+}
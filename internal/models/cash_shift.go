@@ -0,0 +1,118 @@
+package models
+
+import "time"
+
+// Cash shift states: a shift is open while the staff member collects, closed once the drawer is
+// counted and approved when a manager signs the count off
+const (
+	CashShiftOpen     = "open"
+	CashShiftClosed   = "closed"
+	CashShiftApproved = "approved"
+)
+
+// CashPaymentMethod is the payment method code whose payments go into the drawer
+const CashPaymentMethod = "cash"
+
+// CashShift is one staff member's cash drawer from opening float to counted closing cash. While the
+// shift is open the totals are worked out on the fly; closing freezes them.
+type CashShift struct {
+	ShiftID       int        `json:"shift_id"`
+	CompanyID     int        `json:"company_id"`
+	UserID        int        `json:"user_id"`
+	Username      string     `json:"username"`
+	BranchID      *int       `json:"branch_id"` // the user's home branch when the shift opened
+	BranchName    *string    `json:"branch_name,omitempty"`
+	Status        string     `json:"status"`
+	OpeningFloat  float64    `json:"opening_float"`
+	OpenedAt      time.Time  `json:"opened_at"`
+	OpeningNote   *string    `json:"opening_note,omitempty"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+	CashCollected float64    `json:"cash_collected"` // cash payments taken by the user during the shift
+	CashExpenses  float64    `json:"cash_expenses"`  // expenses the user paid in cash during the shift
+	ExpectedCash  float64    `json:"expected_cash"`  // opening float + collected - expenses
+	CountedCash   *float64   `json:"counted_cash,omitempty"`
+	Variance      *float64   `json:"variance,omitempty"` // counted - expected; negative is a shortage
+	ClosingNote   *string    `json:"closing_note,omitempty"`
+	ApprovedBy    *int       `json:"approved_by,omitempty"`
+	ApprovedAt    *time.Time `json:"approved_at,omitempty"`
+	ApprovalNote  *string    `json:"approval_note,omitempty"`
+}
+
+// CashShiftPayment is a cash payment counted in a shift
+type CashShiftPayment struct {
+	PaymentID     int       `json:"payment_id"`
+	SaleID        int       `json:"sale_id"`
+	CustomerName  string    `json:"customer_name"`
+	AmountPaid    float64   `json:"amount_paid"`
+	PaymentStatus string    `json:"payment_status"`
+	PaymentDate   time.Time `json:"payment_date"`
+	ReceiptNumber *string   `json:"receipt_number,omitempty"`
+}
+
+// CashShiftExpense is a cash expense counted in a shift
+type CashShiftExpense struct {
+	ExpenseID      int       `json:"expense_id"`
+	ExpenseType    string    `json:"expense_type"`
+	Amount         float64   `json:"amount"`
+	Description    *string   `json:"description,omitempty"`
+	ApprovalStatus string    `json:"approval_status"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CashShiftDetail is a shift with the payments and expenses behind its totals
+type CashShiftDetail struct {
+	CashShift
+	Payments []CashShiftPayment `json:"payments"`
+	Expenses []CashShiftExpense `json:"expenses"`
+}
+
+type OpenCashShiftRequest struct {
+	OpeningFloat float64 `json:"opening_float" binding:"gte=0"`
+	Note         *string `json:"note"`
+}
+
+type CloseCashShiftRequest struct {
+	CountedCash *float64 `json:"counted_cash" binding:"required,gte=0"`
+	Note        *string  `json:"note"`
+}
+
+// ApproveCashShiftRequest signs a closed shift off; a note is required when the count is off
+type ApproveCashShiftRequest struct {
+	Note *string `json:"note"`
+}
+
+type CashShiftFilter struct {
+	UserID   *int   `form:"user_id"`
+	BranchID *int   `form:"branch_id"`
+	Status   string `form:"status"`
+	Date     string `form:"date"` // YYYY-MM-DD the shift opened on
+}
+
+// CashTotals adds up a set of shifts
+type CashTotals struct {
+	OpenShifts      int     `json:"open_shifts"`
+	AwaitingSignOff int     `json:"awaiting_sign_off"`
+	ApprovedShifts  int     `json:"approved_shifts"`
+	OpeningFloat    float64 `json:"opening_float"`
+	CashCollected   float64 `json:"cash_collected"`
+	CashExpenses    float64 `json:"cash_expenses"`
+	ExpectedCash    float64 `json:"expected_cash"`
+	CountedCash     float64 `json:"counted_cash"` // of shifts that have been counted
+	Variance        float64 `json:"variance"`
+}
+
+// EndOfDayBranch is the part of the end-of-day report for one branch; shifts of users without a
+// home branch are grouped under a nil branch
+type EndOfDayBranch struct {
+	BranchID   *int   `json:"branch_id"`
+	BranchName string `json:"branch_name"`
+	CashTotals
+	Shifts []CashShift `json:"shifts"`
+}
+
+// EndOfDayReport sums up every shift opened on one day, per branch and overall
+type EndOfDayReport struct {
+	Date string `json:"date"`
+	CashTotals
+	Branches []EndOfDayBranch `json:"branches"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// OnboardCompanyRequest creates a company together with the owner account that administers it
+type OnboardCompanyRequest struct {
+	Name        string              `json:"name" binding:"required"`
+	CompanyCode string              `json:"company_code" binding:"required"`
+	Owner       CompanyOwnerRequest `json:"owner" binding:"required"`
+}
+
+type CompanyOwnerRequest struct {
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required,min=8"`
+	MobileNumber string `json:"mobile_number" binding:"required"`
+}
+
+type OnboardCompanyResponse struct {
+	Company Company     `json:"company"`
+	Owner   CompanyUser `json:"owner"`
+}
+
+// CompanyUser is a user as company admins see it, without the password hash
+type CompanyUser struct {
+	ID            int       `json:"id"`
+	Username      string    `json:"username"`
+	MobileNumber  string    `json:"mobile_number"`
+	IsAdmin       bool      `json:"is_admin"`
+	HasAccounting bool      `json:"has_accounting"`
+	IsLocked      bool      `json:"is_locked"`
+	BranchID      *int      `json:"branch_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type CompanyUserFilter struct {
+	Status   string `form:"status"` // active or locked
+	Role     string `form:"role"`   // admin or accounting
+	BranchID *int   `form:"branch_id"`
+	Search   string `form:"search"` // matches username or mobile number
+}
+
+// UpdateUserRolesRequest changes the roles that are given; omitted ones are left as they are
+type UpdateUserRolesRequest struct {
+	IsAdmin       *bool `json:"is_admin"`
+	HasAccounting *bool `json:"has_accounting"`
+}
+
+type InviteUserRequest struct {
+	Username      string `json:"username" binding:"required"`
+	MobileNumber  string `json:"mobile_number" binding:"required"`
+	IsAdmin       bool   `json:"is_admin"`
+	HasAccounting bool   `json:"has_accounting"`
+	BranchID      *int   `json:"branch_id"`
+}
+
+// UserInvite is a pending account an admin set up for someone to finish by choosing a password.
+// The token is only returned when the invite is created.
+type UserInvite struct {
+	InviteID      int        `json:"invite_id"`
+	CompanyID     int        `json:"company_id"`
+	Username      string     `json:"username"`
+	MobileNumber  string     `json:"mobile_number"`
+	IsAdmin       bool       `json:"is_admin"`
+	HasAccounting bool       `json:"has_accounting"`
+	BranchID      *int       `json:"branch_id"`
+	InvitedBy     int        `json:"invited_by"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	AcceptedAt    *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Token         string     `json:"token,omitempty"`
+}
+
+type AcceptInviteRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// DashboardRequest picks the period the dashboard covers. Period is day, week, month or year
+// (default month) around date (default today).
+type DashboardRequest struct {
+	Period string `form:"period"`
+	Date   string `form:"date"`
+}
+
+// DashboardFigure is one dashboard number next to its value for the previous comparable period
+type DashboardFigure struct {
+	Current       float64  `json:"current"`
+	Previous      float64  `json:"previous"`
+	Change        float64  `json:"change"`
+	ChangePercent *float64 `json:"change_percent"` // nil when the previous value is zero
+}
+
+// DashboardSnapshot holds the raw dashboard figures of one period
+type DashboardSnapshot struct {
+	FleetTotal       int
+	FleetAvailable   int
+	FleetRented      int
+	FleetMaintenance int
+	DeliveriesDue    int
+	ReturnsDue       int
+	OverdueReturns   int
+	Receivables      float64
+	CashIn           float64
+	RentedDays       int
+	AvailableDays    int
+}
+
+// DashboardFleet counts vehicles by what they are doing on the as-of day
+type DashboardFleet struct {
+	Total            DashboardFigure `json:"total"`
+	Available        DashboardFigure `json:"available"`
+	Rented           DashboardFigure `json:"rented"`
+	UnderMaintenance DashboardFigure `json:"under_maintenance"`
+}
+
+// Dashboard is the overview shown on the home screen. Fleet, deliveries, returns, overdue returns
+// and receivables are taken on the as-of day (today, or the end of a past period); cash in and
+// utilisation cover the period up to that day.
+type Dashboard struct {
+	Period         string          `json:"period"`
+	StartDate      time.Time       `json:"start_date"`
+	EndDate        time.Time       `json:"end_date"`
+	AsOf           time.Time       `json:"as_of"`
+	PreviousStart  time.Time       `json:"previous_start"`
+	PreviousEnd    time.Time       `json:"previous_end"`
+	PreviousAsOf   time.Time       `json:"previous_as_of"`
+	Fleet          DashboardFleet  `json:"fleet"`
+	DeliveriesDue  DashboardFigure `json:"deliveries_due"`
+	ReturnsDue     DashboardFigure `json:"returns_due"`
+	OverdueReturns DashboardFigure `json:"overdue_returns"`
+	Receivables    DashboardFigure `json:"outstanding_receivables"`
+	CashIn         DashboardFigure `json:"verified_cash_in"`
+	Utilisation    DashboardFigure `json:"utilisation_rate"` // percent of available vehicle-days rented
+}
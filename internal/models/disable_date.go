@@ -2,12 +2,13 @@ package models
 
 // In your models package
 type DisableDateResponse struct {
-	ActiveRentals  []DisabledDateResponse `json:"active_rentals"`
-	FutureBookings []DisabledDateResponse `json:"future_bookings"` // Changed from FutureBooking
+	ActiveRentals     []DisabledDateResponse `json:"active_rentals"`
+	FutureBookings    []DisabledDateResponse `json:"future_bookings"` // Changed from FutureBooking
+	MaintenanceBlocks []DisabledDateResponse `json:"maintenance_blocks"`
 }
 
 type DisabledDateResponse struct {
 	DateOfDelivery string `json:"date_of_delivery"` // Format: YYYY-MM-DD
 	ReturnDate     string `json:"return_date"`      // Format: YYYY-MM-DD
+	Reason         string `json:"reason,omitempty"` // Set for maintenance blocks
 }
-
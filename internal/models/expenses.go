@@ -2,19 +2,83 @@ package models
 
 import "time"
 
+const (
+	ExpenseApprovalPending  = "pending"
+	ExpenseApprovalApproved = "approved"
+	ExpenseApprovalRejected = "rejected"
+)
+
 type Expense struct {
-	ExpenseID   int       `json:"expense_id,omitempty" gorm:"primaryKey;autoIncrement"`
-	ExpenseType string    `json:"expense_type" gorm:"type:varchar(50);not null"`
-	Amount      float64   `json:"amount" gorm:"type:decimal(10,2);not null"`
-	Description *string   `json:"description,omitempty" gorm:"type:text"`
-	ExpenseDate time.Time `json:"expense_date" gorm:"not null"`
-	RecordedBy  *int      `json:"recorded_by,omitempty"`
-	CreatedAt   time.Time `json:"created_at" gorm:"not null;default:now()"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"not null;default:now()"`
+	ExpenseID       int              `json:"expense_id,omitempty" gorm:"primaryKey;autoIncrement"`
+	ExpenseType     string           `json:"expense_type" gorm:"type:varchar(50);not null"` // expense category code
+	Amount          float64          `json:"amount" gorm:"type:decimal(10,2);not null"`
+	Description     *string          `json:"description,omitempty" gorm:"type:text"`
+	ExpenseDate     time.Time        `json:"expense_date" gorm:"not null"`
+	RecordedBy      *int             `json:"recorded_by,omitempty"`
+	VehicleID       *int             `json:"vehicle_id,omitempty"`
+	SaleID          *int             `json:"sale_id,omitempty"`
+	PaidInCash      bool             `json:"paid_in_cash"`           // paid from the recorder's cash drawer
+	RecurringID     *int             `json:"recurring_id,omitempty"` // template that generated the expense
+	ApprovalStatus  string           `json:"approval_status" gorm:"type:varchar(20);not null;default:approved"`
+	ApprovedBy      *int             `json:"approved_by,omitempty"`
+	ApprovedAt      *time.Time       `json:"approved_at,omitempty"`
+	RejectionReason *string          `json:"rejection_reason,omitempty" gorm:"type:text"`
+	Receipts        []ExpenseReceipt `json:"receipts,omitempty" gorm:"-"`
+	CreatedAt       time.Time        `json:"created_at" gorm:"not null;default:now()"`
+	UpdatedAt       time.Time        `json:"updated_at" gorm:"not null;default:now()"`
 }
 
 type ExpenseFilter struct {
-	ExpenseType *string    `form:"expense_type"`
-	StartDate   *time.Time `form:"start_date"`
-	EndDate     *time.Time `form:"end_date"`
+	ExpenseType    *string    `form:"expense_type"`
+	StartDate      *time.Time `form:"start_date"`
+	EndDate        *time.Time `form:"end_date"`
+	VehicleID      *int       `form:"vehicle_id"`
+	SaleID         *int       `form:"sale_id"`
+	RecordedBy     *int       `form:"recorded_by"`
+	MinAmount      *float64   `form:"min_amount"`
+	MaxAmount      *float64   `form:"max_amount"`
+	ApprovalStatus *string    `form:"approval_status"`
+	Limit          int        `form:"limit"`
+	Offset         int        `form:"offset"`
+}
+
+// ExpenseListResponse is a page of expenses
+type ExpenseListResponse struct {
+	Expenses   []Expense  `json:"expenses"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ExpenseCategory is an expense category; built-in categories have no company.
+// ApprovalThreshold overrides the default approval threshold for the category.
+type ExpenseCategory struct {
+	ID                int       `json:"id"`
+	CompanyID         *int      `json:"company_id,omitempty"`
+	Code              string    `json:"code"`
+	Name              string    `json:"name"`
+	ApprovalThreshold *float64  `json:"approval_threshold,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// ExpenseCategoryRequest is the body for creating a company-defined expense category
+type ExpenseCategoryRequest struct {
+	Code              string   `json:"code"`
+	Name              string   `json:"name" binding:"required"`
+	ApprovalThreshold *float64 `json:"approval_threshold"`
+}
+
+// ExpenseReceipt is a receipt image or PDF attached to an expense
+type ExpenseReceipt struct {
+	ReceiptID   int       `json:"receipt_id"`
+	ExpenseID   int       `json:"expense_id"`
+	ReceiptURL  string    `json:"receipt_url"`
+	FileName    *string   `json:"file_name,omitempty"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	UploadedBy  *int      `json:"uploaded_by,omitempty"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+// ExpenseRejectRequest is the body for rejecting a pending expense
+type ExpenseRejectRequest struct {
+	Reason string `json:"reason" binding:"required"`
 }
@@ -0,0 +1,165 @@
+package models
+
+import "time"
+
+// Account types of the chart of accounts
+const (
+	AccountTypeAsset     = "asset"
+	AccountTypeLiability = "liability"
+	AccountTypeEquity    = "equity"
+	AccountTypeRevenue   = "revenue"
+	AccountTypeExpense   = "expense"
+)
+
+// System account codes the automatic postings rely on
+const (
+	AccountCodeCash             = "1000"
+	AccountCodeReceivable       = "1100"
+	AccountCodeVATPayable       = "2100"
+	AccountCodeRetainedEarnings = "3000"
+	AccountCodeRentalRevenue    = "4000"
+	AccountCodeChargeRevenue    = "4100"
+	AccountCodeGeneralExpense   = "5000"
+	AccountCodeServicingExpense = "5100"
+	AccountCodeEMIExpense       = "5200"
+	AccountCodeInsuranceExpense = "5300"
+)
+
+// Journal entry sources, used to find and reverse the postings of a record
+const (
+	JournalSourceSale        = "sale"
+	JournalSourceSalesCharge = "sales_charge"
+	JournalSourcePayment     = "payment"
+	JournalSourceRefund      = "refund"
+	JournalSourceExpense     = "expense"
+	JournalSourceManual      = "manual"
+	JournalSourcePeriodClose = "period_close"
+)
+
+const (
+	AccountingPeriodOpen   = "open"
+	AccountingPeriodClosed = "closed"
+)
+
+// Account is a chart of accounts entry; system accounts are shared by every company and have no CompanyID
+type Account struct {
+	AccountID int       `json:"account_id"`
+	CompanyID *int      `json:"company_id,omitempty"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	IsSystem  bool      `json:"is_system"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AccountRequest struct {
+	Code string `json:"code" binding:"required,max=20"`
+	Name string `json:"name" binding:"required,max=100"`
+	Type string `json:"type" binding:"required,oneof=asset liability equity revenue expense"`
+}
+
+// JournalEntry is a balanced set of debit and credit lines posted together
+type JournalEntry struct {
+	EntryID     int           `json:"entry_id"`
+	CompanyID   *int          `json:"company_id,omitempty"`
+	EntryDate   time.Time     `json:"entry_date"`
+	Description string        `json:"description"`
+	SourceType  string        `json:"source_type"`
+	SourceID    *int          `json:"source_id,omitempty"`
+	SaleID      *int          `json:"sale_id,omitempty"`
+	ReversalOf  *int          `json:"reversal_of,omitempty"`
+	CreatedBy   *int          `json:"created_by,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Lines       []JournalLine `json:"lines"`
+}
+
+type JournalLine struct {
+	LineID      int     `json:"line_id,omitempty"`
+	EntryID     int     `json:"entry_id,omitempty"`
+	AccountID   int     `json:"account_id,omitempty"`
+	AccountCode string  `json:"account_code"`
+	AccountName string  `json:"account_name,omitempty"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+	Memo        *string `json:"memo,omitempty"`
+}
+
+// JournalEntryRequest is a manual adjustment entered by an accountant
+type JournalEntryRequest struct {
+	EntryDate   time.Time            `json:"entry_date" binding:"required"`
+	Description string               `json:"description" binding:"required"`
+	Lines       []JournalLineRequest `json:"lines" binding:"required,min=2,dive"`
+}
+
+type JournalLineRequest struct {
+	AccountCode string  `json:"account_code" binding:"required"`
+	Debit       float64 `json:"debit" binding:"gte=0"`
+	Credit      float64 `json:"credit" binding:"gte=0"`
+	Memo        *string `json:"memo"`
+}
+
+type JournalEntryFilter struct {
+	StartDate  *time.Time `form:"start_date" time_format:"2006-01-02"`
+	EndDate    *time.Time `form:"end_date" time_format:"2006-01-02"`
+	SourceType string     `form:"source_type"`
+	SourceID   *int       `form:"source_id"`
+	SaleID     *int       `form:"sale_id"`
+	Limit      int        `form:"limit"`
+	Offset     int        `form:"offset"`
+}
+
+type TrialBalanceRow struct {
+	AccountID   int     `json:"account_id"`
+	AccountCode string  `json:"account_code"`
+	AccountName string  `json:"account_name"`
+	AccountType string  `json:"account_type"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+	Balance     float64 `json:"balance"` // in the account's normal direction
+}
+
+type TrialBalance struct {
+	AsOf        time.Time         `json:"as_of"`
+	Accounts    []TrialBalanceRow `json:"accounts"`
+	TotalDebit  float64           `json:"total_debit"`
+	TotalCredit float64           `json:"total_credit"`
+	Balanced    bool              `json:"balanced"`
+}
+
+type AccountLedgerLine struct {
+	EntryID     int       `json:"entry_id"`
+	EntryDate   time.Time `json:"entry_date"`
+	Description string    `json:"description"`
+	SourceType  string    `json:"source_type"`
+	SourceID    *int      `json:"source_id,omitempty"`
+	Memo        *string   `json:"memo,omitempty"`
+	Debit       float64   `json:"debit"`
+	Credit      float64   `json:"credit"`
+	Balance     float64   `json:"balance"`
+}
+
+// AccountLedger lists the postings to one account with a running balance
+type AccountLedger struct {
+	Account        Account             `json:"account"`
+	StartDate      time.Time           `json:"start_date"`
+	EndDate        time.Time           `json:"end_date"`
+	OpeningBalance float64             `json:"opening_balance"`
+	ClosingBalance float64             `json:"closing_balance"`
+	Lines          []AccountLedgerLine `json:"lines"`
+}
+
+type AccountingPeriod struct {
+	PeriodID       int        `json:"period_id"`
+	StartDate      time.Time  `json:"start_date"`
+	EndDate        time.Time  `json:"end_date"`
+	Status         string     `json:"status"`
+	NetIncome      float64    `json:"net_income"`
+	ClosingEntryID *int       `json:"closing_entry_id,omitempty"`
+	ClosedBy       *int       `json:"closed_by,omitempty"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+}
+
+type ClosePeriodRequest struct {
+	StartDate time.Time `json:"start_date" binding:"required"`
+	EndDate   time.Time `json:"end_date" binding:"required"`
+}
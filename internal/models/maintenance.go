@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Maintenance block statuses
+const (
+	MaintenanceBlockScheduled = "scheduled"
+	MaintenanceBlockActive    = "active"
+	MaintenanceBlockCompleted = "completed"
+	MaintenanceBlockCancelled = "cancelled"
+)
+
+// MaintenanceBlock is planned workshop downtime that keeps a vehicle off the booking calendar
+type MaintenanceBlock struct {
+	BlockID     int       `json:"block_id"`
+	VehicleID   int       `json:"vehicle_id"`
+	VehicleName string    `json:"vehicle_name,omitempty"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+	Reason      string    `json:"reason"`
+	Status      string    `json:"status"`
+	CreatedBy   *int      `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type MaintenanceBlockRequest struct {
+	VehicleID int       `json:"vehicle_id" binding:"required"`
+	StartDate time.Time `json:"start_date" binding:"required"`
+	EndDate   time.Time `json:"end_date" binding:"required"`
+	Reason    string    `json:"reason" binding:"required"`
+}
+
+type MaintenanceBlockFilter struct {
+	VehicleID *int   `form:"vehicle_id"`
+	Status    string `form:"status"`
+	Upcoming  bool   `form:"upcoming"` // only blocks that have not ended
+}
@@ -6,19 +6,23 @@ import (
 )
 
 type PaymentWithSale struct {
-	PaymentID       int          `json:"payment_id"`
-	PaymentType     string       `json:"payment_type"`
-	AmountPaid      float64      `json:"amount_paid"`
-	SaleType        string       `json:"sale_type"`
-	PaymentDate     time.Time    `json:"payment_date"`
-	PaymentStatus   string       `json:"payment_status"`
-	VerifiedByAdmin bool         `json:"verified_by_admin"`
-	Remark          string       `json:"remark"`
-	CreatedAt       time.Time    `json:"created_at"`
-	UpdatedAt       time.Time    `json:"updated_at"`
-	PaymentUserID   *int         `json:"payment_user_id"`
-	PaymentUsername *string      `json:"payment_username"`
-	Sale            SalesPayment `json:"sale"`
+	PaymentID       int                 `json:"payment_id"`
+	PaymentType     string              `json:"payment_type"`
+	AmountPaid      float64             `json:"amount_paid"`
+	SaleType        string              `json:"sale_type"`
+	PaymentDate     time.Time           `json:"payment_date"`
+	PaymentStatus   string              `json:"payment_status"`
+	VerifiedByAdmin bool                `json:"verified_by_admin"`
+	Remark          string              `json:"remark"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+	PaymentUserID   *int                `json:"payment_user_id"`
+	PaymentUsername *string             `json:"payment_username"`
+	VerifiedBy      *int                `json:"verified_by"`
+	VerifiedAt      *time.Time          `json:"verified_at"`
+	RejectionReason *string             `json:"rejection_reason"`
+	Attachments     []PaymentAttachment `json:"attachments"`
+	Sale            SalesPayment        `json:"sale"`
 }
 
 type SalesPayment struct {
@@ -92,3 +96,15 @@ func (p *Payment) Validate() error {
 
 	return nil
 }
+
+// PaymentAttachment is a screenshot or voucher photo backing up a payment
+type PaymentAttachment struct {
+	AttachmentID int       `json:"attachment_id"`
+	PaymentID    int       `json:"payment_id"`
+	FileURL      string    `json:"file_url"`
+	FileName     *string   `json:"file_name,omitempty"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	UploadedBy   *int      `json:"uploaded_by,omitempty"`
+	UploadedAt   time.Time `json:"uploaded_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Gateway payment statuses
+const (
+	GatewayStatusInitiated = "initiated"
+	GatewayStatusCompleted = "completed"
+	GatewayStatusFailed    = "failed"
+)
+
+// GatewayPayment tracks a payment a customer makes through a wallet provider. The matching row in
+// payments stays Pending until the provider confirms it.
+type GatewayPayment struct {
+	GatewayPaymentID  int        `json:"gateway_payment_id"`
+	Reference         string     `json:"reference"`
+	Provider          string     `json:"provider"`
+	PaymentID         int        `json:"payment_id"`
+	SaleID            int        `json:"sale_id"`
+	Amount            float64    `json:"amount"`
+	Status            string     `json:"status"`
+	ProviderReference *string    `json:"provider_reference,omitempty"`
+	ReturnURL         *string    `json:"return_url,omitempty"`
+	InitiatedBy       *int       `json:"initiated_by,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+type GatewayInitiateRequest struct {
+	Provider   string  `json:"provider" binding:"required"`
+	AmountPaid float64 `json:"amount_paid" binding:"required,gt=0"`
+	SaleType   string  `json:"sale_type"` // booking, delivery, return or manual (default)
+	Remark     string  `json:"remark"`
+	ReturnURL  string  `json:"return_url"` // where the customer lands after paying
+}
+
+// GatewayCheckout is what the app needs to send the customer to the provider: either open
+// PaymentURL, or post FormFields to it the way eSewa expects
+type GatewayCheckout struct {
+	Reference  string            `json:"reference"`
+	Provider   string            `json:"provider"`
+	PaymentID  int               `json:"payment_id"`
+	SaleID     int               `json:"sale_id"`
+	Amount     float64           `json:"amount"`
+	PaymentURL string            `json:"payment_url"`
+	FormFields map[string]string `json:"form_fields,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// PaymentMethod is an accepted way of paying. Built-in methods have no company; a company row
+// with the same code overrides the built-in one, e.g. to rename or switch it off.
+type PaymentMethod struct {
+	MethodID           int       `json:"method_id"`
+	CompanyID          *int      `json:"company_id,omitempty"`
+	Code               string    `json:"code"`
+	Name               string    `json:"name"`
+	IsActive           bool      `json:"is_active"`
+	SortOrder          int       `json:"sort_order"`
+	RequiresAttachment bool      `json:"requires_attachment"` // evidence is needed before verification
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type PaymentMethodRequest struct {
+	Code               string `json:"code"` // taken from the URL on update
+	Name               string `json:"name" binding:"required"`
+	IsActive           *bool  `json:"is_active"`
+	SortOrder          int    `json:"sort_order"`
+	RequiresAttachment bool   `json:"requires_attachment"`
+}
+
+// PaymentReceipt is the printable receipt of a verified payment
+type PaymentReceipt struct {
+	ReceiptNumber string           `json:"receipt_number"`
+	Sequence      int              `json:"sequence"`
+	IssuedAt      time.Time        `json:"issued_at"`
+	Company       StatementCompany `json:"company"`
+	PaymentID     int              `json:"payment_id"`
+	SaleID        int              `json:"sale_id"`
+	CustomerName  string           `json:"customer_name"`
+	CustomerPhone string           `json:"customer_phone"`
+	VehicleName   string           `json:"vehicle_name"`
+	Registration  string           `json:"vehicle_registration_number"`
+	PaymentMethod string           `json:"payment_method"` // display name of the method
+	Stage         string           `json:"stage"`          // booking, delivery, return or manual
+	Amount        float64          `json:"amount"`
+	PaymentDate   time.Time        `json:"payment_date"`
+	PaymentStatus string           `json:"payment_status"`
+	Remark        string           `json:"remark"`
+	ReceivedBy    string           `json:"received_by"`
+}
@@ -15,3 +15,75 @@ type PaymentVerificationRequest struct {
 	UpdatedAt       time.Time `json:"updated_at"`
 	SaleID          int       `json:"sale_id"` // Foreign key to Sale
 }
+
+// VerificationQueueFilter narrows the queue of payments waiting for an admin
+type VerificationQueueFilter struct {
+	SaleType    string
+	SubmittedBy *int
+	Limit       int
+	Offset      int
+}
+
+// VerificationQueue is a page of pending payments, oldest first
+type VerificationQueue struct {
+	Items         []VerificationQueueItem `json:"items"`
+	Total         int                     `json:"total"`
+	PendingAmount float64                 `json:"pending_amount"` // across the whole queue, not just this page
+}
+
+type VerificationQueueItem struct {
+	PaymentID           int                   `json:"payment_id"`
+	SaleID              int                   `json:"sale_id"`
+	AmountPaid          float64               `json:"amount_paid"`
+	PaymentType         string                `json:"payment_type"`
+	SaleType            string                `json:"sale_type"`
+	PaymentDate         time.Time             `json:"payment_date"`
+	Remark              string                `json:"remark"`
+	CreatedAt           time.Time             `json:"created_at"`
+	SubmittedBy         *int                  `json:"submitted_by"`
+	SubmittedByUsername *string               `json:"submitted_by_username"`
+	Sale                VerificationQueueSale `json:"sale"`
+	Evidence            []PaymentEvidence     `json:"evidence"`
+}
+
+type VerificationQueueSale struct {
+	CustomerName  string       `json:"customer_name"`
+	CustomerPhone string       `json:"customer_phone"`
+	VehicleName   string       `json:"vehicle_name"`
+	Registration  string       `json:"vehicle_registration_number"`
+	Status        string       `json:"status"`
+	Balance       *SaleBalance `json:"balance"`
+}
+
+// PaymentEvidence is an image staff uploaded to back a payment up
+type PaymentEvidence struct {
+	Source     string    `json:"source"` // payment_attachment or sale_image
+	URL        string    `json:"url"`
+	FileName   *string   `json:"file_name,omitempty"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// Bulk verification actions
+const (
+	PaymentActionVerify = "verify"
+	PaymentActionReject = "reject"
+)
+
+type BulkVerificationRequest struct {
+	Items []PaymentDecision `json:"items" binding:"required,min=1,max=200,dive"`
+}
+
+type PaymentDecision struct {
+	PaymentID int    `json:"payment_id" binding:"required"`
+	Action    string `json:"action" binding:"required,oneof=verify reject"`
+	Reason    string `json:"reason"` // required when rejecting
+}
+
+type PaymentDecisionResult struct {
+	PaymentID     int    `json:"payment_id"`
+	SaleID        int    `json:"sale_id,omitempty"`
+	Action        string `json:"action"`
+	Success       bool   `json:"success"`
+	PaymentStatus string `json:"payment_status,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
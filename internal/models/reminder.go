@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type ReminderType string
@@ -16,18 +18,27 @@ const (
 	ReminderFrequencyMonthly ReminderFrequency = "monthly"
 	ReminderFrequencyYearly  ReminderFrequency = "yearly"
 	ReminderFrequencyCustom  ReminderFrequency = "custom"
+
+	// Acknowledgement advance modes
+	ReminderAdvanceFromDueDate = "due_date"
+	ReminderAdvanceFromAckDate = "ack_date"
 )
 
+// DefaultReminderLeadTimes are the days-before-due alert points used when a reminder sets none
+var DefaultReminderLeadTimes = pq.Int64Array{30, 7, 1}
+
 // Reminder represents a vehicle-related reminder
 type Reminder struct {
 	ID             int               `json:"id" gorm:"primaryKey;autoIncrement"`
 	VehicleID      int               `json:"vehicle_id" gorm:"not null"`
 	UserID         int               `json:"user_id" gorm:"not null"`
-	Type           ReminderType      `json:"type" gorm:"type:reminder_type;not null"`
+	Type           ReminderType      `json:"type" gorm:"type:varchar(50);not null"` // built-in or company-defined reminder_types code
 	StartDate      time.Time         `json:"start_date" gorm:"not null"`
 	Frequency      ReminderFrequency `json:"frequency" gorm:"type:reminder_frequency;not null"`
 	CustomInterval *int              `json:"custom_interval,omitempty" gorm:"default:null"` // in days
 	NextDueDate    time.Time         `json:"next_due_date" gorm:"not null"`
+	LeadTimeDays   pq.Int64Array     `json:"lead_time_days" gorm:"type:integer[]"`        // days before due to raise alerts
+	SnoozedUntil   *time.Time        `json:"snoozed_until,omitempty" gorm:"default:null"` // alerts are muted until this time
 	CreatedAt      time.Time         `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt      time.Time         `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
 }
@@ -40,14 +51,83 @@ type ReminderWithVehicle struct {
 	VehicleRegistrationNumber string `json:"vehicle_registration_number"`
 }
 
+// UpdateReminderRequest holds the reminder fields that can be changed; omitted fields are kept
+type UpdateReminderRequest struct {
+	VehicleID      *int               `json:"vehicle_id"`
+	Type           *ReminderType      `json:"type"`
+	StartDate      *time.Time         `json:"start_date"`
+	Frequency      *ReminderFrequency `json:"frequency"`
+	CustomInterval *int               `json:"custom_interval"`
+	NextDueDate    *time.Time         `json:"next_due_date"`
+	LeadTimeDays   []int64            `json:"lead_time_days"`
+}
+
+// ReminderFilter narrows the fleet-wide reminder list
+type ReminderFilter struct {
+	Types         []string   `form:"-"`
+	VehicleID     *int       `form:"vehicle_id"`
+	DueFrom       *time.Time `form:"due_from" time_format:"2006-01-02"`
+	DueTo         *time.Time `form:"due_to" time_format:"2006-01-02"`
+	DueWithinDays *int       `form:"due_within_days"` // due on or before today + N days, overdue included
+	Limit         int        `form:"limit"`
+	Offset        int        `form:"offset"`
+}
+
+// ReminderTypeDefinition is a reminder type; built-in types have no company
+type ReminderTypeDefinition struct {
+	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID   *int      `json:"company_id,omitempty" gorm:"default:null"`
+	Code        string    `json:"code" gorm:"type:varchar(50);not null"`
+	Name        string    `json:"name" gorm:"type:varchar(100);not null"`
+	Description *string   `json:"description,omitempty" gorm:"default:null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// ReminderTypeRequest is the body for creating a company-defined reminder type
+type ReminderTypeRequest struct {
+	Code        string  `json:"code"`
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+}
+
+// ReminderAlert is a reminder that has reached one of its lead-time alert points
+type ReminderAlert struct {
+	ReminderWithVehicle
+	DaysUntilDue int `json:"days_until_due"`
+	AlertStage   int `json:"alert_stage"` // the lead time (in days) that triggered the alert, 0 when due or overdue
+}
+
 // ReminderAcknowledgement represents a record of when a reminder was acknowledged
 type ReminderAcknowledgement struct {
-	ID             int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	ReminderID     int       `json:"reminder_id" gorm:"not null"`
-	UserID         int       `json:"user_id" gorm:"not null"`
-	AcknowledgedAt time.Time `json:"acknowledged_at" gorm:"not null"`
-	CreatedAt      time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt      time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	ID              int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	ReminderID      int            `json:"reminder_id" gorm:"not null"`
+	UserID          int            `json:"user_id" gorm:"not null"`
+	AcknowledgedAt  time.Time      `json:"acknowledged_at" gorm:"not null"`
+	DueDate         *time.Time     `json:"due_date,omitempty" gorm:"default:null"` // the due date this acknowledgement settled
+	AmountPaid      *float64       `json:"amount_paid,omitempty" gorm:"type:decimal(10,2);default:null"`
+	ReferenceNumber *string        `json:"reference_number,omitempty" gorm:"default:null"`
+	Documents       pq.StringArray `json:"documents,omitempty" gorm:"type:text[]"`
+	Notes           *string        `json:"notes,omitempty" gorm:"default:null"`
+	AdvanceMode     string         `json:"advance_mode" gorm:"default:due_date"`
+	ExpenseID       *int           `json:"expense_id,omitempty" gorm:"default:null"`
+	CreatedAt       time.Time      `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// AcknowledgeReminderRequest carries the completion record for an acknowledgement
+type AcknowledgeReminderRequest struct {
+	AcknowledgedAt  *time.Time `json:"acknowledged_at"`
+	AdvanceMode     string     `json:"advance_mode"` // due_date (default) keeps the schedule, ack_date restarts it
+	AmountPaid      *float64   `json:"amount_paid"`
+	ReferenceNumber *string    `json:"reference_number"`
+	Documents       []string   `json:"documents"`
+	Notes           *string    `json:"notes"`
+	RecordExpense   bool       `json:"record_expense"` // create a company_expenses row for EMI and insurance payments
+}
+
+// SnoozeReminderRequest mutes a reminder's alerts until the given time
+type SnoozeReminderRequest struct {
+	SnoozedUntil time.Time `json:"snoozed_until" binding:"required"`
 }
 
 // TableName specifies the table name for Reminder
@@ -55,6 +135,11 @@ func (Reminder) TableName() string {
 	return "reminders"
 }
 
+// TableName specifies the table name for ReminderTypeDefinition
+func (ReminderTypeDefinition) TableName() string {
+	return "reminder_types"
+}
+
 // TableName specifies the table name for ReminderAcknowledgement
 func (ReminderAcknowledgement) TableName() string {
 	return "reminder_acknowledgements"
@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// ReportRequest selects the range of a financial report. Period is day, month, year or custom;
+// custom needs start_date and end_date, the others use date (default today) as reference.
+type ReportRequest struct {
+	Period    string `form:"period"`
+	Date      string `form:"date"`
+	StartDate string `form:"start_date"`
+	EndDate   string `form:"end_date"`
+	GroupBy   string `form:"group_by"` // day, month or year buckets for the series
+}
+
+// ReportRange is a resolved, inclusive date range with its bucket size
+type ReportRange struct {
+	Period    string    `json:"period"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	GroupBy   string    `json:"group_by"`
+}
+
+type ExpenseTypeTotal struct {
+	ExpenseType string  `json:"expense_type"`
+	Amount      float64 `json:"amount"`
+	Count       int     `json:"count"`
+}
+
+// ProfitLossBucket is one day, month or year of a P&L series
+type ProfitLossBucket struct {
+	PeriodStart time.Time `json:"period_start"`
+	Revenue     float64   `json:"revenue"`
+	Expenses    float64   `json:"expenses"`
+	NetProfit   float64   `json:"net_profit"`
+}
+
+// VehicleProfit is the profitability of one vehicle over the report range
+type VehicleProfit struct {
+	VehicleID                 int     `json:"vehicle_id"`
+	VehicleName               string  `json:"vehicle_name"`
+	VehicleRegistrationNumber string  `json:"vehicle_registration_number"`
+	VehicleTypeID             int     `json:"vehicle_type_id"`
+	VehicleTypeName           string  `json:"vehicle_type_name"`
+	Revenue                   float64 `json:"revenue"`
+	Expenses                  float64 `json:"expenses"`
+	NetProfit                 float64 `json:"net_profit"`
+	NetMargin                 float64 `json:"net_margin"`
+}
+
+type VehicleTypeProfit struct {
+	VehicleTypeID   int     `json:"vehicle_type_id"`
+	VehicleTypeName string  `json:"vehicle_type_name"`
+	VehicleCount    int     `json:"vehicle_count"`
+	Revenue         float64 `json:"revenue"`
+	Expenses        float64 `json:"expenses"`
+	NetProfit       float64 `json:"net_profit"`
+	NetMargin       float64 `json:"net_margin"`
+}
+
+// ProfitLossReport sets recognised revenue against expenses. Expenses that cannot be
+// tied to a vehicle only appear in the totals, not in the vehicle breakdowns.
+type ProfitLossReport struct {
+	Range              ReportRange         `json:"range"`
+	Revenue            float64             `json:"revenue"`
+	Expenses           float64             `json:"expenses"`
+	NetProfit          float64             `json:"net_profit"`
+	NetMargin          float64             `json:"net_margin"` // percent of revenue
+	ExpensesByType     []ExpenseTypeTotal  `json:"expenses_by_type"`
+	Series             []ProfitLossBucket  `json:"series"`
+	ByVehicle          []VehicleProfit     `json:"by_vehicle"`
+	ByVehicleType      []VehicleTypeProfit `json:"by_vehicle_type"`
+	UnassignedExpenses float64             `json:"unassigned_expenses"`
+}
+
+// CashFlowBucket is one day, month or year of a cash-flow series
+type CashFlowBucket struct {
+	PeriodStart time.Time `json:"period_start"`
+	CashIn      float64   `json:"cash_in"`
+	CashOut     float64   `json:"cash_out"`
+	NetCashFlow float64   `json:"net_cash_flow"`
+}
+
+// CashFlowReport counts money actually received and paid out
+type CashFlowReport struct {
+	Range          ReportRange        `json:"range"`
+	CashIn         float64            `json:"cash_in"`       // verified payments received
+	Refunds        float64            `json:"refunds"`       // payments returned to customers
+	ExpensesPaid   float64            `json:"expenses_paid"` // company expenses
+	CashOut        float64            `json:"cash_out"`      // refunds + expenses
+	NetCashFlow    float64            `json:"net_cash_flow"`
+	CashInByMethod map[string]float64 `json:"cash_in_by_method"`
+	ExpensesByType []ExpenseTypeTotal `json:"expenses_by_type"`
+	Series         []CashFlowBucket   `json:"series"`
+}
+
+// VehicleAnalytics is the utilisation and profitability of one vehicle over a range
+type VehicleAnalytics struct {
+	VehicleID                 int     `json:"vehicle_id"`
+	VehicleName               string  `json:"vehicle_name"`
+	VehicleRegistrationNumber string  `json:"vehicle_registration_number"`
+	VehicleTypeID             int     `json:"vehicle_type_id"`
+	VehicleTypeName           string  `json:"vehicle_type_name"`
+	PeriodDays                int     `json:"period_days"`    // days in range the vehicle was in the fleet
+	DowntimeDays              int     `json:"downtime_days"`  // days blocked for maintenance
+	AvailableDays             int     `json:"available_days"` // period days less downtime
+	RentedDays                int     `json:"rented_days"`
+	Utilisation               float64 `json:"utilisation"` // percent of available days rented
+	Revenue                   float64 `json:"revenue"`
+	RevenuePerAvailableDay    float64 `json:"revenue_per_available_day"`
+	RentalCount               int     `json:"rental_count"`
+	AverageRentalDays         float64 `json:"average_rental_days"`
+	KmDriven                  float64 `json:"km_driven"`
+	ServicingCount            int     `json:"servicing_count"`
+	MaintenanceCost           float64 `json:"maintenance_cost"`
+	MaintenanceCostPerKm      float64 `json:"maintenance_cost_per_km"`
+	Expenses                  float64 `json:"expenses"` // all expenses tied to the vehicle
+	NetContribution           float64 `json:"net_contribution"`
+}
+
+// FleetAnalytics totals vehicle analytics across the fleet
+type FleetAnalytics struct {
+	Range                  ReportRange        `json:"range"`
+	VehicleCount           int                `json:"vehicle_count"`
+	AvailableDays          int                `json:"available_days"`
+	RentedDays             int                `json:"rented_days"`
+	Utilisation            float64            `json:"utilisation"`
+	Revenue                float64            `json:"revenue"`
+	RevenuePerAvailableDay float64            `json:"revenue_per_available_day"`
+	RentalCount            int                `json:"rental_count"`
+	AverageRentalDays      float64            `json:"average_rental_days"`
+	KmDriven               float64            `json:"km_driven"`
+	MaintenanceCost        float64            `json:"maintenance_cost"`
+	MaintenanceCostPerKm   float64            `json:"maintenance_cost_per_km"`
+	Expenses               float64            `json:"expenses"`
+	NetContribution        float64            `json:"net_contribution"`
+	Vehicles               []VehicleAnalytics `json:"vehicles"`
+}
@@ -1,12 +1,63 @@
 package models
 
+import "time"
+
+// Revenue recognition row types
+const (
+	RecognitionEntrySchedule   = "schedule"   // amount spread over the rental days
+	RecognitionEntryReversal   = "reversal"   // cancels the open-month part of an earlier row
+	RecognitionEntryAdjustment = "adjustment" // late change to a rental that lies in closed months
+)
+
+// RevenueRecognitionEntry is one row of a sale's recognition schedule
+type RevenueRecognitionEntry struct {
+	SaleID      int       `json:"sale_id"`
+	TotalAmount float64   `json:"total_amount"`
+	DailyAmount float64   `json:"daily_amount"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+	EntryType   string    `json:"entry_type"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// RevenueRecognitionSchedule is the recognition schedule of a sale; rows net to Recognised
+type RevenueRecognitionSchedule struct {
+	SaleID     int                       `json:"sale_id"`
+	Recognised float64                   `json:"recognised"`
+	Entries    []RevenueRecognitionEntry `json:"entries"`
+}
+
+// Revenue recognition modes
+const (
+	RecognizeAtProrated = "prorated" // spread evenly over the rental days
+	RecognizeAtStart    = "start"    // whole amount on the day the rental starts
+	RecognizeAtEnd      = "end"      // whole amount on the day the vehicle comes back
+)
+
 // RevenueRequest defines the request structure for revenue endpoints
 type RevenueRequest struct {
-	Period      string `form:"period" json:"period"`             // "day", "month", "year", "custom"
+	Period      string `form:"period" json:"period"`             // "day", "week", "month", "year", "custom"
 	Date        string `form:"date" json:"date"`                 // Reference date (format: 2006-01-02)
 	StartDate   string `form:"start_date" json:"start_date"`     // Start date for custom period
 	EndDate     string `form:"end_date" json:"end_date"`         // End date for custom period
 	RecognizeAt string `form:"recognize_at" json:"recognize_at"` // "start", "end", "prorated"
+	Interval    string `form:"interval" json:"interval"`         // "day" or "week" series buckets
+}
+
+// RevenuePoint is the revenue of one bucket of a time series
+type RevenuePoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Amount      float64   `json:"amount"`
+}
+
+// RevenueComparison is the revenue of the period just before the requested one
+type RevenueComparison struct {
+	StartDate     time.Time `json:"start_date"`
+	EndDate       time.Time `json:"end_date"`
+	TotalRevenue  float64   `json:"total_revenue"`
+	Change        float64   `json:"change"`
+	ChangePercent *float64  `json:"change_percent"` // nil when the previous period had no revenue
 }
 
 // ErrorResponse defines a standard error response structure
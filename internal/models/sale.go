@@ -32,6 +32,12 @@ type Sale struct {
 	ActualReturnDate     *time.Time `json:"actual_date_of_return"`
 	PaymentStatus        string     `json:"payment_status"`
 	ModifiedBy           int        `json:"modified_by"`
+	PickupBranchID       *int       `json:"pickup_branch_id"`
+	DropoffBranchID      *int       `json:"dropoff_branch_id"` // differs from pickup for a one-way rental
+	CustomerPAN          *string    `json:"customer_pan,omitempty"`
+	TaxRate              *float64   `json:"tax_rate,omitempty"`       // VAT included in TotalAmount, in percent
+	TaxableAmount        *float64   `json:"taxable_amount,omitempty"` // TotalAmount without VAT
+	TaxAmount            *float64   `json:"tax_amount,omitempty"`
 	// Related fields
 	UserName     string         `json:"username"`
 	SalesCharges []SalesCharge  `json:"sales_charges"`
@@ -81,11 +87,17 @@ type SaleSubmitResponse struct {
 	VehicleName string `json:"vehicle_name"`
 }
 
+// ChargeTypeDiscount is stored as a positive amount but is always deducted from what the sale owes
+const ChargeTypeDiscount = "discount"
+
 type SalesCharge struct {
-	ChargeID   int     `json:"charge_id"`
-	SaleID     int     `json:"sale_id"`
-	ChargeType string  `json:"charge_type"`
-	Amount     float64 `json:"amount"`
+	ChargeID      int      `json:"charge_id"`
+	SaleID        int      `json:"sale_id"`
+	ChargeType    string   `json:"charge_type"`
+	Amount        float64  `json:"amount"`                   // including VAT
+	TaxRate       *float64 `json:"tax_rate,omitempty"`       // set when the charge is posted
+	TaxableAmount *float64 `json:"taxable_amount,omitempty"` // set when the charge is posted, negative for discounts
+	TaxAmount     *float64 `json:"tax_amount,omitempty"`     // set when the charge is posted, negative for discounts
 }
 
 type SalesImage struct {
@@ -0,0 +1,23 @@
+package models
+
+// Sale payment statuses
+const (
+	SalePaymentUnpaid  = "unpaid"
+	SalePaymentPartial = "partial"
+	SalePaymentPaid    = "paid"
+)
+
+// SaleBalance is what a sale is owed and what has been paid against it. Sale payment_status is
+// always derived from it.
+type SaleBalance struct {
+	SaleID         int     `json:"sale_id"`
+	TotalAmount    float64 `json:"total_amount"`
+	Charges        float64 `json:"charges"`         // additional charges
+	Discounts      float64 `json:"discounts"`       // discounts, as a positive amount
+	AmountDue      float64 `json:"amount_due"`      // total plus charges minus discounts
+	PaidAmount     float64 `json:"paid_amount"`     // completed (verified) payments
+	PendingAmount  float64 `json:"pending_amount"`  // payments awaiting verification
+	RefundedAmount float64 `json:"refunded_amount"` // payments paid back to the customer
+	Outstanding    float64 `json:"outstanding"`     // amount due minus paid, never negative
+	PaymentStatus  string  `json:"payment_status"`
+}
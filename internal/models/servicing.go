@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// DefaultServicingIntervalKm is used for vehicle types without a configured interval
+const DefaultServicingIntervalKm = 5000
+
+// ServicingInterval is the service schedule for a vehicle type, by distance and/or time
+type ServicingInterval struct {
+	VehicleTypeID   int       `json:"vehicle_type_id"`
+	VehicleTypeName string    `json:"vehicle_type_name,omitempty"`
+	IntervalKm      float64   `json:"interval_km"`
+	IntervalDays    *int      `json:"interval_days,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type ServicingIntervalRequest struct {
+	IntervalKm   float64 `json:"interval_km" binding:"required,gt=0"`
+	IntervalDays *int    `json:"interval_days" binding:"omitempty,gt=0"`
+}
+
+// VehicleServicingStatus is the current servicing state of a vehicle
+type VehicleServicingStatus struct {
+	VehicleID                 int        `json:"vehicle_id"`
+	VehicleName               string     `json:"vehicle_name"`
+	VehicleRegistrationNumber string     `json:"vehicle_registration_number"`
+	VehicleStatus             string     `json:"vehicle_status"`
+	ServicingID               *int       `json:"servicing_id,omitempty"`
+	CurrentKm                 *float64   `json:"current_km,omitempty"`
+	NextServicingKm           *float64   `json:"next_servicing_km,omitempty"`
+	NextServicingDate         *time.Time `json:"next_servicing_date,omitempty"`
+	ServicingIntervalKm       *float64   `json:"servicing_interval_km,omitempty"`
+	KmRemaining               *float64   `json:"km_remaining,omitempty"`
+	IsServicingDue            bool       `json:"is_servicing_due"`
+	Status                    *string    `json:"status,omitempty"`
+	LastServicedAt            *time.Time `json:"last_serviced_at,omitempty"`
+}
+
+// ServicingRecord is one entry of vehicle_servicing_history
+type ServicingRecord struct {
+	HistoryID     int       `json:"history_id"`
+	VehicleID     int       `json:"vehicle_id"`
+	ServicingID   int       `json:"servicing_id"`
+	KmReading     float64   `json:"km_reading"`
+	ServicingType string    `json:"servicing_type"`
+	ServicingDate time.Time `json:"servicing_date"`
+	Cost          *float64  `json:"servicing_cost,omitempty"`
+	Workshop      *string   `json:"workshop,omitempty"`
+	Notes         *string   `json:"notes,omitempty"`
+	ServicedBy    *int      `json:"serviced_by,omitempty"`
+	ExpenseID     *int      `json:"expense_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// MarkServicedRequest records a completed service
+type MarkServicedRequest struct {
+	KmReading     float64    `json:"km_reading" binding:"required,gt=0"`
+	ServicingType string     `json:"servicing_type"` // e.g. regular, oil_change, major
+	ServicingDate *time.Time `json:"servicing_date"`
+	Cost          *float64   `json:"servicing_cost" binding:"omitempty,gte=0"`
+	Workshop      *string    `json:"workshop"`
+	Notes         *string    `json:"notes"`
+}
+
+// VehicleServicingDetail combines the current state with the service history
+type VehicleServicingDetail struct {
+	VehicleServicingStatus
+	History []ServicingRecord `json:"history"`
+}
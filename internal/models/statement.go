@@ -74,3 +74,66 @@ func (s Statement) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// Receivable ageing buckets, by days since the vehicle came back
+const (
+	AgeingCurrent = "current" // not returned yet, or returned today
+	Ageing1To30   = "1-30"
+	Ageing31To60  = "31-60"
+	Ageing61To90  = "61-90"
+	AgeingOver90  = "90+"
+)
+
+// AgeingSale is one sale with an outstanding balance, aged from its return date
+type AgeingSale struct {
+	SaleID             int        `json:"sale_id"`
+	CustomerName       string     `json:"customer_name"`
+	CustomerPhone      string     `json:"customer_phone"`
+	UserID             int        `json:"user_id"`
+	StaffName          string     `json:"staff_name"`
+	Status             string     `json:"status"`
+	ReturnDate         *time.Time `json:"return_date"`
+	ActualDateOfReturn *time.Time `json:"actual_date_of_return"`
+	DaysSinceReturn    int        `json:"days_since_return"`
+	DueDate            *time.Time `json:"due_date"` // return plus the company's payment grace period
+	DaysOverdue        int        `json:"days_overdue"`
+	Bucket             string     `json:"bucket"`
+	OutstandingBalance float64    `json:"outstanding_balance"`
+}
+
+// AgeingBuckets splits an outstanding amount by age
+type AgeingBuckets struct {
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days_1_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Days61To90 float64 `json:"days_61_90"`
+	Over90     float64 `json:"days_over_90"`
+	Total      float64 `json:"total"`
+}
+
+// AgeingGroup is the ageing of one customer or one staff member's sales
+type AgeingGroup struct {
+	Key           string        `json:"key"` // customer phone (or name) or staff user id, for drill-down
+	CustomerName  string        `json:"customer_name,omitempty"`
+	CustomerPhone string        `json:"customer_phone,omitempty"`
+	UserID        *int          `json:"user_id,omitempty"`
+	StaffName     string        `json:"staff_name,omitempty"`
+	SaleCount     int           `json:"sale_count"`
+	Buckets       AgeingBuckets `json:"buckets"`
+}
+
+// AgeingReport is the accounts-receivable ageing; Totals.Total equals the sum of
+// outstanding_balance over the statement view
+type AgeingReport struct {
+	AsOf     time.Time     `json:"as_of"`
+	GroupBy  string        `json:"group_by"`
+	Currency string        `json:"currency"`
+	Totals   AgeingBuckets `json:"totals"`
+	Groups   []AgeingGroup `json:"groups"`
+}
+
+// AgeingFilter narrows the drill-down to one bucket, customer or staff member
+type AgeingFilter struct {
+	Bucket string `form:"bucket"`
+	Key    string `form:"key"` // group key from the report
+	UserID *int   `form:"user_id"`
+}
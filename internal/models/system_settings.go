@@ -1,16 +1,79 @@
 package models
 
-import "time"
-
-type SystemSetting struct {
-	SettingID    int       `json:"setting_id"`
-	SettingKey   string    `json:"setting_key"`
-	SettingValue bool      `json:"setting_value"`
-	Description  string    `json:"description"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+import (
+	"encoding/json"
+	"time"
+)
+
+// Setting value types
+const (
+	SettingTypeString   = "string"
+	SettingTypeNumber   = "number"
+	SettingTypeBoolean  = "boolean"
+	SettingTypeJSON     = "json"
+	SettingTypeDuration = "duration" // Go duration string such as "72h" or "90m"
+)
+
+// Setting keys
+const (
+	SettingEnableRegistration    = "enable_registration"
+	SettingEnableLogin           = "enable_login"
+	SettingTaxRate               = "tax_rate"
+	SettingVATNumber             = "vat_number"
+	SettingCurrency              = "currency"
+	SettingTimezone              = "timezone"
+	SettingPaymentGracePeriod    = "payment_grace_period"
+	SettingLateReturnGracePeriod = "late_return_grace_period"
+	SettingServicingIntervalKm   = "servicing_interval_km"
+	SettingReceiptPrefix         = "receipt_prefix"
+	SettingBusinessHours         = "business_hours"
+)
+
+// DefaultTimezone is the time zone days are counted in when no timezone setting is stored
+const DefaultTimezone = "Asia/Kathmandu"
+
+// Where a setting's value comes from: the company's own value, the platform default stored in the
+// database, or the built-in default of its definition
+const (
+	SettingSourceCompany  = "company"
+	SettingSourcePlatform = "platform"
+	SettingSourceDefault  = "default"
+)
+
+// SettingDefinition is the schema of a setting that values are checked against
+type SettingDefinition struct {
+	Key          string      `json:"key"`
+	Type         string      `json:"type"`
+	Description  string      `json:"description"`
+	Default      interface{} `json:"default"`
+	Min          *float64    `json:"min,omitempty"`           // numbers, and durations in seconds
+	Max          *float64    `json:"max,omitempty"`           // numbers, and durations in seconds
+	Pattern      string      `json:"pattern,omitempty"`       // regular expression strings must match
+	PlatformOnly bool        `json:"platform_only,omitempty"` // set by platform admins only, never per company
+}
+
+// Setting is the value a setting resolves to for a company
+type Setting struct {
+	SettingDefinition
+	Value     interface{} `json:"value"`
+	Source    string      `json:"source"`
+	UpdatedAt *time.Time  `json:"updated_at,omitempty"`
+}
+
+// StoredSetting is a setting row; CompanyID is nil for platform defaults
+type StoredSetting struct {
+	CompanyID  *int            `json:"company_id"`
+	SettingKey string          `json:"setting_key"`
+	Value      json.RawMessage `json:"value"`
+	UpdatedBy  *int            `json:"updated_by,omitempty"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+type UpdateSettingRequest struct {
+	Value json.RawMessage `json:"value" binding:"required"`
 }
 
+// SystemSettingsResponse is what the login and sign-up screens need before anyone is logged in
 type SystemSettingsResponse struct {
 	EnableRegistration bool `json:"enable_registration"`
 	EnableLogin        bool `json:"enable_login"`
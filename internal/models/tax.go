@@ -0,0 +1,118 @@
+package models
+
+import "time"
+
+// Amounts on sales and charges include VAT; the taxable amount and tax are worked out from the
+// gross amount at the rate of the charge type, or the company's tax_rate setting.
+const (
+	DefaultTaxRate      = 13.0
+	TaxChargeTypeRental = "rental" // the rate key of a sale's own rental amount
+)
+
+// Tax invoice states: a voided invoice keeps its number and the sale can be invoiced again
+const (
+	TaxInvoiceIssued = "issued"
+	TaxInvoiceVoid   = "void"
+)
+
+// ChargeTaxRate is a company's VAT rate for one charge type
+type ChargeTaxRate struct {
+	RateID     int       `json:"rate_id"`
+	CompanyID  int       `json:"company_id"`
+	ChargeType string    `json:"charge_type"`
+	Rate       float64   `json:"rate"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ChargeTaxRateRequest struct {
+	ChargeType string   `json:"charge_type" binding:"required"`
+	Rate       *float64 `json:"rate" binding:"required,gte=0,lte=100"`
+}
+
+// TaxRates lists the rate every charge type is taxed at; types without their own rate use DefaultRate
+type TaxRates struct {
+	DefaultRate float64         `json:"default_rate"`
+	ChargeTypes []ChargeTaxRate `json:"charge_types"`
+}
+
+type TaxInvoiceLine struct {
+	LineID        int     `json:"line_id"`
+	Description   string  `json:"description"`
+	ChargeType    string  `json:"charge_type"`
+	TaxRate       float64 `json:"tax_rate"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	TaxAmount     float64 `json:"tax_amount"`
+	GrossAmount   float64 `json:"gross_amount"`
+}
+
+// TaxInvoice is a VAT invoice for a sale, its lines copied from the sale and its charges at the time
+// it was issued
+type TaxInvoice struct {
+	InvoiceID       int              `json:"invoice_id"`
+	CompanyID       int              `json:"company_id"`
+	SaleID          int              `json:"sale_id"`
+	InvoiceNumber   string           `json:"invoice_number"`
+	InvoiceDate     time.Time        `json:"invoice_date"`
+	SellerPAN       *string          `json:"seller_pan"`
+	CustomerName    string           `json:"customer_name"`
+	CustomerPAN     string           `json:"customer_pan"`
+	CustomerAddress *string          `json:"customer_address,omitempty"`
+	TaxableAmount   float64          `json:"taxable_amount"`
+	TaxAmount       float64          `json:"tax_amount"`
+	GrossAmount     float64          `json:"gross_amount"`
+	Status          string           `json:"status"`
+	IssuedBy        *int             `json:"issued_by"`
+	IssuedAt        time.Time        `json:"issued_at"`
+	VoidReason      *string          `json:"void_reason,omitempty"`
+	VoidedBy        *int             `json:"voided_by,omitempty"`
+	VoidedAt        *time.Time       `json:"voided_at,omitempty"`
+	Lines           []TaxInvoiceLine `json:"lines,omitempty"`
+}
+
+type IssueTaxInvoiceRequest struct {
+	SaleID          int     `json:"sale_id" binding:"required"`
+	CustomerPAN     string  `json:"customer_pan" binding:"required"`
+	CustomerName    *string `json:"customer_name"` // defaults to the sale's customer
+	CustomerAddress *string `json:"customer_address"`
+}
+
+type VoidTaxInvoiceRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type TaxInvoiceFilter struct {
+	Month  string `form:"month"` // YYYY-MM of the invoice date
+	Status string `form:"status"`
+	SaleID *int   `form:"sale_id"`
+}
+
+// SalesTaxRateTotal adds up the invoice lines taxed at one rate
+type SalesTaxRateTotal struct {
+	TaxRate       float64 `json:"tax_rate"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	TaxAmount     float64 `json:"tax_amount"`
+	GrossAmount   float64 `json:"gross_amount"`
+}
+
+// UninvoicedSales adds up the tax on sales booked in the month that have no invoice in force
+type UninvoicedSales struct {
+	Sales         int     `json:"sales"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	TaxAmount     float64 `json:"tax_amount"`
+	GrossAmount   float64 `json:"gross_amount"`
+}
+
+// SalesTaxReport is the month's sales book: every invoice issued in it, voided ones included so
+// the numbering can be accounted for, with the totals of those in force
+type SalesTaxReport struct {
+	Month         string              `json:"month"`
+	InvoiceCount  int                 `json:"invoice_count"`
+	VoidCount     int                 `json:"void_count"`
+	TaxableAmount float64             `json:"taxable_amount"`
+	TaxAmount     float64             `json:"tax_amount"`
+	GrossAmount   float64             `json:"gross_amount"`
+	Rates         []SalesTaxRateTotal `json:"rates"`
+	Invoices      []TaxInvoice        `json:"invoices"`
+	Uninvoiced    UninvoicedSales     `json:"uninvoiced"`
+}
@@ -23,13 +23,12 @@ type Company struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// RegisterRequest is a self sign-up; roles are only given by company admins
 type RegisterRequest struct {
-	Username      string `json:"username"`
-	Password      string `json:"password"`
-	IsAdmin       bool   `json:"is_admin"`
-	HasAccounting bool   `json:"has_accounting"`
-	CompanyID     int    `json:"company_id"`
-	MobileNumber  string `json:"mobile_number"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	CompanyID    int    `json:"company_id"`
+	MobileNumber string `json:"mobile_number"`
 }
 
 type Vehicle struct {
@@ -10,6 +10,8 @@ type VehicleResponse struct {
 	IsAvailable               bool                  `json:"is_available"`
 	SalesImage                string                `json:"image_name"`
 	Status                    string                `json:"status"`
+	BranchID                  *int                  `json:"branch_id"`         // home branch
+	CurrentBranchID           *int                  `json:"current_branch_id"` // where the vehicle is now
 	FutureBookingDetails      []FutureBookingDetail `json:"future_booking_details,omitempty"`
 	SaleID                    int                   `json:"sale_id"`
 }
@@ -38,6 +40,7 @@ type VehicleFilter struct {
 	VehicleRegistrationNumber string `json:"vehicle_registration_number"`
 	IsAvailable               string `json:"is_available"`
 	Status                    string `json:"status"`
+	BranchID                  string `json:"branch_id"` // where the vehicle is now
 	Limit                     int    `json:"limit"`
 	Offset                    int    `json:"offset"`
 }
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types emitted by the repositories
+const (
+	WebhookEventSaleCreated      = "sale.created"
+	WebhookEventSaleReturned     = "sale.returned"
+	WebhookEventPaymentVerified  = "payment.verified"
+	WebhookEventPaymentFailed    = "payment.failed"
+	WebhookEventPaymentCancelled = "payment.cancelled"
+	WebhookEventBookingCancelled = "booking.cancelled"
+	WebhookEventBudgetExceeded   = "budget.exceeded"
+)
+
+// WebhookEventTypes lists every event an endpoint can subscribe to
+var WebhookEventTypes = []string{
+	WebhookEventSaleCreated,
+	WebhookEventSaleReturned,
+	WebhookEventPaymentVerified,
+	WebhookEventPaymentFailed,
+	WebhookEventPaymentCancelled,
+	WebhookEventBookingCancelled,
+	WebhookEventBudgetExceeded,
+}
+
+type WebhookEndpoint struct {
+	EndpointID int       `json:"endpoint_id"`
+	CompanyID  int       `json:"company_id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WebhookEndpointRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+type WebhookDelivery struct {
+	DeliveryID    int             `json:"delivery_id"`
+	EndpointID    int             `json:"endpoint_id"`
+	URL           string          `json:"url"`
+	Secret        string          `json:"-"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     *string         `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// WebhookPayload is the body posted to subscribers
+type WebhookPayload struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
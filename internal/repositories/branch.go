@@ -0,0 +1,297 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+)
+
+var (
+	ErrBranchNotFound   = errors.New("branch not found")
+	ErrInvalidBranch    = errors.New("invalid branch")
+	ErrOneWayNotOffered = errors.New("one-way rental is not offered between these branches")
+)
+
+type BranchRepository struct {
+	db *sql.DB
+}
+
+func NewBranchRepository(db *sql.DB) *BranchRepository {
+	return &BranchRepository{db: db}
+}
+
+const branchColumns = `branch_id, company_id, code, name, address, phone, is_active, created_at, updated_at`
+
+func scanBranch(row rowScanner) (*models.Branch, error) {
+	var b models.Branch
+	err := row.Scan(&b.BranchID, &b.CompanyID, &b.Code, &b.Name, &b.Address, &b.Phone, &b.IsActive,
+		&b.CreatedAt, &b.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrBranchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan branch: %v", err)
+	}
+	return &b, nil
+}
+
+// requireCompanyBranch fails unless branchID is an active branch of the company
+func requireCompanyBranch(ctx context.Context, conn ledgerConn, companyID, branchID int) error {
+	var active bool
+	err := conn.QueryRowContext(ctx, `SELECT is_active FROM branches WHERE branch_id = $1 AND company_id = $2`,
+		branchID, companyID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: branch %d does not belong to the company", ErrInvalidBranch, branchID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch branch %d: %v", branchID, err)
+	}
+	if !active {
+		return fmt.Errorf("%w: branch %d is closed", ErrInvalidBranch, branchID)
+	}
+	return nil
+}
+
+// resolveSaleBranches works out where a rental by userID starts and ends. The pickup branch
+// defaults to where the vehicle is, then to the user's home branch, and must match the vehicle's
+// location when that is known; the drop-off branch defaults to the pickup branch. A drop-off at
+// another branch returns the transfer fee configured for the route.
+func resolveSaleBranches(ctx context.Context, conn ledgerConn, userID, vehicleID int, pickup, dropoff *int) (*int, *int, float64, error) {
+	var companyID int
+	var homeBranch, vehicleBranch sql.NullInt64
+	err := conn.QueryRowContext(ctx, `
+		SELECT u.company_id, u.branch_id,
+			(SELECT COALESCE(v.current_branch_id, v.branch_id) FROM vehicles v WHERE v.vehicle_id = $2)
+		FROM users u
+		WHERE u.id = $1
+	`, userID, vehicleID).Scan(&companyID, &homeBranch, &vehicleBranch)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to fetch branches of the sale: %v", err)
+	}
+
+	if pickup == nil {
+		switch {
+		case vehicleBranch.Valid:
+			id := int(vehicleBranch.Int64)
+			pickup = &id
+		case homeBranch.Valid:
+			id := int(homeBranch.Int64)
+			pickup = &id
+		}
+	} else if vehicleBranch.Valid && int64(*pickup) != vehicleBranch.Int64 {
+		return nil, nil, 0, fmt.Errorf("%w: the vehicle is at branch %d", ErrInvalidBranch, vehicleBranch.Int64)
+	}
+	if dropoff == nil {
+		dropoff = pickup
+	}
+	if pickup == nil {
+		if dropoff != nil {
+			return nil, nil, 0, fmt.Errorf("%w: a drop-off branch needs a pickup branch", ErrInvalidBranch)
+		}
+		return nil, nil, 0, nil
+	}
+
+	if err := requireCompanyBranch(ctx, conn, companyID, *pickup); err != nil {
+		return nil, nil, 0, err
+	}
+	if *dropoff == *pickup {
+		return pickup, dropoff, 0, nil
+	}
+	if err := requireCompanyBranch(ctx, conn, companyID, *dropoff); err != nil {
+		return nil, nil, 0, err
+	}
+
+	var fee float64
+	err = conn.QueryRowContext(ctx, `
+		SELECT fee FROM branch_transfer_fees WHERE from_branch_id = $1 AND to_branch_id = $2
+	`, *pickup, *dropoff).Scan(&fee)
+	if err == sql.ErrNoRows {
+		return nil, nil, 0, fmt.Errorf("%w: %d to %d", ErrOneWayNotOffered, *pickup, *dropoff)
+	}
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to fetch transfer fee: %v", err)
+	}
+	return pickup, dropoff, fee, nil
+}
+
+// GetBranches lists the company's branches, closed ones only when asked for
+func (r *BranchRepository) GetBranches(companyID int, includeInactive bool) ([]models.Branch, error) {
+	rows, err := r.db.Query(`
+		SELECT `+branchColumns+`
+		FROM branches
+		WHERE company_id = $1 AND ($2 OR is_active)
+		ORDER BY name, branch_id
+	`, companyID, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query branches: %v", err)
+	}
+	defer rows.Close()
+
+	branches := []models.Branch{}
+	for rows.Next() {
+		branch, err := scanBranch(rows)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, *branch)
+	}
+	return branches, rows.Err()
+}
+
+func (r *BranchRepository) GetBranch(companyID, branchID int) (*models.Branch, error) {
+	return scanBranch(r.db.QueryRow(`
+		SELECT `+branchColumns+` FROM branches WHERE branch_id = $1 AND company_id = $2
+	`, branchID, companyID))
+}
+
+func (r *BranchRepository) CreateBranch(branch *models.Branch) error {
+	err := r.db.QueryRow(`
+		INSERT INTO branches (company_id, code, name, address, phone, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (company_id, code) DO NOTHING
+		RETURNING branch_id, created_at, updated_at
+	`, branch.CompanyID, branch.Code, branch.Name, branch.Address, branch.Phone, branch.IsActive,
+	).Scan(&branch.BranchID, &branch.CreatedAt, &branch.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: code %s is already used", ErrInvalidBranch, branch.Code)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create branch: %v", err)
+	}
+	return nil
+}
+
+func (r *BranchRepository) UpdateBranch(branch *models.Branch) error {
+	var taken bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM branches WHERE company_id = $1 AND code = $2 AND branch_id <> $3)
+	`, branch.CompanyID, branch.Code, branch.BranchID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check branch code: %v", err)
+	}
+	if taken {
+		return fmt.Errorf("%w: code %s is already used", ErrInvalidBranch, branch.Code)
+	}
+
+	err = r.db.QueryRow(`
+		UPDATE branches
+		SET code = $1, name = $2, address = $3, phone = $4, is_active = $5, updated_at = NOW()
+		WHERE branch_id = $6 AND company_id = $7
+		RETURNING created_at, updated_at
+	`, branch.Code, branch.Name, branch.Address, branch.Phone, branch.IsActive, branch.BranchID,
+		branch.CompanyID).Scan(&branch.CreatedAt, &branch.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrBranchNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update branch: %v", err)
+	}
+	return nil
+}
+
+func (r *BranchRepository) GetTransferFees(companyID int) ([]models.BranchTransferFee, error) {
+	rows, err := r.db.Query(`
+		SELECT f.fee_id, f.from_branch_id, bf.name, f.to_branch_id, bt.name, f.fee, f.created_at, f.updated_at
+		FROM branch_transfer_fees f
+		JOIN branches bf ON bf.branch_id = f.from_branch_id
+		JOIN branches bt ON bt.branch_id = f.to_branch_id
+		WHERE f.company_id = $1
+		ORDER BY bf.name, bt.name
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transfer fees: %v", err)
+	}
+	defer rows.Close()
+
+	fees := []models.BranchTransferFee{}
+	for rows.Next() {
+		var f models.BranchTransferFee
+		if err := rows.Scan(&f.FeeID, &f.FromBranchID, &f.FromBranchName, &f.ToBranchID, &f.ToBranchName, &f.Fee,
+			&f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer fee: %v", err)
+		}
+		fees = append(fees, f)
+	}
+	return fees, rows.Err()
+}
+
+// SetTransferFee offers one-way rentals from one branch to another for fee, replacing any earlier fee
+func (r *BranchRepository) SetTransferFee(companyID int, req models.BranchTransferFeeRequest) (*models.BranchTransferFee, error) {
+	ctx := context.Background()
+	for _, branchID := range []int{req.FromBranchID, req.ToBranchID} {
+		if err := requireCompanyBranch(ctx, r.db, companyID, branchID); err != nil {
+			return nil, err
+		}
+	}
+
+	fee := models.BranchTransferFee{FromBranchID: req.FromBranchID, ToBranchID: req.ToBranchID, Fee: roundCents(req.Fee)}
+	err := r.db.QueryRow(`
+		INSERT INTO branch_transfer_fees (company_id, from_branch_id, to_branch_id, fee)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (from_branch_id, to_branch_id) DO UPDATE SET fee = EXCLUDED.fee, updated_at = NOW()
+		RETURNING fee_id, created_at, updated_at,
+			(SELECT name FROM branches WHERE branch_id = $2), (SELECT name FROM branches WHERE branch_id = $3)
+	`, companyID, fee.FromBranchID, fee.ToBranchID, fee.Fee).Scan(&fee.FeeID, &fee.CreatedAt, &fee.UpdatedAt,
+		&fee.FromBranchName, &fee.ToBranchName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set transfer fee: %v", err)
+	}
+	return &fee, nil
+}
+
+// DeleteTransferFee stops offering one-way rentals on a route
+func (r *BranchRepository) DeleteTransferFee(companyID, feeID int) error {
+	result, err := r.db.Exec(`DELETE FROM branch_transfer_fees WHERE fee_id = $1 AND company_id = $2`, feeID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete transfer fee: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AssignUser sets the home branch of one of the company's users
+func (r *BranchRepository) AssignUser(companyID, userID int, branchID *int) error {
+	if branchID != nil {
+		if err := requireCompanyBranch(context.Background(), r.db, companyID, *branchID); err != nil {
+			return err
+		}
+	}
+	result, err := r.db.Exec(`
+		UPDATE users SET branch_id = $1, updated_at = NOW() WHERE id = $2 AND company_id = $3
+	`, branchID, userID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to assign user branch: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AssignVehicle sets the home branch of a vehicle. A vehicle that is not out on a rental is moved
+// there too; a rented one arrives wherever it is dropped off.
+func (r *BranchRepository) AssignVehicle(companyID, vehicleID int, branchID *int) error {
+	if branchID != nil {
+		if err := requireCompanyBranch(context.Background(), r.db, companyID, *branchID); err != nil {
+			return err
+		}
+	}
+	result, err := r.db.Exec(`
+		UPDATE vehicles
+		SET branch_id = $1,
+			current_branch_id = CASE WHEN status = 'rented' THEN current_branch_id ELSE $1 END,
+			updated_at = NOW()
+		WHERE vehicle_id = $2
+	`, branchID, vehicleID)
+	if err != nil {
+		return fmt.Errorf("failed to assign vehicle branch: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"renting/internal/models"
+	"time"
+)
+
+// companySpendJoin limits company expenses to approved ones recorded by the company's users
+const companySpendJoin = `
+	FROM company_expenses ce
+	JOIN users u ON u.id = ce.recorded_by
+	WHERE ce.approval_status = 'approved'`
+
+type BudgetRepository struct {
+	db *sql.DB
+}
+
+func NewBudgetRepository(db *sql.DB) *BudgetRepository {
+	return &BudgetRepository{db: db}
+}
+
+const recurringExpenseColumns = `recurring_id, company_id, expense_type, amount, description, vehicle_id,
+		frequency, start_date, end_date, next_run_date, run_count, is_active, last_run_at,
+		created_by, created_at, updated_at`
+
+func scanRecurringExpense(row rowScanner, r *models.RecurringExpense) error {
+	return row.Scan(&r.RecurringID, &r.CompanyID, &r.ExpenseType, &r.Amount, &r.Description, &r.VehicleID,
+		&r.Frequency, &r.StartDate, &r.EndDate, &r.NextRunDate, &r.RunCount, &r.IsActive, &r.LastRunAt,
+		&r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+}
+
+func (r *BudgetRepository) queryRecurringExpenses(query string, args ...interface{}) ([]models.RecurringExpense, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recurring expenses: %v", err)
+	}
+	defer rows.Close()
+
+	templates := []models.RecurringExpense{}
+	for rows.Next() {
+		var template models.RecurringExpense
+		if err := scanRecurringExpense(rows, &template); err != nil {
+			return nil, fmt.Errorf("failed to scan recurring expense: %v", err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+func (r *BudgetRepository) GetRecurringExpenses(companyID int) ([]models.RecurringExpense, error) {
+	return r.queryRecurringExpenses(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE company_id = $1
+		ORDER BY is_active DESC, next_run_date, recurring_id`, companyID)
+}
+
+func (r *BudgetRepository) GetRecurringExpense(companyID, recurringID int) (*models.RecurringExpense, error) {
+	var template models.RecurringExpense
+	err := scanRecurringExpense(r.db.QueryRow(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE recurring_id = $1 AND company_id = $2`, recurringID, companyID), &template)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetDueRecurringExpenses returns active templates whose next run is on or before today, of one
+// company or, with a nil companyID, of every company
+func (r *BudgetRepository) GetDueRecurringExpenses(today time.Time, companyID *int) ([]models.RecurringExpense, error) {
+	return r.queryRecurringExpenses(`
+		SELECT `+recurringExpenseColumns+`
+		FROM recurring_expenses
+		WHERE is_active = TRUE
+		AND next_run_date <= $1::date
+		AND (end_date IS NULL OR next_run_date <= end_date)
+		AND ($2::int IS NULL OR company_id = $2)
+		ORDER BY recurring_id`, today, companyID)
+}
+
+func (r *BudgetRepository) CreateRecurringExpense(template *models.RecurringExpense) error {
+	err := r.db.QueryRow(`
+		INSERT INTO recurring_expenses (company_id, expense_type, amount, description, vehicle_id,
+			frequency, start_date, end_date, next_run_date, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING recurring_id, run_count, created_at, updated_at`,
+		template.CompanyID, template.ExpenseType, template.Amount, template.Description, template.VehicleID,
+		template.Frequency, template.StartDate, template.EndDate, template.NextRunDate, template.IsActive, template.CreatedBy,
+	).Scan(&template.RecurringID, &template.RunCount, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create recurring expense: %v", err)
+	}
+	return nil
+}
+
+// UpdateRecurringExpense saves the template; the schedule and run count are set by the caller
+func (r *BudgetRepository) UpdateRecurringExpense(template *models.RecurringExpense) error {
+	err := r.db.QueryRow(`
+		UPDATE recurring_expenses
+		SET expense_type = $1,
+			amount = $2,
+			description = $3,
+			vehicle_id = $4,
+			frequency = $5,
+			start_date = $6,
+			end_date = $7,
+			next_run_date = $8,
+			run_count = $9,
+			is_active = $10,
+			updated_at = NOW()
+		WHERE recurring_id = $11 AND company_id = $12
+		RETURNING updated_at`,
+		template.ExpenseType, template.Amount, template.Description, template.VehicleID, template.Frequency,
+		template.StartDate, template.EndDate, template.NextRunDate, template.RunCount, template.IsActive,
+		template.RecurringID, template.CompanyID,
+	).Scan(&template.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update recurring expense: %v", err)
+	}
+	return nil
+}
+
+// DeleteRecurringExpense removes the template; expenses it already generated are kept
+func (r *BudgetRepository) DeleteRecurringExpense(companyID, recurringID int) error {
+	result, err := r.db.Exec(`DELETE FROM recurring_expenses WHERE recurring_id = $1 AND company_id = $2`,
+		recurringID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recurring expense: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RunRecurringExpense books one expense per occurrence date and moves the template on to
+// nextRunDate. It returns false without writing anything when another run got there first.
+func (r *BudgetRepository) RunRecurringExpense(template models.RecurringExpense, occurrences []time.Time,
+	approvalStatus string, nextRunDate time.Time, stillActive bool) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE recurring_expenses
+		SET next_run_date = $1,
+			run_count = run_count + $2,
+			is_active = $3,
+			last_run_at = NOW(),
+			updated_at = NOW()
+		WHERE recurring_id = $4 AND next_run_date = $5::date AND is_active = TRUE`,
+		nextRunDate, len(occurrences), stillActive, template.RecurringID, template.NextRunDate)
+	if err != nil {
+		return false, fmt.Errorf("failed to advance recurring expense %d: %v", template.RecurringID, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	ctx := context.Background()
+	for _, date := range occurrences {
+		var expenseID int
+		err := tx.QueryRow(`
+			INSERT INTO company_expenses (expense_type, amount, description, expense_date, recorded_by,
+				vehicle_id, approval_status, recurring_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING expense_id`,
+			template.ExpenseType, template.Amount, template.Description, date, template.CreatedBy,
+			template.VehicleID, approvalStatus, template.RecurringID,
+		).Scan(&expenseID)
+		if err != nil {
+			return false, fmt.Errorf("failed to book recurring expense %d: %v", template.RecurringID, err)
+		}
+		if err := postExpense(ctx, tx, expenseID, &template.CreatedBy); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+func (r *BudgetRepository) GetBudgets(companyID int, month *time.Time) ([]models.ExpenseBudget, error) {
+	query := `
+		SELECT budget_id, company_id, expense_type, month, amount, created_by, created_at, updated_at
+		FROM expense_budgets
+		WHERE company_id = $1`
+	args := []interface{}{companyID}
+	if month != nil {
+		query += " AND month = $2::date"
+		args = append(args, *month)
+	}
+	query += " ORDER BY month DESC, expense_type"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budgets: %v", err)
+	}
+	defer rows.Close()
+
+	budgets := []models.ExpenseBudget{}
+	for rows.Next() {
+		var b models.ExpenseBudget
+		if err := rows.Scan(&b.BudgetID, &b.CompanyID, &b.ExpenseType, &b.Month, &b.Amount,
+			&b.CreatedBy, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %v", err)
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+// SetBudget creates or replaces the budget of a category for a month. Changing the amount
+// clears an earlier overspend alert so it is raised again if spending is still over.
+func (r *BudgetRepository) SetBudget(budget *models.ExpenseBudget) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO expense_budgets (company_id, expense_type, month, amount, created_by)
+		VALUES ($1, $2, $3::date, $4, $5)
+		ON CONFLICT (company_id, expense_type, month)
+		DO UPDATE SET amount = EXCLUDED.amount, updated_at = NOW()
+		RETURNING budget_id, created_by, created_at, updated_at`,
+		budget.CompanyID, budget.ExpenseType, budget.Month, budget.Amount, budget.CreatedBy,
+	).Scan(&budget.BudgetID, &budget.CreatedBy, &budget.CreatedAt, &budget.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save budget: %v", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM budget_alerts WHERE budget_id = $1 AND budget_amount <> $2`,
+		budget.BudgetID, budget.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to reset budget alert: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (r *BudgetRepository) DeleteBudget(companyID, budgetID int) error {
+	result, err := r.db.Exec(`DELETE FROM expense_budgets WHERE budget_id = $1 AND company_id = $2`,
+		budgetID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetBudgetVsActual returns budget and approved spending per category for a month,
+// including categories that were spent in without a budget
+func (r *BudgetRepository) GetBudgetVsActual(companyID int, month time.Time) ([]models.BudgetReportRow, error) {
+	rows, err := r.db.Query(`
+		WITH spend AS (
+			SELECT ce.expense_type, SUM(ce.amount) AS actual
+			`+companySpendJoin+`
+			AND u.company_id = $1
+			AND ce.expense_date >= $2::date
+			AND ce.expense_date < $2::date + interval '1 month'
+			GROUP BY ce.expense_type
+		),
+		budgets AS (
+			SELECT expense_type, amount
+			FROM expense_budgets
+			WHERE company_id = $1 AND month = $2::date
+		)
+		SELECT COALESCE(b.expense_type, s.expense_type), b.amount, COALESCE(s.actual, 0)
+		FROM budgets b
+		FULL OUTER JOIN spend s ON s.expense_type = b.expense_type
+		ORDER BY b.amount IS NULL, COALESCE(b.expense_type, s.expense_type)
+	`, companyID, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget against actual: %v", err)
+	}
+	defer rows.Close()
+
+	report := []models.BudgetReportRow{}
+	for rows.Next() {
+		var row models.BudgetReportRow
+		var budget sql.NullFloat64
+		if err := rows.Scan(&row.ExpenseType, &budget, &row.Actual); err != nil {
+			return nil, fmt.Errorf("failed to scan budget row: %v", err)
+		}
+		row.Budget = budget.Float64
+		row.Budgeted = budget.Valid
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
+
+// RaiseBudgetAlerts records an alert for every budget whose approved spending has gone over
+// the budgeted amount and queues a budget.exceeded webhook for the company. Each budget
+// alerts once until its amount is changed. A nil companyID checks the budgets of every company.
+func (r *BudgetRepository) RaiseBudgetAlerts(companyID *int) ([]models.BudgetAlert, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		WITH over_budget AS (
+			SELECT b.budget_id, b.amount, spend.actual
+			FROM expense_budgets b
+			CROSS JOIN LATERAL (
+				SELECT COALESCE(SUM(ce.amount), 0) AS actual
+				`+companySpendJoin+`
+				AND u.company_id = b.company_id
+				AND ce.expense_type = b.expense_type
+				AND ce.expense_date >= b.month
+				AND ce.expense_date < b.month + interval '1 month'
+			) spend
+			WHERE spend.actual > b.amount
+			AND ($1::int IS NULL OR b.company_id = $1)
+		),
+		inserted AS (
+			INSERT INTO budget_alerts (budget_id, budget_amount, actual_amount)
+			SELECT budget_id, amount, actual FROM over_budget
+			ON CONFLICT (budget_id) DO NOTHING
+			RETURNING alert_id, budget_id, budget_amount, actual_amount, created_at
+		)
+		SELECT i.alert_id, i.budget_id, b.company_id, b.expense_type, b.month,
+			i.budget_amount, i.actual_amount, i.created_at
+		FROM inserted i
+		JOIN expense_budgets b ON b.budget_id = i.budget_id
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to raise budget alerts: %v", err)
+	}
+
+	alerts := []models.BudgetAlert{}
+	companies := []int{}
+	for rows.Next() {
+		var alert models.BudgetAlert
+		var companyID int
+		if err := rows.Scan(&alert.AlertID, &alert.BudgetID, &companyID, &alert.ExpenseType, &alert.Month,
+			&alert.Budget, &alert.Actual, &alert.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan budget alert: %v", err)
+		}
+		alerts = append(alerts, alert)
+		companies = append(companies, companyID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, alert := range alerts {
+		err := enqueueCompanyWebhookEvent(tx, companies[i], models.WebhookEventBudgetExceeded, map[string]interface{}{
+			"budget_id":    alert.BudgetID,
+			"expense_type": alert.ExpenseType,
+			"month":        alert.Month.Format("2006-01"),
+			"budget":       alert.Budget,
+			"actual":       alert.Actual,
+			"over_by":      roundCents(alert.Actual - alert.Budget),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return alerts, tx.Commit()
+}
+
+func (r *BudgetRepository) GetBudgetAlerts(companyID int, month *time.Time) ([]models.BudgetAlert, error) {
+	query := `
+		SELECT a.alert_id, a.budget_id, b.expense_type, b.month, a.budget_amount, a.actual_amount, a.created_at
+		FROM budget_alerts a
+		JOIN expense_budgets b ON b.budget_id = a.budget_id
+		WHERE b.company_id = $1`
+	args := []interface{}{companyID}
+	if month != nil {
+		query += " AND b.month = $2::date"
+		args = append(args, *month)
+	}
+	query += " ORDER BY a.created_at DESC"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query budget alerts: %v", err)
+	}
+	defer rows.Close()
+
+	alerts := []models.BudgetAlert{}
+	for rows.Next() {
+		var alert models.BudgetAlert
+		if err := rows.Scan(&alert.AlertID, &alert.BudgetID, &alert.ExpenseType, &alert.Month,
+			&alert.Budget, &alert.Actual, &alert.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan budget alert: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
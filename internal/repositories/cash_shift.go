@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+)
+
+var (
+	ErrCashShiftNotFound    = errors.New("cash shift not found")
+	ErrCashShiftAlreadyOpen = errors.New("user already has an open cash shift")
+	ErrNoOpenCashShift      = errors.New("no open cash shift")
+	ErrCashShiftNotClosed   = errors.New("cash shift is not waiting for sign-off")
+)
+
+type CashShiftRepository struct {
+	db *sql.DB
+}
+
+func NewCashShiftRepository(db *sql.DB) *CashShiftRepository {
+	return &CashShiftRepository{db: db}
+}
+
+// Cash counted in a shift: cash payments the user took, pending or verified, and expenses the user
+// paid from the drawer that were not rejected, between opening and closing (or now while open)
+const (
+	cashShiftPaymentsWhere = `p.user_id = cs.user_id AND p.payment_type = '` + models.CashPaymentMethod + `'
+		AND p.payment_status IN ('Pending', 'Completed')
+		AND p.created_at >= cs.opened_at AND p.created_at < COALESCE(cs.closed_at, NOW())`
+	cashShiftExpensesWhere = `e.recorded_by = cs.user_id AND e.paid_in_cash
+		AND e.approval_status <> 'rejected'
+		AND e.created_at >= cs.opened_at AND e.created_at < COALESCE(cs.closed_at, NOW())`
+)
+
+const cashShiftSelect = `
+	SELECT cs.shift_id, cs.company_id, cs.user_id, u.username, cs.branch_id, b.name, cs.status, cs.opening_float, cs.opened_at,
+		cs.opening_note, cs.closed_at,
+		COALESCE(cs.cash_collected, (SELECT COALESCE(SUM(p.amount_paid), 0) FROM payments p
+			WHERE ` + cashShiftPaymentsWhere + `)),
+		COALESCE(cs.cash_expenses, (SELECT COALESCE(SUM(e.amount), 0) FROM company_expenses e
+			WHERE ` + cashShiftExpensesWhere + `)),
+		cs.counted_cash, cs.variance, cs.closing_note, cs.approved_by, cs.approved_at, cs.approval_note
+	FROM cash_shifts cs
+	JOIN users u ON u.id = cs.user_id
+	LEFT JOIN branches b ON b.branch_id = cs.branch_id`
+
+func scanCashShift(row rowScanner) (*models.CashShift, error) {
+	var s models.CashShift
+	err := row.Scan(&s.ShiftID, &s.CompanyID, &s.UserID, &s.Username, &s.BranchID, &s.BranchName, &s.Status, &s.OpeningFloat, &s.OpenedAt,
+		&s.OpeningNote, &s.ClosedAt, &s.CashCollected, &s.CashExpenses, &s.CountedCash, &s.Variance,
+		&s.ClosingNote, &s.ApprovedBy, &s.ApprovedAt, &s.ApprovalNote)
+	if err == sql.ErrNoRows {
+		return nil, ErrCashShiftNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cash shift: %v", err)
+	}
+	s.ExpectedCash = roundCents(s.OpeningFloat + s.CashCollected - s.CashExpenses)
+	return &s, nil
+}
+
+// OpenShift starts a shift for the user at their home branch; a user has at most one open shift
+// at a time
+func (r *CashShiftRepository) OpenShift(companyID, userID int, openingFloat float64, note *string) (*models.CashShift, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var open bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM cash_shifts WHERE user_id = $1 AND status = 'open')`,
+		userID).Scan(&open)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check open cash shifts: %v", err)
+	}
+	if open {
+		return nil, ErrCashShiftAlreadyOpen
+	}
+
+	var shiftID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO cash_shifts (company_id, user_id, branch_id, opening_float, opening_note)
+		SELECT $1, $2, branch_id, $3, $4 FROM users WHERE id = $2
+		RETURNING shift_id
+	`, companyID, userID, roundCents(openingFloat), note).Scan(&shiftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cash shift: %v", err)
+	}
+
+	shift, err := scanCashShift(tx.QueryRowContext(ctx, cashShiftSelect+` WHERE cs.shift_id = $1`, shiftID))
+	if err != nil {
+		return nil, err
+	}
+	return shift, tx.Commit()
+}
+
+// GetOpenShift returns the user's open shift with its running totals
+func (r *CashShiftRepository) GetOpenShift(userID int) (*models.CashShift, error) {
+	shift, err := scanCashShift(r.db.QueryRow(cashShiftSelect+` WHERE cs.user_id = $1 AND cs.status = 'open'`, userID))
+	if errors.Is(err, ErrCashShiftNotFound) {
+		return nil, ErrNoOpenCashShift
+	}
+	return shift, err
+}
+
+// CloseShift freezes the totals of the user's open shift and records the counted cash and its
+// variance against what the drawer should hold
+func (r *CashShiftRepository) CloseShift(userID int, countedCash float64, note *string) (*models.CashShift, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shift, err := scanCashShift(tx.QueryRowContext(ctx, cashShiftSelect+`
+		WHERE cs.user_id = $1 AND cs.status = 'open'
+		FOR UPDATE OF cs`, userID))
+	if errors.Is(err, ErrCashShiftNotFound) {
+		return nil, ErrNoOpenCashShift
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counted := roundCents(countedCash)
+	variance := roundCents(counted - shift.ExpectedCash)
+	err = tx.QueryRowContext(ctx, `
+		UPDATE cash_shifts
+		SET status = 'closed', closed_at = NOW(), cash_collected = $1, cash_expenses = $2, expected_cash = $3,
+			counted_cash = $4, variance = $5, closing_note = $6
+		WHERE shift_id = $7
+		RETURNING closed_at
+	`, shift.CashCollected, shift.CashExpenses, shift.ExpectedCash, counted, variance, note,
+		shift.ShiftID).Scan(&shift.ClosedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close cash shift: %v", err)
+	}
+
+	shift.Status = models.CashShiftClosed
+	shift.CountedCash, shift.Variance, shift.ClosingNote = &counted, &variance, note
+	return shift, tx.Commit()
+}
+
+// ApproveShift records a manager's sign-off on a closed shift
+func (r *CashShiftRepository) ApproveShift(companyID, shiftID, approvedBy int, note *string) (*models.CashShift, error) {
+	result, err := r.db.Exec(`
+		UPDATE cash_shifts
+		SET status = 'approved', approved_by = $1, approved_at = NOW(), approval_note = $2
+		WHERE shift_id = $3 AND company_id = $4 AND status = 'closed'
+	`, approvedBy, note, shiftID, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve cash shift: %v", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		shift, err := r.GetShift(companyID, shiftID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: it is %s", ErrCashShiftNotClosed, shift.Status)
+	}
+	return r.GetShift(companyID, shiftID)
+}
+
+func (r *CashShiftRepository) GetShift(companyID, shiftID int) (*models.CashShift, error) {
+	return scanCashShift(r.db.QueryRow(cashShiftSelect+` WHERE cs.shift_id = $1 AND cs.company_id = $2`,
+		shiftID, companyID))
+}
+
+// GetShifts lists the company's shifts, newest first
+func (r *CashShiftRepository) GetShifts(companyID int, filter models.CashShiftFilter) ([]models.CashShift, error) {
+	query := cashShiftSelect + ` WHERE cs.company_id = $1`
+	args := []interface{}{companyID}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		query += fmt.Sprintf(" AND cs.user_id = $%d", len(args))
+	}
+	if filter.BranchID != nil {
+		args = append(args, *filter.BranchID)
+		query += fmt.Sprintf(" AND cs.branch_id = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND cs.status = $%d", len(args))
+	}
+	if filter.Date != "" {
+		args = append(args, filter.Date)
+		query += fmt.Sprintf(" AND cs.opened_at::date = $%d::date", len(args))
+	}
+	query += " ORDER BY cs.opened_at DESC, cs.shift_id DESC"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cash shifts: %v", err)
+	}
+	defer rows.Close()
+
+	shifts := []models.CashShift{}
+	for rows.Next() {
+		shift, err := scanCashShift(rows)
+		if err != nil {
+			return nil, err
+		}
+		shifts = append(shifts, *shift)
+	}
+	return shifts, rows.Err()
+}
+
+// GetShiftDetail returns a shift with the cash payments and expenses behind its totals
+func (r *CashShiftRepository) GetShiftDetail(companyID, shiftID int) (*models.CashShiftDetail, error) {
+	shift, err := r.GetShift(companyID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	detail := models.CashShiftDetail{
+		CashShift: *shift,
+		Payments:  []models.CashShiftPayment{},
+		Expenses:  []models.CashShiftExpense{},
+	}
+
+	rows, err := r.db.Query(`
+		SELECT p.payment_id, p.sale_id, COALESCE(s.customer_name, ''), p.amount_paid, p.payment_status,
+			p.created_at, p.receipt_number
+		FROM cash_shifts cs
+		JOIN payments p ON `+cashShiftPaymentsWhere+`
+		JOIN sales s ON s.sale_id = p.sale_id
+		WHERE cs.shift_id = $1
+		ORDER BY p.created_at, p.payment_id
+	`, shiftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shift payments: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.CashShiftPayment
+		if err := rows.Scan(&p.PaymentID, &p.SaleID, &p.CustomerName, &p.AmountPaid, &p.PaymentStatus,
+			&p.PaymentDate, &p.ReceiptNumber); err != nil {
+			return nil, fmt.Errorf("failed to scan shift payment: %v", err)
+		}
+		detail.Payments = append(detail.Payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	expenseRows, err := r.db.Query(`
+		SELECT e.expense_id, e.expense_type, e.amount, e.description, e.approval_status, e.created_at
+		FROM cash_shifts cs
+		JOIN company_expenses e ON `+cashShiftExpensesWhere+`
+		WHERE cs.shift_id = $1
+		ORDER BY e.created_at, e.expense_id
+	`, shiftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shift expenses: %v", err)
+	}
+	defer expenseRows.Close()
+	for expenseRows.Next() {
+		var e models.CashShiftExpense
+		if err := expenseRows.Scan(&e.ExpenseID, &e.ExpenseType, &e.Amount, &e.Description, &e.ApprovalStatus,
+			&e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan shift expense: %v", err)
+		}
+		detail.Expenses = append(detail.Expenses, e)
+	}
+	return &detail, expenseRows.Err()
+}
@@ -0,0 +1,320 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+)
+
+var (
+	ErrCompanyCodeTaken = errors.New("company code is already used")
+	ErrUserExists       = errors.New("username or mobile number is already registered")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrLastAdmin        = errors.New("the company must keep at least one active admin")
+	ErrInviteNotFound   = errors.New("invite not found, expired or already accepted")
+)
+
+type CompanyRepository struct {
+	db *sql.DB
+}
+
+func NewCompanyRepository(db *sql.DB) *CompanyRepository {
+	return &CompanyRepository{db: db}
+}
+
+const companyUserColumns = `id, username, mobile_number, is_admin, has_accounting, is_locked, branch_id, created_at, updated_at`
+
+func scanCompanyUser(row rowScanner) (*models.CompanyUser, error) {
+	var u models.CompanyUser
+	err := row.Scan(&u.ID, &u.Username, &u.MobileNumber, &u.IsAdmin, &u.HasAccounting, &u.IsLocked, &u.BranchID,
+		&u.CreatedAt, &u.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %v", err)
+	}
+	return &u, nil
+}
+
+// requireNewUser fails when the username or mobile number already belongs to someone; both are
+// unique across companies
+func requireNewUser(ctx context.Context, conn ledgerConn, username, mobileNumber string) error {
+	var taken bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE username = $1 OR mobile_number = $2)
+	`, username, mobileNumber).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check existing users: %v", err)
+	}
+	if taken {
+		return ErrUserExists
+	}
+	return nil
+}
+
+// CreateCompanyWithOwner creates a company and its first user, who is an admin with accounting
+// access. owner.Password must already be hashed.
+func (r *CompanyRepository) CreateCompanyWithOwner(company *models.Company, owner *models.User) (*models.CompanyUser, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var taken bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM companies WHERE company_code = $1)`,
+		company.CompanyCode).Scan(&taken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check company code: %v", err)
+	}
+	if taken {
+		return nil, ErrCompanyCodeTaken
+	}
+	if err := requireNewUser(ctx, tx, owner.Username, owner.MobileNumber); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO companies (name, company_code)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`, company.Name, company.CompanyCode).Scan(&company.ID, &company.CreatedAt, &company.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create company: %v", err)
+	}
+
+	user, err := scanCompanyUser(tx.QueryRowContext(ctx, `
+		INSERT INTO users (username, password, is_admin, has_accounting, is_locked, company_id, mobile_number)
+		VALUES ($1, $2, TRUE, TRUE, FALSE, $3, $4)
+		RETURNING `+companyUserColumns,
+		owner.Username, owner.Password, company.ID, owner.MobileNumber))
+	if err != nil {
+		return nil, err
+	}
+	return user, tx.Commit()
+}
+
+// GetUsers lists the company's users by username
+func (r *CompanyRepository) GetUsers(companyID int, filter models.CompanyUserFilter) ([]models.CompanyUser, error) {
+	query := `SELECT ` + companyUserColumns + ` FROM users WHERE company_id = $1`
+	args := []interface{}{companyID}
+	switch filter.Status {
+	case "active":
+		query += " AND NOT is_locked"
+	case "locked":
+		query += " AND is_locked"
+	}
+	switch filter.Role {
+	case "admin":
+		query += " AND is_admin"
+	case "accounting":
+		query += " AND has_accounting"
+	}
+	if filter.BranchID != nil {
+		args = append(args, *filter.BranchID)
+		query += fmt.Sprintf(" AND branch_id = $%d", len(args))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+filter.Search+"%")
+		query += fmt.Sprintf(" AND (username ILIKE $%d OR mobile_number ILIKE $%d)", len(args), len(args))
+	}
+	query += " ORDER BY username, id"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %v", err)
+	}
+	defer rows.Close()
+
+	users := []models.CompanyUser{}
+	for rows.Next() {
+		user, err := scanCompanyUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (r *CompanyRepository) GetUser(companyID, userID int) (*models.CompanyUser, error) {
+	return scanCompanyUser(r.db.QueryRow(`SELECT `+companyUserColumns+` FROM users WHERE id = $1 AND company_id = $2`,
+		userID, companyID))
+}
+
+// updateUser applies set (a SET list over $1.. with its args) to one of the company's users. The
+// company's active admins are locked first so two admins cannot demote or lock each other out at
+// once; the change is refused when it would leave the company without one.
+func (r *CompanyRepository) updateUser(companyID, userID int, set string, args ...interface{}) (*models.CompanyUser, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		SELECT id FROM users WHERE company_id = $1 AND is_admin AND NOT is_locked FOR UPDATE
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock company admins: %v", err)
+	}
+
+	args = append(args, userID, companyID)
+	user, err := scanCompanyUser(tx.QueryRowContext(ctx, fmt.Sprintf(`
+		UPDATE users SET %s, updated_at = NOW()
+		WHERE id = $%d AND company_id = $%d
+		RETURNING `+companyUserColumns, set, len(args)-1, len(args)), args...))
+	if err != nil {
+		return nil, err
+	}
+
+	var admins int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users WHERE company_id = $1 AND is_admin AND NOT is_locked
+	`, companyID).Scan(&admins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count company admins: %v", err)
+	}
+	if admins == 0 {
+		return nil, ErrLastAdmin
+	}
+	return user, tx.Commit()
+}
+
+// SetUserRoles changes the roles that are not nil
+func (r *CompanyRepository) SetUserRoles(companyID, userID int, isAdmin, hasAccounting *bool) (*models.CompanyUser, error) {
+	return r.updateUser(companyID, userID, `is_admin = COALESCE($1, is_admin), has_accounting = COALESCE($2, has_accounting)`,
+		isAdmin, hasAccounting)
+}
+
+// SetUserLocked locks a user out of logging in or lets them back in
+func (r *CompanyRepository) SetUserLocked(companyID, userID int, locked bool) (*models.CompanyUser, error) {
+	return r.updateUser(companyID, userID, `is_locked = $1`, locked)
+}
+
+const userInviteColumns = `invite_id, company_id, username, mobile_number, is_admin, has_accounting, branch_id,
+	invited_by, expires_at, accepted_at, created_at`
+
+func scanUserInvite(row rowScanner) (*models.UserInvite, error) {
+	var i models.UserInvite
+	err := row.Scan(&i.InviteID, &i.CompanyID, &i.Username, &i.MobileNumber, &i.IsAdmin, &i.HasAccounting, &i.BranchID,
+		&i.InvitedBy, &i.ExpiresAt, &i.AcceptedAt, &i.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan invite: %v", err)
+	}
+	return &i, nil
+}
+
+// CreateInvite stores an invite under the hash of its token, replacing any pending invite of the
+// company for the same mobile number
+func (r *CompanyRepository) CreateInvite(invite *models.UserInvite, tokenHash string) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if invite.BranchID != nil {
+		if err := requireCompanyBranch(ctx, tx, invite.CompanyID, *invite.BranchID); err != nil {
+			return err
+		}
+	}
+	if err := requireNewUser(ctx, tx, invite.Username, invite.MobileNumber); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM user_invites WHERE company_id = $1 AND mobile_number = $2 AND accepted_at IS NULL
+	`, invite.CompanyID, invite.MobileNumber)
+	if err != nil {
+		return fmt.Errorf("failed to replace earlier invite: %v", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO user_invites (company_id, token_hash, username, mobile_number, is_admin, has_accounting,
+			branch_id, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING invite_id, created_at
+	`, invite.CompanyID, tokenHash, invite.Username, invite.MobileNumber, invite.IsAdmin, invite.HasAccounting,
+		invite.BranchID, invite.InvitedBy, invite.ExpiresAt).Scan(&invite.InviteID, &invite.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invite: %v", err)
+	}
+	return tx.Commit()
+}
+
+// GetPendingInvites lists the company's invites that can still be accepted
+func (r *CompanyRepository) GetPendingInvites(companyID int) ([]models.UserInvite, error) {
+	rows, err := r.db.Query(`
+		SELECT `+userInviteColumns+`
+		FROM user_invites
+		WHERE company_id = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC, invite_id DESC
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invites: %v", err)
+	}
+	defer rows.Close()
+
+	invites := []models.UserInvite{}
+	for rows.Next() {
+		invite, err := scanUserInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, *invite)
+	}
+	return invites, rows.Err()
+}
+
+// AcceptInvite turns a pending invite into a user with the invited roles and the given, already
+// hashed, password
+func (r *CompanyRepository) AcceptInvite(tokenHash, passwordHash string) (*models.CompanyUser, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invite, err := scanUserInvite(tx.QueryRowContext(ctx, `
+		SELECT `+userInviteColumns+`
+		FROM user_invites
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > NOW()
+		FOR UPDATE
+	`, tokenHash))
+	if err != nil {
+		return nil, err
+	}
+	// Someone may have registered the username or number since the invite was sent
+	if err := requireNewUser(ctx, tx, invite.Username, invite.MobileNumber); err != nil {
+		return nil, err
+	}
+
+	user, err := scanCompanyUser(tx.QueryRowContext(ctx, `
+		INSERT INTO users (username, password, is_admin, has_accounting, is_locked, company_id, mobile_number, branch_id)
+		VALUES ($1, $2, $3, $4, FALSE, $5, $6, $7)
+		RETURNING `+companyUserColumns,
+		invite.Username, passwordHash, invite.IsAdmin, invite.HasAccounting, invite.CompanyID, invite.MobileNumber,
+		invite.BranchID))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_invites SET accepted_at = NOW(), accepted_user_id = $1 WHERE invite_id = $2
+	`, user.ID, invite.InviteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark invite accepted: %v", err)
+	}
+	return user, tx.Commit()
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"renting/internal/models"
+	"time"
+)
+
+// rentalEndExpr is the last day a sale keeps its vehicle: the actual return when known, and for
+// an active rental not yet back, at least the as-of day so overdue vehicles still count as out
+const rentalEndExpr = `COALESCE(s.actual_date_of_return::date,
+	CASE WHEN s.status = 'active' THEN GREATEST(s.return_date::date, $2::date) ELSE s.return_date::date END)`
+
+type DashboardRepository struct {
+	db *sql.DB
+}
+
+func NewDashboardRepository(db *sql.DB) *DashboardRepository {
+	return &DashboardRepository{db: db}
+}
+
+// GetSnapshot gathers every dashboard figure for one period in a single query. Point-in-time
+// figures are evaluated on asOf from the rental and maintenance history, so a past period can be
+// reconstructed the same way as today. Rentals use the actual dates when known. Only the company's
+// vehicles, sales and payments are counted. A rental is overdue once lateGrace has passed after
+// its return date.
+func (r *DashboardRepository) GetSnapshot(companyID int, startDate, asOf time.Time, lateGrace time.Duration) (*models.DashboardSnapshot, error) {
+	var snap models.DashboardSnapshot
+	err := r.db.QueryRow(`
+		WITH company_sales AS (
+			SELECT s.* FROM sales s
+			JOIN users u ON u.id = s.user_id AND u.company_id = $3
+		),
+		company_vehicles AS (
+			SELECT v.* FROM vehicles v WHERE `+companyVehicleSQL("$3")+`
+		),
+		fleet AS (
+			SELECT v.vehicle_id,
+				EXISTS (
+					SELECT 1 FROM company_sales s
+					WHERE s.vehicle_id = v.vehicle_id AND s.status <> 'cancelled'
+					AND COALESCE(s.actual_date_of_delivery, s.date_of_delivery)::date <= $2::date
+					AND `+rentalEndExpr+` >= $2::date
+					AND (s.status IN ('active', 'completed') OR s.actual_date_of_delivery IS NOT NULL)
+				) AS rented,
+				EXISTS (
+					SELECT 1 FROM maintenance_blocks mb
+					WHERE mb.vehicle_id = v.vehicle_id AND mb.status <> 'cancelled'
+					AND $2::date BETWEEN mb.start_date AND mb.end_date
+				) AS in_maintenance
+			FROM company_vehicles v
+			WHERE COALESCE(v.created_at::date, $2::date) <= $2::date
+		),
+		utilisation AS (
+			SELECT
+				COALESCE(SUM($2::date - GREATEST(COALESCE(v.created_at::date, $1::date), $1::date) + 1), 0) AS fleet_days,
+				COALESCE((
+					SELECT SUM(GREATEST(0, LEAST(mb.end_date, $2::date) - GREATEST(mb.start_date, $1::date) + 1))
+					FROM maintenance_blocks mb
+					JOIN company_vehicles cv ON cv.vehicle_id = mb.vehicle_id
+					WHERE mb.status <> 'cancelled' AND mb.start_date <= $2::date AND mb.end_date >= $1::date
+				), 0) AS downtime_days,
+				COALESCE((
+					SELECT SUM(GREATEST(0,
+						LEAST(`+rentalEndExpr+`, $2::date) -
+						GREATEST(COALESCE(s.actual_date_of_delivery, s.date_of_delivery)::date, $1::date) + 1
+					))
+					FROM company_sales s
+					WHERE s.status <> 'cancelled'
+					AND COALESCE(s.actual_date_of_delivery, s.date_of_delivery)::date <= $2::date
+					AND `+rentalEndExpr+` >= $1::date
+				), 0) AS rented_days
+			FROM company_vehicles v
+			WHERE COALESCE(v.created_at::date, $1::date) <= $2::date
+		)
+		SELECT
+			(SELECT COUNT(*) FROM fleet),
+			(SELECT COUNT(*) FROM fleet WHERE NOT rented AND NOT in_maintenance),
+			(SELECT COUNT(*) FROM fleet WHERE rented),
+			(SELECT COUNT(*) FROM fleet WHERE in_maintenance AND NOT rented),
+			(SELECT COUNT(*) FROM company_sales
+				WHERE status <> 'cancelled' AND date_of_delivery::date = $2::date),
+			(SELECT COUNT(*) FROM company_sales
+				WHERE status <> 'cancelled' AND return_date::date = $2::date),
+			(SELECT COUNT(*) FROM company_sales s
+				WHERE s.status <> 'cancelled'
+				AND COALESCE(s.actual_date_of_delivery, s.date_of_delivery)::date <= $2::date
+				AND (s.status IN ('active', 'completed') OR s.actual_date_of_delivery IS NOT NULL)
+				AND s.return_date::date + make_interval(secs => $4) < $2::date
+				AND (s.actual_date_of_return IS NULL OR s.actual_date_of_return::date > $2::date)),
+			(SELECT COALESCE(SUM(GREATEST(0,
+					COALESCE(s.total_amount, 0)
+					+ COALESCE((SELECT SUM(`+signedChargeSQL("sc")+`) FROM sales_charges sc WHERE sc.sale_id = s.sale_id), 0)
+					- COALESCE((SELECT SUM(p.amount_paid) FROM payments p
+						WHERE p.sale_id = s.sale_id AND p.payment_status = 'Completed'
+						AND p.payment_date::date <= $2::date), 0)
+				)), 0)
+				FROM company_sales s
+				WHERE s.status <> 'cancelled' AND COALESCE(s.booking_date, s.created_at::date) <= $2::date),
+			(SELECT COALESCE(SUM(p.amount_paid), 0) FROM payments p
+				JOIN company_sales s ON s.sale_id = p.sale_id
+				WHERE p.verified_by_admin = true AND p.payment_status IN ('Completed', 'Refunded')
+				AND p.payment_date::date BETWEEN $1::date AND $2::date),
+			u.rented_days,
+			GREATEST(u.fleet_days - u.downtime_days, 0)
+		FROM utilisation u
+	`, startDate, asOf, companyID, lateGrace.Seconds()).Scan(&snap.FleetTotal, &snap.FleetAvailable, &snap.FleetRented, &snap.FleetMaintenance,
+		&snap.DeliveriesDue, &snap.ReturnsDue, &snap.OverdueReturns, &snap.Receivables, &snap.CashIn,
+		&snap.RentedDays, &snap.AvailableDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dashboard figures: %v", err)
+	}
+	return &snap, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 )
 
@@ -13,8 +14,33 @@ func NewDataAggregateRepository(db *sql.DB) *DataAggregateRepository {
 	return &DataAggregateRepository{db: db}
 }
 
-// GetPendingRequests (unchanged)
-func (r *DataAggregateRepository) GetPendingRequests(date time.Time, filterType string) (int, error) {
+// periodArgs returns the query arguments of a date, month or year filter
+func periodArgs(date time.Time, filterType string) []interface{} {
+	switch filterType {
+	case "date":
+		return []interface{}{date.Format("2006-01-02")}
+	case "month":
+		return []interface{}{date.Year(), date.Month()}
+	default:
+		return []interface{}{date.Year()}
+	}
+}
+
+// withBranch narrows query to a branch when one is given; condition takes the placeholder number
+func withBranch(query string, args []interface{}, branchID *int, condition string) (string, []interface{}) {
+	if branchID == nil {
+		return query, args
+	}
+	args = append(args, *branchID)
+	return query + fmt.Sprintf(condition, len(args)), args
+}
+
+// Sales, and the payments against them, belong to the branch they were picked up from
+const saleBranchCondition = `
+			AND sale_id IN (SELECT sale_id FROM sales WHERE pickup_branch_id = $%d)`
+
+// GetPendingRequests counts payments waiting for verification in a period, optionally of one branch
+func (r *DataAggregateRepository) GetPendingRequests(date time.Time, filterType string, branchID *int) (int, error) {
 	var query string
 	switch filterType {
 	case "date":
@@ -43,21 +69,15 @@ func (r *DataAggregateRepository) GetPendingRequests(date time.Time, filterType
 		return 0, nil
 	}
 
+	query, args := withBranch(query, periodArgs(date, filterType), branchID, saleBranchCondition)
+
 	var count int
-	var err error
-	switch filterType {
-	case "date":
-		err = r.db.QueryRow(query, date.Format("2006-01-02")).Scan(&count)
-	case "month":
-		err = r.db.QueryRow(query, date.Year(), date.Month()).Scan(&count)
-	case "year":
-		err = r.db.QueryRow(query, date.Year()).Scan(&count)
-	}
+	err := r.db.QueryRow(query, args...).Scan(&count)
 	return count, err
 }
 
 // GetTotalSales returns the count of sales and total verified payments for a given date period
-func (r *DataAggregateRepository) GetTotalSales(date time.Time, filterType string) (int, float64, error) {
+func (r *DataAggregateRepository) GetTotalSales(date time.Time, filterType string, branchID *int) (int, float64, error) {
 	var query string
 	switch filterType {
 	case "date":
@@ -83,22 +103,16 @@ func (r *DataAggregateRepository) GetTotalSales(date time.Time, filterType strin
 		return 0, 0, nil
 	}
 
+	query, args := withBranch(query, periodArgs(date, filterType), branchID, saleBranchCondition)
+
 	var count int
 	var totalRevenue float64
-	var err error
-	switch filterType {
-	case "date":
-		err = r.db.QueryRow(query, date.Format("2006-01-02")).Scan(&count, &totalRevenue)
-	case "month":
-		err = r.db.QueryRow(query, date.Year(), date.Month()).Scan(&count, &totalRevenue)
-	case "year":
-		err = r.db.QueryRow(query, date.Year()).Scan(&count, &totalRevenue)
-	}
+	err := r.db.QueryRow(query, args...).Scan(&count, &totalRevenue)
 	return count, totalRevenue, err
 }
 
-// GetFutureBookings (unchanged)
-func (r *DataAggregateRepository) GetFutureBookings(date time.Time, filterType string) (int, error) {
+// GetFutureBookings counts paid-up bookings made in a period, optionally of one branch
+func (r *DataAggregateRepository) GetFutureBookings(date time.Time, filterType string, branchID *int) (int, error) {
 	var query string
 	switch filterType {
 	case "date":
@@ -145,28 +159,25 @@ func (r *DataAggregateRepository) GetFutureBookings(date time.Time, filterType s
 		return 0, nil
 	}
 
+	query, args := withBranch(query, periodArgs(date, filterType), branchID, `
+			AND s.pickup_branch_id = $%d`)
+
 	var count int
-	var err error
-	switch filterType {
-	case "date":
-		err = r.db.QueryRow(query, date.Format("2006-01-02")).Scan(&count)
-	case "month":
-		err = r.db.QueryRow(query, date.Year(), date.Month()).Scan(&count)
-	case "year":
-		err = r.db.QueryRow(query, date.Year()).Scan(&count)
-	}
+	err := r.db.QueryRow(query, args...).Scan(&count)
 	return count, err
 }
 
-// GetTotalAvailableCars returns the count of available cars
-func (r *DataAggregateRepository) GetTotalAvailableCars() (int, error) {
+// GetTotalAvailableCars returns the count of available cars, optionally of those at one branch
+func (r *DataAggregateRepository) GetTotalAvailableCars(branchID *int) (int, error) {
 	query := `
 		SELECT COUNT(*)
 		FROM vehicles
 		WHERE is_available = true
-		AND status = 'active'`
+		AND status = 'available'`
+	query, args := withBranch(query, nil, branchID, `
+		AND COALESCE(current_branch_id, branch_id) = $%d`)
 
 	var count int
-	err := r.db.QueryRow(query).Scan(&count)
+	err := r.db.QueryRow(query, args...).Scan(&count)
 	return count, err
 }
@@ -58,12 +58,50 @@ func (r *disableDateRepository) GetDisabledDates(vehicleID int, excludeSaleID *i
 		return nil, fmt.Errorf("failed to fetch future bookings: %v", err)
 	}
 
+	// Get planned maintenance downtime
+	maintenanceBlocks, err := r.fetchMaintenanceBlocks(vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch maintenance blocks: %v", err)
+	}
+
 	return &models.DisableDateResponse{
-		ActiveRentals:  activeRentals,
-		FutureBookings: futureBookings,
+		ActiveRentals:     activeRentals,
+		FutureBookings:    futureBookings,
+		MaintenanceBlocks: maintenanceBlocks,
 	}, nil
 }
 
+func (r *disableDateRepository) fetchMaintenanceBlocks(vehicleID int) ([]models.DisabledDateResponse, error) {
+	rows, err := r.db.Query(`
+		SELECT start_date, end_date, reason
+		FROM maintenance_blocks
+		WHERE vehicle_id = $1
+			AND status IN ('scheduled', 'active')
+			AND end_date >= CURRENT_DATE
+		ORDER BY start_date
+	`, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query maintenance blocks: %v", err)
+	}
+	defer rows.Close()
+
+	dates := []models.DisabledDateResponse{}
+	for rows.Next() {
+		var startDate, endDate time.Time
+		var reason string
+		if err := rows.Scan(&startDate, &endDate, &reason); err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance block: %v", err)
+		}
+		dates = append(dates, models.DisabledDateResponse{
+			DateOfDelivery: startDate.Format("2006-01-02"),
+			ReturnDate:     endDate.Format("2006-01-02"),
+			Reason:         reason,
+		})
+	}
+
+	return dates, rows.Err()
+}
+
 func (r *disableDateRepository) fetchDates(query string, vehicleID int, excludeSaleID interface{}) ([]models.DisabledDateResponse, error) {
 	rows, err := r.db.Query(query, vehicleID, excludeSaleID)
 	if err != nil {
@@ -90,4 +128,3 @@ func (r *disableDateRepository) fetchDates(query string, vehicleID int, excludeS
 
 	return dates, nil
 }
-
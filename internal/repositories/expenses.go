@@ -1,17 +1,30 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"renting/internal/models"
 	"strconv"
 )
 
+// ErrExpenseNotPending is returned when approving or rejecting an expense that is not awaiting approval
+var ErrExpenseNotPending = errors.New("expense is not pending approval")
+
 type ExpenseRepository interface {
 	Create(expense *models.Expense) error
 	Update(expense *models.Expense) error
 	Delete(id int) error
 	FindByID(id int) (*models.Expense, error)
-	FindAll(filter models.ExpenseFilter) ([]models.Expense, error)
+	FindAll(filter models.ExpenseFilter) ([]models.Expense, int, error)
+	Approve(id, approvedBy int) error
+	Reject(id, rejectedBy int, reason string) error
+	GetCategories(companyID int) ([]models.ExpenseCategory, error)
+	FindCategory(companyID int, code string) (*models.ExpenseCategory, error)
+	CreateCategory(category *models.ExpenseCategory) error
+	DeleteCategory(companyID, categoryID int) error
+	AddReceipt(receipt *models.ExpenseReceipt) error
 }
 
 type expenseRepository struct {
@@ -22,22 +35,78 @@ func NewExpenseRepository(db *sql.DB) ExpenseRepository {
 	return &expenseRepository{db}
 }
 
+const expenseColumns = `expense_id, expense_type, amount, description, expense_date, recorded_by,
+		vehicle_id, sale_id, paid_in_cash, recurring_id, approval_status, approved_by, approved_at, rejection_reason,
+		created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExpense(row rowScanner, expense *models.Expense) error {
+	return row.Scan(
+		&expense.ExpenseID,
+		&expense.ExpenseType,
+		&expense.Amount,
+		&expense.Description,
+		&expense.ExpenseDate,
+		&expense.RecordedBy,
+		&expense.VehicleID,
+		&expense.SaleID,
+		&expense.PaidInCash,
+		&expense.RecurringID,
+		&expense.ApprovalStatus,
+		&expense.ApprovedBy,
+		&expense.ApprovedAt,
+		&expense.RejectionReason,
+		&expense.CreatedAt,
+		&expense.UpdatedAt,
+	)
+}
+
 func (r *expenseRepository) Create(expense *models.Expense) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO company_expenses (expense_type, amount, description, expense_date, recorded_by)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO company_expenses (expense_type, amount, description, expense_date, recorded_by,
+			vehicle_id, sale_id, paid_in_cash, recurring_id, approval_status, approved_by, approved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING expense_id, created_at, updated_at`
-	err := r.db.QueryRow(query,
+	err = tx.QueryRow(query,
 		expense.ExpenseType,
 		expense.Amount,
 		expense.Description,
 		expense.ExpenseDate,
 		expense.RecordedBy,
+		expense.VehicleID,
+		expense.SaleID,
+		expense.PaidInCash,
+		expense.RecurringID,
+		expense.ApprovalStatus,
+		expense.ApprovedBy,
+		expense.ApprovedAt,
 	).Scan(&expense.ExpenseID, &expense.CreatedAt, &expense.UpdatedAt)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if err := postExpense(context.Background(), tx, expense.ExpenseID, expense.RecordedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *expenseRepository) Update(expense *models.Expense) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE company_expenses
 		SET expense_type = $1,
@@ -45,22 +114,54 @@ func (r *expenseRepository) Update(expense *models.Expense) error {
 			description = $3,
 			expense_date = $4,
 			recorded_by = $5,
+			vehicle_id = $6,
+			sale_id = $7,
+			paid_in_cash = $8,
+			approval_status = $9,
+			approved_by = $10,
+			approved_at = $11,
+			rejection_reason = $12,
 			updated_at = NOW()
-		WHERE expense_id = $6`
-	_, err := r.db.Exec(query,
+		WHERE expense_id = $13`
+	_, err = tx.Exec(query,
 		expense.ExpenseType,
 		expense.Amount,
 		expense.Description,
 		expense.ExpenseDate,
 		expense.RecordedBy,
+		expense.VehicleID,
+		expense.SaleID,
+		expense.PaidInCash,
+		expense.ApprovalStatus,
+		expense.ApprovedBy,
+		expense.ApprovedAt,
+		expense.RejectionReason,
 		expense.ExpenseID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Replace the posting so the ledger follows the edited amount, date and type
+	ctx := context.Background()
+	if err := reverseExpense(ctx, tx, expense.ExpenseID, expense.RecordedBy); err != nil {
+		return err
+	}
+	if err := postExpense(ctx, tx, expense.ExpenseID, expense.RecordedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *expenseRepository) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `DELETE FROM company_expenses WHERE expense_id = $1`
-	result, err := r.db.Exec(query, id)
+	result, err := tx.Exec(query, id)
 	if err != nil {
 		return err
 	}
@@ -71,85 +172,286 @@ func (r *expenseRepository) Delete(id int) error {
 	if rowsAffected == 0 {
 		return sql.ErrNoRows
 	}
-	return nil
+
+	if err := reverseExpense(context.Background(), tx, id, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (r *expenseRepository) FindByID(id int) (*models.Expense, error) {
 	query := `
-		SELECT expense_id, expense_type, amount, description, expense_date, recorded_by, created_at, updated_at
+		SELECT ` + expenseColumns + `
 		FROM company_expenses
 		WHERE expense_id = $1`
 	expense := &models.Expense{}
-	err := r.db.QueryRow(query, id).Scan(
-		&expense.ExpenseID,
-		&expense.ExpenseType,
-		&expense.Amount,
-		&expense.Description,
-		&expense.ExpenseDate,
-		&expense.RecordedBy,
-		&expense.CreatedAt,
-		&expense.UpdatedAt,
-	)
+	err := scanExpense(r.db.QueryRow(query, id), expense)
 	if err == sql.ErrNoRows {
 		return nil, nil // Or return a custom error if preferred
 	}
 	if err != nil {
 		return nil, err
 	}
+
+	expense.Receipts, err = r.getReceipts(id)
+	if err != nil {
+		return nil, err
+	}
 	return expense, nil
 }
 
-func (r *expenseRepository) FindAll(filter models.ExpenseFilter) ([]models.Expense, error) {
-	query := `
-		SELECT expense_id, expense_type, amount, description, expense_date, recorded_by, created_at, updated_at
-		FROM company_expenses
-		WHERE 1=1`
+// FindAll returns one page of the expenses matching the filter and the total number of matches
+func (r *expenseRepository) FindAll(filter models.ExpenseFilter) ([]models.Expense, int, error) {
+	where := " WHERE 1=1"
 	args := []interface{}{}
 	argCount := 1
 
 	if filter.ExpenseType != nil {
-		query += " AND expense_type = $" + strconv.Itoa(argCount)
+		where += " AND expense_type = $" + strconv.Itoa(argCount)
 		args = append(args, *filter.ExpenseType)
 		argCount++
 	}
 	if filter.StartDate != nil {
-		query += " AND expense_date >= $" + strconv.Itoa(argCount)
+		where += " AND expense_date >= $" + strconv.Itoa(argCount)
 		args = append(args, *filter.StartDate)
 		argCount++
 	}
 	if filter.EndDate != nil {
-		query += " AND expense_date <= $" + strconv.Itoa(argCount)
+		where += " AND expense_date <= $" + strconv.Itoa(argCount)
 		args = append(args, *filter.EndDate)
 		argCount++
 	}
-	query += " ORDER BY expense_date DESC"
+	if filter.VehicleID != nil {
+		where += " AND vehicle_id = $" + strconv.Itoa(argCount)
+		args = append(args, *filter.VehicleID)
+		argCount++
+	}
+	if filter.SaleID != nil {
+		where += " AND sale_id = $" + strconv.Itoa(argCount)
+		args = append(args, *filter.SaleID)
+		argCount++
+	}
+	if filter.RecordedBy != nil {
+		where += " AND recorded_by = $" + strconv.Itoa(argCount)
+		args = append(args, *filter.RecordedBy)
+		argCount++
+	}
+	if filter.MinAmount != nil {
+		where += " AND amount >= $" + strconv.Itoa(argCount)
+		args = append(args, *filter.MinAmount)
+		argCount++
+	}
+	if filter.MaxAmount != nil {
+		where += " AND amount <= $" + strconv.Itoa(argCount)
+		args = append(args, *filter.MaxAmount)
+		argCount++
+	}
+	if filter.ApprovalStatus != nil {
+		where += " AND approval_status = $" + strconv.Itoa(argCount)
+		args = append(args, *filter.ApprovalStatus)
+		argCount++
+	}
+
+	var totalCount int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM company_expenses"+where, args...).Scan(&totalCount); err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT " + expenseColumns + " FROM company_expenses" + where +
+		" ORDER BY expense_date DESC, expense_id DESC" +
+		" LIMIT $" + strconv.Itoa(argCount) + " OFFSET $" + strconv.Itoa(argCount+1)
+	args = append(args, filter.Limit, filter.Offset)
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var expenses []models.Expense
+	expenses := []models.Expense{}
 	for rows.Next() {
 		var expense models.Expense
-		err := rows.Scan(
-			&expense.ExpenseID,
-			&expense.ExpenseType,
-			&expense.Amount,
-			&expense.Description,
-			&expense.ExpenseDate,
-			&expense.RecordedBy,
-			&expense.CreatedAt,
-			&expense.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
+		if err := scanExpense(rows, &expense); err != nil {
+			return nil, 0, err
 		}
 		expenses = append(expenses, expense)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	return expenses, totalCount, nil
+}
+
+// Approve marks a pending expense approved and books it
+func (r *expenseRepository) Approve(id, approvedBy int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE company_expenses
+		SET approval_status = $1, approved_by = $2, approved_at = NOW(), rejection_reason = NULL, updated_at = NOW()
+		WHERE expense_id = $3 AND approval_status = $4`,
+		models.ExpenseApprovalApproved, approvedBy, id, models.ExpenseApprovalPending)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrExpenseNotPending
+	}
+
+	if err := postExpense(context.Background(), tx, id, &approvedBy); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Reject marks a pending expense rejected; it was never booked so there is nothing to reverse
+func (r *expenseRepository) Reject(id, rejectedBy int, reason string) error {
+	result, err := r.db.Exec(`
+		UPDATE company_expenses
+		SET approval_status = $1, approved_by = $2, approved_at = NOW(), rejection_reason = $3, updated_at = NOW()
+		WHERE expense_id = $4 AND approval_status = $5`,
+		models.ExpenseApprovalRejected, rejectedBy, reason, id, models.ExpenseApprovalPending)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrExpenseNotPending
+	}
+	return nil
+}
+
+// GetCategories returns the built-in expense categories plus the company's own
+func (r *expenseRepository) GetCategories(companyID int) ([]models.ExpenseCategory, error) {
+	rows, err := r.db.Query(`
+		SELECT id, company_id, code, name, approval_threshold, created_at
+		FROM expense_categories
+		WHERE company_id IS NULL OR company_id = $1
+		ORDER BY company_id NULLS FIRST, name`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense categories: %v", err)
+	}
+	defer rows.Close()
+
+	categories := []models.ExpenseCategory{}
+	for rows.Next() {
+		var category models.ExpenseCategory
+		err := rows.Scan(&category.ID, &category.CompanyID, &category.Code, &category.Name,
+			&category.ApprovalThreshold, &category.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expense category: %v", err)
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// FindCategory looks code up among the built-in and company categories, preferring the company's own
+func (r *expenseRepository) FindCategory(companyID int, code string) (*models.ExpenseCategory, error) {
+	var category models.ExpenseCategory
+	err := r.db.QueryRow(`
+		SELECT id, company_id, code, name, approval_threshold, created_at
+		FROM expense_categories
+		WHERE code = $1 AND (company_id IS NULL OR company_id = $2)
+		ORDER BY company_id NULLS LAST
+		LIMIT 1`, code, companyID).Scan(&category.ID, &category.CompanyID, &category.Code, &category.Name,
+		&category.ApprovalThreshold, &category.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch expense category: %v", err)
+	}
+	return &category, nil
+}
+
+// CreateCategory adds a company-defined expense category
+func (r *expenseRepository) CreateCategory(category *models.ExpenseCategory) error {
+	err := r.db.QueryRow(`
+		INSERT INTO expense_categories (company_id, code, name, approval_threshold)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		category.CompanyID, category.Code, category.Name, category.ApprovalThreshold,
+	).Scan(&category.ID, &category.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create expense category: %v", err)
+	}
+	return nil
+}
+
+// DeleteCategory removes a company-defined category that none of the company's expenses use
+func (r *expenseRepository) DeleteCategory(companyID, categoryID int) error {
+	var code string
+	err := r.db.QueryRow(`
+		SELECT code FROM expense_categories WHERE id = $1 AND company_id = $2`,
+		categoryID, companyID).Scan(&code)
+	if err != nil {
+		return err
+	}
+
+	var inUse int
+	err = r.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM company_expenses ce
+		JOIN users u ON u.id = ce.recorded_by
+		WHERE ce.expense_type = $1 AND u.company_id = $2`, code, companyID).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("failed to check expense category usage: %v", err)
+	}
+	if inUse > 0 {
+		return fmt.Errorf("expense category %s is used by %d expenses", code, inUse)
+	}
+
+	_, err = r.db.Exec(`DELETE FROM expense_categories WHERE id = $1`, categoryID)
+	return err
+}
+
+// AddReceipt records a receipt that has been uploaded for an expense
+func (r *expenseRepository) AddReceipt(receipt *models.ExpenseReceipt) error {
+	err := r.db.QueryRow(`
+		INSERT INTO expense_receipts (expense_id, receipt_url, file_name, content_type, size_bytes, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING receipt_id, uploaded_at`,
+		receipt.ExpenseID, receipt.ReceiptURL, receipt.FileName, receipt.ContentType, receipt.SizeBytes, receipt.UploadedBy,
+	).Scan(&receipt.ReceiptID, &receipt.UploadedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save expense receipt: %v", err)
+	}
+	return nil
+}
+
+func (r *expenseRepository) getReceipts(expenseID int) ([]models.ExpenseReceipt, error) {
+	rows, err := r.db.Query(`
+		SELECT receipt_id, expense_id, receipt_url, file_name, COALESCE(content_type, ''),
+			COALESCE(size_bytes, 0), uploaded_by, uploaded_at
+		FROM expense_receipts
+		WHERE expense_id = $1
+		ORDER BY uploaded_at`, expenseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expense receipts: %v", err)
+	}
+	defer rows.Close()
+
+	receipts := []models.ExpenseReceipt{}
+	for rows.Next() {
+		var receipt models.ExpenseReceipt
+		err := rows.Scan(&receipt.ReceiptID, &receipt.ExpenseID, &receipt.ReceiptURL, &receipt.FileName,
+			&receipt.ContentType, &receipt.SizeBytes, &receipt.UploadedBy, &receipt.UploadedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expense receipt: %v", err)
+		}
+		receipts = append(receipts, receipt)
 	}
-	return expenses, nil
+	return receipts, rows.Err()
 }
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"renting/internal/models"
@@ -281,6 +282,18 @@ func (r *FuturBookingRepository) FutureBookingCancellation(saleID int) error {
 	}
 	fmt.Printf("Successfully updated vehicle %d status to 'available'\n", vehicleID)
 
+	// Back the cancelled booking out of the ledger
+	if err := reverseSalePostings(context.Background(), tx, saleID, nil); err != nil {
+		return err
+	}
+	if err := recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+
+	if err := enqueueSaleWebhookEvent(tx, saleID, models.WebhookEventBookingCancelled, nil); err != nil {
+		return err
+	}
+
 	// Commit the transaction
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
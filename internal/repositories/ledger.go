@@ -0,0 +1,951 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"renting/internal/models"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ledgerConn is satisfied by *sql.Tx and by the connection of a gorm transaction,
+// so postings can join whichever transaction changes the underlying record
+type ledgerConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+
+// expenseAccountCodes maps company expense types onto expense accounts
+var expenseAccountCodes = map[string]string{
+	"servicing":                          models.AccountCodeServicingExpense,
+	string(models.ReminderTypeEMI):       models.AccountCodeEMIExpense,
+	string(models.ReminderTypeInsurance): models.AccountCodeInsuranceExpense,
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// signedChargeAmount is what a sales charge adds to the amount due. Discounts are stored positive
+// but always reduce it; any other charge counts with the sign it was stored with.
+func signedChargeAmount(chargeType string, amount float64) float64 {
+	if chargeType == models.ChargeTypeDiscount {
+		return -math.Abs(amount)
+	}
+	return amount
+}
+
+// signedChargeSQL is signedChargeAmount for the sales_charges row aliased as alias
+func signedChargeSQL(alias string) string {
+	return `(CASE WHEN ` + alias + `.charge_type = '` + models.ChargeTypeDiscount + `' THEN -ABS(` + alias + `.amount) ELSE ` +
+		alias + `.amount END)`
+}
+
+func expenseAccountCode(expenseType string) string {
+	if code, ok := expenseAccountCodes[strings.ToLower(expenseType)]; ok {
+		return code
+	}
+	return models.AccountCodeGeneralExpense
+}
+
+// isClosedPeriodDate reports whether date falls inside a closed accounting period of the company
+func isClosedPeriodDate(ctx context.Context, conn ledgerConn, companyID *int, date time.Time) (bool, error) {
+	var closed bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM accounting_periods
+			WHERE company_id = $2 AND status = 'closed' AND $1::date BETWEEN start_date AND end_date
+		)
+	`, date, companyID).Scan(&closed)
+	if err != nil {
+		return false, fmt.Errorf("failed to check accounting period: %v", err)
+	}
+	return closed, nil
+}
+
+// ledgerPostingDate moves automatic postings that fall in a closed period to today,
+// so late activity on an old sale lands in the open books instead of rewriting history
+func ledgerPostingDate(ctx context.Context, conn ledgerConn, companyID *int, date time.Time) (time.Time, error) {
+	closed, err := isClosedPeriodDate(ctx, conn, companyID, date)
+	if err != nil || !closed {
+		return date, err
+	}
+	today := time.Now()
+	closed, err = isClosedPeriodDate(ctx, conn, companyID, today)
+	if err != nil {
+		return date, err
+	}
+	if closed {
+		return date, errors.New("cannot post to the ledger: the current accounting period is closed")
+	}
+	return today, nil
+}
+
+// postJournalEntry validates and writes a balanced entry, filling in its ids. An entry for a sale
+// that does not name its company is booked to the company of the sale's user; every entry must
+// end up belonging to a company.
+func postJournalEntry(ctx context.Context, conn ledgerConn, entry *models.JournalEntry) error {
+	if len(entry.Lines) < 2 {
+		return errors.New("journal entry needs at least two lines")
+	}
+
+	var totalDebit, totalCredit float64
+	for i := range entry.Lines {
+		line := &entry.Lines[i]
+		line.Debit = roundCents(line.Debit)
+		line.Credit = roundCents(line.Credit)
+		if line.Debit < 0 || line.Credit < 0 || (line.Debit == 0) == (line.Credit == 0) {
+			return fmt.Errorf("journal line for account %s must have either a debit or a credit", line.AccountCode)
+		}
+		totalDebit += line.Debit
+		totalCredit += line.Credit
+	}
+	if roundCents(totalDebit) != roundCents(totalCredit) {
+		return fmt.Errorf("%w: debits %.2f, credits %.2f", ErrUnbalancedEntry, totalDebit, totalCredit)
+	}
+
+	if entry.CompanyID == nil && entry.SaleID != nil {
+		err := conn.QueryRowContext(ctx, `
+			SELECT u.company_id FROM sales s JOIN users u ON u.id = s.user_id WHERE s.sale_id = $1
+		`, *entry.SaleID).Scan(&entry.CompanyID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to look up the company of sale %d: %v", *entry.SaleID, err)
+		}
+	}
+	if entry.CompanyID == nil {
+		return errors.New("cannot post to the ledger: the entry does not belong to a company")
+	}
+
+	entryDate, err := ledgerPostingDate(ctx, conn, entry.CompanyID, entry.EntryDate)
+	if err != nil {
+		return err
+	}
+	entry.EntryDate = entryDate
+
+	for i := range entry.Lines {
+		line := &entry.Lines[i]
+		err = conn.QueryRowContext(ctx, `
+			SELECT account_id, name FROM accounts WHERE code = $1 AND (company_id IS NULL OR company_id = $2)
+		`, line.AccountCode, *entry.CompanyID).Scan(&line.AccountID, &line.AccountName)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("account %s does not exist", line.AccountCode)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up account %s: %v", line.AccountCode, err)
+		}
+	}
+
+	err = conn.QueryRowContext(ctx, `
+		INSERT INTO journal_entries (company_id, entry_date, description, source_type, source_id, sale_id, reversal_of, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING entry_id, created_at
+	`, entry.CompanyID, entry.EntryDate, entry.Description, entry.SourceType, entry.SourceID, entry.SaleID,
+		entry.ReversalOf, entry.CreatedBy).Scan(&entry.EntryID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert journal entry: %v", err)
+	}
+
+	for i := range entry.Lines {
+		line := &entry.Lines[i]
+		line.EntryID = entry.EntryID
+		err = conn.QueryRowContext(ctx, `
+			INSERT INTO journal_lines (entry_id, account_id, debit, credit, memo)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING line_id
+		`, entry.EntryID, line.AccountID, line.Debit, line.Credit, line.Memo).Scan(&line.LineID)
+		if err != nil {
+			return fmt.Errorf("failed to insert journal line: %v", err)
+		}
+	}
+	return nil
+}
+
+// postTwoLineEntry posts amount from the credit account to the debit account;
+// a negative amount swaps the sides and a zero amount posts nothing
+func postTwoLineEntry(ctx context.Context, conn ledgerConn, entry models.JournalEntry, debitCode, creditCode string, amount float64) error {
+	amount = roundCents(amount)
+	if amount == 0 {
+		return nil
+	}
+	if amount < 0 {
+		debitCode, creditCode = creditCode, debitCode
+		amount = -amount
+	}
+	entry.Lines = []models.JournalLine{
+		{AccountCode: debitCode, Debit: amount},
+		{AccountCode: creditCode, Credit: amount},
+	}
+	return postJournalEntry(ctx, conn, &entry)
+}
+
+// postTaxedEntry posts a VAT-inclusive gross amount to the debit account, crediting the revenue
+// account with the amount less tax and VAT payable with the tax; a negative amount swaps the sides
+func postTaxedEntry(ctx context.Context, conn ledgerConn, entry models.JournalEntry, debitCode, revenueCode string, gross, tax float64) error {
+	gross, tax = roundCents(gross), roundCents(tax)
+	if tax == 0 {
+		return postTwoLineEntry(ctx, conn, entry, debitCode, revenueCode, gross)
+	}
+	entry.Lines = []models.JournalLine{
+		{AccountCode: debitCode, Debit: gross},
+		{AccountCode: revenueCode, Credit: roundCents(gross - tax)},
+		{AccountCode: models.AccountCodeVATPayable, Credit: tax},
+	}
+	if gross < 0 {
+		for i := range entry.Lines {
+			entry.Lines[i].Debit, entry.Lines[i].Credit = -entry.Lines[i].Credit, -entry.Lines[i].Debit
+		}
+	}
+	return postJournalEntry(ctx, conn, &entry)
+}
+
+// hasLedgerPosting reports whether a record has postings that were not reversed
+func hasLedgerPosting(ctx context.Context, conn ledgerConn, sourceType string, sourceID int) (bool, error) {
+	var posted bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM journal_entries e
+			WHERE e.source_type = $1 AND e.source_id = $2 AND e.reversal_of IS NULL
+			AND NOT EXISTS (SELECT 1 FROM journal_entries r WHERE r.reversal_of = e.entry_id)
+		)
+	`, sourceType, sourceID).Scan(&posted)
+	if err != nil {
+		return false, fmt.Errorf("failed to check ledger postings: %v", err)
+	}
+	return posted, nil
+}
+
+// reverseJournalEntries posts mirror entries for every unreversed posting of a record
+func reverseJournalEntries(ctx context.Context, conn ledgerConn, sourceType string, sourceID int, description string, createdBy *int) error {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT e.entry_id, e.company_id, e.sale_id
+		FROM journal_entries e
+		WHERE e.source_type = $1 AND e.source_id = $2 AND e.reversal_of IS NULL
+		AND NOT EXISTS (SELECT 1 FROM journal_entries r WHERE r.reversal_of = e.entry_id)
+		ORDER BY e.entry_id
+	`, sourceType, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch journal entries to reverse: %v", err)
+	}
+	var originals []models.JournalEntry
+	for rows.Next() {
+		var entry models.JournalEntry
+		if err := rows.Scan(&entry.EntryID, &entry.CompanyID, &entry.SaleID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan journal entry: %v", err)
+		}
+		originals = append(originals, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate journal entries: %v", err)
+	}
+
+	for _, original := range originals {
+		lines, err := getJournalLines(ctx, conn, original.EntryID)
+		if err != nil {
+			return err
+		}
+		for i := range lines {
+			lines[i].Debit, lines[i].Credit = lines[i].Credit, lines[i].Debit
+		}
+		reversalOf := original.EntryID
+		id := sourceID
+		reversal := models.JournalEntry{
+			CompanyID:   original.CompanyID,
+			EntryDate:   time.Now(),
+			Description: description,
+			SourceType:  sourceType,
+			SourceID:    &id,
+			SaleID:      original.SaleID,
+			ReversalOf:  &reversalOf,
+			CreatedBy:   createdBy,
+			Lines:       lines,
+		}
+		if err := postJournalEntry(ctx, conn, &reversal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getJournalLines(ctx context.Context, conn ledgerConn, entryID int) ([]models.JournalLine, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT l.line_id, l.entry_id, l.account_id, a.code, a.name, l.debit, l.credit, l.memo
+		FROM journal_lines l
+		JOIN accounts a ON a.account_id = l.account_id
+		WHERE l.entry_id = $1
+		ORDER BY l.line_id
+	`, entryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch journal lines: %v", err)
+	}
+	defer rows.Close()
+
+	var lines []models.JournalLine
+	for rows.Next() {
+		var line models.JournalLine
+		if err := rows.Scan(&line.LineID, &line.EntryID, &line.AccountID, &line.AccountCode,
+			&line.AccountName, &line.Debit, &line.Credit, &line.Memo); err != nil {
+			return nil, fmt.Errorf("failed to scan journal line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// postSaleRevenue books the rental amount of a sale as receivable revenue, less the VAT it
+// includes, which is recorded on the sale and owed to the tax office
+func postSaleRevenue(ctx context.Context, conn ledgerConn, saleID int, createdBy *int) error {
+	var totalAmount, taxRate float64
+	var customerName string
+	var bookingDate time.Time
+	var companyID *int
+	err := conn.QueryRowContext(ctx, `
+		SELECT s.total_amount, s.customer_name, s.booking_date, u.company_id,
+			`+taxRateSQL("u.company_id", "'"+models.TaxChargeTypeRental+"'")+`
+		FROM sales s
+		LEFT JOIN users u ON u.id = s.user_id
+		WHERE s.sale_id = $1
+	`, saleID).Scan(&totalAmount, &customerName, &bookingDate, &companyID, &taxRate)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sale %d for posting: %v", saleID, err)
+	}
+
+	taxable, tax := taxBreakdown(totalAmount, taxRate)
+	_, err = conn.ExecContext(ctx, `
+		UPDATE sales SET tax_rate = $1, taxable_amount = $2, tax_amount = $3 WHERE sale_id = $4
+	`, taxRate, taxable, tax, saleID)
+	if err != nil {
+		return fmt.Errorf("failed to record tax of sale %d: %v", saleID, err)
+	}
+	return postTaxedEntry(ctx, conn, models.JournalEntry{
+		CompanyID:   companyID,
+		EntryDate:   bookingDate,
+		Description: fmt.Sprintf("Rental sale #%d for %s", saleID, customerName),
+		SourceType:  models.JournalSourceSale,
+		SourceID:    &saleID,
+		SaleID:      &saleID,
+		CreatedBy:   createdBy,
+	}, models.AccountCodeReceivable, models.AccountCodeRentalRevenue, totalAmount, tax)
+}
+
+// repostSaleRevenue replaces the revenue posting of a sale after its amount changed
+func repostSaleRevenue(ctx context.Context, conn ledgerConn, saleID int, createdBy *int) error {
+	err := reverseJournalEntries(ctx, conn, models.JournalSourceSale, saleID,
+		fmt.Sprintf("Reverse rental sale #%d", saleID), createdBy)
+	if err != nil {
+		return err
+	}
+	return postSaleRevenue(ctx, conn, saleID, createdBy)
+}
+
+// postSalesCharge books an additional charge on a sale, less the VAT it includes; discounts and
+// other negative charges are booked negative and reduce the VAT owed
+func postSalesCharge(ctx context.Context, conn ledgerConn, chargeID int, createdBy *int) error {
+	var saleID int
+	var chargeType string
+	var amount, taxRate float64
+	var companyID *int
+	err := conn.QueryRowContext(ctx, `
+		SELECT c.sale_id, c.charge_type, c.amount, u.company_id, `+taxRateSQL("u.company_id", "c.charge_type")+`
+		FROM sales_charges c
+		JOIN sales s ON s.sale_id = c.sale_id
+		LEFT JOIN users u ON u.id = s.user_id
+		WHERE c.charge_id = $1
+	`, chargeID).Scan(&saleID, &chargeType, &amount, &companyID, &taxRate)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sales charge %d for posting: %v", chargeID, err)
+	}
+
+	amount = signedChargeAmount(chargeType, amount)
+	taxable, tax := taxBreakdown(amount, taxRate)
+	_, err = conn.ExecContext(ctx, `
+		UPDATE sales_charges SET tax_rate = $1, taxable_amount = $2, tax_amount = $3 WHERE charge_id = $4
+	`, taxRate, taxable, tax, chargeID)
+	if err != nil {
+		return fmt.Errorf("failed to record tax of sales charge %d: %v", chargeID, err)
+	}
+	return postTaxedEntry(ctx, conn, models.JournalEntry{
+		CompanyID:   companyID,
+		EntryDate:   time.Now(),
+		Description: fmt.Sprintf("%s charge on sale #%d", chargeType, saleID),
+		SourceType:  models.JournalSourceSalesCharge,
+		SourceID:    &chargeID,
+		SaleID:      &saleID,
+		CreatedBy:   createdBy,
+	}, models.AccountCodeReceivable, models.AccountCodeChargeRevenue, amount, tax)
+}
+
+// reverseSalePostings backs out the revenue and charges of a cancelled sale
+func reverseSalePostings(ctx context.Context, conn ledgerConn, saleID int, createdBy *int) error {
+	err := reverseJournalEntries(ctx, conn, models.JournalSourceSale, saleID,
+		fmt.Sprintf("Cancel rental sale #%d", saleID), createdBy)
+	if err != nil {
+		return err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT charge_id FROM sales_charges WHERE sale_id = $1`, saleID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sales charges: %v", err)
+	}
+	var chargeIDs []int
+	for rows.Next() {
+		var chargeID int
+		if err := rows.Scan(&chargeID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan sales charge: %v", err)
+		}
+		chargeIDs = append(chargeIDs, chargeID)
+	}
+	rows.Close()
+
+	for _, chargeID := range chargeIDs {
+		err = reverseJournalEntries(ctx, conn, models.JournalSourceSalesCharge, chargeID,
+			fmt.Sprintf("Cancel charge on sale #%d", saleID), createdBy)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postPaymentReceipt books a completed payment as cash received against the receivable.
+// It does nothing for payments that are not completed or are already posted.
+func postPaymentReceipt(ctx context.Context, conn ledgerConn, paymentID int, createdBy *int) error {
+	var saleID int
+	var amount float64
+	var paymentDate time.Time
+	var status string
+	err := conn.QueryRowContext(ctx, `
+		SELECT sale_id, amount_paid, payment_date, payment_status FROM payments WHERE payment_id = $1
+	`, paymentID).Scan(&saleID, &amount, &paymentDate, &status)
+	if err != nil {
+		return fmt.Errorf("failed to fetch payment %d for posting: %v", paymentID, err)
+	}
+	if status != "Completed" {
+		return nil
+	}
+	posted, err := hasLedgerPosting(ctx, conn, models.JournalSourcePayment, paymentID)
+	if err != nil || posted {
+		return err
+	}
+	return postTwoLineEntry(ctx, conn, models.JournalEntry{
+		EntryDate:   paymentDate,
+		Description: fmt.Sprintf("Payment #%d received for sale #%d", paymentID, saleID),
+		SourceType:  models.JournalSourcePayment,
+		SourceID:    &paymentID,
+		SaleID:      &saleID,
+		CreatedBy:   createdBy,
+	}, models.AccountCodeCash, models.AccountCodeReceivable, amount)
+}
+
+// reversePaymentReceipt backs out the cash posting of a payment that failed or was cancelled
+func reversePaymentReceipt(ctx context.Context, conn ledgerConn, paymentID int, createdBy *int) error {
+	return reverseJournalEntries(ctx, conn, models.JournalSourcePayment, paymentID,
+		fmt.Sprintf("Reverse payment #%d", paymentID), createdBy)
+}
+
+// postPaymentRefund books money paid back to a customer. The refunded payment no longer counts
+// against the sale, so the amount goes back onto the receivable; revenue is left untouched.
+func postPaymentRefund(ctx context.Context, conn ledgerConn, paymentID, saleID int, amount float64, createdBy *int) error {
+	return postTwoLineEntry(ctx, conn, models.JournalEntry{
+		EntryDate:   time.Now(),
+		Description: fmt.Sprintf("Refund of payment #%d on sale #%d", paymentID, saleID),
+		SourceType:  models.JournalSourceRefund,
+		SourceID:    &paymentID,
+		SaleID:      &saleID,
+		CreatedBy:   createdBy,
+	}, models.AccountCodeReceivable, models.AccountCodeCash, amount)
+}
+
+// postExpense books a company expense as paid in cash
+func postExpense(ctx context.Context, conn ledgerConn, expenseID int, createdBy *int) error {
+	var expenseType, approvalStatus string
+	var amount float64
+	var expenseDate time.Time
+	var description sql.NullString
+	var companyID *int
+	err := conn.QueryRowContext(ctx, `
+		SELECT x.expense_type, x.amount, x.expense_date, x.description, x.approval_status, u.company_id
+		FROM company_expenses x
+		LEFT JOIN users u ON u.id = x.recorded_by
+		WHERE x.expense_id = $1
+	`, expenseID).Scan(&expenseType, &amount, &expenseDate, &description, &approvalStatus, &companyID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch expense %d for posting: %v", expenseID, err)
+	}
+	// Expenses waiting for approval, or rejected, stay off the books
+	if approvalStatus != models.ExpenseApprovalApproved {
+		return nil
+	}
+	text := fmt.Sprintf("%s expense #%d", expenseType, expenseID)
+	if description.Valid && description.String != "" {
+		text += ": " + description.String
+	}
+	return postTwoLineEntry(ctx, conn, models.JournalEntry{
+		CompanyID:   companyID,
+		EntryDate:   expenseDate,
+		Description: text,
+		SourceType:  models.JournalSourceExpense,
+		SourceID:    &expenseID,
+		CreatedBy:   createdBy,
+	}, expenseAccountCode(expenseType), models.AccountCodeCash, amount)
+}
+
+// reverseExpense backs out the posting of an expense that was changed or deleted
+func reverseExpense(ctx context.Context, conn ledgerConn, expenseID int, createdBy *int) error {
+	return reverseJournalEntries(ctx, conn, models.JournalSourceExpense, expenseID,
+		fmt.Sprintf("Reverse expense #%d", expenseID), createdBy)
+}
+
+// normalBalance signs a debit/credit total in the account type's normal direction
+func normalBalance(accountType string, debit, credit float64) float64 {
+	if accountType == models.AccountTypeAsset || accountType == models.AccountTypeExpense {
+		return roundCents(debit - credit)
+	}
+	return roundCents(credit - debit)
+}
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// GetAccounts lists the system accounts together with the company's own accounts
+func (r *LedgerRepository) GetAccounts(companyID int) ([]models.Account, error) {
+	rows, err := r.db.Query(`
+		SELECT account_id, company_id, code, name, type, is_system, created_at
+		FROM accounts
+		WHERE company_id IS NULL OR company_id = $1
+		ORDER BY code
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch accounts: %v", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.Account{}
+	for rows.Next() {
+		var a models.Account
+		if err := rows.Scan(&a.AccountID, &a.CompanyID, &a.Code, &a.Name, &a.Type, &a.IsSystem, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account: %v", err)
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// GetAccountByCode finds a system account or one of the company's own accounts
+func (r *LedgerRepository) GetAccountByCode(companyID int, code string) (*models.Account, error) {
+	var a models.Account
+	err := r.db.QueryRow(`
+		SELECT account_id, company_id, code, name, type, is_system, created_at
+		FROM accounts
+		WHERE code = $1 AND (company_id IS NULL OR company_id = $2)
+	`, code, companyID).Scan(&a.AccountID, &a.CompanyID, &a.Code, &a.Name, &a.Type, &a.IsSystem, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account: %v", err)
+	}
+	return &a, nil
+}
+
+// CreateAccount adds an account to the company's chart; its code may not clash with a system
+// account or another account of the company
+func (r *LedgerRepository) CreateAccount(companyID int, account *models.Account) error {
+	account.CompanyID = &companyID
+	err := r.db.QueryRow(`
+		INSERT INTO accounts (company_id, code, name, type)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (SELECT 1 FROM accounts WHERE code = $2 AND company_id IS NULL)
+		ON CONFLICT (company_id, code) WHERE company_id IS NOT NULL DO NOTHING
+		RETURNING account_id, is_system, created_at
+	`, companyID, account.Code, account.Name, account.Type).Scan(&account.AccountID, &account.IsSystem, &account.CreatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("account code %s already exists", account.Code)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create account: %v", err)
+	}
+	return nil
+}
+
+// GetEntries lists the company's journal entries with their lines, newest first
+func (r *LedgerRepository) GetEntries(companyID int, filter models.JournalEntryFilter) ([]models.JournalEntry, error) {
+	query := `
+		SELECT entry_id, company_id, entry_date, description, source_type, source_id, sale_id, reversal_of, created_by, created_at
+		FROM journal_entries
+		WHERE company_id = $1`
+	args := []interface{}{companyID}
+	if filter.StartDate != nil {
+		args = append(args, *filter.StartDate)
+		query += fmt.Sprintf(" AND entry_date >= $%d", len(args))
+	}
+	if filter.EndDate != nil {
+		args = append(args, *filter.EndDate)
+		query += fmt.Sprintf(" AND entry_date <= $%d", len(args))
+	}
+	if filter.SourceType != "" {
+		args = append(args, filter.SourceType)
+		query += fmt.Sprintf(" AND source_type = $%d", len(args))
+	}
+	if filter.SourceID != nil {
+		args = append(args, *filter.SourceID)
+		query += fmt.Sprintf(" AND source_id = $%d", len(args))
+	}
+	if filter.SaleID != nil {
+		args = append(args, *filter.SaleID)
+		query += fmt.Sprintf(" AND sale_id = $%d", len(args))
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	args = append(args, limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY entry_date DESC, entry_id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch journal entries: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []models.JournalEntry{}
+	index := map[int]int{}
+	var ids []int64
+	for rows.Next() {
+		var e models.JournalEntry
+		if err := rows.Scan(&e.EntryID, &e.CompanyID, &e.EntryDate, &e.Description, &e.SourceType, &e.SourceID,
+			&e.SaleID, &e.ReversalOf, &e.CreatedBy, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %v", err)
+		}
+		e.Lines = []models.JournalLine{}
+		index[e.EntryID] = len(entries)
+		ids = append(ids, int64(e.EntryID))
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	lineRows, err := r.db.Query(`
+		SELECT l.line_id, l.entry_id, l.account_id, a.code, a.name, l.debit, l.credit, l.memo
+		FROM journal_lines l
+		JOIN accounts a ON a.account_id = l.account_id
+		WHERE l.entry_id = ANY($1)
+		ORDER BY l.line_id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch journal lines: %v", err)
+	}
+	defer lineRows.Close()
+	for lineRows.Next() {
+		var line models.JournalLine
+		if err := lineRows.Scan(&line.LineID, &line.EntryID, &line.AccountID, &line.AccountCode,
+			&line.AccountName, &line.Debit, &line.Credit, &line.Memo); err != nil {
+			return nil, fmt.Errorf("failed to scan journal line: %v", err)
+		}
+		e := &entries[index[line.EntryID]]
+		e.Lines = append(e.Lines, line)
+	}
+	return entries, lineRows.Err()
+}
+
+func (r *LedgerRepository) GetEntry(companyID, entryID int) (*models.JournalEntry, error) {
+	var e models.JournalEntry
+	err := r.db.QueryRow(`
+		SELECT entry_id, company_id, entry_date, description, source_type, source_id, sale_id, reversal_of, created_by, created_at
+		FROM journal_entries
+		WHERE entry_id = $1 AND company_id = $2
+	`, entryID, companyID).Scan(&e.EntryID, &e.CompanyID, &e.EntryDate, &e.Description, &e.SourceType, &e.SourceID,
+		&e.SaleID, &e.ReversalOf, &e.CreatedBy, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch journal entry: %v", err)
+	}
+	e.Lines, err = getJournalLines(context.Background(), r.db, entryID)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CreateManualEntry posts an adjustment to the company's books; unlike automatic postings it may not
+// be dated in a closed period
+func (r *LedgerRepository) CreateManualEntry(companyID int, entry *models.JournalEntry) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	entry.CompanyID = &companyID
+	closed, err := isClosedPeriodDate(ctx, tx, entry.CompanyID, entry.EntryDate)
+	if err != nil {
+		return err
+	}
+	if closed {
+		return errors.New("entry date falls in a closed accounting period")
+	}
+
+	entry.SourceType = models.JournalSourceManual
+	if err := postJournalEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTrialBalance totals every account of the company's books up to and including asOf
+func (r *LedgerRepository) GetTrialBalance(companyID int, asOf time.Time) (*models.TrialBalance, error) {
+	rows, err := r.db.Query(`
+		SELECT a.account_id, a.code, a.name, a.type,
+			COALESCE(SUM(l.debit), 0), COALESCE(SUM(l.credit), 0)
+		FROM accounts a
+		LEFT JOIN journal_lines l ON l.account_id = a.account_id
+			AND l.entry_id IN (SELECT entry_id FROM journal_entries WHERE entry_date <= $1 AND company_id = $2)
+		WHERE a.company_id IS NULL OR a.company_id = $2
+		GROUP BY a.account_id, a.code, a.name, a.type
+		ORDER BY a.code
+	`, asOf, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute trial balance: %v", err)
+	}
+	defer rows.Close()
+
+	tb := &models.TrialBalance{AsOf: asOf, Accounts: []models.TrialBalanceRow{}}
+	for rows.Next() {
+		var row models.TrialBalanceRow
+		if err := rows.Scan(&row.AccountID, &row.AccountCode, &row.AccountName, &row.AccountType,
+			&row.Debit, &row.Credit); err != nil {
+			return nil, fmt.Errorf("failed to scan trial balance row: %v", err)
+		}
+		if row.Debit == 0 && row.Credit == 0 {
+			continue
+		}
+		row.Balance = normalBalance(row.AccountType, row.Debit, row.Credit)
+		tb.TotalDebit += row.Debit
+		tb.TotalCredit += row.Credit
+		tb.Accounts = append(tb.Accounts, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tb.TotalDebit = roundCents(tb.TotalDebit)
+	tb.TotalCredit = roundCents(tb.TotalCredit)
+	tb.Balanced = tb.TotalDebit == tb.TotalCredit
+	return tb, nil
+}
+
+// GetAccountLedger lists the company's postings to an account between two dates with a running balance
+func (r *LedgerRepository) GetAccountLedger(companyID int, account models.Account, startDate, endDate time.Time) (*models.AccountLedger, error) {
+	var openingDebit, openingCredit float64
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(l.debit), 0), COALESCE(SUM(l.credit), 0)
+		FROM journal_lines l
+		JOIN journal_entries e ON e.entry_id = l.entry_id
+		WHERE l.account_id = $1 AND e.entry_date < $2 AND e.company_id = $3
+	`, account.AccountID, startDate, companyID).Scan(&openingDebit, &openingCredit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute opening balance: %v", err)
+	}
+
+	ledger := &models.AccountLedger{
+		Account:        account,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		OpeningBalance: normalBalance(account.Type, openingDebit, openingCredit),
+		Lines:          []models.AccountLedgerLine{},
+	}
+
+	rows, err := r.db.Query(`
+		SELECT e.entry_id, e.entry_date, e.description, e.source_type, e.source_id, l.memo, l.debit, l.credit
+		FROM journal_lines l
+		JOIN journal_entries e ON e.entry_id = l.entry_id
+		WHERE l.account_id = $1 AND e.entry_date BETWEEN $2 AND $3 AND e.company_id = $4
+		ORDER BY e.entry_date, e.entry_id, l.line_id
+	`, account.AccountID, startDate, endDate, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch account ledger: %v", err)
+	}
+	defer rows.Close()
+
+	balance := ledger.OpeningBalance
+	for rows.Next() {
+		var line models.AccountLedgerLine
+		if err := rows.Scan(&line.EntryID, &line.EntryDate, &line.Description, &line.SourceType,
+			&line.SourceID, &line.Memo, &line.Debit, &line.Credit); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger line: %v", err)
+		}
+		balance = roundCents(balance + normalBalance(account.Type, line.Debit, line.Credit))
+		line.Balance = balance
+		ledger.Lines = append(ledger.Lines, line)
+	}
+	ledger.ClosingBalance = balance
+	return ledger, rows.Err()
+}
+
+func (r *LedgerRepository) GetPeriods(companyID int) ([]models.AccountingPeriod, error) {
+	rows, err := r.db.Query(`
+		SELECT period_id, start_date, end_date, status, net_income, closing_entry_id, closed_by, closed_at
+		FROM accounting_periods
+		WHERE company_id = $1
+		ORDER BY start_date DESC
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch accounting periods: %v", err)
+	}
+	defer rows.Close()
+
+	periods := []models.AccountingPeriod{}
+	for rows.Next() {
+		var p models.AccountingPeriod
+		if err := rows.Scan(&p.PeriodID, &p.StartDate, &p.EndDate, &p.Status, &p.NetIncome,
+			&p.ClosingEntryID, &p.ClosedBy, &p.ClosedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan accounting period: %v", err)
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
+
+// ClosePeriod moves the period's revenue and expense balances of the company into retained earnings
+// and locks the dates against further postings to its books
+func (r *LedgerRepository) ClosePeriod(companyID int, startDate, endDate time.Time, userID int) (*models.AccountingPeriod, error) {
+	ctx := context.Background()
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Serialise closes so two overlapping periods cannot be closed at once
+	if _, err := tx.Exec(`LOCK TABLE accounting_periods IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return nil, fmt.Errorf("failed to lock accounting periods: %v", err)
+	}
+
+	// A period can only be closed once its last day is over
+	var ended bool
+	err = tx.QueryRow(`SELECT $1::date < `+companyTodaySQL("$2"), endDate, companyID).Scan(&ended)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the period end: %v", err)
+	}
+	if !ended {
+		return nil, errors.New("only periods that have ended can be closed")
+	}
+
+	var overlaps bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM accounting_periods
+			WHERE company_id = $3 AND status = 'closed' AND start_date <= $2 AND end_date >= $1
+		)
+	`, startDate, endDate, companyID).Scan(&overlaps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check accounting periods: %v", err)
+	}
+	if overlaps {
+		return nil, errors.New("period overlaps an already closed period")
+	}
+
+	rows, err := tx.Query(`
+		SELECT a.code, a.type, COALESCE(SUM(l.debit), 0), COALESCE(SUM(l.credit), 0)
+		FROM journal_lines l
+		JOIN journal_entries e ON e.entry_id = l.entry_id
+		JOIN accounts a ON a.account_id = l.account_id
+		WHERE a.type IN ('revenue', 'expense')
+		AND e.entry_date BETWEEN $1 AND $2
+		AND e.company_id = $3
+		AND e.source_type <> 'period_close'
+		GROUP BY a.code, a.type
+		ORDER BY a.code
+	`, startDate, endDate, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute period balances: %v", err)
+	}
+	var lines []models.JournalLine
+	var netIncome float64
+	for rows.Next() {
+		var code, accountType string
+		var debit, credit float64
+		if err := rows.Scan(&code, &accountType, &debit, &credit); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan period balance: %v", err)
+		}
+		net := roundCents(credit - debit)
+		netIncome += net
+		switch {
+		case net > 0:
+			lines = append(lines, models.JournalLine{AccountCode: code, Debit: net})
+		case net < 0:
+			lines = append(lines, models.JournalLine{AccountCode: code, Credit: -net})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	period := &models.AccountingPeriod{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Status:    models.AccountingPeriodClosed,
+		NetIncome: roundCents(netIncome),
+		ClosedBy:  &userID,
+	}
+
+	if len(lines) > 0 {
+		switch {
+		case period.NetIncome > 0:
+			lines = append(lines, models.JournalLine{AccountCode: models.AccountCodeRetainedEarnings, Credit: period.NetIncome})
+		case period.NetIncome < 0:
+			lines = append(lines, models.JournalLine{AccountCode: models.AccountCodeRetainedEarnings, Debit: -period.NetIncome})
+		}
+		entry := models.JournalEntry{
+			CompanyID:   &companyID,
+			EntryDate:   endDate,
+			Description: fmt.Sprintf("Close period %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02")),
+			SourceType:  models.JournalSourcePeriodClose,
+			CreatedBy:   &userID,
+			Lines:       lines,
+		}
+		if len(entry.Lines) >= 2 {
+			if err := postJournalEntry(ctx, tx, &entry); err != nil {
+				return nil, err
+			}
+			period.ClosingEntryID = &entry.EntryID
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO accounting_periods (company_id, start_date, end_date, status, net_income, closing_entry_id, closed_by, closed_at)
+		VALUES ($1, $2, $3, 'closed', $4, $5, $6, NOW())
+		RETURNING period_id, closed_at
+	`, companyID, startDate, endDate, period.NetIncome, period.ClosingEntryID, userID).Scan(&period.PeriodID, &period.ClosedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record accounting period: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return period, nil
+}
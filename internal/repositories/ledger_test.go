@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"testing"
+
+	"renting/internal/models"
+)
+
+func TestNormalBalance(t *testing.T) {
+	tests := []struct {
+		name        string
+		accountType string
+		debit       float64
+		credit      float64
+		want        float64
+	}{
+		{"asset debit balance", models.AccountTypeAsset, 150, 50, 100},
+		{"asset overdrawn", models.AccountTypeAsset, 20, 70, -50},
+		{"expense debit balance", models.AccountTypeExpense, 80.25, 0, 80.25},
+		{"revenue credit balance", models.AccountTypeRevenue, 10, 110, 100},
+		{"liability credit balance", models.AccountTypeLiability, 0, 42.5, 42.5},
+		{"equity debit balance", models.AccountTypeEquity, 30, 0, -30},
+		{"rounds to cents", models.AccountTypeAsset, 0.1 + 0.2, 0, 0.3},
+		{"no activity", models.AccountTypeRevenue, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalBalance(tt.accountType, tt.debit, tt.credit); got != tt.want {
+				t.Errorf("normalBalance(%q, %v, %v) = %v, want %v", tt.accountType, tt.debit, tt.credit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedChargeAmount(t *testing.T) {
+	tests := []struct {
+		name       string
+		chargeType string
+		amount     float64
+		want       float64
+	}{
+		{"charge adds to the sale", "fuel", 25, 25},
+		{"negative charge reduces the sale", "adjustment", -15, -15},
+		{"discount stored positive", models.ChargeTypeDiscount, 40, -40},
+		{"discount stored negative", models.ChargeTypeDiscount, -40, -40},
+		{"zero discount", models.ChargeTypeDiscount, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signedChargeAmount(tt.chargeType, tt.amount); got != tt.want {
+				t.Errorf("signedChargeAmount(%q, %v) = %v, want %v", tt.chargeType, tt.amount, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+	"time"
+)
+
+var (
+	ErrMaintenanceBlockNotFound = errors.New("maintenance block not found")
+	ErrVehicleNotFound          = errors.New("vehicle not found")
+)
+
+// companyVehicleSQL holds for the vehicle aliased v when it is kept at a branch of the company in
+// companyExpr, or has no branch and has been rented out by the company
+func companyVehicleSQL(companyExpr string) string {
+	return `(EXISTS (SELECT 1 FROM branches br WHERE br.branch_id = v.branch_id AND br.company_id = ` + companyExpr + `)
+		OR (v.branch_id IS NULL AND EXISTS (
+			SELECT 1 FROM sales cs JOIN users cu ON cu.id = cs.user_id
+			WHERE cs.vehicle_id = v.vehicle_id AND cu.company_id = ` + companyExpr + `)))`
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type MaintenanceRepository struct {
+	db *sql.DB
+}
+
+func NewMaintenanceRepository(db *sql.DB) *MaintenanceRepository {
+	return &MaintenanceRepository{db: db}
+}
+
+// findMaintenanceOverlap returns the first scheduled or active block of the vehicle that overlaps
+// the given date range, or nil when the range is clear. excludeBlockID skips a block being edited.
+func findMaintenanceOverlap(q queryRower, vehicleID int, startDate, endDate time.Time, excludeBlockID int) (*models.MaintenanceBlock, error) {
+	var block models.MaintenanceBlock
+	err := q.QueryRow(`
+		SELECT block_id, vehicle_id, start_date, end_date, reason, status
+		FROM maintenance_blocks
+		WHERE vehicle_id = $1
+		AND status IN ('scheduled', 'active')
+		AND start_date <= $3::date
+		AND end_date >= $2::date
+		AND block_id != $4
+		ORDER BY start_date
+		LIMIT 1
+	`, vehicleID, startDate, endDate, excludeBlockID).Scan(
+		&block.BlockID, &block.VehicleID, &block.StartDate, &block.EndDate, &block.Reason, &block.Status)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check maintenance blocks: %v", err)
+	}
+	return &block, nil
+}
+
+// maintenanceConflictError describes a booking that collides with planned downtime
+func maintenanceConflictError(block *models.MaintenanceBlock) error {
+	return fmt.Errorf("vehicle is blocked for maintenance from %s to %s: %s",
+		block.StartDate.Format("2006-01-02"), block.EndDate.Format("2006-01-02"), block.Reason)
+}
+
+// hasActiveMaintenanceBlock reports whether the vehicle's current under_maintenance status comes
+// from a block, as opposed to having been set by hand
+func hasActiveMaintenanceBlock(q queryRower, vehicleID int) (bool, error) {
+	var exists bool
+	err := q.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM maintenance_blocks
+			WHERE vehicle_id = $1 AND status = 'active'
+		)
+	`, vehicleID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check active maintenance blocks: %v", err)
+	}
+	return exists, nil
+}
+
+// GetBlocks lists the maintenance blocks of the company's vehicles
+func (r *MaintenanceRepository) GetBlocks(companyID int, filter models.MaintenanceBlockFilter) ([]models.MaintenanceBlock, error) {
+	query := `
+		SELECT mb.block_id, mb.vehicle_id, v.vehicle_name, mb.start_date, mb.end_date, mb.reason,
+			mb.status, mb.created_by, mb.created_at, mb.updated_at
+		FROM maintenance_blocks mb
+		JOIN vehicles v ON v.vehicle_id = mb.vehicle_id
+		WHERE ` + companyVehicleSQL("$1") + `
+	`
+	args := []interface{}{companyID}
+	argIndex := 2
+	if filter.VehicleID != nil {
+		query += fmt.Sprintf(" AND mb.vehicle_id = $%d", argIndex)
+		args = append(args, *filter.VehicleID)
+		argIndex++
+	}
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND mb.status = $%d", argIndex)
+		args = append(args, filter.Status)
+		argIndex++
+	}
+	if filter.Upcoming {
+		query += " AND mb.end_date >= CURRENT_DATE AND mb.status IN ('scheduled', 'active')"
+	}
+	query += " ORDER BY mb.start_date"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch maintenance blocks: %v", err)
+	}
+	defer rows.Close()
+
+	blocks := []models.MaintenanceBlock{}
+	for rows.Next() {
+		var b models.MaintenanceBlock
+		if err := rows.Scan(&b.BlockID, &b.VehicleID, &b.VehicleName, &b.StartDate, &b.EndDate, &b.Reason,
+			&b.Status, &b.CreatedBy, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance block: %v", err)
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, rows.Err()
+}
+
+// checkBookingConflicts rejects downtime that overlaps active or pending rentals of the vehicle
+func checkBookingConflicts(tx *sql.Tx, vehicleID int, startDate, endDate time.Time) error {
+	var saleID int
+	err := tx.QueryRow(`
+		SELECT sale_id
+		FROM sales
+		WHERE vehicle_id = $1
+		AND status IN ('active', 'pending')
+		AND date_of_delivery <= $3::date
+		AND return_date >= $2::date
+		LIMIT 1
+	`, vehicleID, startDate, endDate).Scan(&saleID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check bookings: %v", err)
+	}
+	return fmt.Errorf("maintenance overlaps booking %d", saleID)
+}
+
+// CreateBlock schedules downtime on one of the company's vehicles
+func (r *MaintenanceRepository) CreateBlock(companyID int, block *models.MaintenanceBlock) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Serialise block changes per vehicle
+	var vehicleID int
+	err = tx.QueryRow(`
+		SELECT v.vehicle_id FROM vehicles v
+		WHERE v.vehicle_id = $1 AND `+companyVehicleSQL("$2")+`
+		FOR UPDATE
+	`, block.VehicleID, companyID).Scan(&vehicleID)
+	if err == sql.ErrNoRows {
+		return ErrVehicleNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock vehicle: %v", err)
+	}
+
+	if err = checkBookingConflicts(tx, block.VehicleID, block.StartDate, block.EndDate); err != nil {
+		return err
+	}
+	overlap, err := findMaintenanceOverlap(tx, block.VehicleID, block.StartDate, block.EndDate, 0)
+	if err != nil {
+		return err
+	}
+	if overlap != nil {
+		return fmt.Errorf("overlaps maintenance block %d", overlap.BlockID)
+	}
+
+	block.Status = models.MaintenanceBlockScheduled
+	err = tx.QueryRow(`
+		INSERT INTO maintenance_blocks (vehicle_id, start_date, end_date, reason, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING block_id, created_at, updated_at
+	`, block.VehicleID, block.StartDate, block.EndDate, block.Reason, block.Status, block.CreatedBy,
+	).Scan(&block.BlockID, &block.CreatedAt, &block.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create maintenance block: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// UpdateBlock changes the dates or reason of a block on one of the company's vehicles that has
+// not finished
+func (r *MaintenanceRepository) UpdateBlock(companyID int, block *models.MaintenanceBlock) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var vehicleID int
+	var status string
+	err = tx.QueryRow(`
+		SELECT mb.vehicle_id, mb.status
+		FROM maintenance_blocks mb
+		JOIN vehicles v ON v.vehicle_id = mb.vehicle_id
+		WHERE mb.block_id = $1 AND `+companyVehicleSQL("$2")+`
+		FOR UPDATE OF mb
+	`, block.BlockID, companyID).Scan(&vehicleID, &status)
+	if err == sql.ErrNoRows {
+		return ErrMaintenanceBlockNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch maintenance block: %v", err)
+	}
+	if status != models.MaintenanceBlockScheduled && status != models.MaintenanceBlockActive {
+		return fmt.Errorf("cannot change a %s maintenance block", status)
+	}
+	block.VehicleID = vehicleID
+	block.Status = status
+
+	if err = checkBookingConflicts(tx, vehicleID, block.StartDate, block.EndDate); err != nil {
+		return err
+	}
+	overlap, err := findMaintenanceOverlap(tx, vehicleID, block.StartDate, block.EndDate, block.BlockID)
+	if err != nil {
+		return err
+	}
+	if overlap != nil {
+		return fmt.Errorf("overlaps maintenance block %d", overlap.BlockID)
+	}
+
+	err = tx.QueryRow(`
+		UPDATE maintenance_blocks
+		SET start_date = $1, end_date = $2, reason = $3, updated_at = NOW()
+		WHERE block_id = $4
+		RETURNING created_by, created_at, updated_at
+	`, block.StartDate, block.EndDate, block.Reason, block.BlockID).Scan(&block.CreatedBy, &block.CreatedAt, &block.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update maintenance block: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// CancelBlock cancels a block on one of the company's vehicles and releases the vehicle if the
+// block was holding it
+func (r *MaintenanceRepository) CancelBlock(companyID, blockID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var vehicleID int
+	var status string
+	err = tx.QueryRow(`
+		SELECT mb.vehicle_id, mb.status
+		FROM maintenance_blocks mb
+		JOIN vehicles v ON v.vehicle_id = mb.vehicle_id
+		WHERE mb.block_id = $1 AND `+companyVehicleSQL("$2")+`
+		FOR UPDATE OF mb
+	`, blockID, companyID).Scan(&vehicleID, &status)
+	if err == sql.ErrNoRows {
+		return ErrMaintenanceBlockNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch maintenance block: %v", err)
+	}
+	if status != models.MaintenanceBlockScheduled && status != models.MaintenanceBlockActive {
+		return fmt.Errorf("cannot cancel a %s maintenance block", status)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE maintenance_blocks
+		SET status = 'cancelled', updated_at = NOW()
+		WHERE block_id = $1
+	`, blockID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel maintenance block: %v", err)
+	}
+
+	if status == models.MaintenanceBlockActive {
+		if err = releaseVehicleFromMaintenance(tx, vehicleID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// releaseVehicleFromMaintenance makes the vehicle available again unless another block still holds it
+func releaseVehicleFromMaintenance(tx *sql.Tx, vehicleID int) error {
+	_, err := tx.Exec(`
+		UPDATE vehicles
+		SET status = 'available', updated_at = NOW()
+		WHERE vehicle_id = $1
+		AND status = 'under_maintenance'
+		AND NOT EXISTS (
+			SELECT 1 FROM maintenance_blocks
+			WHERE vehicle_id = $1 AND status = 'active'
+		)
+	`, vehicleID)
+	if err != nil {
+		return fmt.Errorf("failed to release vehicle %d from maintenance: %v", vehicleID, err)
+	}
+	return nil
+}
+
+// SyncVehicleStatuses starts blocks whose start date has arrived and finishes blocks that have ended,
+// flipping vehicle status to match. A block cannot start while its vehicle is still rented out;
+// it is picked up on a later run once the vehicle is returned.
+func (r *MaintenanceRepository) SyncVehicleStatuses() (started int, finished int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Finish blocks that have ended
+	rows, err := tx.Query(`
+		UPDATE maintenance_blocks
+		SET status = 'completed', updated_at = NOW()
+		WHERE status IN ('scheduled', 'active') AND end_date < CURRENT_DATE
+		RETURNING vehicle_id
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to finish maintenance blocks: %v", err)
+	}
+	var vehicleIDs []int
+	for rows.Next() {
+		var vehicleID int
+		if err := rows.Scan(&vehicleID); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan maintenance block: %v", err)
+		}
+		vehicleIDs = append(vehicleIDs, vehicleID)
+	}
+	rows.Close()
+	for _, vehicleID := range vehicleIDs {
+		if err = releaseVehicleFromMaintenance(tx, vehicleID); err != nil {
+			return 0, 0, err
+		}
+	}
+	finished = len(vehicleIDs)
+
+	// Start blocks whose window has begun on vehicles that are not out on rent
+	result, err := tx.Exec(`
+		WITH starting AS (
+			UPDATE maintenance_blocks mb
+			SET status = 'active', updated_at = NOW()
+			FROM vehicles v
+			WHERE v.vehicle_id = mb.vehicle_id
+			AND mb.status = 'scheduled'
+			AND mb.start_date <= CURRENT_DATE
+			AND v.status != 'rented'
+			RETURNING mb.vehicle_id
+		)
+		UPDATE vehicles
+		SET status = 'under_maintenance', updated_at = NOW()
+		WHERE vehicle_id IN (SELECT vehicle_id FROM starting)
+	`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to start maintenance blocks: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	started = int(rowsAffected)
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return started, finished, nil
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -25,6 +26,7 @@ type SaleFilter struct {
 	SaleStatus    *string
 	VerifiedBy    *string
 	SaleType      *string
+	SubmittedBy   *int // staff member who took the payment
 }
 
 // GetPaymentsWithSales (unchanged)
@@ -43,6 +45,9 @@ func (r *PaymentRepository) GetPaymentsWithSales(filter SaleFilter, limit int, o
             p.updated_at,
             p.user_id AS payment_user_id,
             pu.username AS payment_username,
+            p.verified_by,
+            p.verified_at,
+            p.rejection_reason,
             s.sale_id, 
             s.vehicle_id, 
             s.user_id AS sale_user_id,
@@ -129,6 +134,12 @@ func (r *PaymentRepository) GetPaymentsWithSales(filter SaleFilter, limit int, o
 		argCounter++
 	}
 
+	if filter.SubmittedBy != nil {
+		query += fmt.Sprintf(" AND p.user_id = $%d", argCounter)
+		args = append(args, *filter.SubmittedBy)
+		argCounter++
+	}
+
 	query += " ORDER BY p.payment_date DESC"
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argCounter, argCounter+1)
 	args = append(args, limit, offset)
@@ -155,6 +166,9 @@ func (r *PaymentRepository) GetPaymentsWithSales(filter SaleFilter, limit int, o
 			&payment.UpdatedAt,
 			&payment.PaymentUserID,
 			&payment.PaymentUsername,
+			&payment.VerifiedBy,
+			&payment.VerifiedAt,
+			&payment.RejectionReason,
 			&payment.Sale.SaleID,
 			&payment.Sale.VehicleID,
 			&payment.Sale.UserID,
@@ -188,6 +202,24 @@ func (r *PaymentRepository) GetPaymentsWithSales(filter SaleFilter, limit int, o
 		}
 		payments = append(payments, payment)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	paymentIDs := make([]int, len(payments))
+	for i, payment := range payments {
+		paymentIDs[i] = payment.PaymentID
+	}
+	attachments, err := getPaymentAttachments(context.Background(), r.db, paymentIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range payments {
+		payments[i].Attachments = attachments[payments[i].PaymentID]
+		if payments[i].Attachments == nil {
+			payments[i].Attachments = []models.PaymentAttachment{}
+		}
+	}
 
 	return payments, nil
 }
@@ -203,12 +235,13 @@ func (r *PaymentRepository) UpdatePayment(paymentID int, userID int, paymentType
 
 	// Check current payment status
 	var currentStatus string
+	var saleID int
 	statusQuery := `
-		SELECT payment_status
+		SELECT payment_status, sale_id
 		FROM payments
 		WHERE payment_id = $1
 	`
-	err = tx.QueryRow(statusQuery, paymentID).Scan(&currentStatus)
+	err = tx.QueryRow(statusQuery, paymentID).Scan(&currentStatus, &saleID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.New("payment not found")
@@ -221,6 +254,11 @@ func (r *PaymentRepository) UpdatePayment(paymentID int, userID int, paymentType
 		return errors.New("cannot update a completed payment")
 	}
 
+	paymentType, err = resolvePaymentMethod(context.Background(), tx, saleID, paymentType)
+	if err != nil {
+		return err
+	}
+
 	// Determine new status based on user role
 	isAdmin, err := r.isAdmin(userID)
 	if err != nil {
@@ -231,6 +269,18 @@ func (r *PaymentRepository) UpdatePayment(paymentID int, userID int, paymentType
 		newStatus = "Completed"
 	}
 
+	// A payment going back for verification needs evidence if its method asks for it
+	if newStatus == "Pending" {
+		var attachments int
+		err = tx.QueryRow(`SELECT COUNT(*) FROM payment_attachments WHERE payment_id = $1`, paymentID).Scan(&attachments)
+		if err != nil {
+			return err
+		}
+		if err := requirePaymentAttachment(context.Background(), tx, saleID, paymentType, attachments); err != nil {
+			return err
+		}
+	}
+
 	// Update payment
 	query := `
 		UPDATE payments
@@ -262,11 +312,39 @@ func (r *PaymentRepository) UpdatePayment(paymentID int, userID int, paymentType
 		return errors.New("payment not found")
 	}
 
+	// Admin edits complete the payment, so it is booked and receipted right away
+	if err := postPaymentReceipt(context.Background(), tx, paymentID, &userID); err != nil {
+		return err
+	}
+	if err := issuePaymentReceipt(context.Background(), tx, paymentID, &userID); err != nil {
+		return err
+	}
+	if _, err := recomputeSaleBalance(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+	if err := recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-// InsertPayment adds a new payment
-func (r *PaymentRepository) InsertPayment(saleID int, paymentType string, amountPaid float64, remark string) (int, error) {
+// InsertPayment adds a new payment with the evidence already uploaded for it
+func (r *PaymentRepository) InsertPayment(saleID int, userID int, paymentType string, amountPaid float64, remark string, attachments []models.PaymentAttachment) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	paymentType, err = resolvePaymentMethod(context.Background(), tx, saleID, paymentType)
+	if err != nil {
+		return 0, err
+	}
+	if err := requirePaymentAttachment(context.Background(), tx, saleID, paymentType, len(attachments)); err != nil {
+		return 0, err
+	}
+
 	paymentStatus := "Pending"
 	sale_type := "manual"
 	query := `
@@ -279,13 +357,14 @@ func (r *PaymentRepository) InsertPayment(saleID int, paymentType string, amount
             created_at,
             updated_at,
 			sale_type,
-            remark
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8,$9)
+            remark,
+            user_id
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8,$9, $10)
         RETURNING payment_id
     `
 
 	var paymentID int
-	err := r.db.QueryRow(query,
+	err = tx.QueryRow(query,
 		saleID,
 		paymentType,
 		amountPaid,
@@ -295,12 +374,25 @@ func (r *PaymentRepository) InsertPayment(saleID int, paymentType string, amount
 		time.Now(),
 		sale_type,
 		remark,
+		userID,
 	).Scan(&paymentID)
 
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert payment: %w", err)
 	}
 
+	if err := insertPaymentAttachments(context.Background(), tx, paymentID, attachments); err != nil {
+		return 0, err
+	}
+
+	if _, err := recomputeSaleBalance(context.Background(), tx, saleID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
 	return paymentID, nil
 }
 
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var ErrAttachmentRequired = errors.New("payment needs at least one attachment")
+
+// requirePaymentAttachment fails when the company of the sale wants evidence for payments by
+// method code and none has been attached
+func requirePaymentAttachment(ctx context.Context, conn ledgerConn, saleID int, code string, attachments int) error {
+	if attachments > 0 {
+		return nil
+	}
+	var required bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT m.requires_attachment
+		FROM sales s
+		JOIN users u ON u.id = s.user_id
+		JOIN LATERAL (`+strings.Replace(effectiveMethodsQuery, "$1", "u.company_id", 1)+`) m ON m.code = $2
+		WHERE s.sale_id = $1
+	`, saleID, code).Scan(&required)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check payment method: %v", err)
+	}
+	if required {
+		return fmt.Errorf("%w: %s payments must include a screenshot or voucher photo", ErrAttachmentRequired,
+			strings.ReplaceAll(code, "_", " "))
+	}
+	return nil
+}
+
+func insertPaymentAttachments(ctx context.Context, conn ledgerConn, paymentID int, attachments []models.PaymentAttachment) error {
+	for i := range attachments {
+		a := &attachments[i]
+		a.PaymentID = paymentID
+		err := conn.QueryRowContext(ctx, `
+			INSERT INTO payment_attachments (payment_id, file_url, file_name, content_type, size_bytes, uploaded_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING attachment_id, uploaded_at
+		`, paymentID, a.FileURL, a.FileName, a.ContentType, a.SizeBytes, a.UploadedBy).Scan(&a.AttachmentID, &a.UploadedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert payment attachment: %v", err)
+		}
+	}
+	return nil
+}
+
+// getPaymentAttachments loads the attachments of the given payments, keyed by payment
+func getPaymentAttachments(ctx context.Context, conn ledgerConn, paymentIDs []int) (map[int][]models.PaymentAttachment, error) {
+	attachments := map[int][]models.PaymentAttachment{}
+	if len(paymentIDs) == 0 {
+		return attachments, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT attachment_id, payment_id, file_url, file_name, COALESCE(content_type, ''), COALESCE(size_bytes, 0),
+			uploaded_by, uploaded_at
+		FROM payment_attachments
+		WHERE payment_id = ANY($1)
+		ORDER BY uploaded_at, attachment_id
+	`, pq.Array(paymentIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment attachments: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.PaymentAttachment
+		if err := rows.Scan(&a.AttachmentID, &a.PaymentID, &a.FileURL, &a.FileName, &a.ContentType, &a.SizeBytes,
+			&a.UploadedBy, &a.UploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payment attachment: %v", err)
+		}
+		attachments[a.PaymentID] = append(attachments[a.PaymentID], a)
+	}
+	return attachments, rows.Err()
+}
+
+// GetPendingPaymentSale returns the sale of one of the company's payments, which must still be
+// waiting for verification to take new attachments
+func (r *PaymentRepository) GetPendingPaymentSale(companyID, paymentID int) (int, error) {
+	var saleID int
+	var status string
+	err := r.db.QueryRow(`
+		SELECT p.sale_id, p.payment_status
+		FROM payments p
+		JOIN sales s ON s.sale_id = p.sale_id
+		JOIN users u ON u.id = s.user_id
+		WHERE p.payment_id = $1 AND u.company_id = $2
+	`, paymentID, companyID).Scan(&saleID, &status)
+	if err == sql.ErrNoRows {
+		return 0, ErrPaymentNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch payment: %v", err)
+	}
+	if status != "Pending" {
+		return 0, fmt.Errorf("%w: it is %s", ErrPaymentNotPending, status)
+	}
+	return saleID, nil
+}
+
+// AddAttachment attaches evidence to a payment that is still pending
+func (r *PaymentRepository) AddAttachment(attachment *models.PaymentAttachment) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT payment_status FROM payments WHERE payment_id = $1 FOR UPDATE`,
+		attachment.PaymentID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrPaymentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch payment: %v", err)
+	}
+	if status != "Pending" {
+		return fmt.Errorf("%w: it is %s", ErrPaymentNotPending, status)
+	}
+
+	attachments := []models.PaymentAttachment{*attachment}
+	if err := insertPaymentAttachments(ctx, tx, attachment.PaymentID, attachments); err != nil {
+		return err
+	}
+	*attachment = attachments[0]
+	return tx.Commit()
+}
@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+)
+
+var (
+	ErrGatewayPaymentNotFound = errors.New("gateway payment not found")
+	ErrGatewayAmountMismatch  = errors.New("confirmed amount does not match the payment")
+)
+
+type PaymentGatewayRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentGatewayRepository(db *sql.DB) *PaymentGatewayRepository {
+	return &PaymentGatewayRepository{db: db}
+}
+
+const gatewayPaymentColumns = `
+	g.gateway_payment_id, g.reference, g.provider, g.payment_id, g.sale_id, g.amount, g.status,
+	g.provider_reference, g.return_url, g.initiated_by, g.created_at, g.updated_at, g.completed_at`
+
+func scanGatewayPayment(row interface{ Scan(...interface{}) error }) (*models.GatewayPayment, error) {
+	var gp models.GatewayPayment
+	err := row.Scan(&gp.GatewayPaymentID, &gp.Reference, &gp.Provider, &gp.PaymentID, &gp.SaleID, &gp.Amount,
+		&gp.Status, &gp.ProviderReference, &gp.ReturnURL, &gp.InitiatedBy, &gp.CreatedAt, &gp.UpdatedAt,
+		&gp.CompletedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrGatewayPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan gateway payment: %v", err)
+	}
+	return &gp, nil
+}
+
+// CreateGatewayPayment records a pending payment of paymentType against a sale of the company together
+// with the gateway row that will confirm it. PaymentID, GatewayPaymentID, Status and the timestamps of
+// gp are filled in.
+func (r *PaymentGatewayRepository) CreateGatewayPayment(companyID int, gp *models.GatewayPayment, paymentType, saleType, remark string) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var saleCompanyID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT u.company_id FROM sales s JOIN users u ON u.id = s.user_id WHERE s.sale_id = $1
+	`, gp.SaleID).Scan(&saleCompanyID)
+	if err == sql.ErrNoRows || (err == nil && saleCompanyID != companyID) {
+		return fmt.Errorf("%w: %d", ErrSaleNotFound, gp.SaleID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch sale %d: %v", gp.SaleID, err)
+	}
+
+	paymentType, err = resolvePaymentMethod(ctx, tx, gp.SaleID, paymentType)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO payments (sale_id, payment_type, amount_paid, payment_date, payment_status,
+			created_at, updated_at, sale_type, remark)
+		VALUES ($1, $2, $3, NOW(), 'Pending', NOW(), NOW(), $4, $5)
+		RETURNING payment_id
+	`, gp.SaleID, paymentType, gp.Amount, saleType, remark).Scan(&gp.PaymentID)
+	if err != nil {
+		return fmt.Errorf("failed to insert payment: %v", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO gateway_payments (reference, provider, payment_id, sale_id, amount, return_url, initiated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING gateway_payment_id, status, created_at, updated_at
+	`, gp.Reference, gp.Provider, gp.PaymentID, gp.SaleID, gp.Amount, gp.ReturnURL, gp.InitiatedBy,
+	).Scan(&gp.GatewayPaymentID, &gp.Status, &gp.CreatedAt, &gp.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert gateway payment: %v", err)
+	}
+
+	if _, err := recomputeSaleBalance(ctx, tx, gp.SaleID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetGatewayPayment looks a gateway payment up by its reference within a company
+func (r *PaymentGatewayRepository) GetGatewayPayment(companyID int, reference string) (*models.GatewayPayment, error) {
+	return scanGatewayPayment(r.db.QueryRow(`
+		SELECT `+gatewayPaymentColumns+`
+		FROM gateway_payments g
+		JOIN sales s ON s.sale_id = g.sale_id
+		JOIN users u ON u.id = s.user_id
+		WHERE g.reference = $1 AND u.company_id = $2
+	`, reference, companyID))
+}
+
+// FindGatewayPayment looks a gateway payment up by provider and reference, as a callback does
+func (r *PaymentGatewayRepository) FindGatewayPayment(provider, reference string) (*models.GatewayPayment, error) {
+	return scanGatewayPayment(r.db.QueryRow(`
+		SELECT `+gatewayPaymentColumns+`
+		FROM gateway_payments g
+		WHERE g.provider = $1 AND g.reference = $2
+	`, provider, reference))
+}
+
+// ConfirmGatewayPayment settles a gateway payment with the provider's verdict. A completed payment
+// is verified through the same path as an admin verification; a failed one is marked Failed.
+// Callbacks for a payment that is already settled leave it untouched, so providers may retry them.
+// If the payment row was already settled by hand, only the gateway row is updated.
+func (r *PaymentGatewayRepository) ConfirmGatewayPayment(provider, reference string, completed bool, amount float64, providerReference string, payload []byte) (*models.GatewayPayment, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	gp, err := scanGatewayPayment(tx.QueryRowContext(ctx, `
+		SELECT `+gatewayPaymentColumns+`
+		FROM gateway_payments g
+		WHERE g.provider = $1 AND g.reference = $2
+		FOR UPDATE
+	`, provider, reference))
+	if err != nil {
+		return nil, err
+	}
+	if gp.Status != models.GatewayStatusInitiated {
+		return gp, nil
+	}
+	if completed && roundCents(amount) < roundCents(gp.Amount) {
+		return nil, fmt.Errorf("%w: paid %.2f, expected %.2f", ErrGatewayAmountMismatch, amount, gp.Amount)
+	}
+
+	status, paymentStatus, reason := models.GatewayStatusFailed, "Failed", "Not completed at "+provider
+	if completed {
+		status, paymentStatus, reason = models.GatewayStatusCompleted, "Completed", ""
+	}
+
+	var providerRef *string
+	if providerReference != "" {
+		providerRef = &providerReference
+	}
+	var callbackPayload interface{}
+	if len(payload) > 0 {
+		callbackPayload = string(payload)
+	}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE gateway_payments
+		SET status = $1, provider_reference = $2, callback_payload = $3::jsonb, updated_at = NOW(),
+			completed_at = CASE WHEN $1 = 'completed' THEN NOW() END
+		WHERE gateway_payment_id = $4
+		RETURNING status, provider_reference, updated_at, completed_at
+	`, status, providerRef, callbackPayload, gp.GatewayPaymentID).Scan(&gp.Status, &gp.ProviderReference,
+		&gp.UpdatedAt, &gp.CompletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update gateway payment: %v", err)
+	}
+
+	var currentStatus string
+	err = tx.QueryRowContext(ctx, `SELECT payment_status FROM payments WHERE payment_id = $1 FOR UPDATE`,
+		gp.PaymentID).Scan(&currentStatus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payment %d: %v", gp.PaymentID, err)
+	}
+	if currentStatus == "Pending" {
+		if err := applyPaymentVerification(ctx, tx, gp.PaymentID, gp.SaleID, paymentStatus, nil, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return gp, nil
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+	"strings"
+)
+
+// ErrInvalidPaymentMethod is returned when a payment names a method the company does not accept
+var ErrInvalidPaymentMethod = errors.New("invalid payment method")
+
+type PaymentMethodRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentMethodRepository(db *sql.DB) *PaymentMethodRepository {
+	return &PaymentMethodRepository{db: db}
+}
+
+// NormalizePaymentMethod turns user input such as "Bank Transfer" into a method code
+func NormalizePaymentMethod(value string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(value)), " ", "_")
+}
+
+// effectiveMethodsQuery lists the methods a company sees: its own rows plus the built-ins it has
+// not overridden. $1 is the company id.
+const effectiveMethodsQuery = `
+	SELECT DISTINCT ON (code) method_id, company_id, code, name, is_active, sort_order, requires_attachment,
+		created_at, updated_at
+	FROM payment_methods
+	WHERE company_id IS NULL OR company_id = $1
+	ORDER BY code, company_id NULLS LAST`
+
+// GetMethods lists the company's payment methods, optionally including switched-off ones
+func (r *PaymentMethodRepository) GetMethods(companyID int, includeInactive bool) ([]models.PaymentMethod, error) {
+	rows, err := r.db.Query(`
+		SELECT * FROM (`+effectiveMethodsQuery+`) m
+		WHERE $2 OR m.is_active
+		ORDER BY m.sort_order, m.name`, companyID, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payment methods: %v", err)
+	}
+	defer rows.Close()
+
+	methods := []models.PaymentMethod{}
+	for rows.Next() {
+		var m models.PaymentMethod
+		if err := rows.Scan(&m.MethodID, &m.CompanyID, &m.Code, &m.Name, &m.IsActive, &m.SortOrder,
+			&m.RequiresAttachment, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payment method: %v", err)
+		}
+		methods = append(methods, m)
+	}
+	return methods, rows.Err()
+}
+
+// SaveMethod creates or updates the company's own row for a method code
+func (r *PaymentMethodRepository) SaveMethod(method *models.PaymentMethod) error {
+	err := r.db.QueryRow(`
+		INSERT INTO payment_methods (company_id, code, name, is_active, sort_order, requires_attachment)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (company_id, code) WHERE company_id IS NOT NULL DO UPDATE
+		SET name = EXCLUDED.name, is_active = EXCLUDED.is_active, sort_order = EXCLUDED.sort_order,
+			requires_attachment = EXCLUDED.requires_attachment, updated_at = NOW()
+		RETURNING method_id, created_at, updated_at`,
+		method.CompanyID, method.Code, method.Name, method.IsActive, method.SortOrder, method.RequiresAttachment,
+	).Scan(&method.MethodID, &method.CreatedAt, &method.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save payment method: %v", err)
+	}
+	return nil
+}
+
+// resolvePaymentMethod checks that paymentType is an active method for the company the sale
+// belongs to and returns its code
+func resolvePaymentMethod(ctx context.Context, conn ledgerConn, saleID int, paymentType string) (string, error) {
+	code := NormalizePaymentMethod(paymentType)
+	if code == "" {
+		return "", fmt.Errorf("%w: payment type is required", ErrInvalidPaymentMethod)
+	}
+
+	var active bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT m.is_active
+		FROM sales s
+		JOIN users u ON u.id = s.user_id
+		JOIN LATERAL (`+strings.Replace(effectiveMethodsQuery, "$1", "u.company_id", 1)+`) m ON m.code = $2
+		WHERE s.sale_id = $1
+	`, saleID, code).Scan(&active)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPaymentMethod, paymentType)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check payment method: %v", err)
+	}
+	if !active {
+		return "", fmt.Errorf("%w: %s is switched off", ErrInvalidPaymentMethod, paymentType)
+	}
+	return code, nil
+}
@@ -1,11 +1,19 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"renting/internal/models"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+// ErrPaymentNotPending is returned when a payment has already been verified, rejected or cancelled
+var ErrPaymentNotPending = errors.New("payment is not pending")
+
 // PaymentVerificationRepository handles database operations for payment verification
 type PaymentVerificationRepository struct {
 	db *sql.DB
@@ -35,7 +43,7 @@ func (r *PaymentVerificationRepository) isAdmin(userID int) (bool, error) {
 }
 
 // VerifyPayment updates payment status and related sale records
-func (r *PaymentVerificationRepository) VerifyPayment(paymentID int, status string, userID int, saleID int) error {
+func (r *PaymentVerificationRepository) VerifyPayment(paymentID int, status string, userID int, saleID int, reason string) error {
 	// 1. Admin check
 	isAdmin, err := r.isAdmin(userID)
 	if err != nil {
@@ -51,9 +59,21 @@ func (r *PaymentVerificationRepository) VerifyPayment(paymentID int, status stri
 	}
 	defer tx.Rollback()
 
+	if err := applyPaymentVerification(context.Background(), tx, paymentID, saleID, status, &userID, reason); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// applyPaymentVerification settles a payment as status and brings the sale in line with it: the
+// ledger and receipt, the sale's payment status, its revenue recognition and the webhook events.
+// verifiedBy is nil when the payment was confirmed by a payment gateway rather than an admin. The
+// reason is kept on rejected payments so the staff member who took them can see it.
+func applyPaymentVerification(ctx context.Context, tx *sql.Tx, paymentID, saleID int, status string, verifiedBy *int, reason string) error {
 	// 2. First get the payment amount being verified
 	var paymentAmount float64
-	err = tx.QueryRow("SELECT amount_paid FROM payments WHERE payment_id = $1", paymentID).Scan(&paymentAmount)
+	err := tx.QueryRowContext(ctx, "SELECT amount_paid FROM payments WHERE payment_id = $1", paymentID).Scan(&paymentAmount)
 	if err != nil {
 		return err
 	}
@@ -64,121 +84,69 @@ func (r *PaymentVerificationRepository) VerifyPayment(paymentID int, status stri
         SET payment_status = $1, 
             verified_by_admin = $2, 
             updated_at = $3, 
-            user_id = $4
+            verified_by = $4,
+            verified_at = $3,
+            rejection_reason = CASE WHEN $1 = 'Failed' THEN NULLIF($6, '') END
         WHERE payment_id = $5
     `
-	_, err = tx.Exec(updateQuery, status, true, time.Now(), userID, paymentID)
-	if err != nil {
-		return err
-	}
-
-	// 4. Fetch sale details
-	var (
-		totalAmount    float64
-		otherCharges   sql.NullFloat64
-		saleStatus     string
-		dateOfDelivery time.Time
-		returnDate     time.Time
-	)
-	saleQuery := `
-        SELECT total_amount, other_charges, status, date_of_delivery, return_date
-        FROM sales
-        WHERE sale_id = $1
-    `
-	err = tx.QueryRow(saleQuery, saleID).Scan(&totalAmount, &otherCharges, &saleStatus, &dateOfDelivery, &returnDate)
+	_, err = tx.ExecContext(ctx, updateQuery, status, true, time.Now(), verifiedBy, paymentID, reason)
 	if err != nil {
 		return err
 	}
 
-	// 5. Calculate total sale amount
-	totalSaleAmount := totalAmount
-	if otherCharges.Valid {
-		totalSaleAmount += otherCharges.Float64
+	// Book the cash once the payment is confirmed, or back it out if it was booked before
+	if status == "Completed" {
+		err = postPaymentReceipt(ctx, tx, paymentID, verifiedBy)
+		if err == nil {
+			err = issuePaymentReceipt(ctx, tx, paymentID, verifiedBy)
+		}
+	} else {
+		err = reversePaymentReceipt(ctx, tx, paymentID, verifiedBy)
 	}
-
-	// 6. Sum completed payments
-	var totalPaid float64
-	paymentsQuery := `
-        SELECT COALESCE(SUM(amount_paid), 0)
-        FROM payments
-        WHERE sale_id = $1 AND payment_status = 'Completed'
-    `
-	err = tx.QueryRow(paymentsQuery, saleID).Scan(&totalPaid)
 	if err != nil {
 		return err
 	}
 
-	// 7. Determine sale payment status
-	var newSalePaymentStatus string
-	switch {
-	case totalPaid >= totalSaleAmount && saleStatus == "completed":
-		newSalePaymentStatus = "paid"
-	case totalPaid > 0:
-		newSalePaymentStatus = "partial"
-	default:
-		newSalePaymentStatus = "unpaid"
+	// 4. Derive the sale's payment status from its balance
+	if _, err := recomputeSaleBalance(ctx, tx, saleID); err != nil {
+		return err
 	}
 
-	// 8. Update the sale's payment status
-	saleUpdateQuery := `
-        UPDATE sales
-        SET payment_status = $1,
-            updated_at = $2
-        WHERE sale_id = $3
-    `
-	_, err = tx.Exec(saleUpdateQuery, newSalePaymentStatus, time.Now(), saleID)
-	if err != nil {
+	// 5. Rebuild the sale's revenue recognition from its verified payments
+	if err := recomputeRevenueRecognition(ctx, tx, saleID); err != nil {
 		return err
 	}
 
-	// 9. Add revenue recognition for THIS payment
-	if status == "Completed" {
-		revenueAmount := paymentAmount
-
-		// For the final payment that completes the sale, adjust for any rounding differences
-		if newSalePaymentStatus == "paid" {
-			revenueAmount = totalSaleAmount - (totalPaid - paymentAmount)
-		}
-
-		// Calculate recognition period
-		recognitionStart := dateOfDelivery
-		recognitionEnd := returnDate
-
-		// For immediate recognition (if no return date or same day)
-		if returnDate.IsZero() || returnDate.Equal(dateOfDelivery) {
-			recognitionEnd = dateOfDelivery
-		}
-
-		// Insert into revenue_recognition with all required fields
-		_, err = tx.Exec(`
-            INSERT INTO revenue_recognition (
-                sale_id, 
-                total_amount, 
-                start_date, 
-                end_date,
-                updated_at
-            ) VALUES (
-                $1, $2, $3, $4, $5
-            )
-        `,
-			saleID,
-			revenueAmount,
-			recognitionStart,
-			recognitionEnd,
-			time.Now(),
-		)
+	// 6. Queue webhook notifications for the verification outcome
+	var eventType string
+	switch status {
+	case "Completed":
+		eventType = models.WebhookEventPaymentVerified
+	case "Failed":
+		eventType = models.WebhookEventPaymentFailed
+	}
+	if eventType != "" {
+		err = enqueueSaleWebhookEvent(tx, saleID, eventType, map[string]interface{}{
+			"payment": map[string]interface{}{
+				"payment_id":     paymentID,
+				"amount_paid":    paymentAmount,
+				"payment_status": status,
+				"verified_by":    verifiedBy,
+			},
+		})
 		if err != nil {
 			return err
 		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 // GetPaymentDetails fetches payment details for verification
 func (r *PaymentVerificationRepository) GetPaymentDetails(paymentID int) (map[string]interface{}, error) {
 	query := `
-		SELECT payment_id, sale_id, amount_paid, payment_status, remark, verified_by_admin, user_id, updated_at
+		SELECT payment_id, sale_id, amount_paid, payment_status, remark, verified_by_admin, user_id, updated_at,
+			verified_by, verified_at, rejection_reason
 		FROM payments
 		WHERE payment_id = $1
 	`
@@ -191,6 +159,9 @@ func (r *PaymentVerificationRepository) GetPaymentDetails(paymentID int) (map[st
 		VerifiedByAdmin bool
 		UserID          sql.NullInt64
 		UpdatedAt       time.Time
+		VerifiedBy      *int
+		VerifiedAt      *time.Time
+		RejectionReason *string
 	}
 
 	err := r.db.QueryRow(query, paymentID).Scan(
@@ -202,6 +173,9 @@ func (r *PaymentVerificationRepository) GetPaymentDetails(paymentID int) (map[st
 		&payment.VerifiedByAdmin,
 		&payment.UserID,
 		&payment.UpdatedAt,
+		&payment.VerifiedBy,
+		&payment.VerifiedAt,
+		&payment.RejectionReason,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -219,6 +193,9 @@ func (r *PaymentVerificationRepository) GetPaymentDetails(paymentID int) (map[st
 		"verified_by_admin": payment.VerifiedByAdmin,
 		"user_id":           payment.UserID.Int64,
 		"updated_at":        payment.UpdatedAt,
+		"verified_by":       payment.VerifiedBy,
+		"verified_at":       payment.VerifiedAt,
+		"rejection_reason":  payment.RejectionReason,
 	}
 	if !payment.Remark.Valid {
 		result["remark"] = nil
@@ -227,6 +204,15 @@ func (r *PaymentVerificationRepository) GetPaymentDetails(paymentID int) (map[st
 		result["user_id"] = nil
 	}
 
+	attachments, err := getPaymentAttachments(context.Background(), r.db, []int{paymentID})
+	if err != nil {
+		return nil, err
+	}
+	result["attachments"] = attachments[paymentID]
+	if attachments[paymentID] == nil {
+		result["attachments"] = []models.PaymentAttachment{}
+	}
+
 	return result, nil
 }
 
@@ -250,21 +236,299 @@ func (r *PaymentVerificationRepository) CancelPayment(paymentID int, userID int)
 		UPDATE payments
 		SET payment_status = 'Failed', 
 			updated_at = $1,
-			user_id = $2
+			verified_by = $2,
+			verified_at = $1
 		WHERE payment_id = $3 AND payment_status != 'Failed'
+		RETURNING sale_id, amount_paid
 	`
-	result, err := tx.Exec(query, time.Now(), userID, paymentID)
+	var saleID int
+	var amountPaid float64
+	err = tx.QueryRow(query, time.Now(), userID, paymentID).Scan(&saleID, &amountPaid)
+	if err == sql.ErrNoRows {
+		return errors.New("payment not found or already canceled")
+	}
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if err := reversePaymentReceipt(context.Background(), tx, paymentID, &userID); err != nil {
+		return err
+	}
+	if _, err := recomputeSaleBalance(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+	if err := recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+
+	err = enqueueSaleWebhookEvent(tx, saleID, models.WebhookEventPaymentCancelled, map[string]interface{}{
+		"payment": map[string]interface{}{
+			"payment_id":     paymentID,
+			"amount_paid":    amountPaid,
+			"payment_status": "Failed",
+			"cancelled_by":   userID,
+		},
+	})
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return errors.New("payment not found or already canceled")
+
+	return tx.Commit()
+}
+
+// ErrPaymentNotFound is returned when a payment does not exist or belongs to another company
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// RefundPayment pays a completed payment of the company back to the customer and books the refund
+func (r *PaymentVerificationRepository) RefundPayment(companyID, paymentID int, userID int, reason string) error {
+	isAdmin, err := r.isAdmin(userID)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return errors.New("only admin users can refund payments")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The payment belongs to the company of the user who made the sale
+	var status string
+	err = tx.QueryRow(`
+		SELECT p.payment_status
+		FROM payments p
+		JOIN sales s ON s.sale_id = p.sale_id
+		JOIN users u ON u.id = s.user_id
+		WHERE p.payment_id = $1 AND u.company_id = $2
+		FOR UPDATE OF p
+	`, paymentID, companyID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrPaymentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status != "Completed" {
+		return errors.New("only completed payments can be refunded")
+	}
+
+	var saleID int
+	var amountPaid float64
+	err = tx.QueryRow(`
+		UPDATE payments
+		SET payment_status = 'Refunded',
+			remark = CASE WHEN $1 = '' THEN remark ELSE $1 END,
+			updated_at = $2
+		WHERE payment_id = $3
+		RETURNING sale_id, amount_paid
+	`, reason, time.Now(), paymentID).Scan(&saleID, &amountPaid)
+	if err != nil {
+		return err
+	}
+
+	if err := postPaymentRefund(context.Background(), tx, paymentID, saleID, amountPaid, &userID); err != nil {
+		return err
+	}
+	if _, err := recomputeSaleBalance(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+	if err := recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
+
+// GetVerificationQueue lists the company's pending payments, oldest first, with the sale they are
+// for, its balance and the images staff uploaded with it. Payments recorded without a user are
+// attributed to the staff member who made the sale.
+func (r *PaymentVerificationRepository) GetVerificationQueue(companyID int, filter models.VerificationQueueFilter) (*models.VerificationQueue, error) {
+	query := `
+		SELECT p.payment_id, p.sale_id, p.amount_paid, COALESCE(p.payment_type, ''), COALESCE(p.sale_type, ''),
+			p.payment_date, COALESCE(p.remark, ''), p.created_at,
+			COALESCE(p.user_id, s.user_id), submitter.username,
+			s.customer_name, COALESCE(s.customer_phone, ''), COALESCE(v.vehicle_name, ''),
+			COALESCE(v.vehicle_registration_number, ''), s.status,
+			COUNT(*) OVER (), COALESCE(SUM(p.amount_paid) OVER (), 0)
+		FROM payments p
+		JOIN sales s ON s.sale_id = p.sale_id
+		JOIN users owner ON owner.id = s.user_id
+		LEFT JOIN vehicles v ON v.vehicle_id = s.vehicle_id
+		LEFT JOIN users submitter ON submitter.id = COALESCE(p.user_id, s.user_id)
+		WHERE p.payment_status = 'Pending' AND owner.company_id = $1`
+	args := []interface{}{companyID}
+	if filter.SaleType != "" {
+		args = append(args, filter.SaleType)
+		query += fmt.Sprintf(" AND p.sale_type = $%d", len(args))
+	}
+	if filter.SubmittedBy != nil {
+		args = append(args, *filter.SubmittedBy)
+		query += fmt.Sprintf(" AND COALESCE(p.user_id, s.user_id) = $%d", len(args))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY p.created_at, p.payment_id LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query verification queue: %v", err)
+	}
+	defer rows.Close()
+
+	queue := &models.VerificationQueue{Items: []models.VerificationQueueItem{}}
+	for rows.Next() {
+		var item models.VerificationQueueItem
+		if err := rows.Scan(&item.PaymentID, &item.SaleID, &item.AmountPaid, &item.PaymentType, &item.SaleType,
+			&item.PaymentDate, &item.Remark, &item.CreatedAt, &item.SubmittedBy, &item.SubmittedByUsername,
+			&item.Sale.CustomerName, &item.Sale.CustomerPhone, &item.Sale.VehicleName, &item.Sale.Registration,
+			&item.Sale.Status, &queue.Total, &queue.PendingAmount); err != nil {
+			return nil, fmt.Errorf("failed to scan pending payment: %v", err)
+		}
+		item.Evidence = []models.PaymentEvidence{}
+		queue.Items = append(queue.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(queue.Items) == 0 {
+		return queue, nil
+	}
+
+	ctx := context.Background()
+	balances := map[int]*models.SaleBalance{}
+	var saleIDs []int
+	for _, item := range queue.Items {
+		if _, ok := balances[item.SaleID]; ok {
+			continue
+		}
+		balance, err := saleBalance(ctx, r.db, item.SaleID)
+		if err != nil {
+			return nil, err
+		}
+		balances[item.SaleID] = balance
+		saleIDs = append(saleIDs, item.SaleID)
+	}
+
+	evidence := map[int][]models.PaymentEvidence{}
+	imageRows, err := r.db.Query(`
+		SELECT sale_id, image_url, file_name, uploaded_at
+		FROM sales_images
+		WHERE sale_id = ANY($1)
+		ORDER BY uploaded_at, image_id
+	`, pq.Array(saleIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sale images: %v", err)
+	}
+	defer imageRows.Close()
+	for imageRows.Next() {
+		var saleID int
+		e := models.PaymentEvidence{Source: "sale_image"}
+		if err := imageRows.Scan(&saleID, &e.URL, &e.FileName, &e.UploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sale image: %v", err)
+		}
+		evidence[saleID] = append(evidence[saleID], e)
+	}
+	if err := imageRows.Err(); err != nil {
+		return nil, err
+	}
+
+	paymentIDs := make([]int, len(queue.Items))
+	for i, item := range queue.Items {
+		paymentIDs[i] = item.PaymentID
+	}
+	attachments, err := getPaymentAttachments(ctx, r.db, paymentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The payment's own attachments come first, then the images of its sale
+	for i := range queue.Items {
+		item := &queue.Items[i]
+		item.Sale.Balance = balances[item.SaleID]
+		for _, a := range attachments[item.PaymentID] {
+			item.Evidence = append(item.Evidence, models.PaymentEvidence{
+				Source:     "payment_attachment",
+				URL:        a.FileURL,
+				FileName:   a.FileName,
+				UploadedAt: a.UploadedAt,
+			})
+		}
+		item.Evidence = append(item.Evidence, evidence[item.SaleID]...)
+	}
+	return queue, nil
+}
+
+// GetPaymentSales maps each of the company's payments among paymentIDs to its sale. Payments of
+// other companies are left out.
+func (r *PaymentVerificationRepository) GetPaymentSales(companyID int, paymentIDs []int) (map[int]int, error) {
+	rows, err := r.db.Query(`
+		SELECT p.payment_id, p.sale_id
+		FROM payments p
+		JOIN sales s ON s.sale_id = p.sale_id
+		JOIN users u ON u.id = s.user_id
+		WHERE p.payment_id = ANY($1) AND u.company_id = $2
+	`, pq.Array(paymentIDs), companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payments: %v", err)
+	}
+	defer rows.Close()
+
+	sales := map[int]int{}
+	for rows.Next() {
+		var paymentID, saleID int
+		if err := rows.Scan(&paymentID, &saleID); err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %v", err)
+		}
+		sales[paymentID] = saleID
+	}
+	return sales, rows.Err()
+}
+
+// DecideSalePayments verifies or rejects pending payments of one sale in a single transaction, so
+// either every decision on the sale is applied or none is. On failure the payment at fault is
+// returned along with the error.
+func (r *PaymentVerificationRepository) DecideSalePayments(saleID, adminID int, decisions []models.PaymentDecision) (int, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the sale first so concurrent decisions on it queue up instead of racing on its balance
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM sales WHERE sale_id = $1 FOR UPDATE`, saleID); err != nil {
+		return 0, fmt.Errorf("failed to lock sale %d: %v", saleID, err)
+	}
+
+	for _, decision := range decisions {
+		var status string
+		err := tx.QueryRowContext(ctx, `
+			SELECT payment_status FROM payments WHERE payment_id = $1 AND sale_id = $2 FOR UPDATE
+		`, decision.PaymentID, saleID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return decision.PaymentID, ErrPaymentNotFound
+		}
+		if err != nil {
+			return decision.PaymentID, err
+		}
+		if status != "Pending" {
+			return decision.PaymentID, fmt.Errorf("%w: it is %s", ErrPaymentNotPending, status)
+		}
+
+		newStatus := "Completed"
+		if decision.Action == models.PaymentActionReject {
+			newStatus = "Failed"
+		}
+		if err := applyPaymentVerification(ctx, tx, decision.PaymentID, saleID, newStatus, &adminID, decision.Reason); err != nil {
+			return decision.PaymentID, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"renting/internal/config"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// R2Storage uploads small documents such as receipts to the R2 bucket
+type R2Storage struct {
+	uploader  *s3manager.Uploader
+	bucket    string
+	publicURL string
+}
+
+func NewR2Storage(cfg *config.Config) *R2Storage {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:         aws.String(cfg.R2Endpoint),
+		Region:           aws.String(cfg.R2Region),
+		Credentials:      credentials.NewStaticCredentials(cfg.R2AccessKeyID, cfg.R2SecretAccessKey, ""),
+		S3ForcePathStyle: aws.Bool(true),
+		HTTPClient:       &http.Client{Timeout: 1 * time.Minute},
+	}))
+
+	return &R2Storage{
+		uploader:  s3manager.NewUploader(sess),
+		bucket:    cfg.R2BucketName,
+		publicURL: strings.TrimRight(cfg.R2PublicURL, "/"),
+	}
+}
+
+// Upload stores the object under key and returns its public URL
+func (s *R2Storage) Upload(reader io.Reader, key, contentType string) (string, error) {
+	_, err := s.uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        reader,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("R2 upload failed: %w", err)
+	}
+	return fmt.Sprintf("%s/%s", s.publicURL, key), nil
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"renting/internal/models"
+	"strings"
+)
+
+type ReceiptRepository struct {
+	db *sql.DB
+}
+
+func NewReceiptRepository(db *sql.DB) *ReceiptRepository {
+	return &ReceiptRepository{db: db}
+}
+
+// issuePaymentReceipt gives a completed payment the next receipt number of its company, prefixed
+// with the company's receipt_prefix setting or else its company code. The per-company counter row
+// stays locked until the surrounding transaction ends, so numbers are handed out in order and a
+// rolled-back payment gives its number back. It does nothing for payments that are not completed
+// or already have a receipt.
+func issuePaymentReceipt(ctx context.Context, conn ledgerConn, paymentID int, issuedBy *int) error {
+	var status, prefix string
+	var companyID int
+	var issued bool
+	err := conn.QueryRowContext(ctx, `
+		SELECT p.payment_status, u.company_id,
+			COALESCE(NULLIF(`+settingTextSQL(models.SettingReceiptPrefix, "c.id")+`, ''), c.company_code),
+			EXISTS (SELECT 1 FROM payment_receipts r WHERE r.payment_id = p.payment_id)
+		FROM payments p
+		JOIN sales s ON s.sale_id = p.sale_id
+		JOIN users u ON u.id = s.user_id
+		JOIN companies c ON c.id = u.company_id
+		WHERE p.payment_id = $1
+	`, paymentID).Scan(&status, &companyID, &prefix, &issued)
+	if err != nil {
+		return fmt.Errorf("failed to fetch payment %d for receipt: %v", paymentID, err)
+	}
+	if status != "Completed" || issued {
+		return nil
+	}
+
+	var sequence int
+	err = conn.QueryRowContext(ctx, `
+		INSERT INTO receipt_sequences (company_id, last_number)
+		VALUES ($1, 1)
+		ON CONFLICT (company_id) DO UPDATE SET last_number = receipt_sequences.last_number + 1
+		RETURNING last_number
+	`, companyID).Scan(&sequence)
+	if err != nil {
+		return fmt.Errorf("failed to allocate receipt number: %v", err)
+	}
+
+	receiptNumber := fmt.Sprintf("%s-%06d", strings.ToUpper(prefix), sequence)
+	_, err = conn.ExecContext(ctx, `
+		INSERT INTO payment_receipts (payment_id, company_id, sequence, receipt_number, issued_by)
+		VALUES ($1, $2, $3, $4, $5)
+	`, paymentID, companyID, sequence, receiptNumber, issuedBy)
+	if err != nil {
+		return fmt.Errorf("failed to record receipt for payment %d: %v", paymentID, err)
+	}
+	_, err = conn.ExecContext(ctx, `UPDATE payments SET receipt_number = $1 WHERE payment_id = $2`, receiptNumber, paymentID)
+	if err != nil {
+		return fmt.Errorf("failed to set receipt number on payment %d: %v", paymentID, err)
+	}
+	return nil
+}
+
+// GetReceipt returns the receipt of a payment made to the company, or sql.ErrNoRows when the
+// payment has no receipt (it is not verified yet) or belongs to another company
+func (r *ReceiptRepository) GetReceipt(companyID, paymentID int) (*models.PaymentReceipt, error) {
+	var rc models.PaymentReceipt
+	err := r.db.QueryRow(`
+		SELECT rc.receipt_number, rc.sequence, rc.issued_at,
+			c.id, c.name, c.company_code,
+			p.payment_id, p.sale_id, COALESCE(s.customer_name, ''), COALESCE(s.customer_phone, ''),
+			COALESCE(v.vehicle_name, ''), COALESCE(v.vehicle_registration_number, ''),
+			COALESCE(m.name, p.payment_type, ''), COALESCE(p.sale_type, ''), p.amount_paid, p.payment_date,
+			p.payment_status, COALESCE(p.remark, ''), COALESCE(collector.username, issuer.username, '')
+		FROM payment_receipts rc
+		JOIN payments p ON p.payment_id = rc.payment_id
+		JOIN sales s ON s.sale_id = p.sale_id
+		JOIN companies c ON c.id = rc.company_id
+		LEFT JOIN vehicles v ON v.vehicle_id = s.vehicle_id
+		LEFT JOIN users collector ON collector.id = p.user_id
+		LEFT JOIN users issuer ON issuer.id = rc.issued_by
+		LEFT JOIN LATERAL (`+strings.Replace(effectiveMethodsQuery, "$1", "rc.company_id", 1)+`) m ON m.code = p.payment_type
+		WHERE rc.payment_id = $1 AND rc.company_id = $2
+	`, paymentID, companyID).Scan(&rc.ReceiptNumber, &rc.Sequence, &rc.IssuedAt,
+		&rc.Company.CompanyID, &rc.Company.Name, &rc.Company.CompanyCode,
+		&rc.PaymentID, &rc.SaleID, &rc.CustomerName, &rc.CustomerPhone,
+		&rc.VehicleName, &rc.Registration,
+		&rc.PaymentMethod, &rc.Stage, &rc.Amount, &rc.PaymentDate,
+		&rc.PaymentStatus, &rc.Remark, &rc.ReceivedBy)
+	if err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ReminderRepository struct {
@@ -18,6 +19,13 @@ func NewReminderRepository(db *gorm.DB) *ReminderRepository {
 	return &ReminderRepository{db: db}
 }
 
+// companyReminders limits a reminders query to those created by the company's users
+func companyReminders(companyID int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("reminders.user_id IN (SELECT id FROM users WHERE company_id = ?)", companyID)
+	}
+}
+
 // CreateReminder creates a new reminder with calculated next due date
 func (r *ReminderRepository) CreateReminder(ctx context.Context, reminder *models.Reminder) error {
 	// Log the incoming reminder for debugging
@@ -48,9 +56,9 @@ func (r *ReminderRepository) CreateReminder(ctx context.Context, reminder *model
 }
 
 // GetReminderByID retrieves a reminder by its ID
-func (r *ReminderRepository) GetReminderByID(ctx context.Context, id int) (*models.Reminder, error) {
+func (r *ReminderRepository) GetReminderByID(ctx context.Context, companyID, id int) (*models.Reminder, error) {
 	var reminder models.Reminder
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&reminder).Error
+	err := r.db.WithContext(ctx).Scopes(companyReminders(companyID)).Where("id = ?", id).First(&reminder).Error
 	if err != nil {
 		return nil, err
 	}
@@ -58,19 +66,21 @@ func (r *ReminderRepository) GetReminderByID(ctx context.Context, id int) (*mode
 }
 
 // GetRemindersByVehicleID retrieves all reminders for a vehicle
-func (r *ReminderRepository) GetRemindersByVehicleID(ctx context.Context, vehicleID int) ([]models.Reminder, error) {
+func (r *ReminderRepository) GetRemindersByVehicleID(ctx context.Context, companyID, vehicleID int) ([]models.Reminder, error) {
 	var reminders []models.Reminder
-	err := r.db.WithContext(ctx).Where("vehicle_id = ?", vehicleID).Find(&reminders).Error
+	err := r.db.WithContext(ctx).Scopes(companyReminders(companyID)).Where("vehicle_id = ?", vehicleID).Find(&reminders).Error
 	return reminders, err
 }
 
 // GetDueReminders retrieves all reminders that are due
-func (r *ReminderRepository) GetDueReminders(ctx context.Context, limit, offset int, reminderType string) ([]models.Reminder, error) {
+func (r *ReminderRepository) GetDueReminders(ctx context.Context, companyID, limit, offset int, reminderType string) ([]models.Reminder, error) {
 	var reminders []models.Reminder
 	today := time.Now().UTC().Truncate(24 * time.Hour)
 
 	query := r.db.WithContext(ctx).
-		Where("next_due_date <= ?", today)
+		Scopes(companyReminders(companyID)).
+		Where("next_due_date <= ?", today).
+		Where("snoozed_until IS NULL OR snoozed_until <= NOW()")
 
 	if reminderType != "" {
 		query = query.Where("type = ?", reminderType)
@@ -106,43 +116,130 @@ func (r *ReminderRepository) UpdateStartDateAndNextDueDate(ctx context.Context,
 		}).Error
 }
 
-// CreateAcknowledgement creates a new reminder acknowledgement and updates the reminder's start date and next due date
-func (r *ReminderRepository) CreateAcknowledgement(ctx context.Context, ack *models.ReminderAcknowledgement) error {
-	// Start a transaction to ensure atomicity
-	tx := r.db.WithContext(ctx).Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
+// CreateAcknowledgement records a completed reminder and advances its schedule in one transaction.
+// In due_date mode the next due date is the schedule's next occurrence after the due date being
+// settled, so paying an EMI late does not shift later instalments; ack_date mode restarts the
+// schedule from the acknowledgement.
+// When recordExpense is set, an amount paid against an EMI or insurance reminder is also booked as a
+// company expense and linked to the acknowledgement.
+func (r *ReminderRepository) CreateAcknowledgement(ctx context.Context, companyID int, ack *models.ReminderAcknowledgement, recordExpense bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reminder models.Reminder
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(companyReminders(companyID)).Where("id = ?", ack.ReminderID).First(&reminder).Error; err != nil {
+			return err
+		}
 
-	// Create the acknowledgement
-	if err := tx.Create(ack).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		dueDate := reminder.NextDueDate
+		ack.DueDate = &dueDate
 
-	// Retrieve the reminder to get its frequency
-	reminder, err := r.GetReminderByID(ctx, ack.ReminderID)
-	if err != nil {
-		tx.Rollback()
-		return err
+		advanceFrom := reminder.NextDueDate
+		if ack.AdvanceMode == models.ReminderAdvanceFromAckDate {
+			reminder.StartDate = ack.AcknowledgedAt
+			advanceFrom = ack.AcknowledgedAt
+		}
+		startDate := reminder.StartDate
+		nextDueDate := r.CalculateNextDueDate(&reminder, advanceFrom)
+		log.Printf("Acknowledging reminder %d due %s (%s mode), next due date: %s",
+			reminder.ID, dueDate.Format(time.RFC3339), ack.AdvanceMode, nextDueDate.Format(time.RFC3339))
+
+		if recordExpense && ack.AmountPaid != nil && *ack.AmountPaid > 0 &&
+			(reminder.Type == models.ReminderTypeEMI || reminder.Type == models.ReminderTypeInsurance) {
+			description := fmt.Sprintf("%s payment for vehicle %d due %s", reminder.Type, reminder.VehicleID, dueDate.Format("2006-01-02"))
+			if ack.ReferenceNumber != nil && *ack.ReferenceNumber != "" {
+				description += fmt.Sprintf(" (ref %s)", *ack.ReferenceNumber)
+			}
+			var expenseID int
+			err := tx.Raw(`
+				INSERT INTO company_expenses (expense_type, amount, description, expense_date, recorded_by, vehicle_id)
+				VALUES (?, ?, ?, ?, ?, ?)
+				RETURNING expense_id
+			`, string(reminder.Type), *ack.AmountPaid, description, ack.AcknowledgedAt, ack.UserID, reminder.VehicleID).Scan(&expenseID).Error
+			if err != nil {
+				return fmt.Errorf("failed to record expense for reminder %d: %v", reminder.ID, err)
+			}
+			if err := postExpense(ctx, tx.Statement.ConnPool, expenseID, &ack.UserID); err != nil {
+				return err
+			}
+			ack.ExpenseID = &expenseID
+		}
+
+		if err := tx.Create(ack).Error; err != nil {
+			return err
+		}
+
+		// Acknowledging also clears any snooze so the next cycle alerts normally
+		return tx.Model(&models.Reminder{}).
+			Where("id = ?", reminder.ID).
+			Updates(map[string]interface{}{
+				"start_date":    startDate,
+				"next_due_date": nextDueDate,
+				"snoozed_until": nil,
+				"updated_at":    time.Now().UTC(),
+			}).Error
+	})
+}
+
+// SnoozeReminder mutes a reminder's alerts until the given time
+func (r *ReminderRepository) SnoozeReminder(ctx context.Context, companyID, reminderID int, until time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Reminder{}).
+		Scopes(companyReminders(companyID)).
+		Where("id = ?", reminderID).
+		Updates(map[string]interface{}{
+			"snoozed_until": until,
+			"updated_at":    time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
+	return nil
+}
 
-	// Update the reminder's start date to the acknowledgement's AcknowledgedAt
-	newStartDate := ack.AcknowledgedAt
-	log.Printf("Setting new start date to acknowledgement date: %s", newStartDate.Format(time.RFC3339))
+// GetUpcomingAlerts returns reminders that have reached their earliest lead-time alert point,
+// or are already due, and are not snoozed
+func (r *ReminderRepository) GetUpcomingAlerts(ctx context.Context, companyID int, reminderType string) ([]models.ReminderAlert, error) {
+	var reminders []models.ReminderWithVehicle
+	query := r.db.WithContext(ctx).
+		Table("reminders").
+		Select("reminders.*, vehicles.vehicle_name, vehicles.vehicle_model, vehicles.vehicle_registration_number").
+		Joins("JOIN vehicles ON reminders.vehicle_id = vehicles.vehicle_id").
+		Scopes(companyReminders(companyID)).
+		Where("reminders.snoozed_until IS NULL OR reminders.snoozed_until <= NOW()").
+		Where("reminders.next_due_date::date <= CURRENT_DATE + (SELECT COALESCE(MAX(d), 0) FROM unnest(reminders.lead_time_days) AS d)::int")
 
-	// Calculate the new next due date based on the frequency and new start date
-	newNextDueDate := r.CalculateNextDueDate(reminder, newStartDate)
-	log.Printf("Calculated new next due date: %s", newNextDueDate.Format(time.RFC3339))
+	if reminderType != "" {
+		query = query.Where("reminders.type = ?", reminderType)
+	}
 
-	// Update the reminder's start date and next due date
-	if err := r.UpdateStartDateAndNextDueDate(ctx, ack.ReminderID, newStartDate, newNextDueDate); err != nil {
-		tx.Rollback()
-		return err
+	if err := query.Order("reminders.next_due_date ASC").Find(&reminders).Error; err != nil {
+		return nil, err
 	}
 
-	// Commit the transaction
-	return tx.Commit().Error
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	alerts := make([]models.ReminderAlert, 0, len(reminders))
+	for _, reminder := range reminders {
+		due := reminder.NextDueDate.UTC().Truncate(24 * time.Hour)
+		daysUntilDue := int(due.Sub(today).Hours() / 24)
+
+		// The stage is the tightest lead time the reminder has crossed
+		stage := 0
+		if daysUntilDue > 0 {
+			for _, lead := range reminder.LeadTimeDays {
+				if int(lead) >= daysUntilDue && (stage == 0 || int(lead) < stage) {
+					stage = int(lead)
+				}
+			}
+		}
+		alerts = append(alerts, models.ReminderAlert{
+			ReminderWithVehicle: reminder,
+			DaysUntilDue:        daysUntilDue,
+			AlertStage:          stage,
+		})
+	}
+	return alerts, nil
 }
 
 // GetAcknowledgements retrieves all acknowledgements for a reminder
@@ -156,28 +253,30 @@ func (r *ReminderRepository) GetAcknowledgements(ctx context.Context, reminderID
 }
 
 // GetRemindersByTypeAndVehicle retrieves reminders by type and vehicle
-func (r *ReminderRepository) GetRemindersByTypeAndVehicle(ctx context.Context, vehicleID int, reminderType string) ([]models.Reminder, error) {
+func (r *ReminderRepository) GetRemindersByTypeAndVehicle(ctx context.Context, companyID, vehicleID int, reminderType string) ([]models.Reminder, error) {
 	var reminders []models.Reminder
 	err := r.db.WithContext(ctx).
+		Scopes(companyReminders(companyID)).
 		Where("vehicle_id = ? AND type = ?", vehicleID, reminderType).
 		Find(&reminders).Error
 	return reminders, err
 }
 
 // GetRemindersByVehicleIDWithVehicleDetails gets reminders with vehicle info
-func (r *ReminderRepository) GetRemindersByVehicleIDWithVehicleDetails(ctx context.Context, vehicleID int) ([]models.ReminderWithVehicle, error) {
+func (r *ReminderRepository) GetRemindersByVehicleIDWithVehicleDetails(ctx context.Context, companyID, vehicleID int) ([]models.ReminderWithVehicle, error) {
 	var reminders []models.ReminderWithVehicle
 	err := r.db.WithContext(ctx).
 		Table("reminders").
 		Select("reminders.*, vehicles.vehicle_name, vehicles.vehicle_model, vehicles.vehicle_registration_number").
 		Joins("JOIN vehicles ON reminders.vehicle_id = vehicles.vehicle_id").
+		Scopes(companyReminders(companyID)).
 		Where("reminders.vehicle_id = ?", vehicleID).
 		Find(&reminders).Error
 	return reminders, err
 }
 
 // GetDueRemindersWithVehicleDetails gets due reminders with vehicle info
-func (r *ReminderRepository) GetDueRemindersWithVehicleDetails(ctx context.Context, limit, offset int, reminderType string) ([]models.ReminderWithVehicle, error) {
+func (r *ReminderRepository) GetDueRemindersWithVehicleDetails(ctx context.Context, companyID, limit, offset int, reminderType string) ([]models.ReminderWithVehicle, error) {
 	var reminders []models.ReminderWithVehicle
 	today := time.Now().UTC().Truncate(24 * time.Hour)
 
@@ -185,7 +284,9 @@ func (r *ReminderRepository) GetDueRemindersWithVehicleDetails(ctx context.Conte
 		Table("reminders").
 		Select("reminders.*, vehicles.vehicle_name, vehicles.vehicle_model, vehicles.vehicle_registration_number").
 		Joins("JOIN vehicles ON reminders.vehicle_id = vehicles.vehicle_id").
-		Where("reminders.next_due_date <= ?", today)
+		Scopes(companyReminders(companyID)).
+		Where("reminders.next_due_date <= ?", today).
+		Where("reminders.snoozed_until IS NULL OR reminders.snoozed_until <= NOW()")
 
 	if reminderType != "" {
 		query = query.Where("reminders.type = ?", reminderType)
@@ -199,21 +300,60 @@ func (r *ReminderRepository) GetDueRemindersWithVehicleDetails(ctx context.Conte
 	return reminders, err
 }
 
-// CalculateNextDueDate computes the next due date based on frequency
+// CalculateNextDueDate returns the first occurrence of the reminder's schedule after fromDate.
+// Occurrences are counted from StartDate rather than chained from the previous due date, so a
+// reminder started on the 31st falls due on the last day of shorter months and returns to the 31st.
 func (r *ReminderRepository) CalculateNextDueDate(reminder *models.Reminder, fromDate time.Time) time.Time {
+	start := reminder.StartDate
+	var n int
 	switch reminder.Frequency {
 	case models.ReminderFrequencyMonthly:
-		return fromDate.AddDate(0, 1, 0) // Add 1 month
+		n = (fromDate.Year()-start.Year())*12 + int(fromDate.Month()-start.Month())
 	case models.ReminderFrequencyYearly:
-		return fromDate.AddDate(1, 0, 0) // Add 1 year
+		n = fromDate.Year() - start.Year()
 	case models.ReminderFrequencyCustom:
-		if reminder.CustomInterval != nil {
-			return fromDate.AddDate(0, 0, *reminder.CustomInterval) // Add custom days
+		if reminder.CustomInterval == nil || *reminder.CustomInterval <= 0 {
+			return fromDate
 		}
-		return fromDate
+		n = int(fromDate.Sub(start).Hours()/24) / *reminder.CustomInterval
 	default:
 		return fromDate
 	}
+
+	// n is at most one short of the occurrence wanted
+	if n < 1 {
+		n = 1
+	}
+	for {
+		due := reminderOccurrence(reminder, n)
+		if due.After(fromDate) {
+			return due
+		}
+		n++
+	}
+}
+
+// reminderOccurrence returns the nth due date after the reminder's start date
+func reminderOccurrence(reminder *models.Reminder, n int) time.Time {
+	switch reminder.Frequency {
+	case models.ReminderFrequencyMonthly:
+		return addMonthsClamped(reminder.StartDate, n)
+	case models.ReminderFrequencyYearly:
+		return addMonthsClamped(reminder.StartDate, 12*n)
+	default:
+		return reminder.StartDate.AddDate(0, 0, *reminder.CustomInterval*n)
+	}
+}
+
+// addMonthsClamped adds months to t, keeping the day of the month but clamping it to the last day
+// of the resulting month instead of overflowing into the next one as time.AddDate does
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
 }
 
 // UpdateReminder updates an existing reminder
@@ -221,7 +361,101 @@ func (r *ReminderRepository) UpdateReminder(ctx context.Context, reminder *model
 	return r.db.WithContext(ctx).Save(reminder).Error
 }
 
-// DeleteReminder deletes a reminder permanently
-func (r *ReminderRepository) DeleteReminder(ctx context.Context, id int) error {
-	return r.db.WithContext(ctx).Delete(&models.Reminder{}, id).Error
+// DeleteReminder permanently deletes one of the company's reminders along with its
+// acknowledgements; expenses booked from them are kept
+func (r *ReminderRepository) DeleteReminder(ctx context.Context, companyID, id int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var reminder models.Reminder
+		if err := tx.Scopes(companyReminders(companyID)).Where("id = ?", id).First(&reminder).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("reminder_id = ?", id).Delete(&models.ReminderAcknowledgement{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&reminder).Error
+	})
+}
+
+// GetReminders lists reminders across the fleet with vehicle details, soonest due first
+func (r *ReminderRepository) GetReminders(ctx context.Context, companyID int, filter models.ReminderFilter) ([]models.ReminderWithVehicle, error) {
+	var reminders []models.ReminderWithVehicle
+	query := r.db.WithContext(ctx).
+		Table("reminders").
+		Select("reminders.*, vehicles.vehicle_name, vehicles.vehicle_model, vehicles.vehicle_registration_number").
+		Joins("JOIN vehicles ON reminders.vehicle_id = vehicles.vehicle_id").
+		Scopes(companyReminders(companyID))
+
+	if len(filter.Types) > 0 {
+		query = query.Where("reminders.type IN ?", filter.Types)
+	}
+	if filter.VehicleID != nil {
+		query = query.Where("reminders.vehicle_id = ?", *filter.VehicleID)
+	}
+	if filter.DueFrom != nil {
+		query = query.Where("reminders.next_due_date >= ?", *filter.DueFrom)
+	}
+	if filter.DueTo != nil {
+		query = query.Where("reminders.next_due_date < ?", filter.DueTo.AddDate(0, 0, 1))
+	}
+	if filter.DueWithinDays != nil {
+		query = query.Where("reminders.next_due_date::date <= CURRENT_DATE + ?::int", *filter.DueWithinDays)
+	}
+
+	err := query.
+		Order("reminders.next_due_date ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// GetReminderTypes returns the built-in reminder types plus the company's own
+func (r *ReminderRepository) GetReminderTypes(ctx context.Context, companyID int) ([]models.ReminderTypeDefinition, error) {
+	var types []models.ReminderTypeDefinition
+	err := r.db.WithContext(ctx).
+		Where("company_id IS NULL OR company_id = ?", companyID).
+		Order("company_id NULLS FIRST, name").
+		Find(&types).Error
+	return types, err
+}
+
+// ReminderTypeExists reports whether code is a built-in type or one defined by the company
+func (r *ReminderRepository) ReminderTypeExists(ctx context.Context, companyID int, code string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.ReminderTypeDefinition{}).
+		Where("code = ? AND (company_id IS NULL OR company_id = ?)", code, companyID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateReminderType adds a company-defined reminder type
+func (r *ReminderRepository) CreateReminderType(ctx context.Context, reminderType *models.ReminderTypeDefinition) error {
+	return r.db.WithContext(ctx).Create(reminderType).Error
+}
+
+// DeleteReminderType removes a company-defined reminder type that no reminder of the company uses
+func (r *ReminderRepository) DeleteReminderType(ctx context.Context, companyID, typeID int) error {
+	var reminderType models.ReminderTypeDefinition
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND company_id = ?", typeID, companyID).
+		First(&reminderType).Error
+	if err != nil {
+		return err
+	}
+
+	var inUse int64
+	err = r.db.WithContext(ctx).
+		Table("reminders").
+		Joins("JOIN users ON users.id = reminders.user_id").
+		Where("reminders.type = ? AND users.company_id = ?", reminderType.Code, companyID).
+		Count(&inUse).Error
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return fmt.Errorf("reminder type %s is used by %d reminders", reminderType.Code, inUse)
+	}
+
+	return r.db.WithContext(ctx).Delete(&reminderType).Error
 }
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"renting/internal/models"
+)
+
+func ymd(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAddMonthsClamped(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  time.Time
+		months int
+		want   time.Time
+	}{
+		{"same day next month", ymd(2024, 3, 15), 1, ymd(2024, 4, 15)},
+		{"31st into 30-day month", ymd(2024, 1, 31), 3, ymd(2024, 4, 30)},
+		{"31st into leap february", ymd(2024, 1, 31), 1, ymd(2024, 2, 29)},
+		{"31st into february", ymd(2023, 1, 31), 1, ymd(2023, 2, 28)},
+		{"across year end", ymd(2024, 11, 30), 3, ymd(2025, 2, 28)},
+		{"leap day plus a year", ymd(2024, 2, 29), 12, ymd(2025, 2, 28)},
+		{"zero months", ymd(2024, 5, 31), 0, ymd(2024, 5, 31)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addMonthsClamped(tt.start, tt.months); !got.Equal(tt.want) {
+				t.Errorf("addMonthsClamped(%s, %d) = %s, want %s", tt.start.Format("2006-01-02"), tt.months, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestCalculateNextDueDate(t *testing.T) {
+	tenDays := 10
+	zero := 0
+	tests := []struct {
+		name     string
+		reminder models.Reminder
+		from     time.Time
+		want     time.Time
+	}{
+		{
+			name:     "monthly first due date",
+			reminder: models.Reminder{Frequency: models.ReminderFrequencyMonthly, StartDate: ymd(2024, 1, 31)},
+			from:     ymd(2024, 1, 31),
+			want:     ymd(2024, 2, 29),
+		},
+		{
+			name:     "monthly returns to the 31st after a short month",
+			reminder: models.Reminder{Frequency: models.ReminderFrequencyMonthly, StartDate: ymd(2024, 1, 31)},
+			from:     ymd(2024, 2, 29),
+			want:     ymd(2024, 3, 31),
+		},
+		{
+			name:     "monthly acknowledged late keeps the schedule",
+			reminder: models.Reminder{Frequency: models.ReminderFrequencyMonthly, StartDate: ymd(2024, 1, 10)},
+			from:     ymd(2024, 4, 25),
+			want:     ymd(2024, 5, 10),
+		},
+		{
+			name:     "yearly from leap day",
+			reminder: models.Reminder{Frequency: models.ReminderFrequencyYearly, StartDate: ymd(2024, 2, 29)},
+			from:     ymd(2024, 2, 29),
+			want:     ymd(2025, 2, 28),
+		},
+		{
+			name:     "custom interval",
+			reminder: models.Reminder{Frequency: models.ReminderFrequencyCustom, StartDate: ymd(2024, 1, 1), CustomInterval: &tenDays},
+			from:     ymd(2024, 1, 25),
+			want:     ymd(2024, 1, 31),
+		},
+		{
+			name:     "custom interval on an occurrence moves to the next",
+			reminder: models.Reminder{Frequency: models.ReminderFrequencyCustom, StartDate: ymd(2024, 1, 1), CustomInterval: &tenDays},
+			from:     ymd(2024, 1, 21),
+			want:     ymd(2024, 1, 31),
+		},
+		{
+			name:     "custom without a positive interval stays put",
+			reminder: models.Reminder{Frequency: models.ReminderFrequencyCustom, StartDate: ymd(2024, 1, 1), CustomInterval: &zero},
+			from:     ymd(2024, 1, 5),
+			want:     ymd(2024, 1, 5),
+		},
+	}
+
+	repo := &ReminderRepository{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reminder := tt.reminder
+			if got := repo.CalculateNextDueDate(&reminder, tt.from); !got.Equal(tt.want) {
+				t.Errorf("CalculateNextDueDate(from %s) = %s, want %s", tt.from.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
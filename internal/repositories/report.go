@@ -0,0 +1,375 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"renting/internal/models"
+	"time"
+)
+
+// expenseVehiclesCTE links company expenses to the vehicle they were spent on, either
+// directly or through servicing history and reminder acknowledgements
+const expenseVehiclesCTE = `
+	expense_vehicles AS (
+		SELECT ce.expense_id, ce.vehicle_id
+		FROM company_expenses ce
+		WHERE ce.vehicle_id IS NOT NULL
+		UNION
+		SELECT h.expense_id, h.vehicle_id
+		FROM vehicle_servicing_history h
+		WHERE h.expense_id IS NOT NULL
+		UNION
+		SELECT a.expense_id, rm.vehicle_id
+		FROM reminder_acknowledgements a
+		JOIN reminders rm ON rm.id = a.reminder_id
+		WHERE a.expense_id IS NOT NULL
+	)`
+
+// reportBucketsCTE splits [$1, $2] into day, month or year buckets clipped to the range;
+// $3 is the date_trunc unit and $4 the matching interval
+const reportBucketsCTE = `
+	buckets AS (
+		SELECT b::date AS label,
+			GREATEST(b::date, $1::date) AS bucket_start,
+			LEAST((b + $4::interval - interval '1 day')::date, $2::date) AS bucket_end
+		FROM generate_series(date_trunc($3, $1::timestamp), $2::timestamp, $4::interval) b
+	)`
+
+type ReportRepository struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// bucketArgs are the reportBucketsCTE arguments followed by the company as $5
+func bucketArgs(companyID int, rng models.ReportRange) []interface{} {
+	return []interface{}{rng.StartDate, rng.EndDate, rng.GroupBy, "1 " + rng.GroupBy, companyID}
+}
+
+// GetRecognisedRevenue prorates the company's revenue_recognition over the range
+func (r *ReportRepository) GetRecognisedRevenue(companyID int, startDate, endDate time.Time) (float64, error) {
+	var total float64
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(rr.daily_amount * (
+			LEAST(rr.end_date, $2::date) - GREATEST(rr.start_date, $1::date) + 1
+		)), 0)
+		FROM revenue_recognition rr
+		JOIN sales s ON s.sale_id = rr.sale_id
+		JOIN users u ON u.id = s.user_id AND u.company_id = $3
+		WHERE rr.start_date <= $2::date AND rr.end_date >= $1::date
+	`, startDate, endDate, companyID).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query recognised revenue: %v", err)
+	}
+	return total, nil
+}
+
+// GetExpensesByType totals the company's expenses in the range per expense type
+func (r *ReportRepository) GetExpensesByType(companyID int, startDate, endDate time.Time) ([]models.ExpenseTypeTotal, error) {
+	rows, err := r.db.Query(`
+		SELECT ce.expense_type, COALESCE(SUM(ce.amount), 0), COUNT(*)
+		FROM company_expenses ce
+		JOIN users u ON u.id = ce.recorded_by AND u.company_id = $3
+		WHERE ce.approval_status = 'approved' AND ce.expense_date::date BETWEEN $1::date AND $2::date
+		GROUP BY ce.expense_type
+		ORDER BY SUM(ce.amount) DESC
+	`, startDate, endDate, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expenses by type: %v", err)
+	}
+	defer rows.Close()
+
+	totals := []models.ExpenseTypeTotal{}
+	for rows.Next() {
+		var t models.ExpenseTypeTotal
+		if err := rows.Scan(&t.ExpenseType, &t.Amount, &t.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan expense total: %v", err)
+		}
+		totals = append(totals, t)
+	}
+	return totals, rows.Err()
+}
+
+// GetProfitLossSeries returns revenue and expenses per bucket of the range
+func (r *ReportRepository) GetProfitLossSeries(companyID int, rng models.ReportRange) ([]models.ProfitLossBucket, error) {
+	rows, err := r.db.Query(`
+		WITH `+reportBucketsCTE+`
+		SELECT bk.label,
+			COALESCE((
+				SELECT SUM(rr.daily_amount * (LEAST(rr.end_date, bk.bucket_end) - GREATEST(rr.start_date, bk.bucket_start) + 1))
+				FROM revenue_recognition rr
+				JOIN sales s ON s.sale_id = rr.sale_id
+				JOIN users u ON u.id = s.user_id AND u.company_id = $5
+				WHERE rr.start_date <= bk.bucket_end AND rr.end_date >= bk.bucket_start
+			), 0),
+			COALESCE((
+				SELECT SUM(ce.amount)
+				FROM company_expenses ce
+				JOIN users u ON u.id = ce.recorded_by AND u.company_id = $5
+				WHERE ce.approval_status = 'approved' AND ce.expense_date::date BETWEEN bk.bucket_start AND bk.bucket_end
+			), 0)
+		FROM buckets bk
+		ORDER BY bk.label
+	`, bucketArgs(companyID, rng)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profit and loss series: %v", err)
+	}
+	defer rows.Close()
+
+	series := []models.ProfitLossBucket{}
+	for rows.Next() {
+		var b models.ProfitLossBucket
+		if err := rows.Scan(&b.PeriodStart, &b.Revenue, &b.Expenses); err != nil {
+			return nil, fmt.Errorf("failed to scan profit and loss bucket: %v", err)
+		}
+		b.NetProfit = roundCents(b.Revenue - b.Expenses)
+		series = append(series, b)
+	}
+	return series, rows.Err()
+}
+
+// GetVehicleProfits returns the company's recognised revenue and attributable expenses for every
+// vehicle of the company
+func (r *ReportRepository) GetVehicleProfits(companyID int, startDate, endDate time.Time) ([]models.VehicleProfit, error) {
+	rows, err := r.db.Query(`
+		WITH `+expenseVehiclesCTE+`,
+		vehicle_revenue AS (
+			SELECT s.vehicle_id, SUM(rr.daily_amount * (
+				LEAST(rr.end_date, $2::date) - GREATEST(rr.start_date, $1::date) + 1
+			)) AS amount
+			FROM revenue_recognition rr
+			JOIN sales s ON s.sale_id = rr.sale_id
+			JOIN users u ON u.id = s.user_id AND u.company_id = $3
+			WHERE rr.start_date <= $2::date AND rr.end_date >= $1::date
+			GROUP BY s.vehicle_id
+		),
+		vehicle_expenses AS (
+			SELECT ev.vehicle_id, SUM(ce.amount) AS amount
+			FROM company_expenses ce
+			JOIN users u ON u.id = ce.recorded_by AND u.company_id = $3
+			JOIN expense_vehicles ev ON ev.expense_id = ce.expense_id
+			WHERE ce.approval_status = 'approved' AND ce.expense_date::date BETWEEN $1::date AND $2::date
+			GROUP BY ev.vehicle_id
+		)
+		SELECT v.vehicle_id, v.vehicle_name, v.vehicle_registration_number,
+			v.vehicle_type_id, COALESCE(vt.vehicle_type_name, ''),
+			COALESCE(vr.amount, 0), COALESCE(ve.amount, 0)
+		FROM vehicles v
+		LEFT JOIN vehicle_types vt ON vt.vehicle_type_id = v.vehicle_type_id
+		LEFT JOIN vehicle_revenue vr ON vr.vehicle_id = v.vehicle_id
+		LEFT JOIN vehicle_expenses ve ON ve.vehicle_id = v.vehicle_id
+		WHERE `+companyVehicleSQL("$3")+`
+		ORDER BY COALESCE(vr.amount, 0) - COALESCE(ve.amount, 0) DESC, v.vehicle_id
+	`, startDate, endDate, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vehicle profits: %v", err)
+	}
+	defer rows.Close()
+
+	profits := []models.VehicleProfit{}
+	for rows.Next() {
+		var p models.VehicleProfit
+		if err := rows.Scan(&p.VehicleID, &p.VehicleName, &p.VehicleRegistrationNumber,
+			&p.VehicleTypeID, &p.VehicleTypeName, &p.Revenue, &p.Expenses); err != nil {
+			return nil, fmt.Errorf("failed to scan vehicle profit: %v", err)
+		}
+		profits = append(profits, p)
+	}
+	return profits, rows.Err()
+}
+
+// GetCashIn totals verified payments the company received in the range, per payment method.
+// Refunded payments still count here because the money did come in; the refund is a cash-out.
+func (r *ReportRepository) GetCashIn(companyID int, startDate, endDate time.Time) (map[string]float64, error) {
+	rows, err := r.db.Query(`
+		SELECT COALESCE(p.payment_type, 'unknown'), COALESCE(SUM(p.amount_paid), 0)
+		FROM payments p
+		JOIN sales s ON s.sale_id = p.sale_id
+		JOIN users u ON u.id = s.user_id AND u.company_id = $3
+		WHERE p.payment_status IN ('Completed', 'Refunded')
+		AND p.payment_date::date BETWEEN $1::date AND $2::date
+		GROUP BY COALESCE(p.payment_type, 'unknown')
+	`, startDate, endDate, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cash received: %v", err)
+	}
+	defer rows.Close()
+
+	byMethod := map[string]float64{}
+	for rows.Next() {
+		var method string
+		var amount float64
+		if err := rows.Scan(&method, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan cash received: %v", err)
+		}
+		byMethod[method] = amount
+	}
+	return byMethod, rows.Err()
+}
+
+// GetRefunds totals the company's payments refunded in the range
+func (r *ReportRepository) GetRefunds(companyID int, startDate, endDate time.Time) (float64, error) {
+	var total float64
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(p.amount_paid), 0)
+		FROM payments p
+		JOIN sales s ON s.sale_id = p.sale_id
+		JOIN users u ON u.id = s.user_id AND u.company_id = $3
+		WHERE p.payment_status = 'Refunded'
+		AND p.updated_at::date BETWEEN $1::date AND $2::date
+	`, startDate, endDate, companyID).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query refunds: %v", err)
+	}
+	return total, nil
+}
+
+// GetCashFlowSeries returns cash in and out per bucket of the range
+func (r *ReportRepository) GetCashFlowSeries(companyID int, rng models.ReportRange) ([]models.CashFlowBucket, error) {
+	rows, err := r.db.Query(`
+		WITH `+reportBucketsCTE+`,
+		company_payments AS (
+			SELECT p.* FROM payments p
+			JOIN sales s ON s.sale_id = p.sale_id
+			JOIN users u ON u.id = s.user_id AND u.company_id = $5
+		)
+		SELECT bk.label,
+			COALESCE((
+				SELECT SUM(p.amount_paid) FROM company_payments p
+				WHERE p.payment_status IN ('Completed', 'Refunded')
+				AND p.payment_date::date BETWEEN bk.bucket_start AND bk.bucket_end
+			), 0),
+			COALESCE((
+				SELECT SUM(p.amount_paid) FROM company_payments p
+				WHERE p.payment_status = 'Refunded'
+				AND p.updated_at::date BETWEEN bk.bucket_start AND bk.bucket_end
+			), 0) + COALESCE((
+				SELECT SUM(ce.amount) FROM company_expenses ce
+				JOIN users u ON u.id = ce.recorded_by AND u.company_id = $5
+				WHERE ce.approval_status = 'approved' AND ce.expense_date::date BETWEEN bk.bucket_start AND bk.bucket_end
+			), 0)
+		FROM buckets bk
+		ORDER BY bk.label
+	`, bucketArgs(companyID, rng)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cash flow series: %v", err)
+	}
+	defer rows.Close()
+
+	series := []models.CashFlowBucket{}
+	for rows.Next() {
+		var b models.CashFlowBucket
+		if err := rows.Scan(&b.PeriodStart, &b.CashIn, &b.CashOut); err != nil {
+			return nil, fmt.Errorf("failed to scan cash flow bucket: %v", err)
+		}
+		b.NetCashFlow = roundCents(b.CashIn - b.CashOut)
+		series = append(series, b)
+	}
+	return series, rows.Err()
+}
+
+// GetVehicleAnalytics gathers the raw usage and money figures per vehicle of the company; a nil vehicleID
+// returns its whole fleet. Rentals count from the actual delivery and return dates when known, the booked
+// dates otherwise, and only the company's own sales and expenses are counted.
+func (r *ReportRepository) GetVehicleAnalytics(companyID int, startDate, endDate time.Time, vehicleID *int) ([]models.VehicleAnalytics, error) {
+	rows, err := r.db.Query(`
+		WITH `+expenseVehiclesCTE+`,
+		company_sales AS (
+			SELECT s.* FROM sales s
+			JOIN users u ON u.id = s.user_id AND u.company_id = $4
+		),
+		rentals AS (
+			SELECT s.vehicle_id, SUM(GREATEST(0,
+				LEAST(COALESCE(s.actual_date_of_return, s.return_date)::date, $2::date) -
+				GREATEST(COALESCE(s.actual_date_of_delivery, s.date_of_delivery)::date, $1::date) + 1
+			)) AS rented_days
+			FROM company_sales s
+			WHERE s.status <> 'cancelled'
+			AND COALESCE(s.actual_date_of_delivery, s.date_of_delivery)::date <= $2::date
+			AND COALESCE(s.actual_date_of_return, s.return_date)::date >= $1::date
+			GROUP BY s.vehicle_id
+		),
+		bookings AS (
+			SELECT vehicle_id, COUNT(*) AS rental_count, AVG(number_of_days) AS avg_days
+			FROM company_sales
+			WHERE status <> 'cancelled' AND date_of_delivery::date BETWEEN $1::date AND $2::date
+			GROUP BY vehicle_id
+		),
+		downtime AS (
+			SELECT vehicle_id, SUM(GREATEST(0, LEAST(end_date, $2::date) - GREATEST(start_date, $1::date) + 1)) AS days
+			FROM maintenance_blocks
+			WHERE status <> 'cancelled' AND start_date <= $2::date AND end_date >= $1::date
+			GROUP BY vehicle_id
+		),
+		vehicle_revenue AS (
+			SELECT s.vehicle_id, SUM(rr.daily_amount * (
+				LEAST(rr.end_date, $2::date) - GREATEST(rr.start_date, $1::date) + 1
+			)) AS amount
+			FROM revenue_recognition rr
+			JOIN company_sales s ON s.sale_id = rr.sale_id
+			WHERE rr.start_date <= $2::date AND rr.end_date >= $1::date
+			GROUP BY s.vehicle_id
+		),
+		distance AS (
+			SELECT ret.vehicle_id, SUM(GREATEST(ret.km_reading - del.km_reading, 0)) AS km
+			FROM vehicle_usage ret
+			JOIN company_sales s ON s.sale_id = ret.sale_id
+			JOIN vehicle_usage del ON del.sale_id = ret.sale_id
+				AND del.vehicle_id = ret.vehicle_id AND del.record_type = 'delivery'
+			WHERE ret.record_type = 'return' AND ret.recorded_at::date BETWEEN $1::date AND $2::date
+			GROUP BY ret.vehicle_id
+		),
+		servicing AS (
+			SELECT vehicle_id, COUNT(*) AS services, SUM(COALESCE(servicing_cost, 0)) AS cost
+			FROM vehicle_servicing_history
+			WHERE servicing_date::date BETWEEN $1::date AND $2::date
+			GROUP BY vehicle_id
+		),
+		vehicle_expenses AS (
+			SELECT ev.vehicle_id, SUM(ce.amount) AS amount
+			FROM company_expenses ce
+			JOIN users u ON u.id = ce.recorded_by AND u.company_id = $4
+			JOIN expense_vehicles ev ON ev.expense_id = ce.expense_id
+			WHERE ce.approval_status = 'approved' AND ce.expense_date::date BETWEEN $1::date AND $2::date
+			GROUP BY ev.vehicle_id
+		)
+		SELECT v.vehicle_id, v.vehicle_name, v.vehicle_registration_number,
+			v.vehicle_type_id, COALESCE(vt.vehicle_type_name, ''),
+			$2::date - GREATEST(COALESCE(v.created_at::date, $1::date), $1::date) + 1,
+			COALESCE(dt.days, 0), COALESCE(rt.rented_days, 0),
+			COALESCE(vr.amount, 0), COALESCE(b.rental_count, 0), COALESCE(b.avg_days, 0),
+			COALESCE(d.km, 0), COALESCE(sv.services, 0), COALESCE(sv.cost, 0), COALESCE(ve.amount, 0)
+		FROM vehicles v
+		LEFT JOIN vehicle_types vt ON vt.vehicle_type_id = v.vehicle_type_id
+		LEFT JOIN rentals rt ON rt.vehicle_id = v.vehicle_id
+		LEFT JOIN bookings b ON b.vehicle_id = v.vehicle_id
+		LEFT JOIN downtime dt ON dt.vehicle_id = v.vehicle_id
+		LEFT JOIN vehicle_revenue vr ON vr.vehicle_id = v.vehicle_id
+		LEFT JOIN distance d ON d.vehicle_id = v.vehicle_id
+		LEFT JOIN servicing sv ON sv.vehicle_id = v.vehicle_id
+		LEFT JOIN vehicle_expenses ve ON ve.vehicle_id = v.vehicle_id
+		WHERE ($3::int IS NULL OR v.vehicle_id = $3)
+		AND COALESCE(v.created_at::date, $1::date) <= $2::date
+		AND `+companyVehicleSQL("$4")+`
+		ORDER BY v.vehicle_id
+	`, startDate, endDate, vehicleID, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vehicle analytics: %v", err)
+	}
+	defer rows.Close()
+
+	analytics := []models.VehicleAnalytics{}
+	for rows.Next() {
+		var a models.VehicleAnalytics
+		if err := rows.Scan(&a.VehicleID, &a.VehicleName, &a.VehicleRegistrationNumber,
+			&a.VehicleTypeID, &a.VehicleTypeName, &a.PeriodDays, &a.DowntimeDays, &a.RentedDays,
+			&a.Revenue, &a.RentalCount, &a.AverageRentalDays, &a.KmDriven, &a.ServicingCount,
+			&a.MaintenanceCost, &a.Expenses); err != nil {
+			return nil, fmt.Errorf("failed to scan vehicle analytics: %v", err)
+		}
+		analytics = append(analytics, a)
+	}
+	return analytics, rows.Err()
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"renting/internal/models"
@@ -20,23 +21,24 @@ func (r *ReturnRepository) CreateReturn(sale models.Sale) (int, error) {
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin transaction: %v", err)
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-			return
-		}
-	}()
+	// Rollback is a no-op once the transaction has committed
+	defer tx.Rollback()
 
 	// Insert sales charges and calculate total additional charge
 	var totalCharge float64
 	for _, charge := range sale.SalesCharges {
-		_, err := tx.Exec(`
+		var chargeID int
+		err := tx.QueryRow(`
             INSERT INTO sales_charges (sale_id, charge_type, amount)
             VALUES ($1, $2, $3)
-        `, sale.SaleID, charge.ChargeType, charge.Amount)
+            RETURNING charge_id
+        `, sale.SaleID, charge.ChargeType, charge.Amount).Scan(&chargeID)
 		if err != nil {
 			return 0, fmt.Errorf("failed to insert sales charge for saleID %d, chargeType %s: %v", sale.SaleID, charge.ChargeType, err)
 		}
+		if err := postSalesCharge(context.Background(), tx, chargeID, &sale.UserID); err != nil {
+			return 0, err
+		}
 		totalCharge += charge.Amount // Accumulate total charge
 	}
 
@@ -99,14 +101,19 @@ func (r *ReturnRepository) CreateReturn(sale models.Sale) (int, error) {
 			}
 
 			if !servicingExists {
-				// Initialize servicing record if it doesn't exist
+				// Initialize servicing record from the vehicle type's interval if it doesn't exist
+				intervalKm, intervalDays, err := servicingIntervalForVehicle(tx, usage.VehicleID, sale.UserID)
+				if err != nil {
+					return 0, err
+				}
 				_, err = tx.Exec(`
 					INSERT INTO vehicle_servicing (
 						vehicle_id, current_km, next_servicing_km, 
-						servicing_interval_km, is_servicing_due, last_serviced_at,
-						status
-					) VALUES ($1, $2, $3, $4, $5, $6, $7)
-				`, usage.VehicleID, usage.KmReading, usage.KmReading+5000, 5000, false, usage.RecordedAt, "pending")
+						servicing_interval_km, servicing_interval_days, next_servicing_date,
+						is_servicing_due, last_serviced_at, status
+					) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				`, usage.VehicleID, usage.KmReading, usage.KmReading+intervalKm, intervalKm, intervalDays,
+					nextServicingDate(usage.RecordedAt, intervalDays), false, usage.RecordedAt, "pending")
 				if err != nil {
 					return 0, fmt.Errorf("failed to initialize servicing record: %v", err)
 				}
@@ -119,11 +126,11 @@ func (r *ReturnRepository) CreateReturn(sale models.Sale) (int, error) {
 					UPDATE vehicle_servicing
 					SET current_km = $1,
 						is_servicing_due = CASE 
-							WHEN $1 >= next_servicing_km THEN true 
+							WHEN $1 >= next_servicing_km OR next_servicing_date <= CURRENT_DATE THEN true 
 							ELSE is_servicing_due 
 						END,
 						status = CASE
-							WHEN $1 >= next_servicing_km AND status = 'pending' THEN 'in_progress'
+							WHEN ($1 >= next_servicing_km OR next_servicing_date <= CURRENT_DATE) AND status = 'pending' THEN 'in_progress'
 							ELSE status
 						END,
 						updated_at = CURRENT_TIMESTAMP
@@ -187,16 +194,29 @@ func (r *ReturnRepository) CreateReturn(sale models.Sale) (int, error) {
 
 	// Insert payments with sale_type
 	for _, payment := range sale.Payments {
-		_, err := tx.Exec(`
+		paymentType, err := resolvePaymentMethod(context.Background(), tx, sale.SaleID, payment.PaymentType)
+		if err != nil {
+			return 0, err
+		}
+
+		var paymentID int
+		err = tx.QueryRow(`
             INSERT INTO payments (
                 sale_id, amount_paid, payment_date, verified_by_admin, 
                 payment_type, payment_status, remark, user_id, sale_type
             ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+            RETURNING payment_id
         `, sale.SaleID, payment.AmountPaid, payment.PaymentDate, payment.VerifiedByAdmin,
-			payment.PaymentType, payment.PaymentStatus, payment.Remark, sale.UserID, saleType)
+			paymentType, payment.PaymentStatus, payment.Remark, sale.UserID, saleType).Scan(&paymentID)
 		if err != nil {
 			return 0, fmt.Errorf("failed to insert payment for saleID %d, amountPaid %.2f: %v", sale.SaleID, payment.AmountPaid, err)
 		}
+		if err := postPaymentReceipt(context.Background(), tx, paymentID, &sale.UserID); err != nil {
+			return 0, err
+		}
+		if err := issuePaymentReceipt(context.Background(), tx, paymentID, &sale.UserID); err != nil {
+			return 0, err
+		}
 	}
 
 	// Update sales status, vehicle status, and delivery/return dates based on record_type
@@ -251,6 +271,41 @@ func (r *ReturnRepository) CreateReturn(sale models.Sale) (int, error) {
 		if err != nil {
 			return 0, fmt.Errorf("failed to update vehicle status for vehicleID %d: %v", usage.VehicleID, err)
 		}
+
+		// The vehicle is now wherever it was dropped off, which differs from pickup on a one-way rental
+		if usage.RecordType == "return" {
+			_, err = tx.Exec(`
+				UPDATE vehicles v
+				SET current_branch_id = s.dropoff_branch_id
+				FROM sales s
+				WHERE s.sale_id = $1 AND v.vehicle_id = $2 AND s.dropoff_branch_id IS NOT NULL
+			`, sale.SaleID, usage.VehicleID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to move vehicle %d to its drop-off branch: %v", usage.VehicleID, err)
+			}
+		}
+	}
+
+	// Return charges and payments change what is still owed
+	if _, err := recomputeSaleBalance(context.Background(), tx, sale.SaleID); err != nil {
+		return 0, err
+	}
+
+	// Charges, payments and the actual delivery or return date all feed the recognition schedule
+	if err := recomputeRevenueRecognition(context.Background(), tx, sale.SaleID); err != nil {
+		return 0, err
+	}
+
+	// Notify subscribers once the vehicle is back
+	if saleType == "return" {
+		err = enqueueSaleWebhookEvent(tx, sale.SaleID, models.WebhookEventSaleReturned, map[string]interface{}{
+			"charges":      sale.SalesCharges,
+			"total_charge": totalCharge,
+			"payments":     sale.Payments,
+		})
+		if err != nil {
+			return 0, err
+		}
 	}
 
 	// Commit the transaction
@@ -1,8 +1,10 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"renting/internal/models"
 	"time"
 )
 
@@ -16,8 +18,12 @@ func NewRevenueRepository(db *sql.DB) *RevenueRepository {
 
 // RevenueFilter defines filter options
 type RevenueFilter struct {
-	Period string    // "daily", "monthly", "yearly"
-	Date   time.Time // Specific date to filter (optional)
+	CompanyID   int       // Only sales of this company's users count
+	Period      string    // "daily", "weekly", "monthly", "yearly", "custom"
+	Date        time.Time // Specific date to filter (optional)
+	StartDate   time.Time // Range start for the custom period
+	EndDate     time.Time // Range end for the custom period
+	RecognizeAt string    // "prorated" (default), "start" or "end"
 }
 
 // MonthlyRevenue represents revenue data for a specific month
@@ -26,65 +32,158 @@ type MonthlyRevenue struct {
 	Amount float64
 }
 
-// GetTotalRevenue calculates total revenue based on the filter
-func (r *RevenueRepository) GetTotalRevenue(filter RevenueFilter) (float64, error) {
-	var query string
-	var args []interface{}
-	today := time.Now().Truncate(24 * time.Hour)
+// saleRevenueCTE nets the recognition rows of each sale of the company in companyExpr and dates
+// the result on the rental's actual (else planned) start and end, for the start and end
+// recognition modes
+func saleRevenueCTE(companyExpr string) string {
+	return `
+	sale_revenue AS (
+		SELECT rr.sale_id, SUM(rr.total_amount) AS amount,
+			COALESCE(s.actual_date_of_delivery, s.date_of_delivery)::date AS start_date,
+			GREATEST(COALESCE(s.actual_date_of_return, s.return_date)::date,
+				COALESCE(s.actual_date_of_delivery, s.date_of_delivery)::date) AS end_date
+		FROM revenue_recognition rr
+		JOIN sales s ON s.sale_id = rr.sale_id
+		JOIN users u ON u.id = s.user_id AND u.company_id = ` + companyExpr + `
+		GROUP BY rr.sale_id, s.actual_date_of_delivery, s.date_of_delivery, s.actual_date_of_return, s.return_date
+	)`
+}
+
+// recognitionDateColumn is the sale_revenue date a recognition mode books revenue on
+func recognitionDateColumn(mode string) (string, error) {
+	switch mode {
+	case models.RecognizeAtStart:
+		return "start_date", nil
+	case models.RecognizeAtEnd:
+		return "end_date", nil
+	default:
+		return "", fmt.Errorf("invalid recognize_at: %s", mode)
+	}
+}
+
+// filterRange resolves the inclusive date range a filter covers
+func filterRange(filter RevenueFilter) (time.Time, time.Time, error) {
+	date := filter.Date
+	if date.IsZero() {
+		date = time.Now().Truncate(24 * time.Hour)
+	}
 
 	switch filter.Period {
 	case "daily":
-		// Default to today if no date provided
-		date := filter.Date
-		if filter.Date.IsZero() {
-			date = today
-		}
-		// Sum daily_amount for sales active on the given date
-		query = `
-            SELECT COALESCE(SUM(daily_amount), 0)
-            FROM revenue_recognition
-            WHERE $1 BETWEEN start_date AND end_date`
-		args = []interface{}{date}
+		return date, date, nil
+	case "weekly":
+		weekStart := date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+		return weekStart, weekStart.AddDate(0, 0, 6), nil
 	case "monthly":
-		// Default to current month
-		date := filter.Date
-		if filter.Date.IsZero() {
-			date = today
-		}
 		monthStart := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
-		monthEnd := monthStart.AddDate(0, 1, -1)
-		// Sum prorated daily_amount for sales active within the month
-		query = `
-            SELECT COALESCE(SUM(daily_amount * (
-                LEAST(end_date, $2) - GREATEST(start_date, $1) + 1
-            )), 0)
-            FROM revenue_recognition
-            WHERE start_date <= $2 AND end_date >= $1`
-		args = []interface{}{monthStart, monthEnd}
+		return monthStart, monthStart.AddDate(0, 1, -1), nil
 	case "yearly":
-		date := filter.Date
-		if filter.Date.IsZero() {
-			date = today
-		}
 		yearStart := time.Date(date.Year(), 1, 1, 0, 0, 0, 0, date.Location())
-		yearEnd := time.Date(date.Year(), 12, 31, 0, 0, 0, 0, date.Location())
+		return yearStart, time.Date(date.Year(), 12, 31, 0, 0, 0, 0, date.Location()), nil
+	case "custom":
+		if filter.StartDate.IsZero() || filter.EndDate.IsZero() || filter.EndDate.Before(filter.StartDate) {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid custom range")
+		}
+		return filter.StartDate, filter.EndDate, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period: %s", filter.Period)
+	}
+}
+
+// GetTotalRevenue calculates total revenue based on the filter
+func (r *RevenueRepository) GetTotalRevenue(filter RevenueFilter) (float64, error) {
+	startDate, endDate, err := filterRange(filter)
+	if err != nil {
+		return 0, err
+	}
+	filter.StartDate, filter.EndDate = startDate, endDate
+	return r.GetRevenueBetween(filter)
+}
+
+// GetRevenueBetween totals the company's revenue recognised in [filter.StartDate, filter.EndDate].
+// Prorated revenue is spread evenly over the rental days; start and end book a sale's whole amount
+// on one day.
+func (r *RevenueRepository) GetRevenueBetween(filter RevenueFilter) (float64, error) {
+	var query string
+	if filter.RecognizeAt == "" || filter.RecognizeAt == models.RecognizeAtProrated {
+		// Sum prorated daily_amount for sales active within the range
 		query = `
-			SELECT COALESCE(SUM(daily_amount * (
-				LEAST(end_date, $2) - GREATEST(start_date, $1) + 1
+			SELECT COALESCE(SUM(rr.daily_amount * (
+				LEAST(rr.end_date, $2::date) - GREATEST(rr.start_date, $1::date) + 1
 			)), 0)
-			FROM revenue_recognition
-			WHERE start_date <= $2 AND end_date >= $1`
-		args = []interface{}{yearStart, yearEnd}
-	default:
-		return 0, fmt.Errorf("invalid period: %s", filter.Period)
+			FROM revenue_recognition rr
+			JOIN sales s ON s.sale_id = rr.sale_id
+			JOIN users u ON u.id = s.user_id AND u.company_id = $3
+			WHERE rr.start_date <= $2::date AND rr.end_date >= $1::date`
+	} else {
+		column, err := recognitionDateColumn(filter.RecognizeAt)
+		if err != nil {
+			return 0, err
+		}
+		query = `
+			WITH ` + saleRevenueCTE("$3") + `
+			SELECT COALESCE(SUM(amount), 0)
+			FROM sale_revenue
+			WHERE ` + column + ` BETWEEN $1::date AND $2::date`
 	}
 
 	var total float64
-	err := r.db.QueryRow(query, args...).Scan(&total)
-	if err != nil {
+	if err := r.db.QueryRow(query, filter.StartDate, filter.EndDate, filter.CompanyID).Scan(&total); err != nil {
 		return 0, fmt.Errorf("failed to query revenue: %v", err)
 	}
-	return total, nil
+	return roundCents(total), nil
+}
+
+// GetRevenueSeries returns the company's revenue per day or week bucket of [filter.StartDate,
+// filter.EndDate]; the first and last weeks are clipped to the range
+func (r *RevenueRepository) GetRevenueSeries(filter RevenueFilter, interval string) ([]models.RevenuePoint, error) {
+	if interval != "day" && interval != "week" {
+		return nil, fmt.Errorf("invalid interval: %s", interval)
+	}
+
+	var amount string
+	ctes := reportBucketsCTE
+	if filter.RecognizeAt == "" || filter.RecognizeAt == models.RecognizeAtProrated {
+		amount = `
+			SELECT SUM(rr.daily_amount * (LEAST(rr.end_date, bk.bucket_end) - GREATEST(rr.start_date, bk.bucket_start) + 1))
+			FROM revenue_recognition rr
+			JOIN sales s ON s.sale_id = rr.sale_id
+			JOIN users u ON u.id = s.user_id AND u.company_id = $5
+			WHERE rr.start_date <= bk.bucket_end AND rr.end_date >= bk.bucket_start`
+	} else {
+		column, err := recognitionDateColumn(filter.RecognizeAt)
+		if err != nil {
+			return nil, err
+		}
+		ctes += "," + saleRevenueCTE("$5")
+		amount = `
+			SELECT SUM(sr.amount)
+			FROM sale_revenue sr
+			WHERE sr.` + column + ` BETWEEN bk.bucket_start AND bk.bucket_end`
+	}
+
+	rows, err := r.db.Query(`
+		WITH `+ctes+`
+		SELECT bk.bucket_start, bk.bucket_end, COALESCE((`+amount+`
+		), 0)
+		FROM buckets bk
+		ORDER BY bk.label
+	`, filter.StartDate, filter.EndDate, interval, "1 "+interval, filter.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revenue series: %v", err)
+	}
+	defer rows.Close()
+
+	series := []models.RevenuePoint{}
+	for rows.Next() {
+		var point models.RevenuePoint
+		if err := rows.Scan(&point.PeriodStart, &point.PeriodEnd, &point.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan revenue series row: %v", err)
+		}
+		point.Amount = roundCents(point.Amount)
+		series = append(series, point)
+	}
+	return series, rows.Err()
 }
 
 // GetMonthlyRevenue returns monthly revenue data within a date range
@@ -135,3 +234,62 @@ func (r *RevenueRepository) GetMonthlyRevenue(startDate, endDate time.Time) ([]M
 
 	return results, nil
 }
+
+// RecomputeSaleRecognition rebuilds the recognition schedule of one sale
+func (r *RevenueRepository) RecomputeSaleRecognition(saleID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSaleIDs returns every sale id, oldest first
+func (r *RevenueRepository) GetSaleIDs() ([]int, error) {
+	rows, err := r.db.Query(`SELECT sale_id FROM sales ORDER BY sale_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sales: %v", err)
+	}
+	defer rows.Close()
+
+	var saleIDs []int
+	for rows.Next() {
+		var saleID int
+		if err := rows.Scan(&saleID); err != nil {
+			return nil, fmt.Errorf("failed to scan sale id: %v", err)
+		}
+		saleIDs = append(saleIDs, saleID)
+	}
+	return saleIDs, rows.Err()
+}
+
+// GetRecognitionSchedule returns the recognition rows of a sale in date order
+func (r *RevenueRepository) GetRecognitionSchedule(saleID int) (*models.RevenueRecognitionSchedule, error) {
+	rows, err := r.db.Query(`
+		SELECT sale_id, total_amount, daily_amount, start_date, end_date, entry_type, updated_at
+		FROM revenue_recognition
+		WHERE sale_id = $1
+		ORDER BY start_date, updated_at`, saleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revenue recognition: %v", err)
+	}
+	defer rows.Close()
+
+	schedule := &models.RevenueRecognitionSchedule{SaleID: saleID, Entries: []models.RevenueRecognitionEntry{}}
+	for rows.Next() {
+		var entry models.RevenueRecognitionEntry
+		if err := rows.Scan(&entry.SaleID, &entry.TotalAmount, &entry.DailyAmount, &entry.StartDate,
+			&entry.EndDate, &entry.EntryType, &entry.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revenue recognition: %v", err)
+		}
+		schedule.Recognised += entry.TotalAmount
+		schedule.Entries = append(schedule.Entries, entry)
+	}
+	schedule.Recognised = roundCents(schedule.Recognised)
+	return schedule, rows.Err()
+}
@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"renting/internal/models"
+	"time"
+)
+
+// recomputeRevenueRecognition rebuilds the recognition schedule of a sale from its current
+// state. The amount to recognise is what has been collected in payments, capped at the rental
+// amount plus charges (discounts are deducted), and nothing for a cancelled sale. A refund puts
+// the money back on the receivable rather than undoing the rental, so refunded payments still
+// count as collected here. It is spread evenly over the actual delivery to actual return dates, falling back to
+// the planned dates while the rental is still open.
+//
+// Days inside the closed accounting periods of the sale's company are never rewritten; see
+// planRecognitionRows.
+func recomputeRevenueRecognition(ctx context.Context, conn ledgerConn, saleID int) error {
+	var status string
+	var totalAmount, charges, collected float64
+	var startDate, plannedEnd time.Time
+	var actualStart, actualEnd sql.NullTime
+	var companyID *int
+	err := conn.QueryRowContext(ctx, `
+		SELECT s.status, s.total_amount, s.date_of_delivery, s.return_date,
+			s.actual_date_of_delivery, s.actual_date_of_return, u.company_id,
+			COALESCE((SELECT SUM(`+signedChargeSQL("c")+`) FROM sales_charges c WHERE c.sale_id = s.sale_id), 0),
+			COALESCE((SELECT SUM(amount_paid) FROM payments WHERE sale_id = s.sale_id AND payment_status IN ('Completed', 'Refunded')), 0)
+		FROM sales s
+		LEFT JOIN users u ON u.id = s.user_id
+		WHERE s.sale_id = $1
+	`, saleID).Scan(&status, &totalAmount, &startDate, &plannedEnd, &actualStart, &actualEnd, &companyID, &charges, &collected)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sale %d for revenue recognition: %v", saleID, err)
+	}
+
+	amount := math.Min(collected, totalAmount+charges)
+	if status == "cancelled" || amount < 0 {
+		amount = 0
+	}
+	endDate := plannedEnd
+	if actualStart.Valid {
+		startDate = actualStart.Time
+	}
+	if actualEnd.Valid {
+		endDate = actualEnd.Time
+	}
+	startDate = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+	if endDate.Before(startDate) {
+		endDate = startDate
+	}
+
+	var lastClosed sql.NullTime
+	err = conn.QueryRowContext(ctx, `
+		SELECT MAX(end_date) FROM accounting_periods WHERE company_id = $1 AND status = 'closed'
+	`, companyID).Scan(&lastClosed)
+	if err != nil {
+		return fmt.Errorf("failed to check accounting periods: %v", err)
+	}
+	var closedThrough *time.Time
+	if lastClosed.Valid {
+		closedThrough = &lastClosed.Time
+	}
+
+	// Rows that only touch open days can go; with no closed months that is every row
+	_, err = conn.ExecContext(ctx, `
+		DELETE FROM revenue_recognition WHERE sale_id = $1 AND ($2::date IS NULL OR start_date > $2::date)
+	`, saleID, closedThrough)
+	if err != nil {
+		return fmt.Errorf("failed to clear revenue recognition for sale %d: %v", saleID, err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT total_amount, start_date, end_date, entry_type
+		FROM revenue_recognition
+		WHERE sale_id = $1
+		ORDER BY id
+	`, saleID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch revenue recognition for sale %d: %v", saleID, err)
+	}
+	var kept []recognitionRow
+	for rows.Next() {
+		var row recognitionRow
+		if err := rows.Scan(&row.Amount, &row.StartDate, &row.EndDate, &row.EntryType); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan revenue recognition: %v", err)
+		}
+		kept = append(kept, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, row := range planRecognitionRows(amount, startDate, endDate, closedThrough, kept) {
+		if err := insertRecognitionRow(ctx, conn, saleID, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recognitionRow is one row of a recognition schedule, spread evenly from StartDate to EndDate
+type recognitionRow struct {
+	Amount    float64
+	StartDate time.Time
+	EndDate   time.Time
+	EntryType string
+}
+
+func (row recognitionRow) days() int {
+	return int(row.EndDate.Sub(row.StartDate).Hours()/24+0.5) + 1
+}
+
+// recognisedThrough is the part of the row that falls on or before date
+func (row recognitionRow) recognisedThrough(date time.Time) float64 {
+	if date.Before(row.StartDate) {
+		return 0
+	}
+	if !date.Before(row.EndDate) {
+		return row.Amount
+	}
+	covered := recognitionRow{StartDate: row.StartDate, EndDate: date}
+	return row.Amount / float64(row.days()) * float64(covered.days())
+}
+
+// planRecognitionRows works out the rows to add to a schedule so that it recognises amount over
+// startDate to endDate. closedThrough is the last closed day of the company's books, or nil when
+// none is closed, and kept are the rows already in the schedule that start on or before it.
+// Kept rows are never changed: the part of each that runs past closedThrough is cancelled by a
+// reversal row, and what the closed days have not recognised is spread over the open days of the
+// rental, or booked on the first open day when the rental lies entirely in closed months.
+func planRecognitionRows(amount float64, startDate, endDate time.Time, closedThrough *time.Time, kept []recognitionRow) []recognitionRow {
+	var planned []recognitionRow
+	add := func(row recognitionRow) {
+		row.Amount = roundCents(row.Amount)
+		if math.Abs(row.Amount) >= 0.005 {
+			planned = append(planned, row)
+		}
+	}
+
+	if closedThrough == nil {
+		add(recognitionRow{Amount: amount, StartDate: startDate, EndDate: endDate, EntryType: models.RecognitionEntrySchedule})
+		return planned
+	}
+
+	firstOpen := closedThrough.AddDate(0, 0, 1)
+	var recognisedClosed float64
+	for _, row := range kept {
+		closedPart := row.recognisedThrough(*closedThrough)
+		recognisedClosed += closedPart
+		if row.EndDate.After(*closedThrough) {
+			add(recognitionRow{
+				Amount:    -(row.Amount - closedPart),
+				StartDate: firstOpen,
+				EndDate:   row.EndDate,
+				EntryType: models.RecognitionEntryReversal,
+			})
+		}
+	}
+
+	remaining := amount - recognisedClosed
+	if endDate.Before(firstOpen) {
+		add(recognitionRow{Amount: remaining, StartDate: firstOpen, EndDate: firstOpen, EntryType: models.RecognitionEntryAdjustment})
+		return planned
+	}
+	if startDate.Before(firstOpen) {
+		startDate = firstOpen
+	}
+	add(recognitionRow{Amount: remaining, StartDate: startDate, EndDate: endDate, EntryType: models.RecognitionEntrySchedule})
+	return planned
+}
+
+func insertRecognitionRow(ctx context.Context, conn ledgerConn, saleID int, row recognitionRow) error {
+	_, err := conn.ExecContext(ctx, `
+		INSERT INTO revenue_recognition (sale_id, total_amount, start_date, end_date, entry_type, updated_at)
+		VALUES ($1, $2, $3::date, $4::date, $5, NOW())
+	`, saleID, row.Amount, row.StartDate, row.EndDate, row.EntryType)
+	if err != nil {
+		return fmt.Errorf("failed to insert revenue recognition for sale %d: %v", saleID, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"renting/internal/models"
+)
+
+func TestPlanRecognitionRows(t *testing.T) {
+	jan31 := ymd(2024, 1, 31)
+	feb29 := ymd(2024, 2, 29)
+	schedule := func(amount float64, start, end time.Time) recognitionRow {
+		return recognitionRow{Amount: amount, StartDate: start, EndDate: end, EntryType: models.RecognitionEntrySchedule}
+	}
+
+	tests := []struct {
+		name          string
+		amount        float64
+		start, end    time.Time
+		closedThrough *time.Time
+		kept          []recognitionRow
+		want          []recognitionRow
+	}{
+		{
+			name:   "no closed periods replaces the schedule",
+			amount: 400, start: ymd(2024, 1, 30), end: ymd(2024, 2, 2),
+			want: []recognitionRow{schedule(400, ymd(2024, 1, 30), ymd(2024, 2, 2))},
+		},
+		{
+			name:   "nothing to recognise",
+			amount: 0, start: ymd(2024, 1, 30), end: ymd(2024, 2, 2),
+		},
+		{
+			name:   "rental straddling the close is reversed past it and respread",
+			amount: 400, start: ymd(2024, 1, 30), end: ymd(2024, 2, 2),
+			closedThrough: &jan31,
+			kept:          []recognitionRow{schedule(300, ymd(2024, 1, 30), ymd(2024, 2, 2))},
+			want: []recognitionRow{
+				{Amount: -150, StartDate: ymd(2024, 2, 1), EndDate: ymd(2024, 2, 2), EntryType: models.RecognitionEntryReversal},
+				schedule(250, ymd(2024, 2, 1), ymd(2024, 2, 2)),
+			},
+		},
+		{
+			name:   "early return moves the remainder into the open days",
+			amount: 300, start: ymd(2024, 1, 30), end: ymd(2024, 2, 1),
+			closedThrough: &jan31,
+			kept:          []recognitionRow{schedule(300, ymd(2024, 1, 30), ymd(2024, 2, 2))},
+			want: []recognitionRow{
+				{Amount: -150, StartDate: ymd(2024, 2, 1), EndDate: ymd(2024, 2, 2), EntryType: models.RecognitionEntryReversal},
+				schedule(150, ymd(2024, 2, 1), ymd(2024, 2, 1)),
+			},
+		},
+		{
+			name:   "late change to a rental in closed months is an adjustment",
+			amount: 120, start: ymd(2024, 1, 10), end: ymd(2024, 1, 11),
+			closedThrough: &jan31,
+			kept:          []recognitionRow{schedule(100, ymd(2024, 1, 10), ymd(2024, 1, 11))},
+			want: []recognitionRow{
+				{Amount: 20, StartDate: ymd(2024, 2, 1), EndDate: ymd(2024, 2, 1), EntryType: models.RecognitionEntryAdjustment},
+			},
+		},
+		{
+			name:   "unchanged rental in closed months adds nothing",
+			amount: 100, start: ymd(2024, 1, 10), end: ymd(2024, 1, 11),
+			closedThrough: &jan31,
+			kept:          []recognitionRow{schedule(100, ymd(2024, 1, 10), ymd(2024, 1, 11))},
+		},
+		{
+			name:   "second close keeps the rows of the first",
+			amount: 620, start: ymd(2024, 1, 1), end: ymd(2024, 3, 1),
+			closedThrough: &feb29,
+			kept: []recognitionRow{
+				schedule(610, ymd(2024, 1, 1), ymd(2024, 3, 1)),
+				{Amount: -300, StartDate: ymd(2024, 2, 1), EndDate: ymd(2024, 3, 1), EntryType: models.RecognitionEntryReversal},
+				schedule(300, ymd(2024, 2, 1), ymd(2024, 3, 1)),
+			},
+			want: []recognitionRow{
+				{Amount: -10, StartDate: ymd(2024, 3, 1), EndDate: ymd(2024, 3, 1), EntryType: models.RecognitionEntryReversal},
+				{Amount: 10, StartDate: ymd(2024, 3, 1), EndDate: ymd(2024, 3, 1), EntryType: models.RecognitionEntryReversal},
+				{Amount: -10, StartDate: ymd(2024, 3, 1), EndDate: ymd(2024, 3, 1), EntryType: models.RecognitionEntryReversal},
+				schedule(20, ymd(2024, 3, 1), ymd(2024, 3, 1)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planRecognitionRows(tt.amount, tt.start, tt.end, tt.closedThrough, tt.kept)
+			if len(got) != len(tt.want) {
+				t.Fatalf("planRecognitionRows() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Amount != tt.want[i].Amount || !got[i].StartDate.Equal(tt.want[i].StartDate) ||
+					!got[i].EndDate.Equal(tt.want[i].EndDate) || got[i].EntryType != tt.want[i].EntryType {
+					t.Errorf("row %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"renting/internal/models"
+)
+
+var ErrSaleNotFound = errors.New("sale not found")
+
+type SaleBalanceRepository struct {
+	db *sql.DB
+}
+
+func NewSaleBalanceRepository(db *sql.DB) *SaleBalanceRepository {
+	return &SaleBalanceRepository{db: db}
+}
+
+// saleBalance works out what the sale is owed and what has been paid against it. Charges come
+// from sales_charges signed by signedChargeAmount; those that reduce the amount due are reported
+// as discounts. Only completed payments count as paid: a refunded payment is owed again.
+func saleBalance(ctx context.Context, conn ledgerConn, saleID int) (*models.SaleBalance, error) {
+	b := models.SaleBalance{SaleID: saleID}
+	err := conn.QueryRowContext(ctx, `
+		SELECT COALESCE(s.total_amount, 0),
+			COALESCE((SELECT SUM(`+signedChargeSQL("c")+`) FROM sales_charges c
+				WHERE c.sale_id = s.sale_id AND `+signedChargeSQL("c")+` > 0), 0),
+			COALESCE((SELECT -SUM(`+signedChargeSQL("c")+`) FROM sales_charges c
+				WHERE c.sale_id = s.sale_id AND `+signedChargeSQL("c")+` < 0), 0),
+			COALESCE(SUM(p.amount_paid) FILTER (WHERE p.payment_status = 'Completed'), 0),
+			COALESCE(SUM(p.amount_paid) FILTER (WHERE p.payment_status = 'Pending'), 0),
+			COALESCE(SUM(p.amount_paid) FILTER (WHERE p.payment_status = 'Refunded'), 0)
+		FROM sales s
+		LEFT JOIN payments p ON p.sale_id = s.sale_id
+		WHERE s.sale_id = $1
+		GROUP BY s.sale_id
+	`, saleID).Scan(&b.TotalAmount, &b.Charges, &b.Discounts, &b.PaidAmount, &b.PendingAmount, &b.RefundedAmount)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", ErrSaleNotFound, saleID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch balance of sale %d: %v", saleID, err)
+	}
+
+	b.AmountDue = roundCents(b.TotalAmount + b.Charges - b.Discounts)
+	b.Outstanding = roundCents(math.Max(b.AmountDue-b.PaidAmount, 0))
+	switch {
+	case b.PaidAmount > 0 && b.Outstanding == 0:
+		b.PaymentStatus = models.SalePaymentPaid
+	case b.PaidAmount > 0:
+		b.PaymentStatus = models.SalePaymentPartial
+	default:
+		b.PaymentStatus = models.SalePaymentUnpaid
+	}
+	return &b, nil
+}
+
+// recomputeSaleBalance brings the sale's payment_status in line with its balance. Every change to
+// a sale's amount, charges or payments calls it inside the same transaction.
+func recomputeSaleBalance(ctx context.Context, conn ledgerConn, saleID int) (*models.SaleBalance, error) {
+	b, err := saleBalance(ctx, conn, saleID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.ExecContext(ctx, `
+		UPDATE sales
+		SET payment_status = $1, updated_at = NOW()
+		WHERE sale_id = $2 AND payment_status IS DISTINCT FROM $1
+	`, b.PaymentStatus, saleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update payment status of sale %d: %v", saleID, err)
+	}
+	return b, nil
+}
+
+// GetSaleBalance returns the balance of a sale of the company; sales of other companies are not found
+func (r *SaleBalanceRepository) GetSaleBalance(companyID, saleID int) (*models.SaleBalance, error) {
+	var owned bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM sales s JOIN users u ON u.id = s.user_id
+			WHERE s.sale_id = $1 AND u.company_id = $2
+		)
+	`, saleID, companyID).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sale %d: %v", saleID, err)
+	}
+	if !owned {
+		return nil, fmt.Errorf("%w: %d", ErrSaleNotFound, saleID)
+	}
+	return saleBalance(context.Background(), r.db, saleID)
+}
+
+// RecomputeSaleBalance repairs the payment status of one sale and reports whether it changed
+func (r *SaleBalanceRepository) RecomputeSaleBalance(saleID int) (*models.SaleBalance, bool, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	var previous sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT payment_status FROM sales WHERE sale_id = $1 FOR UPDATE`, saleID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return nil, false, fmt.Errorf("%w: %d", ErrSaleNotFound, saleID)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to lock sale %d: %v", saleID, err)
+	}
+
+	b, err := recomputeSaleBalance(ctx, tx, saleID)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return b, previous.String != b.PaymentStatus, nil
+}
+
+// GetSaleIDs lists every sale, oldest first, optionally only those of one company
+func (r *SaleBalanceRepository) GetSaleIDs(companyID int) ([]int, error) {
+	rows, err := r.db.Query(`
+		SELECT s.sale_id
+		FROM sales s
+		JOIN users u ON u.id = s.user_id
+		WHERE $1 = 0 OR u.company_id = $1
+		ORDER BY s.sale_id
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sales: %v", err)
+	}
+	defer rows.Close()
+
+	var saleIDs []int
+	for rows.Next() {
+		var saleID int
+		if err := rows.Scan(&saleID); err != nil {
+			return nil, fmt.Errorf("failed to scan sale id: %v", err)
+		}
+		saleIDs = append(saleIDs, saleID)
+	}
+	return saleIDs, rows.Err()
+}
@@ -1,11 +1,15 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"renting/internal/models"
 )
 
+var ErrSalesChargeNotFound = errors.New("sales charge not found")
+
 type SaleChargeRepository struct {
 	db *sql.DB
 }
@@ -14,44 +18,108 @@ func NewSaleChargeRepository(db *sql.DB) *SaleChargeRepository {
 	return &SaleChargeRepository{db: db}
 }
 
+func (r *SaleChargeRepository) AddSalesCharge(saleID int, charge models.SalesCharge) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var chargeID int
+	err = tx.QueryRow(`
+		INSERT INTO sales_charges (sale_id, charge_type, amount)
+		VALUES ($1, $2, $3)
+		RETURNING charge_id
+	`, saleID, charge.ChargeType, charge.Amount).Scan(&chargeID)
+	if err != nil {
+		return fmt.Errorf("failed to insert sales charge: %v", err)
+	}
+	if err := postSalesCharge(context.Background(), tx, chargeID, nil); err != nil {
+		return err
+	}
+	if _, err := recomputeSaleBalance(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+	if err := recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (r *SaleChargeRepository) UpdateSalesCharge(chargeID int, charge models.SalesCharge) error {
-	fmt.Printf("Repository: Updating charge ID %d with type '%s' and amount %f\n", chargeID, charge.ChargeType, charge.Amount)
-	
-	// First check if the charge exists
-	var exists bool
-	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM sales_charges WHERE charge_id = $1)", chargeID).Scan(&exists)
-	if err != nil {
-		fmt.Printf("Repository: Error checking if charge exists: %v\n", err)
-		return fmt.Errorf("failed to check if charge exists: %v", err)
-	}
-	
-	if !exists {
-		fmt.Printf("Repository: Charge ID %d does not exist\n", chargeID)
-		return fmt.Errorf("charge with ID %d does not exist", chargeID)
-	}
-	
-	result, err := r.db.Exec(`
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var saleID int
+	err = tx.QueryRow(`
 		UPDATE sales_charges 
 		SET charge_type = $1, amount = $2
 		WHERE charge_id = $3
-	`, charge.ChargeType, charge.Amount, chargeID)
+		RETURNING sale_id
+	`, charge.ChargeType, charge.Amount, chargeID).Scan(&saleID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %d", ErrSalesChargeNotFound, chargeID)
+	}
 	if err != nil {
-		fmt.Printf("Repository: Database error: %v\n", err)
 		return fmt.Errorf("failed to update sales charge: %v", err)
 	}
-	
-	rowsAffected, _ := result.RowsAffected()
-	fmt.Printf("Repository: Update successful, rows affected: %d\n", rowsAffected)
+
+	// Replace the charge's posting with one for the new amount
+	ctx := context.Background()
+	err = reverseJournalEntries(ctx, tx, models.JournalSourceSalesCharge, chargeID,
+		fmt.Sprintf("Reverse charge #%d before update", chargeID), nil)
+	if err != nil {
+		return err
+	}
+	if err := postSalesCharge(ctx, tx, chargeID, nil); err != nil {
+		return err
+	}
+	if _, err := recomputeSaleBalance(ctx, tx, saleID); err != nil {
+		return err
+	}
+	if err := recomputeRevenueRecognition(ctx, tx, saleID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sales charge update: %v", err)
+	}
 	return nil
 }
 
 func (r *SaleChargeRepository) DeleteSalesCharge(chargeID int) error {
-	_, err := r.db.Exec(`
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = reverseJournalEntries(context.Background(), tx, models.JournalSourceSalesCharge, chargeID,
+		fmt.Sprintf("Reverse deleted charge #%d", chargeID), nil)
+	if err != nil {
+		return err
+	}
+
+	var saleID int
+	err = tx.QueryRow(`
 		DELETE FROM sales_charges 
 		WHERE charge_id = $1
-	`, chargeID)
+		RETURNING sale_id
+	`, chargeID).Scan(&saleID)
+	if err == sql.ErrNoRows {
+		return tx.Commit()
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete sales charge: %v", err)
 	}
-	return nil
+	if _, err := recomputeSaleBalance(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+	if err := recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -75,6 +76,32 @@ func (r *SaleRepository) CreateSale(sale models.Sale) (models.SaleSubmitResponse
 		return models.SaleSubmitResponse{}, errors.New("vehicle is not available for the selected dates")
 	}
 
+	// Planned maintenance downtime blocks the calendar
+	block, err := findMaintenanceOverlap(r.db, sale.VehicleID, sale.DateOfDelivery, sale.ReturnDate, 0)
+	if err != nil {
+		return models.SaleSubmitResponse{}, err
+	}
+	if block != nil {
+		return models.SaleSubmitResponse{}, maintenanceConflictError(block)
+	}
+
+	// A vehicle sent to the workshop by hand has no end date, so it cannot be booked at all.
+	// Downtime from a maintenance block only blocks its own dates, checked above.
+	var vehicleStatus string
+	err = r.db.QueryRow(`SELECT status FROM vehicles WHERE vehicle_id = $1`, sale.VehicleID).Scan(&vehicleStatus)
+	if err != nil {
+		return models.SaleSubmitResponse{}, fmt.Errorf("failed to fetch vehicle status: %v", err)
+	}
+	if vehicleStatus == "under_maintenance" {
+		blocked, err := hasActiveMaintenanceBlock(r.db, sale.VehicleID)
+		if err != nil {
+			return models.SaleSubmitResponse{}, err
+		}
+		if !blocked {
+			return models.SaleSubmitResponse{}, errors.New("vehicle is under maintenance")
+		}
+	}
+
 	// Set actual delivery date if same day and determine status
 	var actualDeliveryDate *time.Time
 	var saleStatus string
@@ -113,19 +140,17 @@ func (r *SaleRepository) CreateSale(sale models.Sale) (models.SaleSubmitResponse
 		}
 	}
 
-	// Calculate payment status
-	paymentStatus := "unpaid"
-	totalVerifiedPaid := 0.0
-	for _, payment := range sale.Payments {
-		if payment.VerifiedByAdmin { // Only count verified payments
-			totalVerifiedPaid += payment.AmountPaid
-		}
+	// Work out where the rental starts and ends; a one-way rental carries the route's transfer fee
+	pickupBranch, dropoffBranch, transferFee, err := resolveSaleBranches(context.Background(), r.db,
+		sale.UserID, sale.VehicleID, sale.PickupBranchID, sale.DropoffBranchID)
+	if err != nil {
+		return models.SaleSubmitResponse{}, err
 	}
-
-	if totalVerifiedPaid >= sale.TotalAmount {
-		paymentStatus = "paid"
-	} else if totalVerifiedPaid > 0 {
-		paymentStatus = "partial"
+	if transferFee > 0 {
+		sale.SalesCharges = append(sale.SalesCharges, models.SalesCharge{
+			ChargeType: models.ChargeTypeTransferFee,
+			Amount:     transferFee,
+		})
 	}
 
 	var salesResponse models.SaleSubmitResponse
@@ -133,11 +158,8 @@ func (r *SaleRepository) CreateSale(sale models.Sale) (models.SaleSubmitResponse
 	if err != nil {
 		return salesResponse, fmt.Errorf("failed to begin transaction: %v", err)
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		}
-	}()
+	// Rollback is a no-op once the transaction has committed
+	defer tx.Rollback()
 
 	// Insert sale record
 	var saleID int
@@ -145,20 +167,25 @@ func (r *SaleRepository) CreateSale(sale models.Sale) (models.SaleSubmitResponse
 		INSERT INTO sales (
 			vehicle_id, user_id, customer_name, total_amount, charge_per_day, booking_date, 
 			date_of_delivery, return_date, number_of_days, remark, status, customer_destination, 
-			customer_phone, actual_date_of_delivery, payment_status
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			customer_phone, actual_date_of_delivery, payment_status, pickup_branch_id, dropoff_branch_id, customer_pan
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING sale_id
 	`,
 		sale.VehicleID, sale.UserID, sale.CustomerName, sale.TotalAmount, sale.ChargePerDay, bookingDate,
 		sale.DateOfDelivery, sale.ReturnDate, sale.NumberOfDays, sale.Remark, saleStatus, sale.Destination,
-		sale.CustomerPhone, actualDeliveryDate, paymentStatus,
+		sale.CustomerPhone, actualDeliveryDate, models.SalePaymentUnpaid, pickupBranch, dropoffBranch, sale.CustomerPAN,
 	).Scan(&saleID)
 	if err != nil {
 		return salesResponse, fmt.Errorf("failed to insert sale: %v", err)
 	}
 
+	// Book the rental amount as receivable revenue
+	if err = postSaleRevenue(context.Background(), tx, saleID, &sale.UserID); err != nil {
+		return salesResponse, err
+	}
+
 	// Insert related records in a transaction
-	if err := r.insertSalesCharges(tx, saleID, sale.SalesCharges); err != nil {
+	if err = r.insertSalesCharges(tx, saleID, sale.SalesCharges, sale.UserID); err != nil {
 		return salesResponse, fmt.Errorf("failed to insert sales charges: %v", err)
 	}
 
@@ -176,16 +203,31 @@ func (r *SaleRepository) CreateSale(sale models.Sale) (models.SaleSubmitResponse
 
 	// Insert payments if any
 	if len(sale.Payments) > 0 {
-		if err := r.insertPayments(tx, saleID, sale.Payments, sale.UserID, sale.VehicleUsage); err != nil {
+		if err = r.insertPayments(tx, saleID, sale.Payments, sale.UserID, sale.VehicleUsage); err != nil {
 			return salesResponse, fmt.Errorf("failed to insert payments: %v", err)
 		}
 	}
 
+	// Derive the payment status from the charges and payments just recorded
+	if _, err = recomputeSaleBalance(context.Background(), tx, saleID); err != nil {
+		return salesResponse, err
+	}
+
+	// Recognise whatever was collected up front over the rental period
+	if err = recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return salesResponse, err
+	}
+
 	// Update vehicle status to 'rented'
 	if err := r.UpdateVehicleStatus(sale.VehicleID, "rented"); err != nil {
 		return salesResponse, fmt.Errorf("failed to update vehicle status: %v", err)
 	}
 
+	// Queue webhook notifications with the sale so they only go out if it commits
+	if err = enqueueSaleWebhookEvent(tx, saleID, models.WebhookEventSaleCreated, nil); err != nil {
+		return salesResponse, err
+	}
+
 	// Commit the transaction
 	if err := tx.Commit(); err != nil {
 		return salesResponse, fmt.Errorf("failed to commit transaction: %v", err)
@@ -212,15 +254,20 @@ func (r *SaleRepository) CreateSale(sale models.Sale) (models.SaleSubmitResponse
 }
 
 // Helper methods for inserting related records
-func (r *SaleRepository) insertSalesCharges(tx *sql.Tx, saleID int, charges []models.SalesCharge) error {
+func (r *SaleRepository) insertSalesCharges(tx *sql.Tx, saleID int, charges []models.SalesCharge, userID int) error {
 	for _, charge := range charges {
-		_, err := tx.Exec(`
+		var chargeID int
+		err := tx.QueryRow(`
 			INSERT INTO sales_charges (sale_id, charge_type, amount)
 			VALUES ($1, $2, $3)
-		`, saleID, charge.ChargeType, charge.Amount)
+			RETURNING charge_id
+		`, saleID, charge.ChargeType, charge.Amount).Scan(&chargeID)
 		if err != nil {
 			return fmt.Errorf("failed to insert sales charge: %v", err)
 		}
+		if err := postSalesCharge(context.Background(), tx, chargeID, &userID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -275,17 +322,31 @@ func (r *SaleRepository) insertPayments(tx *sql.Tx, saleID int, payments []model
 		if err := payment.Validate(); err != nil {
 			return fmt.Errorf("invalid payment: %v", err)
 		}
+		paymentType, err := resolvePaymentMethod(context.Background(), tx, saleID, payment.PaymentType)
+		if err != nil {
+			return err
+		}
 
-		_, err := tx.Exec(`
+		var paymentID int
+		err = tx.QueryRow(`
 			INSERT INTO payments (
 				sale_id, amount_paid, payment_date, verified_by_admin, 
-				payment_type, payment_status, remark, sale_type
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				payment_type, payment_status, remark, sale_type, user_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING payment_id
 		`, saleID, payment.AmountPaid, payment.PaymentDate, payment.VerifiedByAdmin,
-			payment.PaymentType, payment.PaymentStatus, payment.Remark, payment.SaleType)
+			paymentType, payment.PaymentStatus, payment.Remark, payment.SaleType, userID).Scan(&paymentID)
 		if err != nil {
 			return fmt.Errorf("failed to insert payment: %v", err)
 		}
+
+		// Payments taken already completed go straight into the cash account
+		if err := postPaymentReceipt(context.Background(), tx, paymentID, &userID); err != nil {
+			return err
+		}
+		if err := issuePaymentReceipt(context.Background(), tx, paymentID, &userID); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -306,7 +367,8 @@ func (r *SaleRepository) GetSaleByID(saleID int, include []string) (*models.Sale
         SELECT s.sale_id, s.vehicle_id, s.user_id, s.customer_name, s.customer_phone, s.customer_destination, 
                s.total_amount, s.charge_per_day, s.booking_date, s.date_of_delivery, s.return_date, 
                s.number_of_days, s.actual_date_of_delivery, s.actual_date_of_return, u.username, s.payment_status, 
-               s.remark, s.status, s.created_at, s.updated_at
+               s.remark, s.status, s.created_at, s.updated_at, s.pickup_branch_id, s.dropoff_branch_id,
+               s.customer_pan, s.tax_rate, s.taxable_amount, s.tax_amount
         FROM sales s
         LEFT JOIN users u ON s.user_id = u.id
         WHERE s.sale_id = $1
@@ -314,7 +376,8 @@ func (r *SaleRepository) GetSaleByID(saleID int, include []string) (*models.Sale
 		&sale.SaleID, &sale.VehicleID, &sale.UserID, &sale.CustomerName, &sale.CustomerPhone, &sale.Destination,
 		&sale.TotalAmount, &sale.ChargePerDay, &sale.BookingDate, &sale.DateOfDelivery, &sale.ReturnDate,
 		&sale.NumberOfDays, &sale.ActualDateOfDelivery, &sale.ActualReturnDate, &sale.UserName, &sale.PaymentStatus,
-		&sale.Remark, &sale.Status, &sale.CreatedAt, &sale.UpdatedAt,
+		&sale.Remark, &sale.Status, &sale.CreatedAt, &sale.UpdatedAt, &sale.PickupBranchID, &sale.DropoffBranchID,
+		&sale.CustomerPAN, &sale.TaxRate, &sale.TaxableAmount, &sale.TaxAmount,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -472,7 +535,7 @@ func (r *SaleRepository) GetAllSales(include []string) ([]models.Sale, error) {
 // Helper methods for fetching related records
 func (r *SaleRepository) getSalesCharges(saleID int) ([]models.SalesCharge, error) {
 	rows, err := r.db.Query(`
-		SELECT charge_id, sale_id, charge_type, amount 
+		SELECT charge_id, sale_id, charge_type, amount, tax_rate, taxable_amount, tax_amount
 		FROM sales_charges 
 		WHERE sale_id = $1
 	`, saleID)
@@ -484,7 +547,8 @@ func (r *SaleRepository) getSalesCharges(saleID int) ([]models.SalesCharge, erro
 	var charges []models.SalesCharge
 	for rows.Next() {
 		var charge models.SalesCharge
-		err := rows.Scan(&charge.ChargeID, &charge.SaleID, &charge.ChargeType, &charge.Amount)
+		err := rows.Scan(&charge.ChargeID, &charge.SaleID, &charge.ChargeType, &charge.Amount,
+			&charge.TaxRate, &charge.TaxableAmount, &charge.TaxAmount)
 		if err != nil {
 			return nil, err
 		}
@@ -616,7 +680,8 @@ func (r *SaleRepository) GetSales(filters map[string]string, sort string, limit,
             s.sale_id, s.vehicle_id, s.user_id, s.customer_name, s.customer_phone, s.customer_destination,
             s.total_amount, s.charge_per_day, s.booking_date, s.date_of_delivery, s.return_date, 
             s.actual_date_of_delivery, s.actual_date_of_return,
-            s.number_of_days, s.remark, s.status, s.created_at, s.updated_at, u.username, s.payment_status
+            s.number_of_days, s.remark, s.status, s.created_at, s.updated_at, u.username, s.payment_status,
+            s.pickup_branch_id, s.dropoff_branch_id, s.customer_pan, s.tax_rate, s.taxable_amount, s.tax_amount
         FROM sales s
         LEFT JOIN users u ON s.user_id = u.id
     `
@@ -665,6 +730,10 @@ func (r *SaleRepository) GetSales(filters map[string]string, sort string, limit,
 			whereClauses = append(whereClauses, fmt.Sprintf("s.vehicle_id = $%d", argIndex))
 			args = append(args, value)
 			argIndex++
+		case "branch_id":
+			whereClauses = append(whereClauses, fmt.Sprintf("(s.pickup_branch_id = $%d OR s.dropoff_branch_id = $%d)", argIndex, argIndex))
+			args = append(args, value)
+			argIndex++
 		}
 	}
 
@@ -712,6 +781,7 @@ func (r *SaleRepository) GetSales(filters map[string]string, sort string, limit,
 			&sale.TotalAmount, &sale.ChargePerDay, &sale.BookingDate, &sale.DateOfDelivery, &sale.ReturnDate,
 			&sale.ActualDateOfDelivery, &sale.ActualReturnDate,
 			&sale.NumberOfDays, &sale.Remark, &sale.Status, &sale.CreatedAt, &sale.UpdatedAt, &sale.UserName, &sale.PaymentStatus,
+			&sale.PickupBranchID, &sale.DropoffBranchID, &sale.CustomerPAN, &sale.TaxRate, &sale.TaxableAmount, &sale.TaxAmount,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan sale: %v", err)
@@ -885,6 +955,37 @@ func (r *SaleRepository) UpdateSaleByUserID(saleID, userID int, updates map[stri
 		return fmt.Errorf("no fields provided to update")
 	}
 
+	// Moving a booking onto other dates or another vehicle must not collide with maintenance
+	_, datesChanged := updates["date_of_delivery"]
+	_, returnChanged := updates["return_date"]
+	_, vehicleChanged := updates["vehicle_id"]
+	if datesChanged || returnChanged || vehicleChanged {
+		var vehicleID int
+		var deliveryDate, returnDate time.Time
+		err = r.db.QueryRow(`
+			SELECT vehicle_id, date_of_delivery, return_date FROM sales WHERE sale_id = $1
+		`, saleID).Scan(&vehicleID, &deliveryDate, &returnDate)
+		if err != nil {
+			return fmt.Errorf("failed to fetch sale dates: %v", err)
+		}
+		if vehicleChanged {
+			vehicleID = updates["vehicle_id"].(int)
+		}
+		if datesChanged {
+			deliveryDate = updates["date_of_delivery"].(time.Time)
+		}
+		if returnChanged {
+			returnDate = updates["return_date"].(time.Time)
+		}
+		block, err := findMaintenanceOverlap(r.db, vehicleID, deliveryDate, returnDate, 0)
+		if err != nil {
+			return err
+		}
+		if block != nil {
+			return maintenanceConflictError(block)
+		}
+	}
+
 	// Construct the UPDATE query
 	query := fmt.Sprintf(`
         UPDATE sales 
@@ -898,7 +999,13 @@ func (r *SaleRepository) UpdateSaleByUserID(saleID, userID int, updates map[stri
 	// Execute the update
 	fmt.Println("Executing query:", query)
 	fmt.Println("With args:", args)
-	result, err := r.db.Exec(query, args...)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		fmt.Println("Error executing update query:", err)
 		return fmt.Errorf("failed to update sale: %v", err)
@@ -916,6 +1023,30 @@ func (r *SaleRepository) UpdateSaleByUserID(saleID, userID int, updates map[stri
 		return fmt.Errorf("no sale updated for sale_id %d: sale may not exist or values unchanged", saleID)
 	}
 
+	// Keep the ledger in step with the sale amount and cancellation
+	if status, ok := updates["status"]; ok && status == "cancelled" {
+		err = reverseSalePostings(context.Background(), tx, saleID, &userID)
+	} else if _, ok := updates["total_amount"]; ok {
+		err = repostSaleRevenue(context.Background(), tx, saleID, &userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// A new amount changes what is still owed
+	if _, err := recomputeSaleBalance(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+
+	// Amount, date and status changes all move the recognition schedule
+	if err := recomputeRevenueRecognition(context.Background(), tx, saleID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sale update: %v", err)
+	}
+
 	// Handle vehicle status updates
 	fmt.Println("=== VEHICLE STATUS UPDATE DEBUG ===")
 	fmt.Println("Processing updates:", updates)
@@ -0,0 +1,373 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+	"strconv"
+	"time"
+)
+
+type ServicingRepository struct {
+	db *sql.DB
+}
+
+func NewServicingRepository(db *sql.DB) *ServicingRepository {
+	return &ServicingRepository{db: db}
+}
+
+// servicingIntervalKmSQL is the km interval of the vehicle type in typeExpr for the company in
+// companyExpr: the company's interval for the type, else the company's servicing_interval_km
+// setting, else models.DefaultServicingIntervalKm
+func servicingIntervalKmSQL(typeExpr, companyExpr string) string {
+	return `COALESCE(
+		(SELECT si.interval_km FROM servicing_intervals si
+			WHERE si.vehicle_type_id = ` + typeExpr + ` AND si.company_id = ` + companyExpr + `),
+		(` + settingTextSQL(models.SettingServicingIntervalKm, companyExpr) + `)::numeric,
+		` + strconv.Itoa(models.DefaultServicingIntervalKm) + `)`
+}
+
+// servicingIntervalForVehicle returns the km and day interval the user's company configured for
+// the vehicle's type, falling back to the company's servicing_interval_km setting with no time limit
+func servicingIntervalForVehicle(tx *sql.Tx, vehicleID, userID int) (float64, *int, error) {
+	var km float64
+	var intervalDays sql.NullInt64
+	err := tx.QueryRow(`
+		SELECT `+servicingIntervalKmSQL("v.vehicle_type_id", "(SELECT u.company_id FROM users u WHERE u.id = $2)")+`,
+			si.interval_days
+		FROM vehicles v
+		LEFT JOIN servicing_intervals si ON si.vehicle_type_id = v.vehicle_type_id
+			AND si.company_id = (SELECT u.company_id FROM users u WHERE u.id = $2)
+		WHERE v.vehicle_id = $1
+	`, vehicleID, userID).Scan(&km, &intervalDays)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to fetch servicing interval for vehicle %d: %v", vehicleID, err)
+	}
+
+	var days *int
+	if intervalDays.Valid {
+		d := int(intervalDays.Int64)
+		days = &d
+	}
+	return km, days, nil
+}
+
+// nextServicingDate adds the day interval to from, or returns nil when the type has no time interval
+func nextServicingDate(from time.Time, intervalDays *int) *time.Time {
+	if intervalDays == nil {
+		return nil
+	}
+	next := from.AddDate(0, 0, *intervalDays)
+	return &next
+}
+
+// GetIntervals lists every vehicle type with the company's servicing interval for it, types
+// without their own interval showing the company's default
+func (r *ServicingRepository) GetIntervals(companyID int) ([]models.ServicingInterval, error) {
+	rows, err := r.db.Query(`
+		SELECT vt.vehicle_type_id, vt.vehicle_type_name,
+			`+servicingIntervalKmSQL("vt.vehicle_type_id", "$1::int")+`, si.interval_days,
+			COALESCE(si.updated_at, vt.updated_at)
+		FROM vehicle_types vt
+		LEFT JOIN servicing_intervals si ON si.vehicle_type_id = vt.vehicle_type_id AND si.company_id = $1
+		ORDER BY vt.vehicle_type_id
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch servicing intervals: %v", err)
+	}
+	defer rows.Close()
+
+	intervals := []models.ServicingInterval{}
+	for rows.Next() {
+		var i models.ServicingInterval
+		var days sql.NullInt64
+		if err := rows.Scan(&i.VehicleTypeID, &i.VehicleTypeName, &i.IntervalKm, &days, &i.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan servicing interval: %v", err)
+		}
+		if days.Valid {
+			d := int(days.Int64)
+			i.IntervalDays = &d
+		}
+		intervals = append(intervals, i)
+	}
+	return intervals, rows.Err()
+}
+
+// UpsertInterval sets the company's servicing interval for a vehicle type
+func (r *ServicingRepository) UpsertInterval(companyID, vehicleTypeID int, req models.ServicingIntervalRequest) (*models.ServicingInterval, error) {
+	interval := &models.ServicingInterval{
+		VehicleTypeID: vehicleTypeID,
+		IntervalKm:    req.IntervalKm,
+		IntervalDays:  req.IntervalDays,
+	}
+	err := r.db.QueryRow(`
+		INSERT INTO servicing_intervals (company_id, vehicle_type_id, interval_km, interval_days)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (company_id, vehicle_type_id) DO UPDATE
+		SET interval_km = EXCLUDED.interval_km,
+			interval_days = EXCLUDED.interval_days,
+			updated_at = NOW()
+		RETURNING updated_at
+	`, companyID, vehicleTypeID, req.IntervalKm, req.IntervalDays).Scan(&interval.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save servicing interval: %v", err)
+	}
+	return interval, nil
+}
+
+const servicingStatusSelect = `
+	SELECT v.vehicle_id, v.vehicle_name, v.vehicle_registration_number, v.status,
+		vs.servicing_id, vs.current_km, vs.next_servicing_km, vs.next_servicing_date,
+		vs.servicing_interval_km,
+		COALESCE(vs.is_servicing_due, FALSE)
+			OR COALESCE(vs.next_servicing_date <= CURRENT_DATE, FALSE),
+		vs.status, vs.last_serviced_at
+	FROM vehicles v
+	LEFT JOIN vehicle_servicing vs ON vs.vehicle_id = v.vehicle_id
+`
+
+func scanServicingStatus(scanner interface{ Scan(...any) error }) (models.VehicleServicingStatus, error) {
+	var s models.VehicleServicingStatus
+	err := scanner.Scan(&s.VehicleID, &s.VehicleName, &s.VehicleRegistrationNumber, &s.VehicleStatus,
+		&s.ServicingID, &s.CurrentKm, &s.NextServicingKm, &s.NextServicingDate,
+		&s.ServicingIntervalKm, &s.IsServicingDue, &s.Status, &s.LastServicedAt)
+	if err != nil {
+		return s, err
+	}
+	if s.CurrentKm != nil && s.NextServicingKm != nil {
+		remaining := *s.NextServicingKm - *s.CurrentKm
+		s.KmRemaining = &remaining
+	}
+	return s, nil
+}
+
+// GetServicingStatuses lists the servicing state of the fleet, optionally only vehicles that are due
+func (r *ServicingRepository) GetServicingStatuses(dueOnly bool) ([]models.VehicleServicingStatus, error) {
+	query := servicingStatusSelect
+	if dueOnly {
+		query += ` WHERE vs.is_servicing_due = TRUE OR vs.next_servicing_date <= CURRENT_DATE`
+	}
+	query += ` ORDER BY (vs.next_servicing_km - vs.current_km) ASC NULLS LAST, v.vehicle_id`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch servicing status: %v", err)
+	}
+	defer rows.Close()
+
+	statuses := []models.VehicleServicingStatus{}
+	for rows.Next() {
+		s, err := scanServicingStatus(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan servicing status: %v", err)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, rows.Err()
+}
+
+// GetVehicleServicing returns a vehicle's servicing state with its full service history
+func (r *ServicingRepository) GetVehicleServicing(vehicleID int) (*models.VehicleServicingDetail, error) {
+	status, err := scanServicingStatus(r.db.QueryRow(servicingStatusSelect+` WHERE v.vehicle_id = $1`, vehicleID))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("vehicle not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch servicing status: %v", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT history_id, vehicle_id, servicing_id, km_reading, servicing_type, servicing_date,
+			servicing_cost, workshop, notes, serviced_by, expense_id, created_at
+		FROM vehicle_servicing_history
+		WHERE vehicle_id = $1
+		ORDER BY servicing_date DESC, history_id DESC
+	`, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch servicing history: %v", err)
+	}
+	defer rows.Close()
+
+	detail := &models.VehicleServicingDetail{VehicleServicingStatus: status, History: []models.ServicingRecord{}}
+	for rows.Next() {
+		var h models.ServicingRecord
+		if err := rows.Scan(&h.HistoryID, &h.VehicleID, &h.ServicingID, &h.KmReading, &h.ServicingType, &h.ServicingDate,
+			&h.Cost, &h.Workshop, &h.Notes, &h.ServicedBy, &h.ExpenseID, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan servicing history: %v", err)
+		}
+		detail.History = append(detail.History, h)
+	}
+	return detail, rows.Err()
+}
+
+// StartServicing takes a vehicle off the road for servicing
+func (r *ServicingRepository) StartServicing(vehicleID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var vehicleStatus string
+	err = tx.QueryRow(`SELECT status FROM vehicles WHERE vehicle_id = $1 FOR UPDATE`, vehicleID).Scan(&vehicleStatus)
+	if err == sql.ErrNoRows {
+		return errors.New("vehicle not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch vehicle status: %v", err)
+	}
+	if vehicleStatus == "rented" {
+		return errors.New("vehicle is currently rented")
+	}
+
+	if _, err = tx.Exec(`UPDATE vehicles SET status = 'under_maintenance', updated_at = NOW() WHERE vehicle_id = $1`, vehicleID); err != nil {
+		return fmt.Errorf("failed to update vehicle status: %v", err)
+	}
+	if _, err = tx.Exec(`
+		UPDATE vehicle_servicing
+		SET status = 'in_progress', updated_at = NOW()
+		WHERE vehicle_id = $1
+	`, vehicleID); err != nil {
+		return fmt.Errorf("failed to update servicing status: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// MarkServiced records a completed service: it writes the history entry, books the cost as a
+// company expense, resets the next service point from the new odometer reading and returns an
+// under_maintenance vehicle to the available pool.
+func (r *ServicingRepository) MarkServiced(vehicleID, userID int, req models.MarkServicedRequest) (*models.ServicingRecord, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	servicedAt := time.Now()
+	if req.ServicingDate != nil {
+		servicedAt = *req.ServicingDate
+	}
+	servicingType := req.ServicingType
+	if servicingType == "" {
+		servicingType = "regular"
+	}
+
+	var vehicleName, vehicleStatus string
+	err = tx.QueryRow(`SELECT vehicle_name, status FROM vehicles WHERE vehicle_id = $1 FOR UPDATE`, vehicleID).Scan(&vehicleName, &vehicleStatus)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("vehicle not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vehicle: %v", err)
+	}
+
+	intervalKm, intervalDays, err := servicingIntervalForVehicle(tx, vehicleID, userID)
+	if err != nil {
+		return nil, err
+	}
+	nextKm := req.KmReading + intervalKm
+	nextDate := nextServicingDate(servicedAt, intervalDays)
+
+	// Reset the servicing schedule, creating it for vehicles that never had one
+	var servicingID int
+	err = tx.QueryRow(`
+		UPDATE vehicle_servicing
+		SET current_km = GREATEST(current_km, $1),
+			next_servicing_km = $2,
+			servicing_interval_km = $3,
+			servicing_interval_days = $4,
+			next_servicing_date = $5,
+			is_servicing_due = FALSE,
+			status = 'pending',
+			last_serviced_at = $6,
+			updated_at = NOW()
+		WHERE vehicle_id = $7
+		RETURNING servicing_id
+	`, req.KmReading, nextKm, intervalKm, intervalDays, nextDate, servicedAt, vehicleID).Scan(&servicingID)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRow(`
+			INSERT INTO vehicle_servicing (
+				vehicle_id, current_km, next_servicing_km, servicing_interval_km,
+				servicing_interval_days, next_servicing_date, is_servicing_due, status, last_serviced_at
+			) VALUES ($1, $2, $3, $4, $5, $6, FALSE, 'pending', $7)
+			RETURNING servicing_id
+		`, vehicleID, req.KmReading, nextKm, intervalKm, intervalDays, nextDate, servicedAt).Scan(&servicingID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset servicing schedule: %v", err)
+	}
+
+	// Feed the servicing cost into company expenses
+	var expenseID *int
+	if req.Cost != nil && *req.Cost > 0 {
+		description := fmt.Sprintf("%s servicing of %s at %.0f km", servicingType, vehicleName, req.KmReading)
+		if req.Workshop != nil && *req.Workshop != "" {
+			description += " (" + *req.Workshop + ")"
+		}
+		var id int
+		err = tx.QueryRow(`
+			INSERT INTO company_expenses (expense_type, amount, description, expense_date, recorded_by, vehicle_id)
+			VALUES ('servicing', $1, $2, $3, $4, $5)
+			RETURNING expense_id
+		`, *req.Cost, description, servicedAt, userID, vehicleID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record servicing expense: %v", err)
+		}
+		if err = postExpense(context.Background(), tx, id, &userID); err != nil {
+			return nil, err
+		}
+		expenseID = &id
+	}
+
+	record := &models.ServicingRecord{
+		VehicleID:     vehicleID,
+		ServicingID:   servicingID,
+		KmReading:     req.KmReading,
+		ServicingType: servicingType,
+		ServicingDate: servicedAt,
+		Cost:          req.Cost,
+		Workshop:      req.Workshop,
+		Notes:         req.Notes,
+		ServicedBy:    &userID,
+		ExpenseID:     expenseID,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO vehicle_servicing_history (
+			vehicle_id, servicing_id, km_reading, servicing_type, servicing_date,
+			servicing_cost, workshop, notes, serviced_by, expense_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING history_id, created_at
+	`, vehicleID, servicingID, req.KmReading, servicingType, servicedAt,
+		req.Cost, req.Workshop, req.Notes, userID, expenseID,
+	).Scan(&record.HistoryID, &record.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write servicing history: %v", err)
+	}
+
+	// Clear the reminders CreateReturn raised automatically for this service
+	_, err = tx.Exec(`
+		DELETE FROM reminders
+		WHERE vehicle_id = $1
+		AND type = 'servicing'
+		AND frequency = 'custom'
+		AND custom_interval IS NULL
+	`, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear servicing reminders: %v", err)
+	}
+
+	// A vehicle still inside a maintenance block stays off the road until the block ends
+	if vehicleStatus == "under_maintenance" {
+		if err = releaseVehicleFromMaintenance(tx, vehicleID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return record, nil
+}
@@ -3,13 +3,20 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"renting/internal/models"
 	"strconv"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
 type StatementRepository interface {
 	GetOutstandingStatements(ctx context.Context, filters map[string]string, offset, limit int) ([]*models.Statement, error)
+	GetOutstandingBalances(ctx context.Context, companyID int) ([]*models.AgeingSale, error)
+	GetStatementCompany(ctx context.Context, companyID int) (*models.StatementCompany, error)
+	FindAccountSales(ctx context.Context, companyID int, saleID *int, customerPhone, customerName string) ([]models.AccountSale, error)
+	GetAccountLines(ctx context.Context, saleIDs []int) ([]models.AccountStatementLine, error)
 }
 
 type statementRepository struct {
@@ -97,6 +104,13 @@ func (r *statementRepository) GetOutstandingStatements(ctx context.Context, filt
         argIndex++
     }
 
+    // Branch filter (the branch the vehicle was picked up from)
+    if branchID, ok := filters["branch_id"]; ok && branchID != "" {
+        conditions = append(conditions, "sale_id IN (SELECT sale_id FROM sales WHERE pickup_branch_id = $"+strconv.Itoa(argIndex)+")")
+        args = append(args, branchID)
+        argIndex++
+    }
+
     if len(conditions) > 0 {
         baseQuery += " WHERE " + strings.Join(conditions, " AND ")
     }
@@ -149,3 +163,143 @@ func (r *statementRepository) GetOutstandingStatements(ctx context.Context, filt
 
     return statements, nil
 }
+
+// GetOutstandingBalances returns every sale of the company in the statement view with a non-zero
+// balance, with the staff member who created it. Ageing is left to the caller.
+func (r *statementRepository) GetOutstandingBalances(ctx context.Context, companyID int) ([]*models.AgeingSale, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT v.sale_id, COALESCE(v.customer_name, ''), COALESCE(v.customer_phone, ''),
+			v.user_id, COALESCE(u.username, ''), v.status, v.return_date, v.actual_date_of_return,
+			v.outstanding_balance
+		FROM sales_statement_view v
+		JOIN users u ON u.id = v.user_id
+		WHERE u.company_id = $1 AND v.outstanding_balance <> 0
+		ORDER BY v.sale_id`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outstanding balances: %v", err)
+	}
+	defer rows.Close()
+
+	var sales []*models.AgeingSale
+	for rows.Next() {
+		var s models.AgeingSale
+		var returnDate, actualReturn sql.NullTime
+		if err := rows.Scan(&s.SaleID, &s.CustomerName, &s.CustomerPhone, &s.UserID, &s.StaffName,
+			&s.Status, &returnDate, &actualReturn, &s.OutstandingBalance); err != nil {
+			return nil, fmt.Errorf("failed to scan outstanding balance: %v", err)
+		}
+		if returnDate.Valid {
+			s.ReturnDate = &returnDate.Time
+		}
+		if actualReturn.Valid {
+			s.ActualDateOfReturn = &actualReturn.Time
+		}
+		sales = append(sales, &s)
+	}
+	return sales, rows.Err()
+}
+
+// GetStatementCompany returns the company details printed on statements
+func (r *statementRepository) GetStatementCompany(ctx context.Context, companyID int) (*models.StatementCompany, error) {
+	var company models.StatementCompany
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, company_code FROM companies WHERE id = $1
+	`, companyID).Scan(&company.CompanyID, &company.Name, &company.CompanyCode)
+	if err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// FindAccountSales returns the company's sales for one sale id, or for a customer matched by
+// phone number (else by case-insensitive name)
+func (r *statementRepository) FindAccountSales(ctx context.Context, companyID int, saleID *int, customerPhone, customerName string) ([]models.AccountSale, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.sale_id, COALESCE(s.customer_name, ''), COALESCE(s.customer_phone, ''), s.status
+		FROM sales s
+		JOIN users u ON u.id = s.user_id
+		WHERE u.company_id = $1
+		AND ($2::int IS NULL OR s.sale_id = $2)
+		AND ($3 = '' OR s.customer_phone = $3)
+		AND ($4 = '' OR LOWER(TRIM(s.customer_name)) = LOWER(TRIM($4)))
+		ORDER BY s.sale_id
+	`, companyID, saleID, customerPhone, customerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statement sales: %v", err)
+	}
+	defer rows.Close()
+
+	var sales []models.AccountSale
+	for rows.Next() {
+		var sale models.AccountSale
+		if err := rows.Scan(&sale.SaleID, &sale.CustomerName, &sale.CustomerPhone, &sale.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan statement sale: %v", err)
+		}
+		sales = append(sales, sale)
+	}
+	return sales, rows.Err()
+}
+
+// GetAccountLines lists rentals, charges, deposits, payments and refunds of the given sales in
+// date order. Cancelled rentals and their charges are left out; money taken on them still shows.
+func (r *statementRepository) GetAccountLines(ctx context.Context, saleIDs []int) ([]models.AccountStatementLine, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH target AS (
+			SELECT s.*, v.vehicle_name, v.vehicle_registration_number
+			FROM sales s
+			LEFT JOIN vehicles v ON v.vehicle_id = s.vehicle_id
+			WHERE s.sale_id = ANY($1)
+		),
+		lines AS (
+			SELECT t.sale_id, COALESCE(t.booking_date, t.created_at::date) AS line_date, 0 AS seq,
+				'rental' AS line_type,
+				format('Rental #%s %s (%s), %s to %s', t.sale_id, COALESCE(t.vehicle_name, ''),
+					COALESCE(t.vehicle_registration_number, ''),
+					to_char(COALESCE(t.actual_date_of_delivery, t.date_of_delivery), 'DD Mon YYYY'),
+					to_char(COALESCE(t.actual_date_of_return, t.return_date), 'DD Mon YYYY')) AS description,
+				COALESCE(t.total_amount, 0) AS debit, 0 AS credit
+			FROM target t
+			WHERE t.status <> 'cancelled'
+			UNION ALL
+			SELECT t.sale_id, COALESCE(t.actual_date_of_return, t.return_date, t.booking_date)::date, 1,
+				'charge', format('%s charge on #%s', initcap(sc.charge_type), t.sale_id),
+				GREATEST(`+signedChargeSQL("sc")+`, 0), GREATEST(-`+signedChargeSQL("sc")+`, 0)
+			FROM sales_charges sc
+			JOIN target t ON t.sale_id = sc.sale_id
+			WHERE t.status <> 'cancelled'
+			UNION ALL
+			SELECT p.sale_id, p.payment_date::date, 2,
+				CASE WHEN p.sale_type = 'booking' THEN 'deposit' ELSE 'payment' END,
+				format('%s on #%s by %s%s',
+					CASE WHEN p.sale_type = 'booking' THEN 'Deposit' ELSE 'Payment' END,
+					p.sale_id, replace(COALESCE(p.payment_type, 'unknown'), '_', ' '),
+					CASE WHEN p.receipt_number IS NOT NULL THEN ', receipt ' || p.receipt_number ELSE '' END),
+				0, p.amount_paid
+			FROM payments p
+			WHERE p.sale_id = ANY($1) AND p.payment_status IN ('Completed', 'Refunded')
+			UNION ALL
+			SELECT p.sale_id, p.updated_at::date, 3, 'refund',
+				format('Refund on #%s by %s', p.sale_id, replace(COALESCE(p.payment_type, 'unknown'), '_', ' ')),
+				p.amount_paid, 0
+			FROM payments p
+			WHERE p.sale_id = ANY($1) AND p.payment_status = 'Refunded'
+		)
+		SELECT line_date, sale_id, line_type, description, debit, credit
+		FROM lines
+		ORDER BY line_date, sale_id, seq
+	`, pq.Array(saleIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statement lines: %v", err)
+	}
+	defer rows.Close()
+
+	lines := []models.AccountStatementLine{}
+	for rows.Next() {
+		var line models.AccountStatementLine
+		if err := rows.Scan(&line.Date, &line.SaleID, &line.Type, &line.Description, &line.Debit, &line.Credit); err != nil {
+			return nil, fmt.Errorf("failed to scan statement line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
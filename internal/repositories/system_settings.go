@@ -2,9 +2,13 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"renting/internal/models"
 )
 
+var ErrCompanyNotFound = errors.New("company not found")
+
 type SystemSettingsRepository struct {
 	db *sql.DB
 }
@@ -13,46 +17,81 @@ func NewSystemSettingsRepository(db *sql.DB) *SystemSettingsRepository {
 	return &SystemSettingsRepository{db: db}
 }
 
-func (r *SystemSettingsRepository) GetSystemSettings() (*models.SystemSettingsResponse, error) {
-	query := `
-		SELECT setting_key, setting_value 
-		FROM system_settings 
-		WHERE setting_key IN ('enable_registration', 'enable_login')
-	`
-	rows, err := r.db.Query(query)
+// settingTextSQL is a scalar subquery for the text of setting key for the company in companyExpr,
+// falling back to the platform default; it is NULL when neither is stored. key must be one of the
+// models.Setting* constants.
+func settingTextSQL(key, companyExpr string) string {
+	return `(SELECT st.value #>> '{}' FROM settings st
+		WHERE st.setting_key = '` + key + `' AND (st.company_id = ` + companyExpr + ` OR st.company_id IS NULL)
+		ORDER BY st.company_id NULLS LAST LIMIT 1)`
+}
+
+// companyTodaySQL is the current date in the timezone of the company in companyExpr
+func companyTodaySQL(companyExpr string) string {
+	return `(NOW() AT TIME ZONE COALESCE(` + settingTextSQL(models.SettingTimezone, companyExpr) + `, '` +
+		models.DefaultTimezone + `'))::date`
+}
+
+// GetSettings returns the platform defaults and, when companyID is not nil, the company's own values
+func (r *SystemSettingsRepository) GetSettings(companyID *int) ([]models.StoredSetting, error) {
+	rows, err := r.db.Query(`
+		SELECT company_id, setting_key, value, updated_by, updated_at
+		FROM settings
+		WHERE company_id IS NULL OR company_id = $1
+	`, companyID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to query settings: %v", err)
 	}
 	defer rows.Close()
 
-	settings := &models.SystemSettingsResponse{
-		EnableRegistration: true, // Default values
-		EnableLogin:        true,
-	}
-
+	settings := []models.StoredSetting{}
 	for rows.Next() {
-		var key string
-		var value bool
-		if err := rows.Scan(&key, &value); err != nil {
-			return nil, err
-		}
-		switch key {
-		case "enable_registration":
-			settings.EnableRegistration = value
-		case "enable_login":
-			settings.EnableLogin = value
+		var s models.StoredSetting
+		var value []byte
+		if err := rows.Scan(&s.CompanyID, &s.SettingKey, &value, &s.UpdatedBy, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %v", err)
 		}
+		s.Value = value
+		settings = append(settings, s)
 	}
+	return settings, rows.Err()
+}
 
-	return settings, nil
+// SetSetting stores a value, as the platform default when companyID is nil. value must be JSON.
+func (r *SystemSettingsRepository) SetSetting(companyID *int, key string, value []byte, updatedBy *int) error {
+	_, err := r.db.Exec(`
+		INSERT INTO settings (company_id, setting_key, value, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT ((COALESCE(company_id, 0)), setting_key)
+		DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	`, companyID, key, string(value), updatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to save setting %s: %v", key, err)
+	}
+	return nil
 }
 
-func (r *SystemSettingsRepository) UpdateSystemSetting(key string, value bool) error {
-	query := `
-		UPDATE system_settings 
-		SET setting_value = $1, updated_at = CURRENT_TIMESTAMP 
-		WHERE setting_key = $2
-	`
-	_, err := r.db.Exec(query, value, key)
-	return err
+// DeleteSetting drops a company's own value so the platform default applies again; it returns
+// sql.ErrNoRows when the company had none
+func (r *SystemSettingsRepository) DeleteSetting(companyID int, key string) error {
+	result, err := r.db.Exec(`DELETE FROM settings WHERE company_id = $1 AND setting_key = $2`, companyID, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete setting %s: %v", key, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SystemSettingsRepository) GetCompanyIDByCode(companyCode string) (int, error) {
+	var companyID int
+	err := r.db.QueryRow(`SELECT id FROM companies WHERE company_code = $1`, companyCode).Scan(&companyID)
+	if err == sql.ErrNoRows {
+		return 0, ErrCompanyNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch company: %v", err)
+	}
+	return companyID, nil
 }
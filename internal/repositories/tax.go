@@ -0,0 +1,473 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrTaxInvoiceNotFound = errors.New("tax invoice not found")
+	ErrTaxInvoiceExists   = errors.New("sale already has a tax invoice")
+	ErrInvalidTaxInvoice  = errors.New("invalid tax invoice")
+)
+
+type TaxRepository struct {
+	db *sql.DB
+}
+
+func NewTaxRepository(db *sql.DB) *TaxRepository {
+	return &TaxRepository{db: db}
+}
+
+// taxRateSQL is a scalar expression for the VAT rate of the charge type in typeExpr for the company
+// in companyExpr: the company's rate for the type, else its tax_rate setting, else DefaultTaxRate
+func taxRateSQL(companyExpr, typeExpr string) string {
+	return `COALESCE(
+		(SELECT tr.rate FROM charge_tax_rates tr WHERE tr.company_id = ` + companyExpr + ` AND tr.charge_type = ` + typeExpr + `),
+		(` + settingTextSQL(models.SettingTaxRate, companyExpr) + `)::numeric,
+		` + strconv.FormatFloat(models.DefaultTaxRate, 'f', -1, 64) + `)`
+}
+
+// taxBreakdown splits a VAT-inclusive gross amount into the taxable amount and the tax at rate percent
+func taxBreakdown(gross, rate float64) (taxable, tax float64) {
+	gross = roundCents(gross)
+	taxable = roundCents(gross / (1 + rate/100))
+	return taxable, roundCents(gross - taxable)
+}
+
+// GetRates returns the company's default rate and the charge types that have a rate of their own
+func (r *TaxRepository) GetRates(companyID int) (*models.TaxRates, error) {
+	rates := models.TaxRates{ChargeTypes: []models.ChargeTaxRate{}}
+	err := r.db.QueryRow(`SELECT `+taxRateSQL("$1::int", "NULL"), companyID).Scan(&rates.DefaultRate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch default tax rate: %v", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT rate_id, company_id, charge_type, rate, created_at, updated_at
+		FROM charge_tax_rates
+		WHERE company_id = $1
+		ORDER BY charge_type
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax rates: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rate models.ChargeTaxRate
+		if err := rows.Scan(&rate.RateID, &rate.CompanyID, &rate.ChargeType, &rate.Rate, &rate.CreatedAt, &rate.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tax rate: %v", err)
+		}
+		rates.ChargeTypes = append(rates.ChargeTypes, rate)
+	}
+	return &rates, rows.Err()
+}
+
+// SetRate sets the company's rate for a charge type; postings made before keep the rate they used
+func (r *TaxRepository) SetRate(companyID int, chargeType string, rate float64) (*models.ChargeTaxRate, error) {
+	result := models.ChargeTaxRate{}
+	err := r.db.QueryRow(`
+		INSERT INTO charge_tax_rates (company_id, charge_type, rate)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (company_id, charge_type) DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW()
+		RETURNING rate_id, company_id, charge_type, rate, created_at, updated_at
+	`, companyID, chargeType, rate).Scan(&result.RateID, &result.CompanyID, &result.ChargeType, &result.Rate,
+		&result.CreatedAt, &result.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save tax rate for %s: %v", chargeType, err)
+	}
+	return &result, nil
+}
+
+// DeleteRate drops the company's rate for a charge type so the default rate applies again; it
+// returns sql.ErrNoRows when the type had none
+func (r *TaxRepository) DeleteRate(companyID int, chargeType string) error {
+	result, err := r.db.Exec(`DELETE FROM charge_tax_rates WHERE company_id = $1 AND charge_type = $2`, companyID, chargeType)
+	if err != nil {
+		return fmt.Errorf("failed to delete tax rate for %s: %v", chargeType, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// IssueInvoice numbers and records a tax invoice for a sale of the company. Its lines are the
+// rental and each charge, discounts deducted, taxed at the rate they were posted with, or the current
+// rate when the sale has not been posted yet. The gross total is the sale's amount due.
+func (r *TaxRepository) IssueInvoice(companyID int, req models.IssueTaxInvoiceRequest, issuedBy int) (*models.TaxInvoice, error) {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var saleCompanyID sql.NullInt64
+	var customerName sql.NullString
+	var status string
+	var totalAmount, rentalRate float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT u.company_id, s.customer_name, s.status, COALESCE(s.total_amount, 0),
+			COALESCE(s.tax_rate, `+taxRateSQL("$2::int", "'"+models.TaxChargeTypeRental+"'")+`)
+		FROM sales s
+		LEFT JOIN users u ON u.id = s.user_id
+		WHERE s.sale_id = $1
+		FOR UPDATE OF s
+	`, req.SaleID, companyID).Scan(&saleCompanyID, &customerName, &status, &totalAmount, &rentalRate)
+	if err == sql.ErrNoRows || (err == nil && (!saleCompanyID.Valid || int(saleCompanyID.Int64) != companyID)) {
+		return nil, fmt.Errorf("%w: %d", ErrSaleNotFound, req.SaleID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sale %d: %v", req.SaleID, err)
+	}
+	if status == "cancelled" {
+		return nil, fmt.Errorf("%w: sale %d is cancelled", ErrInvalidTaxInvoice, req.SaleID)
+	}
+
+	var invoiced bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tax_invoices WHERE sale_id = $1 AND status = 'issued')`,
+		req.SaleID).Scan(&invoiced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tax invoices of sale %d: %v", req.SaleID, err)
+	}
+	if invoiced {
+		return nil, fmt.Errorf("%w: %d", ErrTaxInvoiceExists, req.SaleID)
+	}
+
+	lines := []models.TaxInvoiceLine{{
+		Description: "Vehicle rental",
+		ChargeType:  models.TaxChargeTypeRental,
+		TaxRate:     rentalRate,
+		GrossAmount: totalAmount,
+	}}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.charge_type, `+signedChargeSQL("c")+`, COALESCE(c.tax_rate, `+taxRateSQL("$2::int", "c.charge_type")+`)
+		FROM sales_charges c
+		WHERE c.sale_id = $1
+		ORDER BY c.charge_id
+	`, req.SaleID, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query charges of sale %d: %v", req.SaleID, err)
+	}
+	for rows.Next() {
+		var line models.TaxInvoiceLine
+		if err := rows.Scan(&line.ChargeType, &line.GrossAmount, &line.TaxRate); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan sales charge: %v", err)
+		}
+		line.Description = strings.ToUpper(line.ChargeType[:1]) + line.ChargeType[1:] + " charge"
+		lines = append(lines, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	invoice := models.TaxInvoice{
+		CompanyID:       companyID,
+		SaleID:          req.SaleID,
+		CustomerName:    customerName.String,
+		CustomerPAN:     req.CustomerPAN,
+		CustomerAddress: req.CustomerAddress,
+		Status:          models.TaxInvoiceIssued,
+		IssuedBy:        &issuedBy,
+	}
+	if req.CustomerName != nil {
+		invoice.CustomerName = *req.CustomerName
+	}
+	if invoice.CustomerName == "" {
+		return nil, fmt.Errorf("%w: customer name is required", ErrInvalidTaxInvoice)
+	}
+	for _, line := range lines {
+		if roundCents(line.GrossAmount) == 0 {
+			continue
+		}
+		line.GrossAmount = roundCents(line.GrossAmount)
+		line.TaxableAmount, line.TaxAmount = taxBreakdown(line.GrossAmount, line.TaxRate)
+		invoice.TaxableAmount += line.TaxableAmount
+		invoice.TaxAmount += line.TaxAmount
+		invoice.GrossAmount += line.GrossAmount
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	invoice.TaxableAmount = roundCents(invoice.TaxableAmount)
+	invoice.TaxAmount = roundCents(invoice.TaxAmount)
+	invoice.GrossAmount = roundCents(invoice.GrossAmount)
+	if invoice.GrossAmount <= 0 {
+		return nil, fmt.Errorf("%w: sale %d has nothing to invoice", ErrInvalidTaxInvoice, req.SaleID)
+	}
+
+	var companyCode string
+	if err := tx.QueryRowContext(ctx, `SELECT company_code FROM companies WHERE id = $1`, companyID).Scan(&companyCode); err != nil {
+		return nil, fmt.Errorf("failed to fetch company: %v", err)
+	}
+	var sequence int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO tax_invoice_sequences (company_id, last_number)
+		VALUES ($1, 1)
+		ON CONFLICT (company_id) DO UPDATE SET last_number = tax_invoice_sequences.last_number + 1
+		RETURNING last_number
+	`, companyID).Scan(&sequence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate invoice number: %v", err)
+	}
+	invoice.InvoiceNumber = fmt.Sprintf("%s-INV-%06d", strings.ToUpper(companyCode), sequence)
+
+	// The invoice date is the company's local date, which is what the tax period is reckoned in
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO tax_invoices (company_id, sale_id, sequence, invoice_number, invoice_date, seller_pan,
+			customer_name, customer_pan, customer_address, taxable_amount, tax_amount, gross_amount, status, issued_by)
+		VALUES ($1, $2, $3, $4,
+			`+companyTodaySQL("$1")+`,
+			NULLIF(`+settingTextSQL(models.SettingVATNumber, "$1")+`, ''),
+			$5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING invoice_id
+	`, companyID, req.SaleID, sequence, invoice.InvoiceNumber, invoice.CustomerName, invoice.CustomerPAN,
+		invoice.CustomerAddress, invoice.TaxableAmount, invoice.TaxAmount, invoice.GrossAmount,
+		invoice.Status, issuedBy).Scan(&invoice.InvoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record tax invoice: %v", err)
+	}
+	for _, line := range invoice.Lines {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO tax_invoice_lines (invoice_id, description, charge_type, tax_rate, taxable_amount, tax_amount, gross_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, invoice.InvoiceID, line.Description, line.ChargeType, line.TaxRate, line.TaxableAmount, line.TaxAmount, line.GrossAmount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record tax invoice line: %v", err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE sales SET customer_pan = $1 WHERE sale_id = $2`, invoice.CustomerPAN, req.SaleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set customer PAN on sale %d: %v", req.SaleID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetInvoice(companyID, invoice.InvoiceID)
+}
+
+const taxInvoiceSelect = `
+	SELECT ti.invoice_id, ti.company_id, ti.sale_id, ti.invoice_number, ti.invoice_date, ti.seller_pan,
+		ti.customer_name, ti.customer_pan, ti.customer_address, ti.taxable_amount, ti.tax_amount, ti.gross_amount,
+		ti.status, ti.issued_by, ti.issued_at, ti.void_reason, ti.voided_by, ti.voided_at
+	FROM tax_invoices ti`
+
+func scanTaxInvoice(row rowScanner) (*models.TaxInvoice, error) {
+	var inv models.TaxInvoice
+	err := row.Scan(&inv.InvoiceID, &inv.CompanyID, &inv.SaleID, &inv.InvoiceNumber, &inv.InvoiceDate, &inv.SellerPAN,
+		&inv.CustomerName, &inv.CustomerPAN, &inv.CustomerAddress, &inv.TaxableAmount, &inv.TaxAmount, &inv.GrossAmount,
+		&inv.Status, &inv.IssuedBy, &inv.IssuedAt, &inv.VoidReason, &inv.VoidedBy, &inv.VoidedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTaxInvoiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan tax invoice: %v", err)
+	}
+	return &inv, nil
+}
+
+// GetInvoice returns an invoice of the company with its lines
+func (r *TaxRepository) GetInvoice(companyID, invoiceID int) (*models.TaxInvoice, error) {
+	invoice, err := scanTaxInvoice(r.db.QueryRow(taxInvoiceSelect+` WHERE ti.invoice_id = $1 AND ti.company_id = $2`,
+		invoiceID, companyID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT line_id, description, charge_type, tax_rate, taxable_amount, tax_amount, gross_amount
+		FROM tax_invoice_lines
+		WHERE invoice_id = $1
+		ORDER BY line_id
+	`, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax invoice lines: %v", err)
+	}
+	defer rows.Close()
+
+	invoice.Lines = []models.TaxInvoiceLine{}
+	for rows.Next() {
+		var line models.TaxInvoiceLine
+		if err := rows.Scan(&line.LineID, &line.Description, &line.ChargeType, &line.TaxRate,
+			&line.TaxableAmount, &line.TaxAmount, &line.GrossAmount); err != nil {
+			return nil, fmt.Errorf("failed to scan tax invoice line: %v", err)
+		}
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	return invoice, rows.Err()
+}
+
+// GetInvoices returns the company's invoices, newest first, without their lines
+func (r *TaxRepository) GetInvoices(companyID int, filter models.TaxInvoiceFilter) ([]models.TaxInvoice, error) {
+	query := taxInvoiceSelect + ` WHERE ti.company_id = $1`
+	args := []interface{}{companyID}
+	if filter.Month != "" {
+		args = append(args, filter.Month)
+		query += fmt.Sprintf(" AND ti.invoice_date >= to_date($%d, 'YYYY-MM') AND ti.invoice_date < to_date($%d, 'YYYY-MM') + INTERVAL '1 month'",
+			len(args), len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND ti.status = $%d", len(args))
+	}
+	if filter.SaleID != nil {
+		args = append(args, *filter.SaleID)
+		query += fmt.Sprintf(" AND ti.sale_id = $%d", len(args))
+	}
+	query += " ORDER BY ti.sequence DESC"
+
+	return r.queryInvoices(query, args...)
+}
+
+func (r *TaxRepository) queryInvoices(query string, args ...interface{}) ([]models.TaxInvoice, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax invoices: %v", err)
+	}
+	defer rows.Close()
+
+	invoices := []models.TaxInvoice{}
+	for rows.Next() {
+		invoice, err := scanTaxInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *invoice)
+	}
+	return invoices, rows.Err()
+}
+
+// VoidInvoice cancels an issued invoice; it keeps its number and the sale can be invoiced again
+func (r *TaxRepository) VoidInvoice(companyID, invoiceID, voidedBy int, reason string) (*models.TaxInvoice, error) {
+	result, err := r.db.Exec(`
+		UPDATE tax_invoices
+		SET status = 'void', void_reason = $1, voided_by = $2, voided_at = NOW()
+		WHERE invoice_id = $3 AND company_id = $4 AND status = 'issued'
+	`, reason, voidedBy, invoiceID, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to void tax invoice %d: %v", invoiceID, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		invoice, err := r.GetInvoice(companyID, invoiceID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: invoice %s is already void", ErrInvalidTaxInvoice, invoice.InvoiceNumber)
+	}
+	return r.GetInvoice(companyID, invoiceID)
+}
+
+// GetSalesTaxReport returns the sales book of a month given as YYYY-MM
+func (r *TaxRepository) GetSalesTaxReport(companyID int, month string) (*models.SalesTaxReport, error) {
+	report := models.SalesTaxReport{Month: month, Rates: []models.SalesTaxRateTotal{}}
+
+	invoices, err := r.queryInvoices(taxInvoiceSelect+`
+		WHERE ti.company_id = $1
+			AND ti.invoice_date >= to_date($2, 'YYYY-MM') AND ti.invoice_date < to_date($2, 'YYYY-MM') + INTERVAL '1 month'
+		ORDER BY ti.sequence
+	`, companyID, month)
+	if err != nil {
+		return nil, err
+	}
+	report.Invoices = invoices
+	for _, invoice := range invoices {
+		if invoice.Status == models.TaxInvoiceVoid {
+			report.VoidCount++
+			continue
+		}
+		report.InvoiceCount++
+		report.TaxableAmount += invoice.TaxableAmount
+		report.TaxAmount += invoice.TaxAmount
+		report.GrossAmount += invoice.GrossAmount
+	}
+	report.TaxableAmount = roundCents(report.TaxableAmount)
+	report.TaxAmount = roundCents(report.TaxAmount)
+	report.GrossAmount = roundCents(report.GrossAmount)
+
+	rows, err := r.db.Query(`
+		SELECT l.tax_rate, SUM(l.taxable_amount), SUM(l.tax_amount), SUM(l.gross_amount)
+		FROM tax_invoice_lines l
+		JOIN tax_invoices ti ON ti.invoice_id = l.invoice_id
+		WHERE ti.company_id = $1 AND ti.status = 'issued'
+			AND ti.invoice_date >= to_date($2, 'YYYY-MM') AND ti.invoice_date < to_date($2, 'YYYY-MM') + INTERVAL '1 month'
+		GROUP BY l.tax_rate
+		ORDER BY l.tax_rate DESC
+	`, companyID, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sales tax by rate: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var total models.SalesTaxRateTotal
+		if err := rows.Scan(&total.TaxRate, &total.TaxableAmount, &total.TaxAmount, &total.GrossAmount); err != nil {
+			return nil, fmt.Errorf("failed to scan sales tax by rate: %v", err)
+		}
+		report.Rates = append(report.Rates, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	uninvoiced, err := r.getUninvoicedSales(companyID, month)
+	if err != nil {
+		return nil, err
+	}
+	report.Uninvoiced = *uninvoiced
+	return &report, nil
+}
+
+// getUninvoicedSales adds up sales booked in the month, and their charges, that are not cancelled
+// and have no invoice in force, so the accountant can see what is still to be invoiced
+func (r *TaxRepository) getUninvoicedSales(companyID int, month string) (*models.UninvoicedSales, error) {
+	const saleWhere = `u.company_id = $1 AND s.status <> 'cancelled'
+		AND s.booking_date >= to_date($2, 'YYYY-MM') AND s.booking_date < to_date($2, 'YYYY-MM') + INTERVAL '1 month'
+		AND NOT EXISTS (SELECT 1 FROM tax_invoices ti WHERE ti.sale_id = s.sale_id AND ti.status = 'issued')`
+	rows, err := r.db.Query(`
+		SELECT s.sale_id, COALESCE(s.total_amount, 0),
+			COALESCE(s.tax_rate, `+taxRateSQL("$1::int", "'"+models.TaxChargeTypeRental+"'")+`)
+		FROM sales s
+		JOIN users u ON u.id = s.user_id
+		WHERE `+saleWhere+`
+		UNION ALL
+		SELECT s.sale_id, `+signedChargeSQL("c")+`, COALESCE(c.tax_rate, `+taxRateSQL("$1::int", "c.charge_type")+`)
+		FROM sales_charges c
+		JOIN sales s ON s.sale_id = c.sale_id
+		JOIN users u ON u.id = s.user_id
+		WHERE `+saleWhere+`
+	`, companyID, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query uninvoiced sales: %v", err)
+	}
+	defer rows.Close()
+
+	var uninvoiced models.UninvoicedSales
+	sales := map[int]bool{}
+	for rows.Next() {
+		var saleID int
+		var gross, rate float64
+		if err := rows.Scan(&saleID, &gross, &rate); err != nil {
+			return nil, fmt.Errorf("failed to scan uninvoiced sale: %v", err)
+		}
+		sales[saleID] = true
+		taxable, tax := taxBreakdown(gross, rate)
+		uninvoiced.TaxableAmount += taxable
+		uninvoiced.TaxAmount += tax
+		uninvoiced.GrossAmount += roundCents(gross)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	uninvoiced.Sales = len(sales)
+	uninvoiced.TaxableAmount = roundCents(uninvoiced.TaxableAmount)
+	uninvoiced.TaxAmount = roundCents(uninvoiced.TaxAmount)
+	uninvoiced.GrossAmount = roundCents(uninvoiced.GrossAmount)
+	return &uninvoiced, nil
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import "testing"
+
+func TestTaxBreakdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		gross, rate float64
+		wantTaxable float64
+		wantTax     float64
+	}{
+		{"standard rate", 113, 13, 100, 13},
+		{"rounded to cents", 1000, 13, 884.96, 115.04},
+		{"zero rated", 250, 0, 250, 0},
+		{"discount is negative", -113, 13, -100, -13},
+		{"nothing", 0, 13, 0, 0},
+		{"gross is rounded first", 112.999, 13, 100, 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taxable, tax := taxBreakdown(tt.gross, tt.rate)
+			if taxable != tt.wantTaxable || tax != tt.wantTax {
+				t.Errorf("taxBreakdown(%v, %v) = %v, %v, want %v, %v", tt.gross, tt.rate, taxable, tax, tt.wantTaxable, tt.wantTax)
+			}
+		})
+	}
+}
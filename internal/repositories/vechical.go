@@ -31,7 +31,9 @@ func (r *VehicleRepository) GetVehicles(filters models.VehicleFilter, includeBoo
 			v.vehicle_registration_number, 
 			v.is_available, 
 			v.status,
-			v.image_name
+			v.image_name,
+			v.branch_id,
+			COALESCE(v.current_branch_id, v.branch_id)
 		FROM vehicles v
 		WHERE 1=1
 	`
@@ -55,6 +57,9 @@ func (r *VehicleRepository) GetVehicles(filters models.VehicleFilter, includeBoo
 	if filters.Status != "" {
 		baseQuery += " AND v.status = '" + filters.Status + "'"
 	}
+	if filters.BranchID != "" {
+		baseQuery += " AND COALESCE(v.current_branch_id, v.branch_id) = " + filters.BranchID
+	}
 
 	// Add pagination
 	if filters.Limit > 0 {
@@ -84,6 +89,8 @@ func (r *VehicleRepository) GetVehicles(filters models.VehicleFilter, includeBoo
 			&v.IsAvailable,
 			&v.Status,
 			&v.SalesImage,
+			&v.BranchID,
+			&v.CurrentBranchID,
 		)
 		if err != nil {
 			return nil, err
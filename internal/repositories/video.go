@@ -189,7 +189,11 @@ func (r *videoRepository) UploadFile(filePath string, contentType string, saleID
 func (r *videoRepository) GetUploadStats() UploadStats {
 	r.stats.mu.RLock()
 	defer r.stats.mu.RUnlock()
-	return r.stats
+	return UploadStats{
+		TotalUploads:   r.stats.TotalUploads,
+		TotalBytes:     r.stats.TotalBytes,
+		AverageSpeedMB: r.stats.AverageSpeedMB,
+	}
 }
 
 // Helper function to generate consistent filenames
@@ -0,0 +1,303 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"renting/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// enqueueSaleWebhookEvent writes one outbox row per active endpoint of the sale's company
+// that is subscribed to eventType. It runs inside the caller's transaction so events are
+// only delivered when the business change commits. extra is merged into the payload data
+// next to the sale snapshot.
+func enqueueSaleWebhookEvent(tx *sql.Tx, saleID int, eventType string, extra map[string]interface{}) error {
+	if extra == nil {
+		extra = map[string]interface{}{}
+	}
+	extraJSON, err := json.Marshal(extra)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+		SELECT e.endpoint_id, $2::varchar, jsonb_build_object(
+			'event', $2::text,
+			'occurred_at', NOW(),
+			'data', jsonb_build_object(
+				'sale', jsonb_build_object(
+					'sale_id', s.sale_id,
+					'vehicle_id', s.vehicle_id,
+					'vehicle_name', v.vehicle_name,
+					'customer_name', s.customer_name,
+					'customer_phone', s.customer_phone,
+					'total_amount', s.total_amount,
+					'other_charges', s.other_charges,
+					'status', s.status,
+					'payment_status', s.payment_status,
+					'date_of_delivery', s.date_of_delivery,
+					'return_date', s.return_date,
+					'actual_date_of_delivery', s.actual_date_of_delivery,
+					'actual_date_of_return', s.actual_date_of_return
+				)
+			) || $3::jsonb
+		)
+		FROM sales s
+		JOIN users u ON u.id = s.user_id
+		JOIN webhook_endpoints e ON e.company_id = u.company_id
+		LEFT JOIN vehicles v ON v.vehicle_id = s.vehicle_id
+		WHERE s.sale_id = $1
+		AND e.is_active = TRUE
+		AND $2::text = ANY(e.event_types)
+	`, saleID, eventType, string(extraJSON))
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s webhook for sale %d: %v", eventType, saleID, err)
+	}
+	return nil
+}
+
+// enqueueCompanyWebhookEvent writes one outbox row per active endpoint of the company that is
+// subscribed to eventType, for events that are not about a single sale
+func enqueueCompanyWebhookEvent(tx *sql.Tx, companyID int, eventType string, data map[string]interface{}) error {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+		SELECT e.endpoint_id, $2::varchar, jsonb_build_object(
+			'event', $2::text,
+			'occurred_at', NOW(),
+			'data', $3::jsonb
+		)
+		FROM webhook_endpoints e
+		WHERE e.company_id = $1
+		AND e.is_active = TRUE
+		AND $2::text = ANY(e.event_types)
+	`, companyID, eventType, string(dataJSON))
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s webhook for company %d: %v", eventType, companyID, err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	err := r.db.QueryRow(`
+		INSERT INTO webhook_endpoints (company_id, url, secret, event_types, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING endpoint_id, created_at, updated_at
+	`, endpoint.CompanyID, endpoint.URL, endpoint.Secret, pq.Array(endpoint.EventTypes), endpoint.IsActive,
+	).Scan(&endpoint.EndpointID, &endpoint.CreatedAt, &endpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) GetEndpoints(companyID int) ([]models.WebhookEndpoint, error) {
+	rows, err := r.db.Query(`
+		SELECT endpoint_id, company_id, url, event_types, is_active, created_at, updated_at
+		FROM webhook_endpoints
+		WHERE company_id = $1
+		ORDER BY endpoint_id
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook endpoints: %v", err)
+	}
+	defer rows.Close()
+
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		var e models.WebhookEndpoint
+		if err := rows.Scan(&e.EndpointID, &e.CompanyID, &e.URL, pq.Array(&e.EventTypes), &e.IsActive, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %v", err)
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+// UpdateEndpoint overwrites url, subscriptions and active flag. The secret is only
+// replaced when a new one is supplied.
+func (r *WebhookRepository) UpdateEndpoint(endpoint *models.WebhookEndpoint) error {
+	err := r.db.QueryRow(`
+		UPDATE webhook_endpoints
+		SET url = $1,
+			secret = COALESCE(NULLIF($2, ''), secret),
+			event_types = $3,
+			is_active = $4,
+			updated_at = NOW()
+		WHERE endpoint_id = $5 AND company_id = $6
+		RETURNING created_at, updated_at
+	`, endpoint.URL, endpoint.Secret, pq.Array(endpoint.EventTypes), endpoint.IsActive, endpoint.EndpointID, endpoint.CompanyID,
+	).Scan(&endpoint.CreatedAt, &endpoint.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return errors.New("webhook endpoint not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) DeleteEndpoint(companyID, endpointID int) error {
+	result, err := r.db.Exec(`
+		DELETE FROM webhook_endpoints
+		WHERE endpoint_id = $1 AND company_id = $2
+	`, endpointID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("webhook endpoint not found")
+	}
+	return nil
+}
+
+// ClaimDueDeliveries leases up to limit pending deliveries whose next attempt is due.
+// The lease pushes next_attempt_at forward so concurrent dispatchers skip the rows,
+// and an attempt that crashes mid-flight is picked up again once the lease expires.
+func (r *WebhookRepository) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(`
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM webhook_endpoints e
+		WHERE d.endpoint_id = e.endpoint_id
+		AND d.delivery_id IN (
+			SELECT delivery_id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.delivery_id, d.endpoint_id, e.url, e.secret, d.event_type, d.payload,
+			d.status, d.attempts, d.next_attempt_at, d.created_at
+	`, limit, int(lease.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(&d.DeliveryID, &d.EndpointID, &d.URL, &d.Secret, &d.EventType, &payload,
+			&d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) MarkDelivered(deliveryID int) error {
+	_, err := r.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'delivered',
+			attempts = attempts + 1,
+			last_error = NULL,
+			delivered_at = NOW()
+		WHERE delivery_id = $1
+	`, deliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook delivery %d delivered: %v", deliveryID, err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt. A nil nextAttempt moves the delivery to the dead-letter state.
+func (r *WebhookRepository) MarkFailed(deliveryID int, lastError string, nextAttempt *time.Time) error {
+	status := "pending"
+	if nextAttempt == nil {
+		status = "dead"
+	}
+	_, err := r.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = $1,
+			attempts = attempts + 1,
+			last_error = $2,
+			next_attempt_at = COALESCE($3, next_attempt_at)
+		WHERE delivery_id = $4
+	`, status, lastError, nextAttempt, deliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery failure %d: %v", deliveryID, err)
+	}
+	return nil
+}
+
+// GetDeadDeliveries lists deliveries that exhausted their retries for the company's endpoints
+func (r *WebhookRepository) GetDeadDeliveries(companyID, limit, offset int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(`
+		SELECT d.delivery_id, d.endpoint_id, e.url, d.event_type, d.payload, d.status,
+			d.attempts, d.next_attempt_at, d.last_error, d.created_at
+		FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.endpoint_id = d.endpoint_id
+		WHERE e.company_id = $1 AND d.status = 'dead'
+		ORDER BY d.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, companyID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dead webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(&d.DeliveryID, &d.EndpointID, &d.URL, &d.EventType, &payload, &d.Status,
+			&d.Attempts, &d.NextAttemptAt, &d.LastError, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RetryDelivery puts a dead delivery back in the queue with a fresh retry budget
+func (r *WebhookRepository) RetryDelivery(companyID, deliveryID int) error {
+	result, err := r.db.Exec(`
+		UPDATE webhook_deliveries d
+		SET status = 'pending',
+			attempts = 0,
+			next_attempt_at = NOW()
+		FROM webhook_endpoints e
+		WHERE d.endpoint_id = e.endpoint_id
+		AND d.delivery_id = $1
+		AND e.company_id = $2
+		AND d.status = 'dead'
+	`, deliveryID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to requeue webhook delivery: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("dead webhook delivery not found")
+	}
+	return nil
+}
@@ -69,8 +69,10 @@ func (s *authService) Register(ctx context.Context, user *models.User, companyCo
 		return fmt.Errorf("failed to fetch company ID: %v", err)
 	}
 
-	// Set the company ID in the user model
+	// Set the company ID in the user model; self-registered users start without roles
 	user.CompanyID = companyID
+	user.IsAdmin = false
+	user.HasAccounting = false
 
 	// Check if the mobile number already exists
 	existingUser, err := s.userRepo.FindByMobileAndCompanyCode(ctx, user.MobileNumber, companyCode)
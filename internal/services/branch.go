@@ -0,0 +1,95 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"strings"
+)
+
+var branchCodePattern = regexp.MustCompile(`^[A-Z0-9_]{1,50}$`)
+
+type BranchService struct {
+	repo *repositories.BranchRepository
+}
+
+func NewBranchService(repo *repositories.BranchRepository) *BranchService {
+	return &BranchService{repo: repo}
+}
+
+// branchFromRequest checks a branch request; codes are upper-cased with spaces turned into
+// underscores, e.g. "ktm main" becomes KTM_MAIN
+func branchFromRequest(companyID int, req models.BranchRequest) (*models.Branch, error) {
+	code := strings.ToUpper(strings.Join(strings.Fields(req.Code), "_"))
+	if !branchCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("%w: code may only contain letters, digits and underscores", repositories.ErrInvalidBranch)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", repositories.ErrInvalidBranch)
+	}
+	return &models.Branch{
+		CompanyID: companyID,
+		Code:      code,
+		Name:      name,
+		Address:   trimmedNote(req.Address),
+		Phone:     trimmedNote(req.Phone),
+		IsActive:  req.IsActive == nil || *req.IsActive,
+	}, nil
+}
+
+func (s *BranchService) GetBranches(companyID int, includeInactive bool) ([]models.Branch, error) {
+	return s.repo.GetBranches(companyID, includeInactive)
+}
+
+func (s *BranchService) GetBranch(companyID, branchID int) (*models.Branch, error) {
+	return s.repo.GetBranch(companyID, branchID)
+}
+
+func (s *BranchService) CreateBranch(companyID int, req models.BranchRequest) (*models.Branch, error) {
+	branch, err := branchFromRequest(companyID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateBranch(branch); err != nil {
+		return nil, err
+	}
+	return branch, nil
+}
+
+// UpdateBranch replaces a branch; setting is_active to false closes it to new rentals
+func (s *BranchService) UpdateBranch(companyID, branchID int, req models.BranchRequest) (*models.Branch, error) {
+	branch, err := branchFromRequest(companyID, req)
+	if err != nil {
+		return nil, err
+	}
+	branch.BranchID = branchID
+	if err := s.repo.UpdateBranch(branch); err != nil {
+		return nil, err
+	}
+	return branch, nil
+}
+
+func (s *BranchService) GetTransferFees(companyID int) ([]models.BranchTransferFee, error) {
+	return s.repo.GetTransferFees(companyID)
+}
+
+func (s *BranchService) SetTransferFee(companyID int, req models.BranchTransferFeeRequest) (*models.BranchTransferFee, error) {
+	if req.FromBranchID == req.ToBranchID {
+		return nil, fmt.Errorf("%w: a transfer needs two different branches", repositories.ErrInvalidBranch)
+	}
+	return s.repo.SetTransferFee(companyID, req)
+}
+
+func (s *BranchService) DeleteTransferFee(companyID, feeID int) error {
+	return s.repo.DeleteTransferFee(companyID, feeID)
+}
+
+func (s *BranchService) AssignUser(companyID, userID int, req models.AssignBranchRequest) error {
+	return s.repo.AssignUser(companyID, userID, req.BranchID)
+}
+
+func (s *BranchService) AssignVehicle(companyID, vehicleID int, req models.AssignBranchRequest) error {
+	return s.repo.AssignVehicle(companyID, vehicleID, req.BranchID)
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"strings"
+	"time"
+)
+
+const (
+	budgetSchedulerInterval = 15 * time.Minute
+	// maxRecurringBackfill caps how many missed occurrences one run books for a template
+	maxRecurringBackfill = 60
+)
+
+type BudgetService struct {
+	repo              *repositories.BudgetRepository
+	expenseRepo       repositories.ExpenseRepository
+	approvalThreshold float64
+}
+
+func NewBudgetService(repo *repositories.BudgetRepository, expenseRepo repositories.ExpenseRepository, approvalThreshold float64) *BudgetService {
+	return &BudgetService{repo: repo, expenseRepo: expenseRepo, approvalThreshold: approvalThreshold}
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// addMonthsClamped adds months keeping the day of month, clamped to the end of shorter months
+func addMonthsClamped(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, months, 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// occurrenceDate is the date of the nth occurrence (0-based) of a schedule starting on start
+func occurrenceDate(start time.Time, frequency string, n int) time.Time {
+	switch frequency {
+	case models.RecurrenceWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case models.RecurrenceQuarterly:
+		return addMonthsClamped(start, 3*n)
+	case models.RecurrenceYearly:
+		return addMonthsClamped(start, 12*n)
+	default:
+		return addMonthsClamped(start, n)
+	}
+}
+
+// parseBudgetMonth reads a YYYY-MM month, defaulting to the current month
+func parseBudgetMonth(value string) (time.Time, error) {
+	if value == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month format, use YYYY-MM: %v", err)
+	}
+	return month, nil
+}
+
+func (s *BudgetService) validateCategory(companyID int, expenseType string) (*models.ExpenseCategory, error) {
+	category, err := s.expenseRepo.FindCategory(companyID, expenseType)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, fmt.Errorf("unknown expense category: %s", expenseType)
+	}
+	return category, nil
+}
+
+func (s *BudgetService) validateRecurringRequest(companyID int, req *models.RecurringExpenseRequest) error {
+	req.ExpenseType = strings.ToLower(strings.TrimSpace(req.ExpenseType))
+	req.Frequency = strings.ToLower(strings.TrimSpace(req.Frequency))
+	if req.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	switch req.Frequency {
+	case models.RecurrenceWeekly, models.RecurrenceMonthly, models.RecurrenceQuarterly, models.RecurrenceYearly:
+	default:
+		return fmt.Errorf("invalid frequency: %s", req.Frequency)
+	}
+	req.StartDate = dateOnly(req.StartDate)
+	if req.EndDate != nil {
+		end := dateOnly(*req.EndDate)
+		if end.Before(req.StartDate) {
+			return errors.New("end_date must not be before start_date")
+		}
+		req.EndDate = &end
+	}
+	_, err := s.validateCategory(companyID, req.ExpenseType)
+	return err
+}
+
+func (s *BudgetService) GetRecurringExpenses(companyID int) ([]models.RecurringExpense, error) {
+	return s.repo.GetRecurringExpenses(companyID)
+}
+
+// CreateRecurringExpense sets up a template; its first expense is booked on start_date
+func (s *BudgetService) CreateRecurringExpense(companyID, userID int, req models.RecurringExpenseRequest) (*models.RecurringExpense, error) {
+	if err := s.validateRecurringRequest(companyID, &req); err != nil {
+		return nil, err
+	}
+	template := &models.RecurringExpense{
+		CompanyID:   companyID,
+		ExpenseType: req.ExpenseType,
+		Amount:      req.Amount,
+		Description: req.Description,
+		VehicleID:   req.VehicleID,
+		Frequency:   req.Frequency,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		NextRunDate: req.StartDate,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+		CreatedBy:   userID,
+	}
+	if err := s.repo.CreateRecurringExpense(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// UpdateRecurringExpense edits a template. Changing the start date or frequency restarts the
+// schedule; occurrences before today are skipped once the template has run, so nothing is booked twice.
+func (s *BudgetService) UpdateRecurringExpense(companyID, recurringID int, req models.RecurringExpenseRequest) (*models.RecurringExpense, error) {
+	template, err := s.repo.GetRecurringExpense(companyID, recurringID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateRecurringRequest(companyID, &req); err != nil {
+		return nil, err
+	}
+
+	if !req.StartDate.Equal(dateOnly(template.StartDate)) || req.Frequency != template.Frequency {
+		hasRun := template.LastRunAt != nil
+		template.StartDate, template.Frequency, template.RunCount = req.StartDate, req.Frequency, 0
+		template.NextRunDate = req.StartDate
+		if hasRun {
+			for template.NextRunDate.Before(today()) {
+				template.RunCount++
+				template.NextRunDate = occurrenceDate(template.StartDate, template.Frequency, template.RunCount)
+			}
+		}
+	}
+	template.ExpenseType = req.ExpenseType
+	template.Amount = req.Amount
+	template.Description = req.Description
+	template.VehicleID = req.VehicleID
+	template.EndDate = req.EndDate
+	if req.IsActive != nil {
+		template.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.UpdateRecurringExpense(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (s *BudgetService) DeleteRecurringExpense(companyID, recurringID int) error {
+	return s.repo.DeleteRecurringExpense(companyID, recurringID)
+}
+
+// RunRecurringExpenses books every occurrence that has come due, catching up on missed runs.
+// A nil companyID runs the templates of every company.
+func (s *BudgetService) RunRecurringExpenses(companyID *int) (int, error) {
+	now := today()
+	templates, err := s.repo.GetDueRecurringExpenses(now, companyID)
+	if err != nil {
+		return 0, err
+	}
+
+	booked := 0
+	for _, template := range templates {
+		var occurrences []time.Time
+		n := template.RunCount
+		next := dateOnly(template.NextRunDate)
+		for !next.After(now) && (template.EndDate == nil || !next.After(*template.EndDate)) &&
+			len(occurrences) < maxRecurringBackfill {
+			occurrences = append(occurrences, next)
+			n++
+			next = occurrenceDate(dateOnly(template.StartDate), template.Frequency, n)
+		}
+		if len(occurrences) == 0 {
+			continue
+		}
+		stillActive := template.EndDate == nil || !next.After(*template.EndDate)
+
+		// Generated expenses follow the same approval rule as ones entered by hand
+		approvalStatus := models.ExpenseApprovalApproved
+		category, err := s.expenseRepo.FindCategory(template.CompanyID, template.ExpenseType)
+		if err != nil {
+			log.Printf("[ERROR] Recurring expense %d: %v", template.RecurringID, err)
+			continue
+		}
+		if category == nil {
+			category = &models.ExpenseCategory{Code: template.ExpenseType}
+		}
+		if expenseNeedsApproval(category, s.approvalThreshold, template.Amount) {
+			approvalStatus = models.ExpenseApprovalPending
+		}
+
+		ran, err := s.repo.RunRecurringExpense(template, occurrences, approvalStatus, next, stillActive)
+		if err != nil {
+			log.Printf("[ERROR] Recurring expense %d: %v", template.RecurringID, err)
+			continue
+		}
+		if ran {
+			booked += len(occurrences)
+		}
+	}
+	return booked, nil
+}
+
+// CheckBudgets raises alerts for budgets that spending has gone over, of one company or, with a
+// nil companyID, of every company
+func (s *BudgetService) CheckBudgets(companyID *int) {
+	alerts, err := s.repo.RaiseBudgetAlerts(companyID)
+	if err != nil {
+		log.Printf("[ERROR] Budget check: %v", err)
+		return
+	}
+	for _, alert := range alerts {
+		log.Printf("[INFO] Budget %d for %s %s exceeded: spent %.2f of %.2f",
+			alert.BudgetID, alert.ExpenseType, alert.Month.Format("2006-01"), alert.Actual, alert.Budget)
+	}
+}
+
+// StartScheduler books recurring expenses and checks budgets in the background
+func (s *BudgetService) StartScheduler() {
+	run := func() {
+		booked, err := s.RunRecurringExpenses(nil)
+		if err != nil {
+			log.Printf("[ERROR] Recurring expenses: %v", err)
+		} else if booked > 0 {
+			log.Printf("[INFO] Recurring expenses: booked %d expenses", booked)
+		}
+		s.CheckBudgets(nil)
+	}
+	go func() {
+		run()
+		ticker := time.NewTicker(budgetSchedulerInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
+
+func (s *BudgetService) GetBudgets(companyID int, month string) ([]models.ExpenseBudget, error) {
+	if month == "" {
+		return s.repo.GetBudgets(companyID, nil)
+	}
+	m, err := parseBudgetMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetBudgets(companyID, &m)
+}
+
+// SetBudget creates or replaces a category's budget for a month
+func (s *BudgetService) SetBudget(companyID, userID int, req models.ExpenseBudgetRequest) (*models.ExpenseBudget, error) {
+	expenseType := strings.ToLower(strings.TrimSpace(req.ExpenseType))
+	if req.Amount < 0 {
+		return nil, errors.New("amount must not be negative")
+	}
+	month, err := parseBudgetMonth(req.Month)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.validateCategory(companyID, expenseType); err != nil {
+		return nil, err
+	}
+
+	budget := &models.ExpenseBudget{
+		CompanyID:   companyID,
+		ExpenseType: expenseType,
+		Month:       month,
+		Amount:      req.Amount,
+		CreatedBy:   &userID,
+	}
+	if err := s.repo.SetBudget(budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *BudgetService) DeleteBudget(companyID, budgetID int) error {
+	return s.repo.DeleteBudget(companyID, budgetID)
+}
+
+// GetBudgetReport compares each category's budget with approved spending for a month
+func (s *BudgetService) GetBudgetReport(companyID int, month string) (*models.BudgetReport, error) {
+	m, err := parseBudgetMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.GetBudgetVsActual(companyID, m)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BudgetReport{Month: m, Categories: rows}
+	for i := range report.Categories {
+		row := &report.Categories[i]
+		row.Actual = round2(row.Actual)
+		row.Remaining = round2(row.Budget - row.Actual)
+		row.OverBudget = row.Budgeted && row.Actual > row.Budget
+		if row.Budget > 0 {
+			row.UsedPercent = round2(row.Actual / row.Budget * 100)
+		}
+		report.TotalBudget += row.Budget
+		report.TotalActual += row.Actual
+	}
+	report.TotalBudget = round2(report.TotalBudget)
+	report.TotalActual = round2(report.TotalActual)
+	return report, nil
+}
+
+func (s *BudgetService) GetBudgetAlerts(companyID int, month string) ([]models.BudgetAlert, error) {
+	if month == "" {
+		return s.repo.GetBudgetAlerts(companyID, nil)
+	}
+	m, err := parseBudgetMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetBudgetAlerts(companyID, &m)
+}
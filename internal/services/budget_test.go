@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"renting/internal/models"
+)
+
+func ymd(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAddMonthsClamped(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  time.Time
+		months int
+		want   time.Time
+	}{
+		{"same day next month", ymd(2024, 3, 15), 1, ymd(2024, 4, 15)},
+		{"31st into 30-day month", ymd(2024, 1, 31), 3, ymd(2024, 4, 30)},
+		{"31st into leap february", ymd(2024, 1, 31), 1, ymd(2024, 2, 29)},
+		{"31st into february", ymd(2023, 1, 31), 1, ymd(2023, 2, 28)},
+		{"across year end", ymd(2024, 11, 30), 3, ymd(2025, 2, 28)},
+		{"leap day plus a year", ymd(2024, 2, 29), 12, ymd(2025, 2, 28)},
+		{"zero months", ymd(2024, 5, 31), 0, ymd(2024, 5, 31)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addMonthsClamped(tt.start, tt.months); !got.Equal(tt.want) {
+				t.Errorf("addMonthsClamped(%s, %d) = %s, want %s", tt.start.Format("2006-01-02"), tt.months, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestOccurrenceDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     time.Time
+		frequency string
+		n         int
+		want      time.Time
+	}{
+		{"first occurrence is the start", ymd(2024, 1, 31), models.RecurrenceMonthly, 0, ymd(2024, 1, 31)},
+		{"weekly", ymd(2024, 2, 26), models.RecurrenceWeekly, 2, ymd(2024, 3, 11)},
+		{"monthly clamps to february", ymd(2024, 1, 31), models.RecurrenceMonthly, 1, ymd(2024, 2, 29)},
+		{"monthly keeps the start day after a short month", ymd(2024, 1, 31), models.RecurrenceMonthly, 2, ymd(2024, 3, 31)},
+		{"quarterly", ymd(2024, 11, 30), models.RecurrenceQuarterly, 1, ymd(2025, 2, 28)},
+		{"yearly from a leap day", ymd(2024, 2, 29), models.RecurrenceYearly, 4, ymd(2028, 2, 29)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := occurrenceDate(tt.start, tt.frequency, tt.n); !got.Equal(tt.want) {
+				t.Errorf("occurrenceDate(%s, %s, %d) = %s, want %s", tt.start.Format("2006-01-02"), tt.frequency, tt.n, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
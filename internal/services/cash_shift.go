@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCashShift = errors.New("invalid cash shift request")
+
+type CashShiftService struct {
+	repo *repositories.CashShiftRepository
+}
+
+func NewCashShiftService(repo *repositories.CashShiftRepository) *CashShiftService {
+	return &CashShiftService{repo: repo}
+}
+
+func (s *CashShiftService) OpenShift(companyID, userID int, req models.OpenCashShiftRequest) (*models.CashShift, error) {
+	return s.repo.OpenShift(companyID, userID, req.OpeningFloat, trimmedNote(req.Note))
+}
+
+// GetCurrentShift returns the caller's open shift with what the drawer should hold right now
+func (s *CashShiftService) GetCurrentShift(userID int) (*models.CashShift, error) {
+	return s.repo.GetOpenShift(userID)
+}
+
+func (s *CashShiftService) CloseShift(userID int, req models.CloseCashShiftRequest) (*models.CashShift, error) {
+	return s.repo.CloseShift(userID, *req.CountedCash, trimmedNote(req.Note))
+}
+
+// ApproveShift signs a closed shift off. Staff cannot sign off their own drawer, and a count that
+// does not match needs a note explaining the variance.
+func (s *CashShiftService) ApproveShift(companyID, shiftID, managerID int, req models.ApproveCashShiftRequest) (*models.CashShift, error) {
+	shift, err := s.repo.GetShift(companyID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if shift.UserID == managerID {
+		return nil, fmt.Errorf("%w: a shift must be signed off by someone else", ErrInvalidCashShift)
+	}
+	note := trimmedNote(req.Note)
+	if shift.Variance != nil && math.Abs(*shift.Variance) >= 0.01 && note == nil {
+		return nil, fmt.Errorf("%w: a note is required to sign off a variance of %.2f", ErrInvalidCashShift, *shift.Variance)
+	}
+	return s.repo.ApproveShift(companyID, shiftID, managerID, note)
+}
+
+func (s *CashShiftService) GetShift(companyID, shiftID int) (*models.CashShiftDetail, error) {
+	return s.repo.GetShiftDetail(companyID, shiftID)
+}
+
+func (s *CashShiftService) GetShifts(companyID int, filter models.CashShiftFilter) ([]models.CashShift, error) {
+	switch filter.Status {
+	case "", models.CashShiftOpen, models.CashShiftClosed, models.CashShiftApproved:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidCashShift, filter.Status)
+	}
+	if filter.Date != "" {
+		if _, err := time.Parse("2006-01-02", filter.Date); err != nil {
+			return nil, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidCashShift)
+		}
+	}
+	return s.repo.GetShifts(companyID, filter)
+}
+
+// GetEndOfDayReport sums up the shifts opened on date (YYYY-MM-DD, today when empty) per branch and
+// overall, optionally for a single branch
+func (s *CashShiftService) GetEndOfDayReport(companyID int, date string, branchID *int) (*models.EndOfDayReport, error) {
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	shifts, err := s.GetShifts(companyID, models.CashShiftFilter{Date: date, BranchID: branchID})
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.EndOfDayReport{Date: date, Branches: []models.EndOfDayBranch{}}
+	index := map[int]int{} // branch id, 0 for no branch, to its place in report.Branches
+	for _, shift := range shifts {
+		key := 0
+		if shift.BranchID != nil {
+			key = *shift.BranchID
+		}
+		i, ok := index[key]
+		if !ok {
+			branch := models.EndOfDayBranch{BranchID: shift.BranchID, Shifts: []models.CashShift{}}
+			if shift.BranchName != nil {
+				branch.BranchName = *shift.BranchName
+			}
+			report.Branches = append(report.Branches, branch)
+			i = len(report.Branches) - 1
+			index[key] = i
+		}
+		report.Branches[i].Shifts = append(report.Branches[i].Shifts, shift)
+		addCashShift(&report.Branches[i].CashTotals, shift)
+		addCashShift(&report.CashTotals, shift)
+	}
+	for i := range report.Branches {
+		roundCashTotals(&report.Branches[i].CashTotals)
+	}
+	roundCashTotals(&report.CashTotals)
+	return &report, nil
+}
+
+func addCashShift(totals *models.CashTotals, shift models.CashShift) {
+	switch shift.Status {
+	case models.CashShiftOpen:
+		totals.OpenShifts++
+	case models.CashShiftClosed:
+		totals.AwaitingSignOff++
+	case models.CashShiftApproved:
+		totals.ApprovedShifts++
+	}
+	totals.OpeningFloat += shift.OpeningFloat
+	totals.CashCollected += shift.CashCollected
+	totals.CashExpenses += shift.CashExpenses
+	totals.ExpectedCash += shift.ExpectedCash
+	if shift.CountedCash != nil {
+		totals.CountedCash += *shift.CountedCash
+	}
+	if shift.Variance != nil {
+		totals.Variance += *shift.Variance
+	}
+}
+
+func roundCashTotals(totals *models.CashTotals) {
+	totals.OpeningFloat = round2(totals.OpeningFloat)
+	totals.CashCollected = round2(totals.CashCollected)
+	totals.CashExpenses = round2(totals.CashExpenses)
+	totals.ExpectedCash = round2(totals.ExpectedCash)
+	totals.CountedCash = round2(totals.CountedCash)
+	totals.Variance = round2(totals.Variance)
+}
+
+// trimmedNote drops notes that are only whitespace
+func trimmedNote(note *string) *string {
+	if note == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*note)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"renting/internal/utils"
+	"strings"
+	"time"
+)
+
+// userInviteTTL is how long an invite can be accepted for
+const userInviteTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidCompany = errors.New("invalid company")
+	ErrInvalidUser    = errors.New("invalid user")
+)
+
+var companyCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,50}$`)
+
+type CompanyService struct {
+	repo *repositories.CompanyRepository
+}
+
+func NewCompanyService(repo *repositories.CompanyRepository) *CompanyService {
+	return &CompanyService{repo: repo}
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateInviteToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate invite token: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// OnboardCompany creates a company and its owner, who can then log in with the company code and
+// invite the rest of the staff
+func (s *CompanyService) OnboardCompany(req models.OnboardCompanyRequest) (*models.OnboardCompanyResponse, error) {
+	company := models.Company{Name: strings.TrimSpace(req.Name), CompanyCode: strings.TrimSpace(req.CompanyCode)}
+	if company.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidCompany)
+	}
+	if !companyCodePattern.MatchString(company.CompanyCode) {
+		return nil, fmt.Errorf("%w: company code must be 2 to 50 letters, digits, dashes or underscores", ErrInvalidCompany)
+	}
+
+	owner := models.User{
+		Username:     strings.TrimSpace(req.Owner.Username),
+		MobileNumber: strings.TrimSpace(req.Owner.MobileNumber),
+	}
+	if owner.Username == "" || owner.MobileNumber == "" {
+		return nil, fmt.Errorf("%w: owner username and mobile number are required", ErrInvalidUser)
+	}
+	hashedPassword, err := utils.HashPassword(req.Owner.Password)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+	owner.Password = hashedPassword
+
+	user, err := s.repo.CreateCompanyWithOwner(&company, &owner)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OnboardCompanyResponse{Company: company, Owner: *user}, nil
+}
+
+func (s *CompanyService) GetUsers(companyID int, filter models.CompanyUserFilter) ([]models.CompanyUser, error) {
+	switch filter.Status {
+	case "", "active", "locked":
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidUser, filter.Status)
+	}
+	switch filter.Role {
+	case "", "admin", "accounting":
+	default:
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidUser, filter.Role)
+	}
+	filter.Search = strings.TrimSpace(filter.Search)
+	return s.repo.GetUsers(companyID, filter)
+}
+
+func (s *CompanyService) GetUser(companyID, userID int) (*models.CompanyUser, error) {
+	return s.repo.GetUser(companyID, userID)
+}
+
+func (s *CompanyService) SetUserRoles(companyID, userID int, req models.UpdateUserRolesRequest) (*models.CompanyUser, error) {
+	if req.IsAdmin == nil && req.HasAccounting == nil {
+		return nil, fmt.Errorf("%w: no role given", ErrInvalidUser)
+	}
+	return s.repo.SetUserRoles(companyID, userID, req.IsAdmin, req.HasAccounting)
+}
+
+func (s *CompanyService) LockUser(companyID, userID int) (*models.CompanyUser, error) {
+	return s.repo.SetUserLocked(companyID, userID, true)
+}
+
+func (s *CompanyService) UnlockUser(companyID, userID int) (*models.CompanyUser, error) {
+	return s.repo.SetUserLocked(companyID, userID, false)
+}
+
+// InviteUser sets up an account for someone to join the company with the given roles. The returned
+// invite carries the token they accept it with; only its hash is stored.
+func (s *CompanyService) InviteUser(companyID, invitedBy int, req models.InviteUserRequest) (*models.UserInvite, error) {
+	invite := models.UserInvite{
+		CompanyID:     companyID,
+		Username:      strings.TrimSpace(req.Username),
+		MobileNumber:  strings.TrimSpace(req.MobileNumber),
+		IsAdmin:       req.IsAdmin,
+		HasAccounting: req.HasAccounting,
+		BranchID:      req.BranchID,
+		InvitedBy:     invitedBy,
+		ExpiresAt:     time.Now().Add(userInviteTTL),
+	}
+	if invite.Username == "" || invite.MobileNumber == "" {
+		return nil, fmt.Errorf("%w: username and mobile number are required", ErrInvalidUser)
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateInvite(&invite, hashInviteToken(token)); err != nil {
+		return nil, err
+	}
+	invite.Token = token
+	return &invite, nil
+}
+
+func (s *CompanyService) GetPendingInvites(companyID int) ([]models.UserInvite, error) {
+	return s.repo.GetPendingInvites(companyID)
+}
+
+// AcceptInvite creates the invited user with the password they chose
+func (s *CompanyService) AcceptInvite(req models.AcceptInviteRequest) (*models.CompanyUser, error) {
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+	return s.repo.AcceptInvite(hashInviteToken(strings.TrimSpace(req.Token)), hashedPassword)
+}
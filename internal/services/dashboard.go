@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"renting/internal/models"
+	"renting/internal/repositories"
+)
+
+// ErrInvalidDashboardPeriod marks a dashboard request with a bad period or date
+var ErrInvalidDashboardPeriod = errors.New("invalid dashboard period")
+
+type DashboardService struct {
+	repo     *repositories.DashboardRepository
+	settings *SystemSettingsService
+}
+
+func NewDashboardService(repo *repositories.DashboardRepository, settings *SystemSettingsService) *DashboardService {
+	return &DashboardService{repo: repo, settings: settings}
+}
+
+func dashboardFigure(current, previous float64) models.DashboardFigure {
+	figure := models.DashboardFigure{
+		Current:  round2(current),
+		Previous: round2(previous),
+		Change:   round2(current - previous),
+	}
+	if previous != 0 {
+		percent := round2((current - previous) / math.Abs(previous) * 100)
+		figure.ChangePercent = &percent
+	}
+	return figure
+}
+
+func utilisationRate(snap *models.DashboardSnapshot) float64 {
+	if snap.AvailableDays <= 0 {
+		return 0
+	}
+	return math.Min(float64(snap.RentedDays)/float64(snap.AvailableDays), 1) * 100
+}
+
+// GetDashboard returns the dashboard for a day, week, month or year, each figure compared with
+// the previous period of the same kind. A period still running is measured up to today and
+// compared with the same stretch of the previous period.
+func (s *DashboardService) GetDashboard(companyID int, req models.DashboardRequest) (*models.Dashboard, error) {
+	if req.Period == "custom" {
+		return nil, fmt.Errorf("%w: period must be day, week, month or year", ErrInvalidDashboardPeriod)
+	}
+	start, end, prevStart, prevEnd, period, err := revenueRange(models.RevenueRequest{Period: req.Period, Date: req.Date})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDashboardPeriod, err)
+	}
+
+	asOf := end
+	if now := today(); now.Before(asOf) {
+		asOf = now
+	}
+	if asOf.Before(start) {
+		return nil, fmt.Errorf("%w: the period has not started yet", ErrInvalidDashboardPeriod)
+	}
+	prevAsOf := prevEnd
+	if elapsed := int(asOf.Sub(start).Hours() / 24); elapsed < int(prevEnd.Sub(prevStart).Hours()/24) {
+		prevAsOf = prevStart.AddDate(0, 0, elapsed)
+	}
+
+	// A return only counts as overdue once the company's late-return grace period has passed
+	grace, err := s.settings.Duration(companyID, models.SettingLateReturnGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.repo.GetSnapshot(companyID, start, asOf, grace)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetSnapshot(companyID, prevStart, prevAsOf, grace)
+	if err != nil {
+		return nil, err
+	}
+
+	count := func(n int) float64 { return float64(n) }
+	return &models.Dashboard{
+		Period:        period,
+		StartDate:     start,
+		EndDate:       end,
+		AsOf:          asOf,
+		PreviousStart: prevStart,
+		PreviousEnd:   prevEnd,
+		PreviousAsOf:  prevAsOf,
+		Fleet: models.DashboardFleet{
+			Total:            dashboardFigure(count(current.FleetTotal), count(previous.FleetTotal)),
+			Available:        dashboardFigure(count(current.FleetAvailable), count(previous.FleetAvailable)),
+			Rented:           dashboardFigure(count(current.FleetRented), count(previous.FleetRented)),
+			UnderMaintenance: dashboardFigure(count(current.FleetMaintenance), count(previous.FleetMaintenance)),
+		},
+		DeliveriesDue:  dashboardFigure(count(current.DeliveriesDue), count(previous.DeliveriesDue)),
+		ReturnsDue:     dashboardFigure(count(current.ReturnsDue), count(previous.ReturnsDue)),
+		OverdueReturns: dashboardFigure(count(current.OverdueReturns), count(previous.OverdueReturns)),
+		Receivables:    dashboardFigure(current.Receivables, previous.Receivables),
+		CashIn:         dashboardFigure(current.CashIn, previous.CashIn),
+		Utilisation:    dashboardFigure(utilisationRate(current), utilisationRate(previous)),
+	}, nil
+}
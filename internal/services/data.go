@@ -23,56 +23,58 @@ type AggregatedData struct {
 	FutureBookings     int     `json:"future_bookings"`
 	TotalRevenue       float64 `json:"total_revenue"`
 	TotalAvailableCars int     `json:"total_available_cars"`
+	BranchID           *int    `json:"branch_id,omitempty"`
 }
 
-func (s *DataAggregateService) GetAggregatedData(date time.Time, filterType string) (AggregatedData, error) {
+// GetAggregatedData sums up a date, month or year, of one branch when branchID is not nil
+func (s *DataAggregateService) GetAggregatedData(date time.Time, filterType string, branchID *int) (AggregatedData, error) {
 	var pendingRequests, totalSales, futureBookings, totalAvailableCars int
 	var totalRevenue float64
 	var err error
 
 	// Get total available cars (this is independent of date filter)
-	totalAvailableCars, err = s.dataRepo.GetTotalAvailableCars()
+	totalAvailableCars, err = s.dataRepo.GetTotalAvailableCars(branchID)
 	if err != nil {
 		return AggregatedData{}, err
 	}
 
 	switch filterType {
 	case "date":
-		pendingRequests, err = s.saleRepo.GetPendingRequests(date, "date")
+		pendingRequests, err = s.saleRepo.GetPendingRequests(date, "date", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
-		totalSales, totalRevenue, err = s.saleRepo.GetTotalSales(date, "date")
+		totalSales, totalRevenue, err = s.saleRepo.GetTotalSales(date, "date", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
-		futureBookings, err = s.saleRepo.GetFutureBookings(date, "date")
+		futureBookings, err = s.saleRepo.GetFutureBookings(date, "date", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
 	case "month":
-		pendingRequests, err = s.saleRepo.GetPendingRequests(date, "month")
+		pendingRequests, err = s.saleRepo.GetPendingRequests(date, "month", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
-		totalSales, totalRevenue, err = s.saleRepo.GetTotalSales(date, "month")
+		totalSales, totalRevenue, err = s.saleRepo.GetTotalSales(date, "month", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
-		futureBookings, err = s.saleRepo.GetFutureBookings(date, "month")
+		futureBookings, err = s.saleRepo.GetFutureBookings(date, "month", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
 	case "year":
-		pendingRequests, err = s.saleRepo.GetPendingRequests(date, "year")
+		pendingRequests, err = s.saleRepo.GetPendingRequests(date, "year", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
-		totalSales, totalRevenue, err = s.saleRepo.GetTotalSales(date, "year")
+		totalSales, totalRevenue, err = s.saleRepo.GetTotalSales(date, "year", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
-		futureBookings, err = s.saleRepo.GetFutureBookings(date, "year")
+		futureBookings, err = s.saleRepo.GetFutureBookings(date, "year", branchID)
 		if err != nil {
 			return AggregatedData{}, err
 		}
@@ -89,6 +91,7 @@ func (s *DataAggregateService) GetAggregatedData(date time.Time, filterType stri
 			FutureBookings:     futureBookings,
 			TotalRevenue:       totalRevenue,
 			TotalAvailableCars: totalAvailableCars,
+			BranchID:           branchID,
 		}, nil
 	case "month":
 		return AggregatedData{
@@ -98,6 +101,7 @@ func (s *DataAggregateService) GetAggregatedData(date time.Time, filterType stri
 			FutureBookings:     futureBookings,
 			TotalRevenue:       totalRevenue,
 			TotalAvailableCars: totalAvailableCars,
+			BranchID:           branchID,
 		}, nil
 	case "year":
 		return AggregatedData{
@@ -107,13 +111,14 @@ func (s *DataAggregateService) GetAggregatedData(date time.Time, filterType stri
 			FutureBookings:     futureBookings,
 			TotalRevenue:       totalRevenue,
 			TotalAvailableCars: totalAvailableCars,
+			BranchID:           branchID,
 		}, nil
 	default:
 		return AggregatedData{}, nil
 	}
 }
 
-// GetTotalAvailableCars returns the count of available cars
-func (s *DataAggregateService) GetTotalAvailableCars() (int, error) {
-	return s.dataRepo.GetTotalAvailableCars()
+// GetTotalAvailableCars returns the count of available cars, optionally of those at one branch
+func (s *DataAggregateService) GetTotalAvailableCars(branchID *int) (int, error) {
+	return s.dataRepo.GetTotalAvailableCars(branchID)
 }
@@ -23,11 +23,11 @@ func (s *DisableDateService) GetDisabledDates(vehicleID int, excludeSaleID *int)
 	// Ensure we never return nil for the slices
 	if response == nil {
 		response = &models.DisableDateResponse{
-			ActiveRentals:  []models.DisabledDateResponse{},
-			FutureBookings: []models.DisabledDateResponse{}, // Changed to FutureBookings
+			ActiveRentals:     []models.DisabledDateResponse{},
+			FutureBookings:    []models.DisabledDateResponse{}, // Changed to FutureBookings
+			MaintenanceBlocks: []models.DisabledDateResponse{},
 		}
 	}
 
 	return response, nil
 }
-
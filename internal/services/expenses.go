@@ -1,36 +1,122 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"path/filepath"
+	"regexp"
 	"renting/internal/models"
 	"renting/internal/repositories"
+	"strings"
+	"time"
 )
 
+const (
+	defaultExpensePageSize = 50
+	maxExpensePageSize     = 200
+)
+
+var expenseCategoryCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
 type ExpenseService interface {
-	CreateExpense(expense *models.Expense) error
-	UpdateExpense(id int, expense *models.Expense) error
+	CreateExpense(companyID int, expense *models.Expense) error
+	UpdateExpense(companyID, id int, expense *models.Expense) error
 	DeleteExpense(id int) error
 	GetExpense(id int) (*models.Expense, error)
-	GetAllExpenses(filter models.ExpenseFilter) ([]models.Expense, error)
+	GetAllExpenses(filter models.ExpenseFilter) (*models.ExpenseListResponse, error)
+	ApproveExpense(id, userID int) (*models.Expense, error)
+	RejectExpense(id, userID int, reason string) (*models.Expense, error)
+	GetCategories(companyID int) ([]models.ExpenseCategory, error)
+	CreateCategory(companyID int, req models.ExpenseCategoryRequest) (*models.ExpenseCategory, error)
+	DeleteCategory(companyID, categoryID int) error
+	UploadReceipt(expenseID, userID int, file *multipart.FileHeader) (*models.ExpenseReceipt, error)
 }
 
 type expenseService struct {
-	repo repositories.ExpenseRepository
+	repo              repositories.ExpenseRepository
+	storage           *repositories.R2Storage
+	approvalThreshold float64
+}
+
+// NewExpenseService creates the expense service; expenses above approvalThreshold wait for
+// admin approval unless their category sets its own threshold, and 0 turns approval off
+func NewExpenseService(repo repositories.ExpenseRepository, storage *repositories.R2Storage, approvalThreshold float64) ExpenseService {
+	return &expenseService{repo: repo, storage: storage, approvalThreshold: approvalThreshold}
+}
+
+// resolveCategory normalises the expense type and checks it against the company's categories
+func (s *expenseService) resolveCategory(companyID int, expense *models.Expense) (*models.ExpenseCategory, error) {
+	expense.ExpenseType = strings.ToLower(strings.TrimSpace(expense.ExpenseType))
+	category, err := s.repo.FindCategory(companyID, expense.ExpenseType)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, fmt.Errorf("unknown expense category: %s", expense.ExpenseType)
+	}
+	return category, nil
 }
 
-func NewExpenseService(repo repositories.ExpenseRepository) ExpenseService {
-	return &expenseService{repo}
+// expenseNeedsApproval reports whether amount is above the category's or the default approval threshold
+func expenseNeedsApproval(category *models.ExpenseCategory, defaultThreshold, amount float64) bool {
+	threshold := defaultThreshold
+	if category.ApprovalThreshold != nil {
+		threshold = *category.ApprovalThreshold
+	}
+	return threshold > 0 && amount > threshold
 }
 
-func (s *expenseService) CreateExpense(expense *models.Expense) error {
+func (s *expenseService) CreateExpense(companyID int, expense *models.Expense) error {
 	if expense.ExpenseType == "" || expense.Amount == 0 || expense.ExpenseDate.IsZero() {
 		return errors.New("missing required fields")
 	}
+	if expense.Amount < 0 {
+		return errors.New("amount must be positive")
+	}
+	category, err := s.resolveCategory(companyID, expense)
+	if err != nil {
+		return err
+	}
+
+	expense.ApprovedBy, expense.ApprovedAt, expense.RejectionReason = nil, nil, nil
+	expense.ApprovalStatus = models.ExpenseApprovalApproved
+	if expenseNeedsApproval(category, s.approvalThreshold, expense.Amount) {
+		expense.ApprovalStatus = models.ExpenseApprovalPending
+	}
 	return s.repo.Create(expense)
 }
 
-func (s *expenseService) UpdateExpense(id int, expense *models.Expense) error {
+// UpdateExpense replaces an expense. An approved expense keeps its approval unless the
+// amount goes up past the threshold; anything else above the threshold goes back to pending.
+func (s *expenseService) UpdateExpense(companyID, id int, expense *models.Expense) error {
+	existing, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return sql.ErrNoRows
+	}
+	if expense.Amount < 0 {
+		return errors.New("amount must be positive")
+	}
+	category, err := s.resolveCategory(companyID, expense)
+	if err != nil {
+		return err
+	}
+
 	expense.ExpenseID = id
+	expense.ApprovalStatus = models.ExpenseApprovalApproved
+	expense.ApprovedBy, expense.ApprovedAt, expense.RejectionReason = nil, nil, nil
+	if expenseNeedsApproval(category, s.approvalThreshold, expense.Amount) {
+		if existing.ApprovalStatus == models.ExpenseApprovalApproved && expense.Amount <= existing.Amount {
+			expense.ApprovedBy, expense.ApprovedAt = existing.ApprovedBy, existing.ApprovedAt
+		} else {
+			expense.ApprovalStatus = models.ExpenseApprovalPending
+		}
+	}
 	return s.repo.Update(expense)
 }
 
@@ -42,6 +128,142 @@ func (s *expenseService) GetExpense(id int) (*models.Expense, error) {
 	return s.repo.FindByID(id)
 }
 
-func (s *expenseService) GetAllExpenses(filter models.ExpenseFilter) ([]models.Expense, error) {
-	return s.repo.FindAll(filter)
+func (s *expenseService) GetAllExpenses(filter models.ExpenseFilter) (*models.ExpenseListResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultExpensePageSize
+	}
+	if filter.Limit > maxExpensePageSize {
+		filter.Limit = maxExpensePageSize
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	expenses, totalCount, err := s.repo.FindAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ExpenseListResponse{
+		Expenses: expenses,
+		Pagination: models.Pagination{
+			CurrentPage: filter.Offset/filter.Limit + 1,
+			PageSize:    filter.Limit,
+			TotalCount:  totalCount,
+			TotalPages:  (totalCount + filter.Limit - 1) / filter.Limit,
+			HasNext:     filter.Offset+filter.Limit < totalCount,
+			HasPrevious: filter.Offset > 0,
+		},
+	}, nil
+}
+
+// ApproveExpense approves a pending expense, which books it in the ledger
+func (s *expenseService) ApproveExpense(id, userID int) (*models.Expense, error) {
+	if err := s.repo.Approve(id, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(id)
+}
+
+// RejectExpense rejects a pending expense; rejected expenses stay out of the ledger and reports
+func (s *expenseService) RejectExpense(id, userID int, reason string) (*models.Expense, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a rejection reason is required")
+	}
+	if err := s.repo.Reject(id, userID, reason); err != nil {
+		return nil, err
+	}
+	return s.repo.FindByID(id)
+}
+
+// GetCategories lists the expense categories available to a company
+func (s *expenseService) GetCategories(companyID int) ([]models.ExpenseCategory, error) {
+	return s.repo.GetCategories(companyID)
+}
+
+// CreateCategory adds a company-defined expense category such as tolls or parking
+func (s *expenseService) CreateCategory(companyID int, req models.ExpenseCategoryRequest) (*models.ExpenseCategory, error) {
+	code := req.Code
+	if code == "" {
+		code = req.Name
+	}
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.Join(strings.Fields(code), "_")
+	if !expenseCategoryCodePattern.MatchString(code) {
+		return nil, errors.New("code may only contain lowercase letters, digits and underscores")
+	}
+	if req.ApprovalThreshold != nil && *req.ApprovalThreshold < 0 {
+		return nil, errors.New("approval_threshold must not be negative")
+	}
+
+	existing, err := s.repo.FindCategory(companyID, code)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("expense category %s already exists", code)
+	}
+
+	category := &models.ExpenseCategory{
+		CompanyID:         &companyID,
+		Code:              code,
+		Name:              strings.TrimSpace(req.Name),
+		ApprovalThreshold: req.ApprovalThreshold,
+	}
+	if err := s.repo.CreateCategory(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// DeleteCategory removes an unused company-defined expense category
+func (s *expenseService) DeleteCategory(companyID, categoryID int) error {
+	return s.repo.DeleteCategory(companyID, categoryID)
+}
+
+// UploadReceipt stores a receipt image or PDF in R2 and attaches it to the expense
+func (s *expenseService) UploadReceipt(expenseID, userID int, file *multipart.FileHeader) (*models.ExpenseReceipt, error) {
+	expense, err := s.repo.FindByID(expenseID)
+	if err != nil {
+		return nil, err
+	}
+	if expense == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	contentType := file.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = mime.TypeByExtension(ext)
+	}
+	if !strings.HasPrefix(contentType, "image/") && contentType != "application/pdf" {
+		return nil, errors.New("only image or PDF receipts are allowed")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	key := fmt.Sprintf("expense_receipts/%d_%s%s", expenseID, time.Now().Format("20060102_150405.000"), ext)
+	url, err := s.storage.Upload(src, key, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := file.Filename
+	receipt := &models.ExpenseReceipt{
+		ExpenseID:   expenseID,
+		ReceiptURL:  url,
+		FileName:    &fileName,
+		ContentType: contentType,
+		SizeBytes:   file.Size,
+		UploadedBy:  &userID,
+	}
+	if err := s.repo.AddReceipt(receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
 }
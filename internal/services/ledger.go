@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"time"
+)
+
+type LedgerService struct {
+	repo *repositories.LedgerRepository
+}
+
+func NewLedgerService(repo *repositories.LedgerRepository) *LedgerService {
+	return &LedgerService{repo: repo}
+}
+
+func (s *LedgerService) GetAccounts(companyID int) ([]models.Account, error) {
+	return s.repo.GetAccounts(companyID)
+}
+
+func (s *LedgerService) CreateAccount(companyID int, req models.AccountRequest) (*models.Account, error) {
+	account := &models.Account{Code: req.Code, Name: req.Name, Type: req.Type}
+	if err := s.repo.CreateAccount(companyID, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *LedgerService) GetEntries(companyID int, filter models.JournalEntryFilter) ([]models.JournalEntry, error) {
+	return s.repo.GetEntries(companyID, filter)
+}
+
+func (s *LedgerService) GetEntry(companyID, entryID int) (*models.JournalEntry, error) {
+	entry, err := s.repo.GetEntry(companyID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, errors.New("journal entry not found")
+	}
+	return entry, nil
+}
+
+func (s *LedgerService) CreateManualEntry(companyID, userID int, req models.JournalEntryRequest) (*models.JournalEntry, error) {
+	entry := &models.JournalEntry{
+		EntryDate:   req.EntryDate,
+		Description: req.Description,
+		CreatedBy:   &userID,
+	}
+	for _, line := range req.Lines {
+		entry.Lines = append(entry.Lines, models.JournalLine{
+			AccountCode: line.AccountCode,
+			Debit:       line.Debit,
+			Credit:      line.Credit,
+			Memo:        line.Memo,
+		})
+	}
+	if err := s.repo.CreateManualEntry(companyID, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *LedgerService) GetTrialBalance(companyID int, asOf time.Time) (*models.TrialBalance, error) {
+	return s.repo.GetTrialBalance(companyID, asOf)
+}
+
+// GetAccountLedger defaults to the current month when no range is given
+func (s *LedgerService) GetAccountLedger(companyID int, code string, startDate, endDate *time.Time) (*models.AccountLedger, error) {
+	account, err := s.repo.GetAccountByCode(companyID, code)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, errors.New("account not found")
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := now
+	if startDate != nil {
+		start = *startDate
+	}
+	if endDate != nil {
+		end = *endDate
+	}
+	if end.Before(start) {
+		return nil, errors.New("end_date must not be before start_date")
+	}
+	return s.repo.GetAccountLedger(companyID, *account, start, end)
+}
+
+func (s *LedgerService) GetPeriods(companyID int) ([]models.AccountingPeriod, error) {
+	return s.repo.GetPeriods(companyID)
+}
+
+func (s *LedgerService) ClosePeriod(companyID, userID int, req models.ClosePeriodRequest) (*models.AccountingPeriod, error) {
+	if req.EndDate.Before(req.StartDate) {
+		return nil, errors.New("end_date must not be before start_date")
+	}
+	return s.repo.ClosePeriod(companyID, req.StartDate, req.EndDate, userID)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"time"
+)
+
+const maintenanceSyncInterval = 15 * time.Minute
+
+type MaintenanceService struct {
+	repo *repositories.MaintenanceRepository
+}
+
+func NewMaintenanceService(repo *repositories.MaintenanceRepository) *MaintenanceService {
+	return &MaintenanceService{repo: repo}
+}
+
+func validateMaintenanceDates(startDate, endDate time.Time) error {
+	if endDate.Before(startDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+	return nil
+}
+
+func (s *MaintenanceService) GetBlocks(companyID int, filter models.MaintenanceBlockFilter) ([]models.MaintenanceBlock, error) {
+	return s.repo.GetBlocks(companyID, filter)
+}
+
+// CreateBlock schedules downtime and applies it straight away when it starts today
+func (s *MaintenanceService) CreateBlock(companyID, userID int, req models.MaintenanceBlockRequest) (*models.MaintenanceBlock, error) {
+	if err := validateMaintenanceDates(req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
+	block := &models.MaintenanceBlock{
+		VehicleID: req.VehicleID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Reason:    req.Reason,
+		CreatedBy: &userID,
+	}
+	if err := s.repo.CreateBlock(companyID, block); err != nil {
+		return nil, err
+	}
+	s.SyncVehicleStatuses()
+	return block, nil
+}
+
+func (s *MaintenanceService) UpdateBlock(companyID, blockID int, req models.MaintenanceBlockRequest) (*models.MaintenanceBlock, error) {
+	if err := validateMaintenanceDates(req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
+	block := &models.MaintenanceBlock{
+		BlockID:   blockID,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Reason:    req.Reason,
+	}
+	if err := s.repo.UpdateBlock(companyID, block); err != nil {
+		return nil, err
+	}
+	s.SyncVehicleStatuses()
+	return block, nil
+}
+
+func (s *MaintenanceService) CancelBlock(companyID, blockID int) error {
+	return s.repo.CancelBlock(companyID, blockID)
+}
+
+// SyncVehicleStatuses flips vehicle status for blocks that have started or ended
+func (s *MaintenanceService) SyncVehicleStatuses() {
+	started, finished, err := s.repo.SyncVehicleStatuses()
+	if err != nil {
+		log.Printf("[ERROR] Maintenance sync: %v", err)
+		return
+	}
+	if started > 0 || finished > 0 {
+		log.Printf("[INFO] Maintenance sync: %d blocks started, %d finished", started, finished)
+	}
+}
+
+// StartScheduler keeps vehicle status in line with maintenance blocks in the background
+func (s *MaintenanceService) StartScheduler() {
+	go func() {
+		s.SyncVehicleStatuses()
+		ticker := time.NewTicker(maintenanceSyncInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.SyncVehicleStatuses()
+		}
+	}()
+}
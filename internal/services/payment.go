@@ -1,17 +1,29 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"path/filepath"
 	"renting/internal/models"
 	"renting/internal/repositories"
+	"strings"
 	"time"
 )
 
+// maxPaymentAttachments caps how many files can back up one payment in a single upload
+const maxPaymentAttachments = 5
+
+var ErrInvalidPaymentAttachment = errors.New("invalid payment attachment")
+
 type PaymentService struct {
 	paymentRepo *repositories.PaymentRepository
+	storage     *repositories.R2Storage
 }
 
-func NewPaymentService(paymentRepo *repositories.PaymentRepository) *PaymentService {
-	return &PaymentService{paymentRepo: paymentRepo}
+func NewPaymentService(paymentRepo *repositories.PaymentRepository, storage *repositories.R2Storage) *PaymentService {
+	return &PaymentService{paymentRepo: paymentRepo, storage: storage}
 }
 
 type SaleFilter struct {
@@ -23,6 +35,7 @@ type SaleFilter struct {
 	SaleStatus    *string
 	VerifiedBy    *string
 	SaleType      *string
+	SubmittedBy   *int // staff member who took the payment
 }
 
 // GetPaymentsWithSales (unchanged except for repository filter mapping)
@@ -37,6 +50,7 @@ func (s *PaymentService) GetPaymentsWithSales(filter SaleFilter, limit int, offs
 			SaleStatus:    filter.SaleStatus,
 			VerifiedBy:    filter.VerifiedBy,
 			SaleType:      filter.SaleType,
+			SubmittedBy:   filter.SubmittedBy,
 		},
 		limit,
 		offset,
@@ -48,7 +62,86 @@ func (s *PaymentService) UpdatePayment(paymentID int, userID int, paymentType st
 	return s.paymentRepo.UpdatePayment(paymentID, userID, paymentType, amountPaid)
 }
 
-// InsertPayment creates a new payment
-func (s *PaymentService) InsertPayment(saleID int, paymentType string, amountPaid float64, remark string) (int, error) {
-	return s.paymentRepo.InsertPayment(saleID, paymentType, amountPaid, remark)
+// InsertPayment creates a new payment, uploading the screenshots or voucher photos sent with it
+func (s *PaymentService) InsertPayment(saleID int, userID int, paymentType string, amountPaid float64, remark string, files []*multipart.FileHeader) (int, error) {
+	if len(files) > maxPaymentAttachments {
+		return 0, fmt.Errorf("%w: at most %d files per payment", ErrInvalidPaymentAttachment, maxPaymentAttachments)
+	}
+	for _, file := range files {
+		if _, err := paymentAttachmentContentType(file); err != nil {
+			return 0, err
+		}
+	}
+
+	attachments := make([]models.PaymentAttachment, 0, len(files))
+	for i, file := range files {
+		attachment, err := s.uploadAttachment(fmt.Sprintf("sale%d_%d", saleID, i), userID, file)
+		if err != nil {
+			return 0, err
+		}
+		attachments = append(attachments, *attachment)
+	}
+	return s.paymentRepo.InsertPayment(saleID, userID, paymentType, amountPaid, remark, attachments)
+}
+
+// AddAttachment uploads evidence for a payment that is still waiting for verification
+func (s *PaymentService) AddAttachment(companyID, paymentID, userID int, file *multipart.FileHeader) (*models.PaymentAttachment, error) {
+	if _, err := paymentAttachmentContentType(file); err != nil {
+		return nil, err
+	}
+	if _, err := s.paymentRepo.GetPendingPaymentSale(companyID, paymentID); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.uploadAttachment(fmt.Sprintf("payment%d", paymentID), userID, file)
+	if err != nil {
+		return nil, err
+	}
+	attachment.PaymentID = paymentID
+	if err := s.paymentRepo.AddAttachment(attachment); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// paymentAttachmentContentType accepts images and PDFs, working the type out from the file name
+// when the client did not send one
+func paymentAttachmentContentType(file *multipart.FileHeader) (string, error) {
+	contentType := file.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = mime.TypeByExtension(strings.ToLower(filepath.Ext(file.Filename)))
+	}
+	if !strings.HasPrefix(contentType, "image/") && contentType != "application/pdf" {
+		return "", fmt.Errorf("%w: only images or PDFs are allowed, got %s", ErrInvalidPaymentAttachment, file.Filename)
+	}
+	return contentType, nil
+}
+
+func (s *PaymentService) uploadAttachment(prefix string, userID int, file *multipart.FileHeader) (*models.PaymentAttachment, error) {
+	contentType, err := paymentAttachmentContentType(file)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	key := fmt.Sprintf("payment_attachments/%s_%s%s", prefix, time.Now().Format("20060102_150405.000"), ext)
+	url, err := s.storage.Upload(src, key, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := file.Filename
+	return &models.PaymentAttachment{
+		FileURL:     url,
+		FileName:    &fileName,
+		ContentType: contentType,
+		SizeBytes:   file.Size,
+		UploadedBy:  &userID,
+	}, nil
 }
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnknownGateway          = errors.New("unknown payment gateway")
+	ErrInvalidGatewayRequest   = errors.New("invalid gateway payment request")
+	ErrInvalidGatewaySignature = errors.New("invalid gateway signature")
+)
+
+// GatewayResult is a provider's verdict on a payment, read from a callback it signed
+type GatewayResult struct {
+	Reference         string  // our reference for the payment
+	ProviderReference string  // the provider's transaction code
+	Completed         bool    // false when the customer cancelled or the payment failed
+	Amount            float64 // amount the provider collected
+	Payload           []byte  // the decoded callback as JSON, kept for audit
+}
+
+// PaymentGateway is a wallet provider customers pay through, e.g. eSewa or Khalti
+type PaymentGateway interface {
+	// Code names the provider in URLs and on gateway payments
+	Code() string
+	// Method is the payment method code payments through this provider are recorded with
+	Method() string
+	// Checkout tells the app how to send the customer to the provider. The provider reports back to
+	// callbackURL.
+	Checkout(payment *models.GatewayPayment, callbackURL string) (*models.GatewayCheckout, error)
+	// VerifyCallback checks the provider's signature on a redirect or webhook and reads the verdict
+	// from it. Errors wrap ErrInvalidGatewaySignature when the callback cannot be trusted.
+	VerifyCallback(query url.Values, body []byte) (*GatewayResult, error)
+}
+
+var gatewaySaleTypes = map[string]bool{"booking": true, "delivery": true, "return": true, "manual": true}
+
+type PaymentGatewayService struct {
+	repo     *repositories.PaymentGatewayRepository
+	gateways map[string]PaymentGateway
+	baseURL  string
+}
+
+// NewPaymentGatewayService serves the given providers. baseURL is the public address of this API,
+// used to build the callback URLs providers are given.
+func NewPaymentGatewayService(repo *repositories.PaymentGatewayRepository, baseURL string, gateways ...PaymentGateway) *PaymentGatewayService {
+	byCode := make(map[string]PaymentGateway, len(gateways))
+	for _, gateway := range gateways {
+		byCode[gateway.Code()] = gateway
+	}
+	return &PaymentGatewayService{repo: repo, gateways: byCode, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Providers lists the codes of the configured providers
+func (s *PaymentGatewayService) Providers() []string {
+	codes := make([]string, 0, len(s.gateways))
+	for code := range s.gateways {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func (s *PaymentGatewayService) gateway(provider string) (PaymentGateway, error) {
+	gateway, ok := s.gateways[strings.ToLower(provider)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownGateway, provider)
+	}
+	return gateway, nil
+}
+
+// CallbackURL is where the provider sends the customer, and its verdict, once they have paid
+func (s *PaymentGatewayService) CallbackURL(provider string) string {
+	return s.baseURL + "/api/v1/payment-gateway/" + provider + "/callback"
+}
+
+func generateGatewayReference(saleID int) (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate payment reference: %v", err)
+	}
+	return fmt.Sprintf("RNT-%d-%s", saleID, hex.EncodeToString(buf)), nil
+}
+
+// Initiate records a pending payment for a sale of the company and returns how to send the customer to the
+// provider to pay it
+func (s *PaymentGatewayService) Initiate(companyID, saleID, userID int, req models.GatewayInitiateRequest) (*models.GatewayCheckout, error) {
+	gateway, err := s.gateway(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if req.AmountPaid <= 0 {
+		return nil, fmt.Errorf("%w: amount_paid must be positive", ErrInvalidGatewayRequest)
+	}
+	saleType := strings.ToLower(strings.TrimSpace(req.SaleType))
+	if saleType == "" {
+		saleType = "manual"
+	}
+	if !gatewaySaleTypes[saleType] {
+		return nil, fmt.Errorf("%w: sale_type must be booking, delivery, return or manual", ErrInvalidGatewayRequest)
+	}
+
+	reference, err := generateGatewayReference(saleID)
+	if err != nil {
+		return nil, err
+	}
+	payment := &models.GatewayPayment{
+		Reference:   reference,
+		Provider:    gateway.Code(),
+		SaleID:      saleID,
+		Amount:      round2(req.AmountPaid),
+		InitiatedBy: &userID,
+	}
+	if returnURL := strings.TrimSpace(req.ReturnURL); returnURL != "" {
+		if u, err := url.Parse(returnURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("%w: return_url must be an http(s) URL", ErrInvalidGatewayRequest)
+		}
+		payment.ReturnURL = &returnURL
+	}
+
+	remark := req.Remark
+	if remark == "" {
+		remark = "Awaiting " + gateway.Code() + " payment " + reference
+	}
+	if err := s.repo.CreateGatewayPayment(companyID, payment, gateway.Method(), saleType, remark); err != nil {
+		return nil, err
+	}
+
+	checkout, err := gateway.Checkout(payment, s.CallbackURL(gateway.Code()))
+	if err != nil {
+		return nil, err
+	}
+	checkout.Reference = payment.Reference
+	checkout.Provider = payment.Provider
+	checkout.PaymentID = payment.PaymentID
+	checkout.SaleID = payment.SaleID
+	checkout.Amount = payment.Amount
+	return checkout, nil
+}
+
+// HandleCallback verifies a provider callback and settles the payment it is about
+func (s *PaymentGatewayService) HandleCallback(provider string, query url.Values, body []byte) (*models.GatewayPayment, error) {
+	gateway, err := s.gateway(provider)
+	if err != nil {
+		return nil, err
+	}
+	result, err := gateway.VerifyCallback(query, body)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ConfirmGatewayPayment(gateway.Code(), result.Reference, result.Completed, result.Amount,
+		result.ProviderReference, result.Payload)
+}
+
+func (s *PaymentGatewayService) GetPayment(companyID int, reference string) (*models.GatewayPayment, error) {
+	return s.repo.GetGatewayPayment(companyID, reference)
+}
+
+// SimulateMockPayment plays the customer's part at the mock provider: it returns the signed
+// callback URL the provider would redirect them to after paying, or cancelling when completed is
+// false
+func (s *PaymentGatewayService) SimulateMockPayment(reference string, completed bool) (string, error) {
+	mock, ok := s.gateways[MockGatewayCode].(*MockGateway)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownGateway, MockGatewayCode)
+	}
+	payment, err := s.repo.FindGatewayPayment(MockGatewayCode, reference)
+	if err != nil {
+		return "", err
+	}
+	query, err := mock.SignCallback(payment.Reference, payment.Amount, completed)
+	if err != nil {
+		return "", err
+	}
+	return s.CallbackURL(MockGatewayCode) + "?" + query.Encode(), nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"renting/internal/models"
+	"strconv"
+	"strings"
+)
+
+const MockGatewayCode = "mock"
+
+// Statuses the mock provider reports, as eSewa does
+const (
+	mockStatusComplete = "COMPLETE"
+	mockStatusCanceled = "CANCELED"
+)
+
+// MockGateway is a local stand-in for an eSewa-style wallet. Checkout fields and callbacks are
+// signed the way eSewa signs them: an HMAC-SHA256 over "field=value" pairs of the fields listed in
+// signed_field_names, base64 encoded. The callback is a base64 JSON document passed as ?data=.
+type MockGateway struct {
+	secret      string
+	method      string
+	productCode string
+	checkoutURL string
+}
+
+// NewMockGateway signs with secret and records payments under the given payment method code.
+// baseURL is the public address of this API, which serves the mock checkout page.
+func NewMockGateway(secret, method, baseURL string) *MockGateway {
+	return &MockGateway{
+		secret:      secret,
+		method:      method,
+		productCode: "RENTING_MOCK",
+		checkoutURL: strings.TrimRight(baseURL, "/") + "/api/v1/payment-gateway/mock/checkout/",
+	}
+}
+
+func (g *MockGateway) Code() string   { return MockGatewayCode }
+func (g *MockGateway) Method() string { return g.method }
+
+func (g *MockGateway) sign(fields map[string]string, signedFieldNames string) string {
+	names := strings.Split(signedFieldNames, ",")
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + fields[name]
+	}
+	mac := hmac.New(sha256.New, []byte(g.secret))
+	mac.Write([]byte(strings.Join(pairs, ",")))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func formatGatewayAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func (g *MockGateway) Checkout(payment *models.GatewayPayment, callbackURL string) (*models.GatewayCheckout, error) {
+	fields := map[string]string{
+		"amount":           formatGatewayAmount(payment.Amount),
+		"total_amount":     formatGatewayAmount(payment.Amount),
+		"transaction_uuid": payment.Reference,
+		"product_code":     g.productCode,
+		"success_url":      callbackURL,
+		"failure_url":      callbackURL,
+	}
+	fields["signed_field_names"] = "total_amount,transaction_uuid,product_code"
+	fields["signature"] = g.sign(fields, fields["signed_field_names"])
+
+	return &models.GatewayCheckout{
+		PaymentURL: g.checkoutURL + url.PathEscape(payment.Reference),
+		FormFields: fields,
+	}, nil
+}
+
+// SignCallback builds the query the mock provider redirects the customer back with
+func (g *MockGateway) SignCallback(reference string, amount float64, completed bool) (url.Values, error) {
+	code := make([]byte, 4)
+	if _, err := rand.Read(code); err != nil {
+		return nil, fmt.Errorf("failed to generate transaction code: %v", err)
+	}
+	status := mockStatusComplete
+	if !completed {
+		status = mockStatusCanceled
+	}
+
+	fields := map[string]string{
+		"transaction_code":   "MOCK" + strings.ToUpper(hex.EncodeToString(code)),
+		"status":             status,
+		"total_amount":       formatGatewayAmount(amount),
+		"transaction_uuid":   reference,
+		"product_code":       g.productCode,
+		"signed_field_names": "transaction_code,status,total_amount,transaction_uuid,product_code,signed_field_names",
+	}
+	fields["signature"] = g.sign(fields, fields["signed_field_names"])
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return url.Values{"data": {base64.StdEncoding.EncodeToString(data)}}, nil
+}
+
+// VerifyCallback reads ?data= from a redirect, or a webhook body that is either {"data": "..."} or
+// the encoded document itself
+func (g *MockGateway) VerifyCallback(query url.Values, body []byte) (*GatewayResult, error) {
+	encoded := query.Get("data")
+	if encoded == "" && len(body) > 0 {
+		var envelope struct {
+			Data string `json:"data"`
+		}
+		if json.Unmarshal(body, &envelope) == nil && envelope.Data != "" {
+			encoded = envelope.Data
+		} else {
+			encoded = strings.TrimSpace(string(body))
+		}
+	}
+	if encoded == "" {
+		return nil, fmt.Errorf("%w: callback has no data", ErrInvalidGatewaySignature)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: data is not base64", ErrInvalidGatewaySignature)
+	}
+	var fields map[string]string
+	if err := json.Unmarshal(decoded, &fields); err != nil {
+		return nil, fmt.Errorf("%w: data is not a JSON object", ErrInvalidGatewaySignature)
+	}
+
+	signedFieldNames := fields["signed_field_names"]
+	if signedFieldNames == "" || fields["transaction_uuid"] == "" ||
+		!strings.Contains(","+signedFieldNames+",", ",transaction_uuid,") ||
+		!strings.Contains(","+signedFieldNames+",", ",status,") ||
+		!strings.Contains(","+signedFieldNames+",", ",total_amount,") {
+		return nil, fmt.Errorf("%w: required fields are not signed", ErrInvalidGatewaySignature)
+	}
+	if !hmac.Equal([]byte(g.sign(fields, signedFieldNames)), []byte(fields["signature"])) {
+		return nil, ErrInvalidGatewaySignature
+	}
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(fields["total_amount"], ",", ""), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid total_amount %q", ErrInvalidGatewaySignature, fields["total_amount"])
+	}
+
+	return &GatewayResult{
+		Reference:         fields["transaction_uuid"],
+		ProviderReference: fields["transaction_code"],
+		Completed:         fields["status"] == mockStatusComplete,
+		Amount:            amount,
+		Payload:           decoded,
+	}, nil
+}
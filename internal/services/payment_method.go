@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"strings"
+)
+
+var paymentMethodCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+type PaymentMethodService struct {
+	repo     *repositories.PaymentMethodRepository
+	receipts *repositories.ReceiptRepository
+}
+
+func NewPaymentMethodService(repo *repositories.PaymentMethodRepository, receipts *repositories.ReceiptRepository) *PaymentMethodService {
+	return &PaymentMethodService{repo: repo, receipts: receipts}
+}
+
+func (s *PaymentMethodService) GetMethods(companyID int, includeInactive bool) ([]models.PaymentMethod, error) {
+	return s.repo.GetMethods(companyID, includeInactive)
+}
+
+// SaveMethod adds a company payment method, or overrides a built-in one with the same code
+func (s *PaymentMethodService) SaveMethod(companyID int, req models.PaymentMethodRequest) (*models.PaymentMethod, error) {
+	code := repositories.NormalizePaymentMethod(req.Code)
+	if !paymentMethodCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("%w: code may only contain letters, digits and underscores", repositories.ErrInvalidPaymentMethod)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	method := &models.PaymentMethod{
+		CompanyID:          &companyID,
+		Code:               code,
+		Name:               name,
+		IsActive:           req.IsActive == nil || *req.IsActive,
+		SortOrder:          req.SortOrder,
+		RequiresAttachment: req.RequiresAttachment,
+	}
+	if err := s.repo.SaveMethod(method); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+// GetReceipt returns the receipt of a verified payment
+func (s *PaymentMethodService) GetReceipt(companyID, paymentID int) (*models.PaymentReceipt, error) {
+	return s.receipts.GetReceipt(companyID, paymentID)
+}
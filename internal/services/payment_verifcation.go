@@ -2,9 +2,15 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"renting/internal/models"
 	"renting/internal/repositories"
+	"strings"
 )
 
+// ErrInvalidBulkVerification is returned for a bulk verification request that cannot be processed
+var ErrInvalidBulkVerification = errors.New("invalid bulk verification request")
+
 type PaymentVerificationService struct {
 	paymentRepo *repositories.PaymentVerificationRepository
 }
@@ -13,11 +19,11 @@ func NewPaymentVerificationService(paymentRepo *repositories.PaymentVerification
 	return &PaymentVerificationService{paymentRepo: paymentRepo}
 }
 
-func (s *PaymentVerificationService) VerifyPayment(paymentID int, status string, userID int, saleID int) error {
+func (s *PaymentVerificationService) VerifyPayment(paymentID int, status string, userID int, saleID int, reason string) error {
 	if status != "Completed" && status != "Failed" {
 		return errors.New("invalid payment status")
 	}
-	return s.paymentRepo.VerifyPayment(paymentID, status, userID, saleID)
+	return s.paymentRepo.VerifyPayment(paymentID, status, userID, saleID, strings.TrimSpace(reason))
 }
 
 // GetPaymentDetails retrieves payment details, now accepting userID
@@ -31,3 +37,98 @@ func (s *PaymentVerificationService) GetPaymentDetails(paymentID int, userID int
 func (s *PaymentVerificationService) CancelPayment(paymentID int, userID int) error {
 	return s.paymentRepo.CancelPayment(paymentID, userID)
 }
+
+// RefundPayment refunds a completed payment
+func (s *PaymentVerificationService) RefundPayment(companyID, paymentID int, userID int, reason string) error {
+	return s.paymentRepo.RefundPayment(companyID, paymentID, userID, reason)
+}
+
+// GetVerificationQueue lists the company's payments waiting for an admin
+func (s *PaymentVerificationService) GetVerificationQueue(companyID int, filter models.VerificationQueueFilter) (*models.VerificationQueue, error) {
+	if filter.Limit <= 0 || filter.Limit > 100 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.paymentRepo.GetVerificationQueue(companyID, filter)
+}
+
+// BulkVerify verifies or rejects many payments at once. Decisions are grouped by sale and each
+// sale is settled in its own transaction: if any decision on a sale fails, none of that sale's
+// decisions are applied, while other sales go ahead. Results come back in request order.
+func (s *PaymentVerificationService) BulkVerify(companyID, adminID int, req models.BulkVerificationRequest) ([]models.PaymentDecisionResult, error) {
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: no payments given", ErrInvalidBulkVerification)
+	}
+
+	results := make([]models.PaymentDecisionResult, len(req.Items))
+	paymentIDs := make([]int, 0, len(req.Items))
+	seen := map[int]bool{}
+	for i, item := range req.Items {
+		item.Action = strings.ToLower(strings.TrimSpace(item.Action))
+		item.Reason = strings.TrimSpace(item.Reason)
+		req.Items[i] = item
+		results[i] = models.PaymentDecisionResult{PaymentID: item.PaymentID, Action: item.Action}
+
+		switch {
+		case seen[item.PaymentID]:
+			results[i].Error = "payment listed more than once"
+		case item.Action != models.PaymentActionVerify && item.Action != models.PaymentActionReject:
+			results[i].Error = "action must be verify or reject"
+		case item.Action == models.PaymentActionReject && item.Reason == "":
+			results[i].Error = "a reason is required to reject a payment"
+		}
+		seen[item.PaymentID] = true
+		paymentIDs = append(paymentIDs, item.PaymentID)
+	}
+
+	paymentSales, err := s.paymentRepo.GetPaymentSales(companyID, paymentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the valid decisions by sale, keeping the order sales first appear in
+	var saleOrder []int
+	bySale := map[int][]int{} // sale id to indexes into req.Items
+	for i, item := range req.Items {
+		if results[i].Error != "" {
+			continue
+		}
+		saleID, ok := paymentSales[item.PaymentID]
+		if !ok {
+			results[i].Error = "payment not found"
+			continue
+		}
+		results[i].SaleID = saleID
+		if _, ok := bySale[saleID]; !ok {
+			saleOrder = append(saleOrder, saleID)
+		}
+		bySale[saleID] = append(bySale[saleID], i)
+	}
+
+	for _, saleID := range saleOrder {
+		indexes := bySale[saleID]
+		decisions := make([]models.PaymentDecision, len(indexes))
+		for j, i := range indexes {
+			decisions[j] = req.Items[i]
+		}
+
+		failedPayment, err := s.paymentRepo.DecideSalePayments(saleID, adminID, decisions)
+		for _, i := range indexes {
+			switch {
+			case err == nil:
+				results[i].Success = true
+				results[i].PaymentStatus = "Completed"
+				if req.Items[i].Action == models.PaymentActionReject {
+					results[i].PaymentStatus = "Failed"
+				}
+			case req.Items[i].PaymentID == failedPayment || failedPayment == 0:
+				results[i].Error = err.Error()
+			default:
+				results[i].Error = fmt.Sprintf("not applied: payment #%d of sale #%d failed", failedPayment, saleID)
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"renting/internal/models"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Thermal printer line widths in characters
+const (
+	ReceiptWidth58mm = 32
+	ReceiptWidth80mm = 42
+)
+
+// receiptFields are the label/value rows printed on every receipt
+func receiptFields(rc *models.PaymentReceipt) [][2]string {
+	rows := [][2]string{
+		{"Receipt No", rc.ReceiptNumber},
+		{"Date", rc.PaymentDate.Format("02 Jan 2006 15:04")},
+		{"Sale", fmt.Sprintf("#%d", rc.SaleID)},
+		{"Customer", rc.CustomerName},
+	}
+	if rc.CustomerPhone != "" {
+		rows = append(rows, [2]string{"Phone", rc.CustomerPhone})
+	}
+	if rc.VehicleName != "" {
+		rows = append(rows, [2]string{"Vehicle", strings.TrimSpace(rc.VehicleName + " " + rc.Registration)})
+	}
+	if rc.Stage != "" {
+		rows = append(rows, [2]string{"For", strings.ToUpper(rc.Stage[:1]) + rc.Stage[1:]})
+	}
+	rows = append(rows, [2]string{"Method", rc.PaymentMethod})
+	if rc.ReceivedBy != "" {
+		rows = append(rows, [2]string{"Received by", rc.ReceivedBy})
+	}
+	if rc.PaymentStatus == "Refunded" {
+		rows = append(rows, [2]string{"Status", "REFUNDED"})
+	}
+	return rows
+}
+
+func centreText(text string, width int) string {
+	if len(text) >= width {
+		return text[:width]
+	}
+	return strings.Repeat(" ", (width-len(text))/2) + text
+}
+
+// padRow prints label on the left and value on the right, wrapping a long value onto its own lines
+func padRow(label, value string, width int) string {
+	gap := width - len(label) - len(value)
+	if gap >= 1 {
+		return label + strings.Repeat(" ", gap) + value
+	}
+	lines := []string{label + ":"}
+	for len(value) > width {
+		lines = append(lines, value[:width])
+		value = value[width:]
+	}
+	lines = append(lines, strings.Repeat(" ", width-len(value))+value)
+	return strings.Join(lines, "\n")
+}
+
+// RenderReceiptText lays a receipt out as plain text for a thermal printer of the given width
+func RenderReceiptText(rc *models.PaymentReceipt, width int) string {
+	if width < ReceiptWidth58mm {
+		width = ReceiptWidth58mm
+	}
+	rule := strings.Repeat("-", width)
+
+	var b strings.Builder
+	b.WriteString(centreText(strings.ToUpper(rc.Company.Name), width) + "\n")
+	b.WriteString(centreText(rc.Company.CompanyCode, width) + "\n")
+	b.WriteString(rule + "\n")
+	b.WriteString(centreText("PAYMENT RECEIPT", width) + "\n")
+	b.WriteString(rule + "\n")
+	for _, row := range receiptFields(rc) {
+		b.WriteString(padRow(row[0], row[1], width) + "\n")
+	}
+	b.WriteString(rule + "\n")
+	b.WriteString(padRow("AMOUNT", fmt.Sprintf("%.2f", rc.Amount), width) + "\n")
+	b.WriteString(rule + "\n")
+	if rc.Remark != "" {
+		b.WriteString(rc.Remark + "\n")
+	}
+	b.WriteString(centreText("Thank you!", width) + "\n\n\n")
+	return b.String()
+}
+
+// RenderReceiptPDF writes a receipt to w as an A5 PDF
+func RenderReceiptPDF(w io.Writer, rc *models.PaymentReceipt) error {
+	pdf := gofpdf.New("P", "mm", "A5", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle("Receipt "+rc.ReceiptNumber, true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 7, tr(rc.Company.Name), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 5, tr(rc.Company.CompanyCode), "", 1, "C", false, 0, "")
+	pdf.Ln(3)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(0, 7, "Payment Receipt", "TB", 1, "C", false, 0, "")
+	pdf.Ln(3)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, row := range receiptFields(rc) {
+		pdf.CellFormat(35, 6, tr(row[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, tr(row[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(3)
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(35, 8, "Amount", "TB", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 8, fmt.Sprintf("%.2f", rc.Amount), "TB", 1, "R", false, 0, "")
+
+	if rc.Remark != "" {
+		pdf.Ln(3)
+		pdf.SetFont("Helvetica", "I", 9)
+		pdf.MultiCell(0, 5, tr(rc.Remark), "", "L", false)
+	}
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.CellFormat(0, 5, tr("Issued "+rc.IssuedAt.Format("02 Jan 2006 15:04")), "", 1, "C", false, 0, "")
+	return pdf.Output(w)
+}
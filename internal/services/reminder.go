@@ -2,11 +2,17 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
 	"renting/internal/models"
 	"renting/internal/repositories"
+	"strings"
 	"time"
 )
 
+var reminderTypeCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
 type ReminderService struct {
 	reminderRepo *repositories.ReminderRepository
 }
@@ -17,70 +23,229 @@ func NewReminderService(reminderRepo *repositories.ReminderRepository) *Reminder
 	}
 }
 
+// validateReminderType checks the type against the built-in and company-defined reminder types
+func (s *ReminderService) validateReminderType(ctx context.Context, companyID int, reminderType models.ReminderType) error {
+	exists, err := s.reminderRepo.ReminderTypeExists(ctx, companyID, string(reminderType))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("unknown reminder type: %s", reminderType)
+	}
+	return nil
+}
+
+func validateLeadTimes(leadTimes []int64) error {
+	for _, lead := range leadTimes {
+		if lead < 0 {
+			return errors.New("lead_time_days must not be negative")
+		}
+	}
+	return nil
+}
+
 // CreateReminder creates a new reminder
-func (s *ReminderService) CreateReminder(ctx context.Context, reminder *models.Reminder) error {
-	// Set initial next_due_date to start_date
-	reminder.NextDueDate = reminder.StartDate
+func (s *ReminderService) CreateReminder(ctx context.Context, companyID int, reminder *models.Reminder) error {
+	if err := s.validateReminderType(ctx, companyID, reminder.Type); err != nil {
+		return err
+	}
+	if len(reminder.LeadTimeDays) == 0 {
+		reminder.LeadTimeDays = models.DefaultReminderLeadTimes
+	}
+	if err := validateLeadTimes(reminder.LeadTimeDays); err != nil {
+		return err
+	}
 	return s.reminderRepo.CreateReminder(ctx, reminder)
 }
 
-// AcknowledgeReminder acknowledges a reminder, updates the start date to now, and calculates the next due date
-func (s *ReminderService) AcknowledgeReminder(ctx context.Context, reminderID, userID int) error {
-	// Get the reminder
-	reminder, err := s.reminderRepo.GetReminderByID(ctx, reminderID)
-	if err != nil {
-		return err
+// AcknowledgeReminder records what was done for the current due date and advances the reminder
+func (s *ReminderService) AcknowledgeReminder(ctx context.Context, companyID, reminderID, userID int, req models.AcknowledgeReminderRequest) (*models.ReminderAcknowledgement, error) {
+	advanceMode := req.AdvanceMode
+	if advanceMode == "" {
+		advanceMode = models.ReminderAdvanceFromDueDate
+	}
+	if advanceMode != models.ReminderAdvanceFromDueDate && advanceMode != models.ReminderAdvanceFromAckDate {
+		return nil, fmt.Errorf("invalid advance_mode %q, expected %s or %s", advanceMode, models.ReminderAdvanceFromDueDate, models.ReminderAdvanceFromAckDate)
+	}
+	if req.AmountPaid != nil && *req.AmountPaid < 0 {
+		return nil, errors.New("amount_paid must not be negative")
 	}
 
-	// Current time to use for acknowledgment and new start date
-	currentTime := time.Now().UTC()
+	acknowledgedAt := time.Now().UTC()
+	if req.AcknowledgedAt != nil {
+		acknowledgedAt = req.AcknowledgedAt.UTC()
+	}
 
-	// Create acknowledgement
 	ack := &models.ReminderAcknowledgement{
-		ReminderID:     reminderID,
-		UserID:         userID,
-		AcknowledgedAt: currentTime,
+		ReminderID:      reminderID,
+		UserID:          userID,
+		AcknowledgedAt:  acknowledgedAt,
+		AmountPaid:      req.AmountPaid,
+		ReferenceNumber: req.ReferenceNumber,
+		Documents:       req.Documents,
+		Notes:           req.Notes,
+		AdvanceMode:     advanceMode,
 	}
-	if err := s.reminderRepo.CreateAcknowledgement(ctx, ack); err != nil {
-		return err
+	if err := s.reminderRepo.CreateAcknowledgement(ctx, companyID, ack, req.RecordExpense); err != nil {
+		return nil, err
 	}
+	return ack, nil
+}
 
-	// Update the start date to current time and calculate the next due date from this new start date
-	nextDueDate := s.reminderRepo.CalculateNextDueDate(reminder, currentTime)
-	return s.reminderRepo.UpdateStartDateAndNextDueDate(ctx, reminderID, currentTime, nextDueDate)
+// SnoozeReminder mutes a reminder's alerts until the given time
+func (s *ReminderService) SnoozeReminder(ctx context.Context, companyID, reminderID int, until time.Time) error {
+	if !until.After(time.Now()) {
+		return errors.New("snoozed_until must be in the future")
+	}
+	return s.reminderRepo.SnoozeReminder(ctx, companyID, reminderID, until)
+}
+
+// GetUpcomingAlerts gets reminders within their lead-time alert window
+func (s *ReminderService) GetUpcomingAlerts(ctx context.Context, companyID int, reminderType string) ([]models.ReminderAlert, error) {
+	return s.reminderRepo.GetUpcomingAlerts(ctx, companyID, reminderType)
 }
 
 // GetDueReminders gets all reminders that are due
-func (s *ReminderService) GetDueReminders(ctx context.Context, limit, offset int, reminderType string) ([]models.Reminder, error) {
-	return s.reminderRepo.GetDueReminders(ctx, limit, offset, reminderType)
+func (s *ReminderService) GetDueReminders(ctx context.Context, companyID, limit, offset int, reminderType string) ([]models.Reminder, error) {
+	return s.reminderRepo.GetDueReminders(ctx, companyID, limit, offset, reminderType)
 }
 
 // GetRemindersByVehicleID gets all reminders for a vehicle
-func (s *ReminderService) GetRemindersByVehicleID(ctx context.Context, vehicleID int) ([]models.Reminder, error) {
-	return s.reminderRepo.GetRemindersByVehicleID(ctx, vehicleID)
+func (s *ReminderService) GetRemindersByVehicleID(ctx context.Context, companyID, vehicleID int) ([]models.Reminder, error) {
+	return s.reminderRepo.GetRemindersByVehicleID(ctx, companyID, vehicleID)
 }
 
 // GetRemindersByVehicleIDWithVehicleDetails gets all reminders for a vehicle with vehicle details
-func (s *ReminderService) GetRemindersByVehicleIDWithVehicleDetails(ctx context.Context, vehicleID int) ([]models.ReminderWithVehicle, error) {
-	return s.reminderRepo.GetRemindersByVehicleIDWithVehicleDetails(ctx, vehicleID)
+func (s *ReminderService) GetRemindersByVehicleIDWithVehicleDetails(ctx context.Context, companyID, vehicleID int) ([]models.ReminderWithVehicle, error) {
+	return s.reminderRepo.GetRemindersByVehicleIDWithVehicleDetails(ctx, companyID, vehicleID)
 }
 
 // GetDueRemindersWithVehicleDetails gets all reminders that are due with vehicle details
-func (s *ReminderService) GetDueRemindersWithVehicleDetails(ctx context.Context, limit, offset int, reminderType string) ([]models.ReminderWithVehicle, error) {
-	return s.reminderRepo.GetDueRemindersWithVehicleDetails(ctx, limit, offset, reminderType)
+func (s *ReminderService) GetDueRemindersWithVehicleDetails(ctx context.Context, companyID, limit, offset int, reminderType string) ([]models.ReminderWithVehicle, error) {
+	return s.reminderRepo.GetDueRemindersWithVehicleDetails(ctx, companyID, limit, offset, reminderType)
 }
 
 // GetReminderHistory gets the acknowledgement history for a reminder
-func (s *ReminderService) GetReminderHistory(ctx context.Context, reminderID int) ([]models.ReminderAcknowledgement, error) {
+func (s *ReminderService) GetReminderHistory(ctx context.Context, companyID, reminderID int) ([]models.ReminderAcknowledgement, error) {
+	if _, err := s.reminderRepo.GetReminderByID(ctx, companyID, reminderID); err != nil {
+		return nil, err
+	}
 	return s.reminderRepo.GetAcknowledgements(ctx, reminderID)
 }
 
-// UpdateReminder updates an existing reminder
-func (s *ReminderService) UpdateReminder(ctx context.Context, reminder *models.Reminder) error {
-	return s.reminderRepo.UpdateReminder(ctx, reminder)
+// GetReminder gets a single reminder
+func (s *ReminderService) GetReminder(ctx context.Context, companyID, id int) (*models.Reminder, error) {
+	return s.reminderRepo.GetReminderByID(ctx, companyID, id)
+}
+
+// GetReminders lists reminders across the fleet
+func (s *ReminderService) GetReminders(ctx context.Context, companyID int, filter models.ReminderFilter) ([]models.ReminderWithVehicle, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.reminderRepo.GetReminders(ctx, companyID, filter)
+}
+
+// UpdateReminder applies the supplied fields to an existing reminder. Changing the schedule
+// without an explicit next_due_date recalculates it from the start date.
+func (s *ReminderService) UpdateReminder(ctx context.Context, companyID, id int, req models.UpdateReminderRequest) (*models.Reminder, error) {
+	reminder, err := s.reminderRepo.GetReminderByID(ctx, companyID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.VehicleID != nil {
+		reminder.VehicleID = *req.VehicleID
+	}
+	if req.Type != nil {
+		if err := s.validateReminderType(ctx, companyID, *req.Type); err != nil {
+			return nil, err
+		}
+		reminder.Type = *req.Type
+	}
+	if req.LeadTimeDays != nil {
+		if err := validateLeadTimes(req.LeadTimeDays); err != nil {
+			return nil, err
+		}
+		reminder.LeadTimeDays = req.LeadTimeDays
+	}
+
+	scheduleChanged := false
+	if req.StartDate != nil {
+		reminder.StartDate = *req.StartDate
+		scheduleChanged = true
+	}
+	if req.Frequency != nil {
+		reminder.Frequency = *req.Frequency
+		scheduleChanged = true
+	}
+	if req.CustomInterval != nil {
+		reminder.CustomInterval = req.CustomInterval
+		scheduleChanged = true
+	}
+	if reminder.Frequency == models.ReminderFrequencyCustom && reminder.CustomInterval == nil {
+		return nil, errors.New("custom_interval is required for custom frequency")
+	}
+
+	if req.NextDueDate != nil {
+		reminder.NextDueDate = *req.NextDueDate
+	} else if scheduleChanged {
+		reminder.NextDueDate = s.reminderRepo.CalculateNextDueDate(reminder, reminder.StartDate)
+	}
+	reminder.UpdatedAt = time.Now().UTC()
+
+	if err := s.reminderRepo.UpdateReminder(ctx, reminder); err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
+
+// DeleteReminder permanently deletes a reminder and its acknowledgement history
+func (s *ReminderService) DeleteReminder(ctx context.Context, companyID, id int) error {
+	return s.reminderRepo.DeleteReminder(ctx, companyID, id)
+}
+
+// GetReminderTypes lists the reminder types available to a company
+func (s *ReminderService) GetReminderTypes(ctx context.Context, companyID int) ([]models.ReminderTypeDefinition, error) {
+	return s.reminderRepo.GetReminderTypes(ctx, companyID)
+}
+
+// CreateReminderType adds a company-defined reminder type such as pollution_check or route_permit
+func (s *ReminderService) CreateReminderType(ctx context.Context, companyID int, req models.ReminderTypeRequest) (*models.ReminderTypeDefinition, error) {
+	code := req.Code
+	if code == "" {
+		code = req.Name
+	}
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.Join(strings.Fields(code), "_")
+	if !reminderTypeCodePattern.MatchString(code) {
+		return nil, errors.New("code may only contain lowercase letters, digits and underscores")
+	}
+
+	exists, err := s.reminderRepo.ReminderTypeExists(ctx, companyID, code)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("reminder type %s already exists", code)
+	}
+
+	reminderType := &models.ReminderTypeDefinition{
+		CompanyID:   &companyID,
+		Code:        code,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+	}
+	if err := s.reminderRepo.CreateReminderType(ctx, reminderType); err != nil {
+		return nil, err
+	}
+	return reminderType, nil
 }
 
-// DeleteReminder soft deletes a reminder
-func (s *ReminderService) DeleteReminder(ctx context.Context, id int) error {
-	return s.reminderRepo.DeleteReminder(ctx, id)
+// DeleteReminderType removes an unused company-defined reminder type
+func (s *ReminderService) DeleteReminderType(ctx context.Context, companyID, typeID int) error {
+	return s.reminderRepo.DeleteReminderType(ctx, companyID, typeID)
 }
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"sort"
+	"time"
+)
+
+// maxDailyBuckets caps day-by-day series so a wide custom range cannot explode the response
+const maxDailyBuckets = 366
+
+var (
+	ErrInvalidReportRange = errors.New("invalid report range")
+	ErrVehicleNotInRange  = errors.New("vehicle not found or not in the fleet during this period")
+)
+
+type ReportService struct {
+	repo *repositories.ReportRepository
+}
+
+func NewReportService(repo *repositories.ReportRepository) *ReportService {
+	return &ReportService{repo: repo}
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// netMargin is net profit as a percentage of revenue
+func netMargin(net, revenue float64) float64 {
+	if revenue == 0 {
+		return 0
+	}
+	return round2(net / revenue * 100)
+}
+
+// resolveReportRange turns a day/month/year/custom request into an inclusive date range
+func resolveReportRange(req models.ReportRequest) (models.ReportRange, error) {
+	now := time.Now()
+	ref := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if req.Date != "" {
+		date, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return models.ReportRange{}, fmt.Errorf("invalid date format, use YYYY-MM-DD: %v", err)
+		}
+		ref = date
+	}
+
+	rng := models.ReportRange{Period: req.Period}
+	switch req.Period {
+	case "day", "daily":
+		rng.Period = "day"
+		rng.StartDate, rng.EndDate = ref, ref
+	case "", "month", "monthly":
+		rng.Period = "month"
+		rng.StartDate = time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, time.UTC)
+		rng.EndDate = rng.StartDate.AddDate(0, 1, -1)
+	case "year", "yearly":
+		rng.Period = "year"
+		rng.StartDate = time.Date(ref.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		rng.EndDate = time.Date(ref.Year(), 12, 31, 0, 0, 0, 0, time.UTC)
+	case "custom":
+		if req.StartDate == "" || req.EndDate == "" {
+			return rng, errors.New("start_date and end_date are required for a custom period")
+		}
+		start, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return rng, fmt.Errorf("invalid start_date format, use YYYY-MM-DD: %v", err)
+		}
+		end, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return rng, fmt.Errorf("invalid end_date format, use YYYY-MM-DD: %v", err)
+		}
+		if end.Before(start) {
+			return rng, errors.New("end_date must not be before start_date")
+		}
+		rng.StartDate, rng.EndDate = start, end
+	default:
+		return rng, fmt.Errorf("invalid period: %s", req.Period)
+	}
+
+	days := int(rng.EndDate.Sub(rng.StartDate).Hours()/24) + 1
+	switch req.GroupBy {
+	case "":
+		switch {
+		case rng.Period == "year":
+			rng.GroupBy = "month"
+		case days <= 62:
+			rng.GroupBy = "day"
+		case days <= 3*366:
+			rng.GroupBy = "month"
+		default:
+			rng.GroupBy = "year"
+		}
+	case "day", "month", "year":
+		rng.GroupBy = req.GroupBy
+	default:
+		return rng, fmt.Errorf("invalid group_by: %s", req.GroupBy)
+	}
+	if rng.GroupBy == "day" && days > maxDailyBuckets {
+		return rng, fmt.Errorf("group_by=day supports at most %d days", maxDailyBuckets)
+	}
+	return rng, nil
+}
+
+// GetProfitLoss sets recognised revenue against expenses, overall and per vehicle and vehicle type
+func (s *ReportService) GetProfitLoss(companyID int, req models.ReportRequest) (*models.ProfitLossReport, error) {
+	rng, err := resolveReportRange(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReportRange, err)
+	}
+
+	revenue, err := s.repo.GetRecognisedRevenue(companyID, rng.StartDate, rng.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	byType, err := s.repo.GetExpensesByType(companyID, rng.StartDate, rng.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	series, err := s.repo.GetProfitLossSeries(companyID, rng)
+	if err != nil {
+		return nil, err
+	}
+	vehicles, err := s.repo.GetVehicleProfits(companyID, rng.StartDate, rng.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var expenses float64
+	for _, t := range byType {
+		expenses += t.Amount
+	}
+
+	report := &models.ProfitLossReport{
+		Range:          rng,
+		Revenue:        round2(revenue),
+		Expenses:       round2(expenses),
+		ExpensesByType: byType,
+		Series:         series,
+		ByVehicle:      vehicles,
+	}
+	report.NetProfit = round2(report.Revenue - report.Expenses)
+	report.NetMargin = netMargin(report.NetProfit, report.Revenue)
+
+	// Roll vehicles up into their types
+	types := map[int]*models.VehicleTypeProfit{}
+	var assigned float64
+	for i := range report.ByVehicle {
+		v := &report.ByVehicle[i]
+		v.Revenue = round2(v.Revenue)
+		v.Expenses = round2(v.Expenses)
+		v.NetProfit = round2(v.Revenue - v.Expenses)
+		v.NetMargin = netMargin(v.NetProfit, v.Revenue)
+		assigned += v.Expenses
+
+		t, ok := types[v.VehicleTypeID]
+		if !ok {
+			t = &models.VehicleTypeProfit{VehicleTypeID: v.VehicleTypeID, VehicleTypeName: v.VehicleTypeName}
+			types[v.VehicleTypeID] = t
+		}
+		t.VehicleCount++
+		t.Revenue += v.Revenue
+		t.Expenses += v.Expenses
+	}
+	report.ByVehicleType = []models.VehicleTypeProfit{}
+	for _, t := range types {
+		t.Revenue = round2(t.Revenue)
+		t.Expenses = round2(t.Expenses)
+		t.NetProfit = round2(t.Revenue - t.Expenses)
+		t.NetMargin = netMargin(t.NetProfit, t.Revenue)
+		report.ByVehicleType = append(report.ByVehicleType, *t)
+	}
+	sort.Slice(report.ByVehicleType, func(i, j int) bool {
+		return report.ByVehicleType[i].NetProfit > report.ByVehicleType[j].NetProfit
+	})
+	report.UnassignedExpenses = round2(report.Expenses - assigned)
+
+	return report, nil
+}
+
+// GetCashFlow reports money received from verified payments against refunds and expenses paid
+func (s *ReportService) GetCashFlow(companyID int, req models.ReportRequest) (*models.CashFlowReport, error) {
+	rng, err := resolveReportRange(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReportRange, err)
+	}
+
+	byMethod, err := s.repo.GetCashIn(companyID, rng.StartDate, rng.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := s.repo.GetRefunds(companyID, rng.StartDate, rng.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	byType, err := s.repo.GetExpensesByType(companyID, rng.StartDate, rng.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	series, err := s.repo.GetCashFlowSeries(companyID, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	var cashIn, expenses float64
+	for _, amount := range byMethod {
+		cashIn += amount
+	}
+	for _, t := range byType {
+		expenses += t.Amount
+	}
+
+	report := &models.CashFlowReport{
+		Range:          rng,
+		CashIn:         round2(cashIn),
+		Refunds:        round2(refunds),
+		ExpensesPaid:   round2(expenses),
+		CashInByMethod: byMethod,
+		ExpensesByType: byType,
+		Series:         series,
+	}
+	report.CashOut = round2(report.Refunds + report.ExpensesPaid)
+	report.NetCashFlow = round2(report.CashIn - report.CashOut)
+	return report, nil
+}
+
+// finishVehicleAnalytics derives the ratios from the raw figures of one vehicle
+func finishVehicleAnalytics(a *models.VehicleAnalytics) {
+	a.AvailableDays = a.PeriodDays - a.DowntimeDays
+	if a.AvailableDays < 0 {
+		a.AvailableDays = 0
+	}
+	if a.RentedDays > a.AvailableDays {
+		a.RentedDays = a.AvailableDays
+	}
+	if a.AvailableDays > 0 {
+		a.Utilisation = round2(float64(a.RentedDays) / float64(a.AvailableDays) * 100)
+		a.RevenuePerAvailableDay = round2(a.Revenue / float64(a.AvailableDays))
+	}
+	if a.KmDriven > 0 {
+		a.MaintenanceCostPerKm = round2(a.MaintenanceCost / a.KmDriven)
+	}
+	a.Revenue = round2(a.Revenue)
+	a.AverageRentalDays = round2(a.AverageRentalDays)
+	a.MaintenanceCost = round2(a.MaintenanceCost)
+	a.Expenses = round2(a.Expenses)
+	a.NetContribution = round2(a.Revenue - a.Expenses)
+}
+
+// GetFleetAnalytics reports utilisation and contribution for every vehicle and the fleet as a whole
+func (s *ReportService) GetFleetAnalytics(companyID int, req models.ReportRequest) (*models.FleetAnalytics, error) {
+	rng, err := resolveReportRange(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReportRange, err)
+	}
+
+	vehicles, err := s.repo.GetVehicleAnalytics(companyID, rng.StartDate, rng.EndDate, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := &models.FleetAnalytics{Range: rng, VehicleCount: len(vehicles), Vehicles: vehicles}
+	var rentalDays float64
+	for i := range fleet.Vehicles {
+		v := &fleet.Vehicles[i]
+		finishVehicleAnalytics(v)
+		fleet.AvailableDays += v.AvailableDays
+		fleet.RentedDays += v.RentedDays
+		fleet.Revenue += v.Revenue
+		fleet.RentalCount += v.RentalCount
+		rentalDays += v.AverageRentalDays * float64(v.RentalCount)
+		fleet.KmDriven += v.KmDriven
+		fleet.MaintenanceCost += v.MaintenanceCost
+		fleet.Expenses += v.Expenses
+	}
+	if fleet.AvailableDays > 0 {
+		fleet.Utilisation = round2(float64(fleet.RentedDays) / float64(fleet.AvailableDays) * 100)
+		fleet.RevenuePerAvailableDay = round2(fleet.Revenue / float64(fleet.AvailableDays))
+	}
+	if fleet.RentalCount > 0 {
+		fleet.AverageRentalDays = round2(rentalDays / float64(fleet.RentalCount))
+	}
+	if fleet.KmDriven > 0 {
+		fleet.MaintenanceCostPerKm = round2(fleet.MaintenanceCost / fleet.KmDriven)
+	}
+	fleet.Revenue = round2(fleet.Revenue)
+	fleet.MaintenanceCost = round2(fleet.MaintenanceCost)
+	fleet.Expenses = round2(fleet.Expenses)
+	fleet.NetContribution = round2(fleet.Revenue - fleet.Expenses)
+
+	// Best earners first, so the cars worth selling sit at the bottom
+	sort.SliceStable(fleet.Vehicles, func(i, j int) bool {
+		return fleet.Vehicles[i].NetContribution > fleet.Vehicles[j].NetContribution
+	})
+	return fleet, nil
+}
+
+// GetVehicleAnalytics reports utilisation and contribution for a single vehicle
+func (s *ReportService) GetVehicleAnalytics(companyID, vehicleID int, req models.ReportRequest) (*models.VehicleAnalytics, error) {
+	rng, err := resolveReportRange(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReportRange, err)
+	}
+
+	vehicles, err := s.repo.GetVehicleAnalytics(companyID, rng.StartDate, rng.EndDate, &vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(vehicles) == 0 {
+		return nil, ErrVehicleNotInRange
+	}
+	finishVehicleAnalytics(&vehicles[0])
+	return &vehicles[0], nil
+}
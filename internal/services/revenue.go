@@ -1,7 +1,10 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"renting/internal/models"
 	"renting/internal/repositories"
 	"time"
@@ -15,9 +18,18 @@ func NewRevenueService(repo *repositories.RevenueRepository) *RevenueService {
 	return &RevenueService{repo: repo}
 }
 
+// ErrInvalidRevenueRequest marks revenue requests with a bad period, range, mode or interval
+var ErrInvalidRevenueRequest = errors.New("invalid revenue request")
+
 type RevenueResponse struct {
-	Period       string  `json:"period"`
-	TotalRevenue float64 `json:"total_revenue"`
+	Period       string                    `json:"period"`
+	StartDate    time.Time                 `json:"start_date"`
+	EndDate      time.Time                 `json:"end_date"`
+	RecognizeAt  string                    `json:"recognize_at"`
+	Interval     string                    `json:"interval"`
+	TotalRevenue float64                   `json:"total_revenue"`
+	Series       []models.RevenuePoint     `json:"series"`
+	Previous     *models.RevenueComparison `json:"previous"`
 }
 
 // MonthlyRevenue represents revenue data for a specific month
@@ -28,32 +40,118 @@ type MonthlyRevenue struct {
 
 // MobileRevenueResponse represents the response format for mobile visualization
 type MobileRevenueResponse struct {
-	Labels []string  `json:"labels"` // Month names (e.g., "Jan", "Feb")
+	Labels []string  `json:"labels"` // Month names (e.g., "Jan", "Feb"), with the year when the range spans years
+	Years  []int     `json:"years"`  // Year of each label
 	Data   []float64 `json:"data"`   // Revenue amounts
 	Total  float64   `json:"total"`  // Total revenue for the period
 }
 
-// GetRevenue fetches revenue based on the request
-func (s *RevenueService) GetRevenue(req models.RevenueRequest) (RevenueResponse, error) {
-	filter := repositories.RevenueFilter{Period: req.Period}
+// revenueRange resolves the period of a revenue request and the period before it
+func revenueRange(req models.RevenueRequest) (start, end, prevStart, prevEnd time.Time, period string, err error) {
+	if req.Period == "week" || req.Period == "weekly" {
+		ref := today()
+		if req.Date != "" {
+			if ref, err = time.Parse("2006-01-02", req.Date); err != nil {
+				return start, end, prevStart, prevEnd, "", fmt.Errorf("invalid date format, use YYYY-MM-DD: %v", err)
+			}
+		}
+		start = ref.AddDate(0, 0, -((int(ref.Weekday()) + 6) % 7))
+		end = start.AddDate(0, 0, 6)
+		return start, end, start.AddDate(0, 0, -7), start.AddDate(0, 0, -1), "week", nil
+	}
+
+	rng, err := resolveReportRange(models.ReportRequest{
+		Period:    req.Period,
+		Date:      req.Date,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	})
+	if err != nil {
+		return start, end, prevStart, prevEnd, "", err
+	}
+	start, end = rng.StartDate, rng.EndDate
+
+	switch rng.Period {
+	case "month":
+		prevStart = start.AddDate(0, -1, 0)
+	case "year":
+		prevStart = start.AddDate(-1, 0, 0)
+	default:
+		// A day or custom range is compared with the same number of days just before it
+		days := int(end.Sub(start).Hours()/24) + 1
+		prevStart = start.AddDate(0, 0, -days)
+	}
+	return start, end, prevStart, start.AddDate(0, 0, -1), rng.Period, nil
+}
 
-	// Parse date if provided
-	if req.Date != "" {
-		date, err := time.Parse("2006-01-02", req.Date)
-		if err != nil {
-			return RevenueResponse{}, fmt.Errorf("invalid date format: %v", err)
+// GetRevenue fetches revenue for a day, week, month, year or custom range under the requested
+// recognition mode, with a day or week time series and the previous period for comparison
+func (s *RevenueService) GetRevenue(companyID int, req models.RevenueRequest) (RevenueResponse, error) {
+	start, end, prevStart, prevEnd, period, err := revenueRange(req)
+	if err != nil {
+		return RevenueResponse{}, fmt.Errorf("%w: %v", ErrInvalidRevenueRequest, err)
+	}
+
+	recognizeAt := req.RecognizeAt
+	switch recognizeAt {
+	case "":
+		recognizeAt = models.RecognizeAtProrated
+	case models.RecognizeAtProrated, models.RecognizeAtStart, models.RecognizeAtEnd:
+	default:
+		return RevenueResponse{}, fmt.Errorf("%w: invalid recognize_at: %s", ErrInvalidRevenueRequest, recognizeAt)
+	}
+
+	days := int(end.Sub(start).Hours()/24) + 1
+	interval := req.Interval
+	switch interval {
+	case "":
+		interval = "day"
+		if days > 62 {
+			interval = "week"
 		}
-		filter.Date = date
+	case "day", "week":
+	default:
+		return RevenueResponse{}, fmt.Errorf("%w: invalid interval: %s", ErrInvalidRevenueRequest, interval)
+	}
+	if (interval == "day" && days > maxDailyBuckets) || (interval == "week" && days > 7*maxDailyBuckets) {
+		return RevenueResponse{}, fmt.Errorf("%w: range too long for interval=%s", ErrInvalidRevenueRequest, interval)
 	}
 
-	total, err := s.repo.GetTotalRevenue(filter)
+	filter := repositories.RevenueFilter{CompanyID: companyID, StartDate: start, EndDate: end, RecognizeAt: recognizeAt}
+	total, err := s.repo.GetRevenueBetween(filter)
+	if err != nil {
+		return RevenueResponse{}, err
+	}
+	series, err := s.repo.GetRevenueSeries(filter, interval)
+	if err != nil {
+		return RevenueResponse{}, err
+	}
+	filter.StartDate, filter.EndDate = prevStart, prevEnd
+	previousTotal, err := s.repo.GetRevenueBetween(filter)
 	if err != nil {
 		return RevenueResponse{}, err
 	}
 
+	previous := &models.RevenueComparison{
+		StartDate:    prevStart,
+		EndDate:      prevEnd,
+		TotalRevenue: previousTotal,
+		Change:       round2(total - previousTotal),
+	}
+	if previousTotal != 0 {
+		percent := round2((total - previousTotal) / math.Abs(previousTotal) * 100)
+		previous.ChangePercent = &percent
+	}
+
 	return RevenueResponse{
-		Period:       req.Period,
+		Period:       period,
+		StartDate:    start,
+		EndDate:      end,
+		RecognizeAt:  recognizeAt,
+		Interval:     interval,
 		TotalRevenue: total,
+		Series:       series,
+		Previous:     previous,
 	}, nil
 }
 
@@ -82,19 +180,61 @@ func (s *RevenueService) GetMobileRevenueVisualization(startDate, endDate time.T
 
 	// Format the data for mobile consumption
 	labels := make([]string, len(monthlyData))
+	years := make([]int, len(monthlyData))
 	data := make([]float64, len(monthlyData))
 	var total float64
 
+	// "Jan" alone is ambiguous once the range crosses a year boundary
+	labelFormat := "Jan"
+	if startDate.Year() != endDate.Year() {
+		labelFormat = "Jan 2006"
+	}
+
 	for i, mr := range monthlyData {
-		// Format month as short name (e.g., "Jan", "Feb")
-		labels[i] = mr.Month.Format("Jan")
+		labels[i] = mr.Month.Format(labelFormat)
+		years[i] = mr.Month.Year()
 		data[i] = mr.Amount
 		total += mr.Amount
 	}
 
 	return MobileRevenueResponse{
 		Labels: labels,
+		Years:  years,
 		Data:   data,
 		Total:  total,
 	}, nil
 }
+
+// GetRecognitionSchedule returns how a sale's revenue is spread over the rental days
+func (s *RevenueService) GetRecognitionSchedule(saleID int) (*models.RevenueRecognitionSchedule, error) {
+	return s.repo.GetRecognitionSchedule(saleID)
+}
+
+// RecomputeSaleRecognition rebuilds one sale's schedule from its current amounts and dates
+func (s *RevenueService) RecomputeSaleRecognition(saleID int) (*models.RevenueRecognitionSchedule, error) {
+	if err := s.repo.RecomputeSaleRecognition(saleID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRecognitionSchedule(saleID)
+}
+
+// RecomputeAllRecognition rebuilds the schedule of every sale, e.g. after a backfill. A sale
+// that fails is logged and skipped so one bad row does not stop the run.
+func (s *RevenueService) RecomputeAllRecognition() (int, []int, error) {
+	saleIDs, err := s.repo.GetSaleIDs()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	recomputed := 0
+	failed := []int{}
+	for _, saleID := range saleIDs {
+		if err := s.repo.RecomputeSaleRecognition(saleID); err != nil {
+			log.Printf("[ERROR] Revenue recognition for sale %d: %v", saleID, err)
+			failed = append(failed, saleID)
+			continue
+		}
+		recomputed++
+	}
+	return recomputed, failed, nil
+}
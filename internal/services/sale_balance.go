@@ -0,0 +1,47 @@
+package services
+
+import (
+	"log"
+	"renting/internal/models"
+	"renting/internal/repositories"
+)
+
+type SaleBalanceService struct {
+	repo *repositories.SaleBalanceRepository
+}
+
+func NewSaleBalanceService(repo *repositories.SaleBalanceRepository) *SaleBalanceService {
+	return &SaleBalanceService{repo: repo}
+}
+
+func (s *SaleBalanceService) GetSaleBalance(companyID, saleID int) (*models.SaleBalance, error) {
+	return s.repo.GetSaleBalance(companyID, saleID)
+}
+
+// RecomputeAll repairs the payment status of every sale, or of one company's sales when
+// companyID is not 0, and returns how many were checked and which changed. A sale that fails is
+// logged and skipped so one bad row does not stop the run.
+func (s *SaleBalanceService) RecomputeAll(companyID int) (int, []int, []int, error) {
+	saleIDs, err := s.repo.GetSaleIDs(companyID)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	checked := 0
+	changed := []int{}
+	failed := []int{}
+	for _, saleID := range saleIDs {
+		balance, updated, err := s.repo.RecomputeSaleBalance(saleID)
+		if err != nil {
+			log.Printf("[ERROR] Balance of sale %d: %v", saleID, err)
+			failed = append(failed, saleID)
+			continue
+		}
+		checked++
+		if updated {
+			log.Printf("Sale %d payment status is now %s", saleID, balance.PaymentStatus)
+			changed = append(changed, saleID)
+		}
+	}
+	return checked, changed, failed, nil
+}
@@ -19,7 +19,7 @@ func NewSaleChargeService(saleRepo *repositories.SaleChargeRepository) *SaleChar
 func (s *SaleChargeService) UpdateSalesCharge(chargeID int, charge models.SalesCharge) error {
 	err := s.saleRepo.UpdateSalesCharge(chargeID, charge)
 	if err != nil {
-		return fmt.Errorf("failed to update sales charge: %v", err)
+		return fmt.Errorf("failed to update sales charge: %w", err)
 	}
 	return nil
 }
@@ -31,3 +31,11 @@ func (s *SaleChargeService) DeleteSalesCharge(chargeID int) error {
 	}
 	return nil
 }
+
+func (s *SaleChargeService) AddSalesCharge(saleID int, charge models.SalesCharge) error {
+	err := s.saleRepo.AddSalesCharge(saleID, charge)
+	if err != nil {
+		return fmt.Errorf("failed to add sales charge: %v", err)
+	}
+	return nil
+}
@@ -19,7 +19,7 @@ func (s *SaleService) CreateSale(sale models.Sale) (models.SaleSubmitResponse, e
 	// Call the repository method to create the sale
 	response, err := s.saleRepo.CreateSale(sale)
 	if err != nil {
-		return models.SaleSubmitResponse{}, fmt.Errorf("failed to create sale: %v", err)
+		return models.SaleSubmitResponse{}, fmt.Errorf("failed to create sale: %w", err)
 	}
 
 	// Return the response from the repository
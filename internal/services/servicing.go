@@ -0,0 +1,38 @@
+package services
+
+import (
+	"renting/internal/models"
+	"renting/internal/repositories"
+)
+
+type ServicingService struct {
+	repo *repositories.ServicingRepository
+}
+
+func NewServicingService(repo *repositories.ServicingRepository) *ServicingService {
+	return &ServicingService{repo: repo}
+}
+
+func (s *ServicingService) GetIntervals(companyID int) ([]models.ServicingInterval, error) {
+	return s.repo.GetIntervals(companyID)
+}
+
+func (s *ServicingService) UpdateInterval(companyID, vehicleTypeID int, req models.ServicingIntervalRequest) (*models.ServicingInterval, error) {
+	return s.repo.UpsertInterval(companyID, vehicleTypeID, req)
+}
+
+func (s *ServicingService) GetServicingStatuses(dueOnly bool) ([]models.VehicleServicingStatus, error) {
+	return s.repo.GetServicingStatuses(dueOnly)
+}
+
+func (s *ServicingService) GetVehicleServicing(vehicleID int) (*models.VehicleServicingDetail, error) {
+	return s.repo.GetVehicleServicing(vehicleID)
+}
+
+func (s *ServicingService) StartServicing(vehicleID int) error {
+	return s.repo.StartServicing(vehicleID)
+}
+
+func (s *ServicingService) MarkServiced(vehicleID, userID int, req models.MarkServicedRequest) (*models.ServicingRecord, error) {
+	return s.repo.MarkServiced(vehicleID, userID, req)
+}
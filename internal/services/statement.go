@@ -2,22 +2,298 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"renting/internal/models"
 	"renting/internal/repositories"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// ErrInvalidAgeingRequest marks an ageing request with a bad group_by or bucket
+var ErrInvalidAgeingRequest = errors.New("invalid ageing request")
+
+// ErrCustomerRequired is returned when a customer statement names neither a phone nor a name
+var ErrCustomerRequired = errors.New("customer phone or name is required")
+
 type StatementService interface {
 	GetOutstandingStatements(ctx context.Context, filters map[string]string, offset, limit int) ([]*models.Statement, error)
+	GetAgeingReport(ctx context.Context, companyID int, asOf time.Time, groupBy string) (*models.AgeingReport, error)
+	GetAgeingSales(ctx context.Context, companyID int, asOf time.Time, filter models.AgeingFilter) ([]*models.AgeingSale, error)
+	GetSaleStatement(ctx context.Context, companyID, saleID int) (*models.AccountStatement, error)
+	GetCustomerStatement(ctx context.Context, companyID int, customerPhone, customerName string) (*models.AccountStatement, error)
 }
 
 type statementService struct {
-	repo repositories.StatementRepository
+	repo     repositories.StatementRepository
+	settings *SystemSettingsService
 }
 
-func NewStatementService(repo repositories.StatementRepository) StatementService {
-	return &statementService{repo: repo}
+func NewStatementService(repo repositories.StatementRepository, settings *SystemSettingsService) StatementService {
+	return &statementService{repo: repo, settings: settings}
 }
 
 func (s *statementService) GetOutstandingStatements(ctx context.Context, filters map[string]string, offset, limit int) ([]*models.Statement, error) {
 	return s.repo.GetOutstandingStatements(ctx, filters, offset, limit)
 }
+
+// ageSale works out how long a balance has been owed since the vehicle came back, and how long
+// it has been overdue once the grace period after the return ran out
+func ageSale(sale *models.AgeingSale, asOf time.Time, grace time.Duration) {
+	returned := sale.ReturnDate
+	if sale.ActualDateOfReturn != nil {
+		returned = sale.ActualDateOfReturn
+	}
+	sale.DaysSinceReturn = 0
+	sale.DaysOverdue = 0
+	sale.DueDate = nil
+	if returned != nil {
+		if days := int(asOf.Sub(dateOnly(*returned)).Hours() / 24); days > 0 {
+			sale.DaysSinceReturn = days
+		}
+		due := dateOnly(*returned).Add(grace)
+		sale.DueDate = &due
+		if days := int(asOf.Sub(due).Hours() / 24); days > 0 {
+			sale.DaysOverdue = days
+		}
+	}
+
+	switch days := sale.DaysSinceReturn; {
+	case days == 0:
+		sale.Bucket = models.AgeingCurrent
+	case days <= 30:
+		sale.Bucket = models.Ageing1To30
+	case days <= 60:
+		sale.Bucket = models.Ageing31To60
+	case days <= 90:
+		sale.Bucket = models.Ageing61To90
+	default:
+		sale.Bucket = models.AgeingOver90
+	}
+}
+
+func addToBuckets(b *models.AgeingBuckets, bucket string, amount float64) {
+	switch bucket {
+	case models.AgeingCurrent:
+		b.Current += amount
+	case models.Ageing1To30:
+		b.Days1To30 += amount
+	case models.Ageing31To60:
+		b.Days31To60 += amount
+	case models.Ageing61To90:
+		b.Days61To90 += amount
+	default:
+		b.Over90 += amount
+	}
+	b.Total += amount
+}
+
+func roundBuckets(b *models.AgeingBuckets) {
+	b.Current = round2(b.Current)
+	b.Days1To30 = round2(b.Days1To30)
+	b.Days31To60 = round2(b.Days31To60)
+	b.Days61To90 = round2(b.Days61To90)
+	b.Over90 = round2(b.Over90)
+	b.Total = round2(b.Total)
+}
+
+// customerKey identifies a customer by phone number, or by name when no phone was recorded
+func customerKey(sale *models.AgeingSale) string {
+	if phone := strings.TrimSpace(sale.CustomerPhone); phone != "" {
+		return phone
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(sale.CustomerName))
+}
+
+// agedBalances ages the company's outstanding balances, each falling due the company's payment
+// grace period after its return date
+func (s *statementService) agedBalances(ctx context.Context, companyID int, asOf time.Time) ([]*models.AgeingSale, error) {
+	grace, err := s.settings.Duration(companyID, models.SettingPaymentGracePeriod)
+	if err != nil {
+		return nil, err
+	}
+	sales, err := s.repo.GetOutstandingBalances(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sale := range sales {
+		ageSale(sale, asOf, grace)
+	}
+	return sales, nil
+}
+
+// GetAgeingReport buckets outstanding balances by days since return, per customer or per staff
+// member who created the sale
+func (s *statementService) GetAgeingReport(ctx context.Context, companyID int, asOf time.Time, groupBy string) (*models.AgeingReport, error) {
+	if groupBy == "" {
+		groupBy = "customer"
+	}
+	if groupBy != "customer" && groupBy != "staff" {
+		return nil, fmt.Errorf("%w: group_by must be customer or staff", ErrInvalidAgeingRequest)
+	}
+	asOf = dateOnly(asOf)
+
+	sales, err := s.agedBalances(ctx, companyID, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := s.settings.String(companyID, models.SettingCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.AgeingReport{AsOf: asOf, GroupBy: groupBy, Currency: currency, Groups: []models.AgeingGroup{}}
+	index := map[string]int{}
+	for _, sale := range sales {
+		var key string
+		if groupBy == "staff" {
+			key = strconv.Itoa(sale.UserID)
+		} else {
+			key = customerKey(sale)
+		}
+		i, ok := index[key]
+		if !ok {
+			group := models.AgeingGroup{Key: key}
+			if groupBy == "staff" {
+				userID := sale.UserID
+				group.UserID = &userID
+				group.StaffName = sale.StaffName
+			} else {
+				group.CustomerName = sale.CustomerName
+				group.CustomerPhone = sale.CustomerPhone
+			}
+			report.Groups = append(report.Groups, group)
+			i = len(report.Groups) - 1
+			index[key] = i
+		}
+		report.Groups[i].SaleCount++
+		addToBuckets(&report.Groups[i].Buckets, sale.Bucket, sale.OutstandingBalance)
+		addToBuckets(&report.Totals, sale.Bucket, sale.OutstandingBalance)
+	}
+
+	for i := range report.Groups {
+		roundBuckets(&report.Groups[i].Buckets)
+	}
+	roundBuckets(&report.Totals)
+	// Largest debts first
+	sort.SliceStable(report.Groups, func(a, b int) bool {
+		return report.Groups[a].Buckets.Total > report.Groups[b].Buckets.Total
+	})
+	return report, nil
+}
+
+// GetAgeingSales lists the sales behind an ageing figure, oldest debt first
+func (s *statementService) GetAgeingSales(ctx context.Context, companyID int, asOf time.Time, filter models.AgeingFilter) ([]*models.AgeingSale, error) {
+	switch filter.Bucket {
+	case "", models.AgeingCurrent, models.Ageing1To30, models.Ageing31To60, models.Ageing61To90, models.AgeingOver90:
+	default:
+		return nil, fmt.Errorf("%w: invalid bucket: %s", ErrInvalidAgeingRequest, filter.Bucket)
+	}
+
+	sales, err := s.agedBalances(ctx, companyID, dateOnly(asOf))
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []*models.AgeingSale{}
+	for _, sale := range sales {
+		if filter.Bucket != "" && sale.Bucket != filter.Bucket {
+			continue
+		}
+		if filter.Key != "" && customerKey(sale) != filter.Key {
+			continue
+		}
+		if filter.UserID != nil && sale.UserID != *filter.UserID {
+			continue
+		}
+		matched = append(matched, sale)
+	}
+	sort.SliceStable(matched, func(a, b int) bool {
+		return matched[a].DaysSinceReturn > matched[b].DaysSinceReturn
+	})
+	return matched, nil
+}
+
+// buildAccountStatement loads the lines of the given sales and works out the running balance
+func (s *statementService) buildAccountStatement(ctx context.Context, companyID int, sales []models.AccountSale) (*models.AccountStatement, error) {
+	company, err := s.repo.GetStatementCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if company.Currency, err = s.settings.String(companyID, models.SettingCurrency); err != nil {
+		return nil, err
+	}
+
+	saleIDs := make([]int, len(sales))
+	for i, sale := range sales {
+		saleIDs[i] = sale.SaleID
+	}
+	lines, err := s.repo.GetAccountLines(ctx, saleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &models.AccountStatement{
+		Company:       *company,
+		CustomerName:  sales[0].CustomerName,
+		CustomerPhone: sales[0].CustomerPhone,
+		Sales:         sales,
+		GeneratedAt:   time.Now(),
+		Lines:         lines,
+	}
+	balance := 0.0
+	for i := range st.Lines {
+		line := &st.Lines[i]
+		balance += line.Debit - line.Credit
+		line.Balance = round2(balance)
+		st.TotalDebits += line.Debit
+		st.TotalCredits += line.Credit
+	}
+	st.TotalDebits = round2(st.TotalDebits)
+	st.TotalCredits = round2(st.TotalCredits)
+	st.ClosingBalance = round2(balance)
+	return st, nil
+}
+
+// GetSaleStatement builds the account statement of a single sale
+func (s *statementService) GetSaleStatement(ctx context.Context, companyID, saleID int) (*models.AccountStatement, error) {
+	sales, err := s.repo.FindAccountSales(ctx, companyID, &saleID, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(sales) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	st, err := s.buildAccountStatement(ctx, companyID, sales)
+	if err != nil {
+		return nil, err
+	}
+	st.SaleID = &saleID
+	return st, nil
+}
+
+// GetCustomerStatement builds one statement across every sale of a customer, matched by phone
+// number when given, otherwise by name
+func (s *statementService) GetCustomerStatement(ctx context.Context, companyID int, customerPhone, customerName string) (*models.AccountStatement, error) {
+	customerPhone = strings.TrimSpace(customerPhone)
+	customerName = strings.TrimSpace(customerName)
+	if customerPhone == "" && customerName == "" {
+		return nil, ErrCustomerRequired
+	}
+	if customerPhone != "" {
+		customerName = ""
+	}
+
+	sales, err := s.repo.FindAccountSales(ctx, companyID, nil, customerPhone, customerName)
+	if err != nil {
+		return nil, err
+	}
+	if len(sales) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return s.buildAccountStatement(ctx, companyID, sales)
+}
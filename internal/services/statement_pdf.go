@@ -0,0 +1,150 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"renting/internal/models"
+	"text/template"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// StatementColumn is one column of the statement table
+type StatementColumn struct {
+	Title string
+	Width float64 // mm
+	Align string  // gofpdf alignment: L, C or R
+}
+
+// StatementTemplate lays out a statement PDF. Intro and Footer are text/templates executed with
+// the *models.AccountStatement, so wording can change without touching the renderer.
+type StatementTemplate struct {
+	Title   string
+	Intro   string
+	Footer  string
+	Columns []StatementColumn
+}
+
+// DefaultStatementTemplate is the layout used for statement downloads
+var DefaultStatementTemplate = StatementTemplate{
+	Title: "Statement of Account",
+	Intro: `Customer: {{.CustomerName}}{{if .CustomerPhone}} ({{.CustomerPhone}}){{end}}
+{{if .SaleID}}Sale: #{{.SaleID}}{{else}}Sales: {{len .Sales}}{{end}}
+Date: {{.GeneratedAt.Format "02 Jan 2006"}}`,
+	Footer: `{{if gt .ClosingBalance 0.0}}Amount due: {{.Company.Currency}} {{printf "%.2f" .ClosingBalance}}. Please quote the sale number with your payment.{{else if lt .ClosingBalance 0.0}}Credit on account: {{.Company.Currency}} {{printf "%.2f" (neg .ClosingBalance)}}.{{else}}Your account is settled. Thank you.{{end}}`,
+	Columns: []StatementColumn{
+		{Title: "Date", Width: 24, Align: "L"},
+		{Title: "Description", Width: 82, Align: "L"},
+		{Title: "Debit", Width: 25, Align: "R"},
+		{Title: "Credit", Width: 25, Align: "R"},
+		{Title: "Balance", Width: 26, Align: "R"},
+	},
+}
+
+var statementFuncs = template.FuncMap{
+	"neg": func(v float64) float64 { return -v },
+}
+
+func executeStatementText(name, text string, st *models.AccountStatement) (string, error) {
+	tpl, err := template.New(name).Funcs(statementFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse statement %s template: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, st); err != nil {
+		return "", fmt.Errorf("failed to execute statement %s template: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+func formatAmount(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// RenderStatementPDF writes the statement to w as an A4 PDF using the given template
+func RenderStatementPDF(w io.Writer, st *models.AccountStatement, layout StatementTemplate) error {
+	intro, err := executeStatementText("intro", layout.Intro, st)
+	if err != nil {
+		return err
+	}
+	footer, err := executeStatementText("footer", layout.Footer, st)
+	if err != nil {
+		return err
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(layout.Title, true)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+
+	// Company header
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 8, tr(st.Company.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 5, tr("Company code: "+st.Company.CompanyCode), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.CellFormat(0, 7, tr(layout.Title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr(intro), "", "L", false)
+	pdf.Ln(4)
+
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for _, col := range layout.Columns {
+			pdf.CellFormat(col.Width, 7, tr(col.Title), "1", 0, col.Align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+	header()
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottomMargin := pdf.GetMargins()
+	for _, line := range st.Lines {
+		if pdf.GetY()+6 > pageHeight-bottomMargin-10 {
+			pdf.AddPage()
+			header()
+		}
+		values := []string{
+			line.Date.Format("02 Jan 2006"),
+			line.Description,
+			formatAmount(line.Debit),
+			formatAmount(line.Credit),
+			fmt.Sprintf("%.2f", line.Balance),
+		}
+		for i, col := range layout.Columns {
+			if i >= len(values) {
+				break
+			}
+			pdf.CellFormat(col.Width, 6, tr(values[i]), "1", 0, col.Align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	// Totals row under the amount columns
+	pdf.SetFont("Helvetica", "B", 9)
+	if len(layout.Columns) >= 5 {
+		pdf.CellFormat(layout.Columns[0].Width+layout.Columns[1].Width, 7, "Total", "1", 0, "R", false, 0, "")
+		pdf.CellFormat(layout.Columns[2].Width, 7, fmt.Sprintf("%.2f", st.TotalDebits), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(layout.Columns[3].Width, 7, fmt.Sprintf("%.2f", st.TotalCredits), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(layout.Columns[4].Width, 7, fmt.Sprintf("%.2f", st.ClosingBalance), "1", 1, "R", false, 0, "")
+	}
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr(footer), "", "L", false)
+
+	return pdf.Output(w)
+}
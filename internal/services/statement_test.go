@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"renting/internal/models"
+)
+
+func TestAgeSale(t *testing.T) {
+	asOf := ymd(2024, 6, 30)
+	daysAgo := func(days int) *time.Time {
+		d := asOf.AddDate(0, 0, -days)
+		return &d
+	}
+	grace := 72 * time.Hour
+
+	tests := []struct {
+		name         string
+		returnDate   *time.Time
+		actualReturn *time.Time
+		wantDays     int
+		wantOverdue  int
+		wantBucket   string
+	}{
+		{"no return date", nil, nil, 0, 0, models.AgeingCurrent},
+		{"due back later", daysAgo(-5), nil, 0, 0, models.AgeingCurrent},
+		{"returned today", daysAgo(0), nil, 0, 0, models.AgeingCurrent},
+		{"one day", daysAgo(1), nil, 1, 0, models.Ageing1To30},
+		{"grace just over", daysAgo(4), nil, 4, 1, models.Ageing1To30},
+		{"thirty days", daysAgo(30), nil, 30, 27, models.Ageing1To30},
+		{"thirty-one days", daysAgo(31), nil, 31, 28, models.Ageing31To60},
+		{"sixty days", daysAgo(60), nil, 60, 57, models.Ageing31To60},
+		{"sixty-one days", daysAgo(61), nil, 61, 58, models.Ageing61To90},
+		{"ninety days", daysAgo(90), nil, 90, 87, models.Ageing61To90},
+		{"ninety-one days", daysAgo(91), nil, 91, 88, models.AgeingOver90},
+		{"actual return wins over the planned one", daysAgo(45), daysAgo(10), 10, 7, models.Ageing1To30},
+		{"late return ages from when it came back", daysAgo(5), daysAgo(2), 2, 0, models.Ageing1To30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sale := &models.AgeingSale{ReturnDate: tt.returnDate, ActualDateOfReturn: tt.actualReturn}
+			ageSale(sale, asOf, grace)
+			if sale.DaysSinceReturn != tt.wantDays || sale.DaysOverdue != tt.wantOverdue || sale.Bucket != tt.wantBucket {
+				t.Errorf("ageSale() = %d days (%d overdue) in %q, want %d days (%d overdue) in %q",
+					sale.DaysSinceReturn, sale.DaysOverdue, sale.Bucket, tt.wantDays, tt.wantOverdue, tt.wantBucket)
+			}
+		})
+	}
+}
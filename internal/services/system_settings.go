@@ -1,22 +1,367 @@
 package services
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
 	"renting/internal/models"
 	"renting/internal/repositories"
+	"sync"
+	"time"
 )
 
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidSetting = errors.New("invalid setting value")
+	ErrPlatformOnly   = errors.New("setting can only be changed as a platform default")
+)
+
+// settingsCacheTTL bounds how long another instance's change can go unseen; changes made through
+// this instance clear its cache straight away
+const settingsCacheTTL = 5 * time.Minute
+
+func settingLimit(v float64) *float64 {
+	return &v
+}
+
+// settingDefinitions is the schema of every setting, in the order they are listed
+var settingDefinitions = []models.SettingDefinition{
+	{Key: models.SettingEnableRegistration, Type: models.SettingTypeBoolean, Default: true, PlatformOnly: true,
+		Description: "Whether new users can sign up with the company code"},
+	{Key: models.SettingEnableLogin, Type: models.SettingTypeBoolean, Default: true, PlatformOnly: true,
+		Description: "Whether users can log in"},
+	{Key: models.SettingTaxRate, Type: models.SettingTypeNumber, Default: models.DefaultTaxRate, Min: settingLimit(0),
+		Max: settingLimit(100), Description: "VAT rate in percent of charge types without a rate of their own"},
+	{Key: models.SettingVATNumber, Type: models.SettingTypeString, Default: "", Pattern: `^([0-9]{9})?$`,
+		Description: "The company's PAN/VAT number printed on tax invoices"},
+	{Key: models.SettingCurrency, Type: models.SettingTypeString, Default: "NPR", Pattern: `^[A-Z]{3}$`,
+		Description: "ISO 4217 code of the currency amounts are in"},
+	{Key: models.SettingTimezone, Type: models.SettingTypeString, Default: models.DefaultTimezone,
+		Description: "IANA time zone that days and reports are counted in"},
+	{Key: models.SettingPaymentGracePeriod, Type: models.SettingTypeDuration, Default: "72h", Min: settingLimit(0),
+		Description: "How long after the return date an unpaid balance is not yet overdue"},
+	{Key: models.SettingLateReturnGracePeriod, Type: models.SettingTypeDuration, Default: "1h", Min: settingLimit(0),
+		Description: "How late a vehicle can come back before it counts as a late return"},
+	{Key: models.SettingServicingIntervalKm, Type: models.SettingTypeNumber, Default: float64(models.DefaultServicingIntervalKm),
+		Min: settingLimit(1), Description: "Kilometres between servicings for vehicle types without their own interval"},
+	{Key: models.SettingReceiptPrefix, Type: models.SettingTypeString, Default: "", Pattern: `^[A-Za-z0-9_-]{0,20}$`,
+		Description: "Prefix of receipt numbers; empty uses the company code"},
+	{Key: models.SettingBusinessHours, Type: models.SettingTypeJSON,
+		Default:     json.RawMessage(`{"open": "08:00", "close": "20:00"}`),
+		Description: "Opening hours shown to customers"},
+}
+
+func settingDefinition(key string) (models.SettingDefinition, error) {
+	for _, def := range settingDefinitions {
+		if def.Key == key {
+			return def, nil
+		}
+	}
+	return models.SettingDefinition{}, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+}
+
+// decodeSettingValue checks a JSON value against the setting's schema and returns it as a string,
+// float64, bool or json.RawMessage; durations stay strings
+func decodeSettingValue(def models.SettingDefinition, raw []byte) (interface{}, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, fmt.Errorf("%w: %s needs a value", ErrInvalidSetting, def.Key)
+	}
+
+	switch def.Type {
+	case models.SettingTypeString:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("%w: %s must be a string", ErrInvalidSetting, def.Key)
+		}
+		if def.Pattern != "" && !regexp.MustCompile(def.Pattern).MatchString(value) {
+			return nil, fmt.Errorf("%w: %s must match %s", ErrInvalidSetting, def.Key, def.Pattern)
+		}
+		if def.Key == models.SettingTimezone {
+			if _, err := time.LoadLocation(value); err != nil {
+				return nil, fmt.Errorf("%w: %s is not a known time zone", ErrInvalidSetting, value)
+			}
+		}
+		return value, nil
+
+	case models.SettingTypeNumber:
+		var value float64
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidSetting, def.Key)
+		}
+		if err := checkSettingLimits(def, value); err != nil {
+			return nil, err
+		}
+		return value, nil
+
+	case models.SettingTypeBoolean:
+		var value bool
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("%w: %s must be true or false", ErrInvalidSetting, def.Key)
+		}
+		return value, nil
+
+	case models.SettingTypeDuration:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("%w: %s must be a duration such as \"72h\"", ErrInvalidSetting, def.Key)
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a duration such as \"72h\"", ErrInvalidSetting, def.Key)
+		}
+		if err := checkSettingLimits(def, duration.Seconds()); err != nil {
+			return nil, err
+		}
+		return value, nil
+
+	case models.SettingTypeJSON:
+		if !json.Valid(raw) {
+			return nil, fmt.Errorf("%w: %s must be valid JSON", ErrInvalidSetting, def.Key)
+		}
+		return json.RawMessage(append([]byte(nil), raw...)), nil
+	}
+	return nil, fmt.Errorf("%w: %s has unknown type %s", ErrInvalidSetting, def.Key, def.Type)
+}
+
+func checkSettingLimits(def models.SettingDefinition, value float64) error {
+	if def.Min != nil && value < *def.Min {
+		return fmt.Errorf("%w: %s must be at least %v", ErrInvalidSetting, def.Key, *def.Min)
+	}
+	if def.Max != nil && value > *def.Max {
+		return fmt.Errorf("%w: %s must be at most %v", ErrInvalidSetting, def.Key, *def.Max)
+	}
+	return nil
+}
+
+type cachedSettings struct {
+	settings map[string]models.Setting
+	loadedAt time.Time
+}
+
+// SystemSettingsService resolves settings for a company from its own values, the platform
+// defaults and the built-in defaults, in that order, and caches the result per company
 type SystemSettingsService struct {
-	repo *repositories.SystemSettingsRepository
+	repo  *repositories.SystemSettingsRepository
+	mu    sync.RWMutex
+	cache map[int]cachedSettings // by company id; 0 holds the platform defaults alone
 }
 
 func NewSystemSettingsService(repo *repositories.SystemSettingsRepository) *SystemSettingsService {
-	return &SystemSettingsService{repo: repo}
+	return &SystemSettingsService{repo: repo, cache: map[int]cachedSettings{}}
+}
+
+func (s *SystemSettingsService) resolve(companyID int) (map[string]models.Setting, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[companyID]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < settingsCacheTTL {
+		return cached.settings, nil
+	}
+
+	var company *int
+	if companyID != 0 {
+		company = &companyID
+	}
+	stored, err := s.repo.GetSettings(company)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]models.Setting{}
+	for _, def := range settingDefinitions {
+		settings[def.Key] = models.Setting{SettingDefinition: def, Value: def.Default, Source: models.SettingSourceDefault}
+	}
+	// Platform defaults first so company values are laid over them
+	for _, platform := range []bool{true, false} {
+		for _, row := range stored {
+			if (row.CompanyID == nil) != platform {
+				continue
+			}
+			setting, known := settings[row.SettingKey]
+			if !known || (!platform && setting.PlatformOnly) {
+				continue
+			}
+			value, err := decodeSettingValue(setting.SettingDefinition, row.Value)
+			if err != nil {
+				log.Printf("[WARN] Ignoring stored setting for company %d: %v", companyID, err)
+				continue
+			}
+			updatedAt := row.UpdatedAt
+			setting.Value, setting.UpdatedAt = value, &updatedAt
+			setting.Source = models.SettingSourceCompany
+			if platform {
+				setting.Source = models.SettingSourcePlatform
+			}
+			settings[row.SettingKey] = setting
+		}
+	}
+
+	s.mu.Lock()
+	s.cache[companyID] = cachedSettings{settings: settings, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return settings, nil
+}
+
+// invalidate drops the cached settings of a company, or of every company when the platform
+// defaults change
+func (s *SystemSettingsService) invalidate(companyID *int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if companyID == nil {
+		s.cache = map[int]cachedSettings{}
+		return
+	}
+	delete(s.cache, *companyID)
+}
+
+// GetSettings lists every setting as it applies to the company; companyID 0 gives the platform
+// defaults
+func (s *SystemSettingsService) GetSettings(companyID int) ([]models.Setting, error) {
+	resolved, err := s.resolve(companyID)
+	if err != nil {
+		return nil, err
+	}
+	settings := make([]models.Setting, 0, len(settingDefinitions))
+	for _, def := range settingDefinitions {
+		settings = append(settings, resolved[def.Key])
+	}
+	return settings, nil
+}
+
+func (s *SystemSettingsService) GetSetting(companyID int, key string) (*models.Setting, error) {
+	if _, err := settingDefinition(key); err != nil {
+		return nil, err
+	}
+	resolved, err := s.resolve(companyID)
+	if err != nil {
+		return nil, err
+	}
+	setting := resolved[key]
+	return &setting, nil
+}
+
+// typedSetting fetches a setting's value after checking it has the expected type
+func (s *SystemSettingsService) typedSetting(companyID int, key, settingType string) (interface{}, error) {
+	setting, err := s.GetSetting(companyID, key)
+	if err != nil {
+		return nil, err
+	}
+	if setting.Type != settingType {
+		return nil, fmt.Errorf("setting %s is a %s, not a %s", key, setting.Type, settingType)
+	}
+	return setting.Value, nil
+}
+
+func (s *SystemSettingsService) String(companyID int, key string) (string, error) {
+	value, err := s.typedSetting(companyID, key, models.SettingTypeString)
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+func (s *SystemSettingsService) Number(companyID int, key string) (float64, error) {
+	value, err := s.typedSetting(companyID, key, models.SettingTypeNumber)
+	if err != nil {
+		return 0, err
+	}
+	return value.(float64), nil
+}
+
+func (s *SystemSettingsService) Bool(companyID int, key string) (bool, error) {
+	value, err := s.typedSetting(companyID, key, models.SettingTypeBoolean)
+	if err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+func (s *SystemSettingsService) Duration(companyID int, key string) (time.Duration, error) {
+	value, err := s.typedSetting(companyID, key, models.SettingTypeDuration)
+	if err != nil {
+		return 0, err
+	}
+	return time.ParseDuration(value.(string))
+}
+
+// SetCompanySetting gives the company its own value for a setting; platform-only settings are refused
+func (s *SystemSettingsService) SetCompanySetting(companyID, userID int, key string, raw json.RawMessage) (*models.Setting, error) {
+	def, err := settingDefinition(key)
+	if err != nil {
+		return nil, err
+	}
+	if def.PlatformOnly {
+		return nil, fmt.Errorf("%w: %s", ErrPlatformOnly, key)
+	}
+	if err := s.setSetting(&companyID, &userID, key, raw); err != nil {
+		return nil, err
+	}
+	return s.GetSetting(companyID, key)
+}
+
+// ResetCompanySetting drops the company's own value so the platform default applies again
+func (s *SystemSettingsService) ResetCompanySetting(companyID int, key string) (*models.Setting, error) {
+	if _, err := settingDefinition(key); err != nil {
+		return nil, err
+	}
+	if err := s.repo.DeleteSetting(companyID, key); err != nil {
+		return nil, err
+	}
+	s.invalidate(&companyID)
+	return s.GetSetting(companyID, key)
 }
 
-func (s *SystemSettingsService) GetSystemSettings() (*models.SystemSettingsResponse, error) {
-	return s.repo.GetSystemSettings()
+// SetPlatformDefault changes the value of a setting for every company without one of its own
+func (s *SystemSettingsService) SetPlatformDefault(key string, raw json.RawMessage) (*models.Setting, error) {
+	if err := s.setSetting(nil, nil, key, raw); err != nil {
+		return nil, err
+	}
+	return s.GetSetting(0, key)
 }
 
-func (s *SystemSettingsService) UpdateSystemSetting(key string, value bool) error {
-	return s.repo.UpdateSystemSetting(key, value)
+func (s *SystemSettingsService) setSetting(companyID, userID *int, key string, raw json.RawMessage) error {
+	def, err := settingDefinition(key)
+	if err != nil {
+		return err
+	}
+	value, err := decodeSettingValue(def, raw)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode setting %s: %v", key, err)
+	}
+	if err := s.repo.SetSetting(companyID, key, encoded, userID); err != nil {
+		return err
+	}
+	s.invalidate(companyID)
+	return nil
+}
+
+// GetSystemSettings tells the login and sign-up screens whether they are open for the company
+// with the given code; an unknown or empty code gets the platform defaults
+func (s *SystemSettingsService) GetSystemSettings(companyCode string) (*models.SystemSettingsResponse, error) {
+	companyID := 0
+	if companyCode != "" {
+		id, err := s.repo.GetCompanyIDByCode(companyCode)
+		if err != nil && !errors.Is(err, repositories.ErrCompanyNotFound) {
+			return nil, err
+		}
+		companyID = id
+	}
+
+	var response models.SystemSettingsResponse
+	var err error
+	if response.EnableRegistration, err = s.Bool(companyID, models.SettingEnableRegistration); err != nil {
+		return nil, err
+	}
+	if response.EnableLogin, err = s.Bool(companyID, models.SettingEnableLogin); err != nil {
+		return nil, err
+	}
+	return &response, nil
 }
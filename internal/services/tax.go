@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTaxRequest = errors.New("invalid tax request")
+
+// A PAN (permanent account number) is nine digits
+var panPattern = regexp.MustCompile(`^[0-9]{9}$`)
+
+// taxChargeTypes are the rate keys a company can set: the rental itself and each sales charge type
+var taxChargeTypes = map[string]bool{
+	models.TaxChargeTypeRental:   true,
+	"damage":                     true,
+	"wash":                       true,
+	"delay":                      true,
+	"discount":                   true,
+	models.ChargeTypeTransferFee: true,
+}
+
+type TaxService struct {
+	repo *repositories.TaxRepository
+}
+
+func NewTaxService(repo *repositories.TaxRepository) *TaxService {
+	return &TaxService{repo: repo}
+}
+
+func (s *TaxService) GetRates(companyID int) (*models.TaxRates, error) {
+	return s.repo.GetRates(companyID)
+}
+
+func (s *TaxService) SetRate(companyID int, req models.ChargeTaxRateRequest) (*models.ChargeTaxRate, error) {
+	chargeType := strings.ToLower(strings.TrimSpace(req.ChargeType))
+	if !taxChargeTypes[chargeType] {
+		return nil, fmt.Errorf("%w: unknown charge type %s", ErrInvalidTaxRequest, req.ChargeType)
+	}
+	return s.repo.SetRate(companyID, chargeType, *req.Rate)
+}
+
+func (s *TaxService) DeleteRate(companyID int, chargeType string) error {
+	return s.repo.DeleteRate(companyID, strings.ToLower(chargeType))
+}
+
+func (s *TaxService) IssueInvoice(companyID, userID int, req models.IssueTaxInvoiceRequest) (*models.TaxInvoice, error) {
+	req.CustomerPAN = strings.TrimSpace(req.CustomerPAN)
+	if !panPattern.MatchString(req.CustomerPAN) {
+		return nil, fmt.Errorf("%w: customer PAN must be 9 digits", ErrInvalidTaxRequest)
+	}
+	if req.CustomerName != nil {
+		name := strings.TrimSpace(*req.CustomerName)
+		req.CustomerName = &name
+	}
+	req.CustomerAddress = trimmedNote(req.CustomerAddress)
+	return s.repo.IssueInvoice(companyID, req, userID)
+}
+
+func (s *TaxService) GetInvoice(companyID, invoiceID int) (*models.TaxInvoice, error) {
+	return s.repo.GetInvoice(companyID, invoiceID)
+}
+
+func (s *TaxService) GetInvoices(companyID int, filter models.TaxInvoiceFilter) ([]models.TaxInvoice, error) {
+	if filter.Month != "" {
+		if err := validateTaxMonth(filter.Month); err != nil {
+			return nil, err
+		}
+	}
+	switch filter.Status {
+	case "", models.TaxInvoiceIssued, models.TaxInvoiceVoid:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %s", ErrInvalidTaxRequest, filter.Status)
+	}
+	return s.repo.GetInvoices(companyID, filter)
+}
+
+func (s *TaxService) VoidInvoice(companyID, invoiceID, userID int, req models.VoidTaxInvoiceRequest) (*models.TaxInvoice, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a reason is required to void an invoice", ErrInvalidTaxRequest)
+	}
+	return s.repo.VoidInvoice(companyID, invoiceID, userID, reason)
+}
+
+// GetSalesTaxReport returns the sales book of a month given as YYYY-MM, the current month when empty
+func (s *TaxService) GetSalesTaxReport(companyID int, month string) (*models.SalesTaxReport, error) {
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	}
+	if err := validateTaxMonth(month); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSalesTaxReport(companyID, month)
+}
+
+func validateTaxMonth(month string) error {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return fmt.Errorf("%w: month must be YYYY-MM", ErrInvalidTaxRequest)
+	}
+	return nil
+}
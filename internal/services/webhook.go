@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"renting/internal/models"
+	"renting/internal/repositories"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	webhookBatchSize    = 20
+	webhookLease        = 2 * time.Minute
+	webhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookMaxBackoff   = 6 * time.Hour
+	webhookPollInterval = 10 * time.Second
+)
+
+type WebhookService struct {
+	repo   *repositories.WebhookRepository
+	client *http.Client
+}
+
+func NewWebhookService(repo *repositories.WebhookRepository) *WebhookService {
+	// Check the address actually dialled so a hostname that resolves to an internal
+	// address at delivery time is refused, and don't follow redirects off the endpoint.
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicWebhookIP(ip) {
+				return fmt.Errorf("webhook address %s is not public", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookService{
+		repo: repo,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// cgnatRange is the shared address space carriers use behind NAT (RFC 6598)
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicWebhookIP reports whether ip is routable on the public internet, so
+// deliveries can't be pointed at loopback, link-local (cloud metadata) or private hosts.
+func isPublicWebhookIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() &&
+		!cgnatRange.Contains(ip)
+}
+
+// validateWebhookURL only accepts https URLs whose host is, or resolves to, public addresses
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %v", err)
+	}
+	if u.Scheme != "https" {
+		return errors.New("webhook url must use https")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("webhook url must include a host")
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return errors.New("webhook url must not point at localhost")
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = net.LookupIP(host); err != nil {
+			return fmt.Errorf("could not resolve webhook host %s: %v", host, err)
+		}
+	}
+	for _, ip := range ips {
+		if !isPublicWebhookIP(ip) {
+			return fmt.Errorf("webhook host %s resolves to a non-public address", host)
+		}
+	}
+	return nil
+}
+
+func validateWebhookEventTypes(eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		valid := false
+		for _, known := range models.WebhookEventTypes {
+			if eventType == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown webhook event type: %s", eventType)
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// CreateEndpoint registers a new endpoint. A signing secret is generated when none is
+// supplied and is only returned in this response.
+func (s *WebhookService) CreateEndpoint(companyID int, req models.WebhookEndpointRequest) (*models.WebhookEndpoint, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+	endpoint := &models.WebhookEndpoint{
+		CompanyID:  companyID,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+		IsActive:   req.IsActive == nil || *req.IsActive,
+	}
+	if err := s.repo.CreateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *WebhookService) GetEndpoints(companyID int) ([]models.WebhookEndpoint, error) {
+	return s.repo.GetEndpoints(companyID)
+}
+
+func (s *WebhookService) UpdateEndpoint(companyID, endpointID int, req models.WebhookEndpointRequest) (*models.WebhookEndpoint, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+	endpoint := &models.WebhookEndpoint{
+		EndpointID: endpointID,
+		CompanyID:  companyID,
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		IsActive:   req.IsActive == nil || *req.IsActive,
+	}
+	if err := s.repo.UpdateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *WebhookService) DeleteEndpoint(companyID, endpointID int) error {
+	return s.repo.DeleteEndpoint(companyID, endpointID)
+}
+
+func (s *WebhookService) GetDeadDeliveries(companyID, limit, offset int) ([]models.WebhookDelivery, error) {
+	return s.repo.GetDeadDeliveries(companyID, limit, offset)
+}
+
+func (s *WebhookService) RetryDelivery(companyID, deliveryID int) error {
+	return s.repo.RetryDelivery(companyID, deliveryID)
+}
+
+// StartDispatcher polls the outbox in the background and delivers due events
+func (s *WebhookService) StartDispatcher() {
+	go func() {
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.DispatchPending()
+		}
+	}()
+}
+
+// DispatchPending delivers one batch of due webhook events
+func (s *WebhookService) DispatchPending() {
+	deliveries, err := s.repo.ClaimDueDeliveries(webhookBatchSize, webhookLease)
+	if err != nil {
+		log.Printf("[ERROR] Webhook dispatcher: %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if err := s.send(delivery); err != nil {
+			attempts := delivery.Attempts + 1
+			var nextAttempt *time.Time
+			if attempts < webhookMaxAttempts {
+				next := time.Now().Add(webhookBackoff(attempts))
+				nextAttempt = &next
+			}
+			log.Printf("[WARN] Webhook delivery %d to %s failed (attempt %d): %v", delivery.DeliveryID, delivery.URL, attempts, err)
+			if markErr := s.repo.MarkFailed(delivery.DeliveryID, err.Error(), nextAttempt); markErr != nil {
+				log.Printf("[ERROR] Webhook dispatcher: %v", markErr)
+			}
+			continue
+		}
+		if err := s.repo.MarkDelivered(delivery.DeliveryID); err != nil {
+			log.Printf("[ERROR] Webhook dispatcher: %v", err)
+		}
+	}
+}
+
+// webhookBackoff doubles the wait after every failed attempt, capped at webhookMaxBackoff
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>" under secret.
+// Receivers recompute it from the X-Webhook-Timestamp header and the raw body.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookService) send(delivery models.WebhookDelivery) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	// Only deliver over https; the dialer refuses non-public addresses
+	if u, err := url.Parse(delivery.URL); err != nil || u.Scheme != "https" {
+		return errors.New("webhook url must use https")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.DeliveryID))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New("endpoint responded with " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIsPublicWebhookIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.10", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicWebhookIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublicWebhookIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://93.184.216.34/hooks", false},
+		{"http://93.184.216.34/hooks", true},
+		{"https://localhost/hooks", true},
+		{"https://api.localhost/hooks", true},
+		{"https://127.0.0.1:8443/hooks", true},
+		{"https://169.254.169.254/latest/meta-data", true},
+		{"https://[::1]/hooks", true},
+		{"https://10.0.0.5/hooks", true},
+		{"ftp://93.184.216.34/hooks", true},
+	}
+	for _, tt := range tests {
+		err := validateWebhookURL(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateWebhookURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{20, webhookMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}